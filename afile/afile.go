@@ -1,6 +1,7 @@
 package afile
 
 import (
+	"strconv"
 	"strings"
 
 	"github.com/navionguy/basicwasm/ast"
@@ -9,10 +10,106 @@ import (
 	"github.com/navionguy/basicwasm/object"
 )
 
+// fileData holds the contents of the file, and my position in it
+// the contents are shared with any other handles open on the same file
+type fileData struct {
+	data   *[]byte // the file contents
+	pos    int     // current read/write position
+	record []byte  // record buffer for random access
+	curRec int     // last record read or written
+}
+
+func (fd *fileData) EOF() bool {
+	b, ok := fd.PeekByte()
+
+	// a ctrl-z also marks the end of a file
+	return !ok || (b == 0x1a)
+}
+
+func (fd *fileData) Loc() int { return fd.pos }
+func (fd *fileData) Lof() int {
+	if fd.data == nil {
+		return 0
+	}
+	return len(*fd.data)
+}
+
+func (fd *fileData) PeekByte() (byte, bool) {
+	if fd.pos >= fd.Lof() {
+		return 0, false
+	}
+
+	return (*fd.data)[fd.pos], true
+}
+
+func (fd *fileData) GetByte() (byte, bool) {
+	b, ok := fd.PeekByte()
+
+	if ok {
+		fd.pos++
+	}
+
+	return b, ok
+}
+
+func (fd *fileData) Write(bt []byte) {
+	if fd.data == nil {
+		return
+	}
+
+	// grow the file if I'm writing past the end
+	end := fd.pos + len(bt)
+	if end > len(*fd.data) {
+		*fd.data = append(*fd.data, make([]byte, end-len(*fd.data))...)
+	}
+
+	copy((*fd.data)[fd.pos:], bt)
+	fd.pos = end
+}
+
+func (fd *fileData) RecLen() int    { return len(fd.record) }
+func (fd *fileData) Record() []byte { return fd.record }
+func (fd *fileData) CurRecord() int { return fd.curRec }
+
+// GetRecord reads record rec into the record buffer
+// anything past the end of the file comes back as zeros
+func (fd *fileData) GetRecord(rec int) {
+	fd.curRec = rec
+	fd.pos = (rec - 1) * fd.RecLen()
+
+	for i := range fd.record {
+		b, ok := fd.GetByte()
+		if !ok {
+			b = 0
+		}
+		fd.record[i] = b
+	}
+
+	// leave pos at the end of the record so a short read shows up as EOF
+	fd.pos = rec * fd.RecLen()
+}
+
+// PutRecord writes the record buffer out as record rec
+// the file grows as needed to hold it
+func (fd *fileData) PutRecord(rec int) {
+	fd.curRec = rec
+	fd.pos = (rec - 1) * fd.RecLen()
+
+	// writing well past the end leaves a gap of zeros
+	if fd.pos > fd.Lof() {
+		gap := fd.pos - fd.Lof()
+		fd.pos = fd.Lof()
+		fd.Write(make([]byte, gap))
+	}
+
+	fd.Write(fd.record)
+}
+
 // sharedFile is in Shared mode
 type sharedFile struct {
 	filename   string             // the fully qualified (drive:path/filename.ext) for the file
 	accessmode gwtypes.AccessMode // access mode for the file
+	fileData
 }
 
 func (sf *sharedFile) AccessMode() gwtypes.AccessMode { return sf.accessmode }
@@ -23,6 +120,7 @@ func (sf *sharedFile) LockMode() gwtypes.LockMode     { return gwtypes.Shared }
 type lockReadFile struct {
 	filename   string             // the fully qualified (drive:path/filename.ext) for the file
 	accessmode gwtypes.AccessMode // access mode for the file
+	fileData
 }
 
 func (lrf *lockReadFile) FQFN() string                   { return lrf.filename }
@@ -33,6 +131,7 @@ func (lrf *lockReadFile) LockMode() gwtypes.LockMode     { return gwtypes.LockRe
 type lockWriteFile struct {
 	filename   string             // the fully qualified (drive:path/filename.ext) for the file
 	accessmode gwtypes.AccessMode // access mode for the file
+	fileData
 }
 
 func (lwf *lockWriteFile) FQFN() string                   { return lwf.filename }
@@ -43,6 +142,7 @@ func (lwf *lockWriteFile) LockMode() gwtypes.LockMode     { return gwtypes.LockW
 type lockReadWriteFile struct {
 	filename   string             // the fully qualified (drive:path/filename.ext) for the file
 	accessmode gwtypes.AccessMode // access mode for the file
+	fileData
 }
 
 func (lrwf *lockReadWriteFile) AccessMode() gwtypes.AccessMode { return lrwf.accessmode }
@@ -53,6 +153,7 @@ func (lrwf *lockReadWriteFile) LockMode() gwtypes.LockMode     { return gwtypes.
 type defaultFile struct {
 	filename   string             // the fully qualified (drive:path/filename.ext) for the file
 	accessmode gwtypes.AccessMode // access mode for the file
+	fileData
 }
 
 func (df *defaultFile) AccessMode() gwtypes.AccessMode { return df.accessmode }
@@ -62,26 +163,61 @@ func (df *defaultFile) LockMode() gwtypes.LockMode     { return gwtypes.Default
 // OpenFile starts the process of opening a file for access by the program
 // being executed.  First step, create the correct type of AnOpenFile based
 // on the share mode being requested.
-func OpenFile(FQFN string, stmt ast.OpenStatement, env *object.Environment) (gwtypes.AnOpenFile, object.Object) {
+func OpenFile(FQFN string, data *[]byte, stmt ast.OpenStatement, env *object.Environment) (gwtypes.AnOpenFile, object.Object) {
 	var af gwtypes.AnOpenFile
 
+	am := accessMode(stmt.Mode)
+	fd := fileData{data: data, record: make([]byte, recordLength(stmt.RecLen))}
+
+	// appending starts at the end of the file
+	if am == gwtypes.Append {
+		fd.pos = fd.Lof()
+	}
+
 	switch strings.ToUpper(stmt.Mode) {
 	case gwtypes.Shared.String():
-		af = &sharedFile{filename: FQFN}
+		af = &sharedFile{filename: FQFN, accessmode: am, fileData: fd}
 	case gwtypes.LockRead.String():
-		af = &lockReadFile{filename: FQFN}
+		af = &lockReadFile{filename: FQFN, accessmode: am, fileData: fd}
 	case gwtypes.LockWrite.String():
-		af = &lockWriteFile{filename: FQFN}
+		af = &lockWriteFile{filename: FQFN, accessmode: am, fileData: fd}
 	case gwtypes.LockReadWrite.String():
-		af = &lockReadWriteFile{filename: FQFN}
+		af = &lockReadWriteFile{filename: FQFN, accessmode: am, fileData: fd}
 	default:
-		af = &defaultFile{filename: FQFN}
+		af = &defaultFile{filename: FQFN, accessmode: am, fileData: fd}
 	}
 
 	// next step, see if file is already open
 	return checkFileAlreadyOpen(af, env)
 }
 
+// accessMode converts the mode from the OPEN statement
+// the long form spells it out, the short form uses the first letter
+// anything I don't recognize is treated as random
+func accessMode(mode string) gwtypes.AccessMode {
+	switch strings.ToUpper(mode) {
+	case gwtypes.Input.String(), "I":
+		return gwtypes.Input
+	case gwtypes.Output.String(), "O":
+		return gwtypes.Output
+	case gwtypes.Append.String(), "A":
+		return gwtypes.Append
+	}
+
+	return gwtypes.Random
+}
+
+// recordLength converts the LEN= from the OPEN statement
+// GW-BASIC uses 128 byte records when no good length is given
+func recordLength(recLen string) int {
+	rl, err := strconv.Atoi(recLen)
+	if (err != nil) || (rl < 1) {
+		return 128
+	}
+
+	return rl
+}
+
 // checkFileAlreadyOpen determines if the file is already open in a mode
 // that conflicts with this request
 func checkFileAlreadyOpen(AnOpenFile gwtypes.AnOpenFile, env *object.Environment) (gwtypes.AnOpenFile, object.Object) {
@@ -1,6 +1,7 @@
 package afile
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/navionguy/basicwasm/ast"
@@ -132,7 +133,7 @@ func TestOpenFile(t *testing.T) {
 	for _, tt := range tests {
 		var trm mocks.MockTerm
 		env := object.NewTermEnvironment(trm)
-		af, err := OpenFile(tt.FqFn, tt.OpenStmt, env)
+		af, err := OpenFile(tt.FqFn, nil, tt.OpenStmt, env)
 
 		if !tt.fail {
 			assert.Equal(t, tt.FqFn, af.FQFN())
@@ -142,3 +143,114 @@ func TestOpenFile(t *testing.T) {
 		}
 	}
 }
+
+func TestAccessMode(t *testing.T) {
+	tests := []struct {
+		mode string
+		exp  gwtypes.AccessMode
+	}{
+		{mode: "INPUT", exp: gwtypes.Input},
+		{mode: "i", exp: gwtypes.Input},
+		{mode: "output", exp: gwtypes.Output},
+		{mode: "O", exp: gwtypes.Output},
+		{mode: "APPEND", exp: gwtypes.Append},
+		{mode: "A", exp: gwtypes.Append},
+		{mode: "RANDOM", exp: gwtypes.Random},
+		{mode: "R", exp: gwtypes.Random},
+		{mode: "SHARED", exp: gwtypes.Random},
+	}
+
+	for _, tt := range tests {
+		assert.Equalf(t, tt.exp, accessMode(tt.mode), "accessMode(%s) got the wrong mode", tt.mode)
+	}
+}
+
+func TestFileData(t *testing.T) {
+	tests := []struct {
+		data  string
+		mode  string
+		write string
+		read  string
+		exp   string
+		loc   int
+		eof   bool
+	}{
+		{data: "ABC", mode: "I", read: "ABC", exp: "ABC", loc: 3, eof: true},
+		{data: "ABC", mode: "I", read: "AB", exp: "ABC", loc: 2},
+		{data: "AB\x1aC", mode: "I", read: "AB", exp: "AB\x1aC", loc: 2, eof: true},
+		{data: "", mode: "O", write: "Hello", exp: "Hello", loc: 5, eof: true},
+		{data: "Hello", mode: "A", write: " World", exp: "Hello World", loc: 11, eof: true},
+		{data: "Hello", mode: "R", write: "J", read: "ello", exp: "Jello", loc: 5, eof: true},
+	}
+
+	for _, tt := range tests {
+		var trm mocks.MockTerm
+		env := object.NewTermEnvironment(trm)
+		data := []byte(tt.data)
+		af, err := OpenFile("test.dat", &data, ast.OpenStatement{Mode: tt.mode}, env)
+
+		assert.Nil(t, err)
+		af.Write([]byte(tt.write))
+
+		rd := ""
+		for i := 0; i < len(tt.read); i++ {
+			b, ok := af.GetByte()
+			assert.True(t, ok, "GetByte() hit the end early")
+			rd += string(b)
+		}
+
+		assert.Equal(t, tt.read, rd)
+		assert.Equal(t, tt.exp, string(data))
+		assert.Equal(t, len(tt.exp), af.Lof())
+		assert.Equal(t, tt.loc, af.Loc())
+		assert.Equalf(t, tt.eof, af.EOF(), "%s EOF() was wrong", tt.exp)
+	}
+
+	// nil data is an empty file
+	fd := fileData{}
+	fd.Write([]byte("lost"))
+	_, ok := fd.GetByte()
+	assert.False(t, ok)
+	assert.Zero(t, fd.Lof())
+	assert.True(t, fd.EOF())
+}
+
+func TestRecords(t *testing.T) {
+	tests := []struct {
+		data   string
+		reclen string
+		put    map[int]string
+		get    int
+		exp    string
+		rec    string
+	}{
+		{data: "AAAABBBB", reclen: "4", get: 2, exp: "AAAABBBB", rec: "BBBB"},
+		{data: "AAAABB", reclen: "4", get: 2, exp: "AAAABB", rec: "BB\x00\x00"},
+		{data: "AAAA", reclen: "4", put: map[int]string{1: "CCCC"}, get: 1, exp: "CCCC", rec: "CCCC"},
+		{data: "AAAA", reclen: "4", put: map[int]string{3: "DDDD"}, get: 2, exp: "AAAA\x00\x00\x00\x00DDDD", rec: "\x00\x00\x00\x00"},
+		{data: "", reclen: "", put: map[int]string{1: "E"}, get: 1, exp: "E" + strings.Repeat("\x00", 127), rec: "E" + strings.Repeat("\x00", 127)},
+	}
+
+	for _, tt := range tests {
+		var trm mocks.MockTerm
+		env := object.NewTermEnvironment(trm)
+		data := []byte(tt.data)
+		af, err := OpenFile("test.dat", &data, ast.OpenStatement{Mode: "R", RecLen: tt.reclen}, env)
+		assert.Nil(t, err)
+
+		rf, ok := af.(gwtypes.RandomFile)
+		assert.True(t, ok, "not a RandomFile")
+
+		for rec, val := range tt.put {
+			copy(rf.Record(), val)
+			rf.PutRecord(rec)
+			assert.Equal(t, rec, rf.CurRecord())
+		}
+
+		rf.GetRecord(tt.get)
+		assert.Equal(t, tt.get, rf.CurRecord())
+		assert.Equal(t, tt.rec, string(rf.Record()))
+		assert.Equal(t, tt.exp, string(data))
+		assert.Equal(t, len(tt.rec), rf.RecLen())
+	}
+}
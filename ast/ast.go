@@ -458,6 +458,15 @@ func (fn *FileNumber) String() string {
 	return out.String()
 }
 
+// fileRedirect builds the "#n, " that sends a statement to a file
+func fileRedirect(fn *FileNumber) string {
+	if fn == nil {
+		return ""
+	}
+
+	return fn.String() + ", "
+}
+
 // FilesCommand gets list of files from basic server
 type FilesCommand struct {
 	Token token.Token
@@ -624,11 +633,44 @@ func (ls *LetStatement) String() string {
 	return out.String()
 }
 
+// LineInputStatement reads an entire line typed at the keyboard into a string variable
+type LineInputStatement struct {
+	Token    token.Token
+	File     *FileNumber    // LINE INPUT #n, reads from a file
+	SameLine bool           // LINE INPUT; leaves the cursor on the input line
+	Prompt   *StringLiteral // optional prompt string
+	Var      Expression     // string variable to receive the line
+	Trash    []TrashStatement
+}
+
+func (li *LineInputStatement) statementNode()       {}
+func (li *LineInputStatement) TokenLiteral() string { return strings.ToUpper(li.Token.Literal) }
+func (li *LineInputStatement) HasTrash() bool       { return len(li.Trash) > 0 }
+func (li *LineInputStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(li.TokenLiteral())
+	if li.File != nil {
+		out.WriteString(" " + fileRedirect(li.File))
+	} else {
+		out.WriteString(inputPrompt(li.SameLine, li.Prompt, true))
+	}
+
+	if li.Var != nil {
+		out.WriteString(li.Var.String())
+	}
+
+	out.WriteString(Trash(li.Trash))
+
+	return out.String()
+}
+
 // LineNumStmt holds the line number
 type LineNumStmt struct {
-	Token token.Token
-	Value int32
-	Trash []TrashStatement
+	Token  token.Token
+	Value  int32
+	Source string // the whole line as it was typed in, if known
+	Trash  []TrashStatement
 }
 
 func (lns *LineNumStmt) statementNode()       {}
@@ -838,6 +880,57 @@ func (og *OnGoStatement) String() string {
 	return out.String()
 }
 
+// OnEventStatement sets up trapping of an event
+// ON TIMER(n) GOSUB line or ON KEY(n) GOSUB line
+type OnEventStatement struct {
+	Token token.Token // "ON"
+	Event token.Token // the event being trapped, TIMER or KEY
+	Param Expression  // the event parameter, the interval in seconds or the key number
+	Jump  int         // line number of the handler, zero turns off trapping
+	Trash []TrashStatement
+}
+
+func (oe *OnEventStatement) statementNode()       {}
+func (oe *OnEventStatement) TokenLiteral() string { return strings.ToUpper(oe.Token.Literal) }
+func (oe *OnEventStatement) HasTrash() bool       { return len(oe.Trash) > 0 }
+func (oe *OnEventStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(oe.TokenLiteral() + " " + strings.ToUpper(oe.Event.Literal))
+	if oe.Param != nil {
+		out.WriteString("(" + oe.Param.String() + ")")
+		out.WriteString(fmt.Sprintf(" GOSUB %d", oe.Jump))
+	}
+
+	return out.String() + Trash(oe.Trash)
+}
+
+// EventTrapStatement turns trapping of an event on or off
+// TIMER ON|OFF|STOP or KEY(n) ON|OFF|STOP
+type EventTrapStatement struct {
+	Token token.Token // the event, TIMER or KEY
+	Param Expression  // the key number for KEY
+	State token.Token // ON, OFF or STOP
+	Trash []TrashStatement
+}
+
+func (et *EventTrapStatement) statementNode()       {}
+func (et *EventTrapStatement) TokenLiteral() string { return strings.ToUpper(et.Token.Literal) }
+func (et *EventTrapStatement) HasTrash() bool       { return len(et.Trash) > 0 }
+func (et *EventTrapStatement) String() string {
+	out := et.TokenLiteral()
+
+	if et.Param != nil {
+		out = out + "(" + et.Param.String() + ")"
+	}
+
+	if len(et.State.Literal) > 0 {
+		out = out + " " + strings.ToUpper(et.State.Literal)
+	}
+
+	return out + Trash(et.Trash)
+}
+
 // ExpressionStatement holds an expression
 type ExpressionStatement struct {
 	Token      token.Token      // the first token of the expression
@@ -1374,6 +1467,9 @@ func (pe *PrefixExpression) HasTrash() bool       { return len(pe.Trash) > 0 }
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString(pe.Operator)
+	if pe.Token.Type == token.NOT {
+		out.WriteString(" ")
+	}
 	out.WriteString(pe.Right.String())
 	out.WriteString(Trash(pe.Trash))
 	return out.String()
@@ -1473,26 +1569,27 @@ func (ifs *IfStatement) String() string {
 	}
 	out.WriteString(" THEN")
 	if ifs.Consequence != nil {
-		_, ok := ifs.Consequence.(*EndStatement)
-		if ok {
-			out.WriteString(" ")
-		}
-		out.WriteString(ifs.Consequence.String())
+		out.WriteString(ifBranch(ifs.Consequence))
 	}
 
 	if ifs.Alternative != nil {
 		out.WriteString(" ELSE")
-		s := ifs.Alternative
-		_, ok := s.(*EndStatement)
-		if ok {
-			out.WriteString(" ")
-		}
-		out.WriteString(s.String())
+		out.WriteString(ifBranch(ifs.Alternative))
 	}
 
 	return out.String()
 }
 
+// a branch needs a space in front of it unless it brings its own
+func ifBranch(stmt Statement) string {
+	s := stmt.String()
+	if strings.HasPrefix(s, " ") {
+		return s
+	}
+
+	return " " + s
+}
+
 // Basic views this variable, but I will evaluate as a expression
 type InkeyExpression struct {
 	Token token.Token
@@ -1511,6 +1608,65 @@ func (ink *InkeyExpression) String() string {
 	return out.String()
 }
 
+// InputStatement reads values typed at the keyboard into variables
+type InputStatement struct {
+	Token    token.Token
+	File     *FileNumber    // INPUT #n, reads from a file
+	SameLine bool           // INPUT; leaves the cursor on the input line
+	Prompt   *StringLiteral // optional prompt string
+	QMark    bool           // display "? " after the prompt
+	Vars     []Expression   // variables to receive the values
+	Trash    []TrashStatement
+}
+
+func (inp *InputStatement) statementNode()       {}
+func (inp *InputStatement) TokenLiteral() string { return strings.ToUpper(inp.Token.Literal) }
+func (inp *InputStatement) HasTrash() bool       { return len(inp.Trash) > 0 }
+func (inp *InputStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(inp.TokenLiteral())
+	if inp.File != nil {
+		out.WriteString(" " + fileRedirect(inp.File))
+	} else {
+		out.WriteString(inputPrompt(inp.SameLine, inp.Prompt, inp.QMark))
+	}
+
+	for i, v := range inp.Vars {
+		out.WriteString(v.String())
+		if (i + 1) < len(inp.Vars) {
+			out.WriteString(", ")
+		}
+	}
+
+	out.WriteString(Trash(inp.Trash))
+
+	return out.String()
+}
+
+// inputPrompt builds the optional prompt shared by INPUT & LINE INPUT
+func inputPrompt(sameLine bool, prompt *StringLiteral, qmark bool) string {
+	var out bytes.Buffer
+
+	if sameLine {
+		out.WriteString(";")
+	}
+	out.WriteString(" ")
+
+	if prompt == nil {
+		return out.String()
+	}
+
+	out.WriteString(prompt.String())
+	if qmark {
+		out.WriteString("; ")
+	} else {
+		out.WriteString(", ")
+	}
+
+	return out.String()
+}
+
 // GosubStatement call subroutine
 type GosubStatement struct {
 	Token token.Token
@@ -1604,11 +1760,14 @@ func (lst *ListStatement) String() string {
 // PrintStatement holds everything to control the output
 type PrintStatement struct {
 	Token      token.Token
+	File       *FileNumber // PRINT #n, sends the output to a file
 	Items      []Expression
 	Seperators []string
+	Trash      []TrashStatement
 }
 
 func (pe *PrintStatement) statementNode() {}
+func (pe *PrintStatement) HasTrash() bool { return len(pe.Trash) > 0 }
 
 // TokenLiteral returns my token literal
 func (pe *PrintStatement) TokenLiteral() string { return strings.ToUpper(pe.Token.Literal) }
@@ -1618,10 +1777,12 @@ func (pe *PrintStatement) String() string {
 
 	out.WriteString(pe.TokenLiteral())
 	out.WriteString(" ")
+	out.WriteString(fileRedirect(pe.File))
 
 	for i, s := range pe.Items {
 		out.WriteString(s.String() + pe.Seperators[i])
 	}
+	out.WriteString(Trash(pe.Trash))
 
 	return out.String()
 }
@@ -1756,6 +1917,15 @@ func (stop *StopStatement) statementNode()       {}
 func (stop *StopStatement) TokenLiteral() string { return strings.ToUpper(stop.Token.Literal) }
 func (stop *StopStatement) String() string       { return strings.ToUpper(stop.Token.Literal) + " " }
 
+// SystemCommand closes any open files and ends the session
+type SystemCommand struct {
+	Token token.Token
+}
+
+func (sys *SystemCommand) statementNode()       {}
+func (sys *SystemCommand) TokenLiteral() string { return strings.ToUpper(sys.Token.Literal) }
+func (sys *SystemCommand) String() string       { return strings.ToUpper(sys.Token.Literal) + " " }
+
 type ToStatement struct {
 	Token token.Token
 }
@@ -1874,3 +2044,388 @@ func (vw *ViewPrintStatement) String() string {
 
 	return out.String()
 }
+
+// WendStatement closes a WHILE loop
+type WendStatement struct {
+	Token token.Token
+	Trash []TrashStatement
+}
+
+func (wnd *WendStatement) statementNode()       {}
+func (wnd *WendStatement) TokenLiteral() string { return strings.ToUpper(wnd.Token.Literal) }
+func (wnd *WendStatement) HasTrash() bool       { return len(wnd.Trash) > 0 }
+func (wnd *WendStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(wnd.TokenLiteral())
+	out.WriteString(Trash(wnd.Trash))
+
+	return out.String()
+}
+
+// WhileStatement loops until the condition is false
+type WhileStatement struct {
+	Token     token.Token
+	Condition Expression
+	Trash     []TrashStatement
+}
+
+func (whl *WhileStatement) statementNode()       {}
+func (whl *WhileStatement) TokenLiteral() string { return strings.ToUpper(whl.Token.Literal) }
+func (whl *WhileStatement) HasTrash() bool       { return len(whl.Trash) > 0 }
+func (whl *WhileStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(whl.TokenLiteral())
+	if whl.Condition != nil {
+		out.WriteString(" " + whl.Condition.String())
+	}
+	out.WriteString(Trash(whl.Trash))
+
+	return out.String()
+}
+
+// WriteStatement outputs data with delimiters, so it can be read back by INPUT
+type WriteStatement struct {
+	Token token.Token
+	File  *FileNumber // WRITE #n, sends the output to a file
+	Items []Expression
+	Trash []TrashStatement
+}
+
+func (wrt *WriteStatement) statementNode()       {}
+func (wrt *WriteStatement) TokenLiteral() string { return strings.ToUpper(wrt.Token.Literal) }
+func (wrt *WriteStatement) HasTrash() bool       { return len(wrt.Trash) > 0 }
+func (wrt *WriteStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(wrt.TokenLiteral() + " ")
+	out.WriteString(fileRedirect(wrt.File))
+
+	for i, item := range wrt.Items {
+		out.WriteString(item.String())
+		if (i + 1) < len(wrt.Items) {
+			out.WriteString(", ")
+		}
+	}
+	out.WriteString(Trash(wrt.Trash))
+
+	return out.String()
+}
+
+// FieldStatement maps string variables onto the record buffer of a random file
+type FieldStatement struct {
+	Token  token.Token
+	File   FileNumber   // the file whose buffer is being mapped
+	Widths []Expression // width of each field
+	Vars   []*Identifier
+	Trash  []TrashStatement
+}
+
+func (fld *FieldStatement) statementNode()       {}
+func (fld *FieldStatement) TokenLiteral() string { return strings.ToUpper(fld.Token.Literal) }
+func (fld *FieldStatement) HasTrash() bool       { return len(fld.Trash) > 0 }
+func (fld *FieldStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(fld.TokenLiteral() + " " + fld.File.String())
+
+	for i := range fld.Widths {
+		out.WriteString(", " + fld.Widths[i].String() + " AS ")
+		if i < len(fld.Vars) {
+			out.WriteString(fld.Vars[i].String())
+		}
+	}
+	out.WriteString(Trash(fld.Trash))
+
+	return out.String()
+}
+
+// GetStatement reads a record from a random file into its buffer
+type GetStatement struct {
+	Token  token.Token
+	File   FileNumber
+	Record Expression // record to read, nil means the next one
+	Trash  []TrashStatement
+}
+
+func (get *GetStatement) statementNode()       {}
+func (get *GetStatement) TokenLiteral() string { return strings.ToUpper(get.Token.Literal) }
+func (get *GetStatement) HasTrash() bool       { return len(get.Trash) > 0 }
+func (get *GetStatement) String() string {
+	return get.TokenLiteral() + " " + recordAccess(get.File, get.Record) + Trash(get.Trash)
+}
+
+// LsetStatement left justifies a string into a FIELD variable
+type LsetStatement struct {
+	Token token.Token
+	Name  *Identifier
+	Value Expression
+	Trash []TrashStatement
+}
+
+func (ls *LsetStatement) statementNode()       {}
+func (ls *LsetStatement) TokenLiteral() string { return strings.ToUpper(ls.Token.Literal) }
+func (ls *LsetStatement) HasTrash() bool       { return len(ls.Trash) > 0 }
+func (ls *LsetStatement) String() string {
+	return ls.TokenLiteral() + " " + justifyString(ls.Name, ls.Value) + Trash(ls.Trash)
+}
+
+// PutStatement writes the buffer of a random file out as a record
+type PutStatement struct {
+	Token  token.Token
+	File   FileNumber
+	Record Expression // record to write, nil means the next one
+	Trash  []TrashStatement
+}
+
+func (put *PutStatement) statementNode()       {}
+func (put *PutStatement) TokenLiteral() string { return strings.ToUpper(put.Token.Literal) }
+func (put *PutStatement) HasTrash() bool       { return len(put.Trash) > 0 }
+func (put *PutStatement) String() string {
+	return put.TokenLiteral() + " " + recordAccess(put.File, put.Record) + Trash(put.Trash)
+}
+
+// RsetStatement right justifies a string into a FIELD variable
+type RsetStatement struct {
+	Token token.Token
+	Name  *Identifier
+	Value Expression
+	Trash []TrashStatement
+}
+
+func (rs *RsetStatement) statementNode()       {}
+func (rs *RsetStatement) TokenLiteral() string { return strings.ToUpper(rs.Token.Literal) }
+func (rs *RsetStatement) HasTrash() bool       { return len(rs.Trash) > 0 }
+func (rs *RsetStatement) String() string {
+	return rs.TokenLiteral() + " " + justifyString(rs.Name, rs.Value) + Trash(rs.Trash)
+}
+
+// recordAccess builds the "#n, rec" used by GET and PUT
+func recordAccess(fn FileNumber, rec Expression) string {
+	if rec == nil {
+		return fn.String()
+	}
+
+	return fn.String() + ", " + rec.String()
+}
+
+// justifyString builds the "var = value" used by LSET and RSET
+func justifyString(name *Identifier, value Expression) string {
+	var out bytes.Buffer
+
+	if name != nil {
+		out.WriteString(name.String())
+	}
+	out.WriteString(" = ")
+	if value != nil {
+		out.WriteString(value.String())
+	}
+
+	return out.String()
+}
+
+// SaveCommand writes the current program to a file
+// SAVE filename[,A|,P]
+type SaveCommand struct {
+	Token  token.Token
+	Path   Expression // file to write the program into
+	Format string     // "A" for ASCII, "P" for protected, tokenized if empty
+	Trash  []TrashStatement
+}
+
+func (sv *SaveCommand) statementNode()       {}
+func (sv *SaveCommand) TokenLiteral() string { return strings.ToUpper(sv.Token.Literal) }
+func (sv *SaveCommand) HasTrash() bool       { return len(sv.Trash) > 0 }
+
+func (sv *SaveCommand) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("SAVE")
+
+	if sv.Path != nil {
+		out.WriteString(" " + sv.Path.String())
+	}
+
+	if len(sv.Format) > 0 {
+		out.WriteString("," + sv.Format)
+	}
+
+	out.WriteString(Trash(sv.Trash))
+
+	return out.String()
+}
+
+// KillStatement deletes files, wildcards are allowed
+// KILL filespec
+type KillStatement struct {
+	Token token.Token
+	Path  Expression // the file(s) to delete
+	Trash []TrashStatement
+}
+
+func (kill *KillStatement) statementNode()       {}
+func (kill *KillStatement) TokenLiteral() string { return strings.ToUpper(kill.Token.Literal) }
+func (kill *KillStatement) HasTrash() bool       { return len(kill.Trash) > 0 }
+func (kill *KillStatement) String() string {
+	return pathString(kill.TokenLiteral(), kill.Path) + Trash(kill.Trash)
+}
+
+// MkDirStatement creates a new directory
+// MKDIR pathname
+type MkDirStatement struct {
+	Token token.Token
+	Path  Expression // the directory to create
+	Trash []TrashStatement
+}
+
+func (md *MkDirStatement) statementNode()       {}
+func (md *MkDirStatement) TokenLiteral() string { return strings.ToUpper(md.Token.Literal) }
+func (md *MkDirStatement) HasTrash() bool       { return len(md.Trash) > 0 }
+func (md *MkDirStatement) String() string {
+	return pathString(md.TokenLiteral(), md.Path) + Trash(md.Trash)
+}
+
+// NameStatement renames a file
+// NAME oldname AS newname
+type NameStatement struct {
+	Token   token.Token
+	OldName Expression
+	NewName Expression
+	Trash   []TrashStatement
+}
+
+func (nm *NameStatement) statementNode()       {}
+func (nm *NameStatement) TokenLiteral() string { return strings.ToUpper(nm.Token.Literal) }
+func (nm *NameStatement) HasTrash() bool       { return len(nm.Trash) > 0 }
+func (nm *NameStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(pathString(nm.TokenLiteral(), nm.OldName))
+
+	if nm.NewName != nil {
+		out.WriteString(" AS " + nm.NewName.String())
+	}
+
+	out.WriteString(Trash(nm.Trash))
+
+	return out.String()
+}
+
+// ResetStatement closes all open files
+type ResetStatement struct {
+	Token token.Token
+	Trash []TrashStatement
+}
+
+func (rs *ResetStatement) statementNode()       {}
+func (rs *ResetStatement) TokenLiteral() string { return strings.ToUpper(rs.Token.Literal) }
+func (rs *ResetStatement) HasTrash() bool       { return len(rs.Trash) > 0 }
+func (rs *ResetStatement) String() string       { return rs.TokenLiteral() + Trash(rs.Trash) }
+
+// RmDirStatement removes an empty directory
+// RMDIR pathname
+type RmDirStatement struct {
+	Token token.Token
+	Path  Expression // the directory to remove
+	Trash []TrashStatement
+}
+
+func (rd *RmDirStatement) statementNode()       {}
+func (rd *RmDirStatement) TokenLiteral() string { return strings.ToUpper(rd.Token.Literal) }
+func (rd *RmDirStatement) HasTrash() bool       { return len(rd.Trash) > 0 }
+func (rd *RmDirStatement) String() string {
+	return pathString(rd.TokenLiteral(), rd.Path) + Trash(rd.Trash)
+}
+
+// pathString builds the "KEYWORD path" used by the file management statements
+func pathString(keyword string, path Expression) string {
+	if path == nil {
+		return keyword
+	}
+
+	return keyword + " " + path.String()
+}
+
+// DeleteCommand removes a range of lines from the program
+// DELETE [line][-[line]]
+type DeleteCommand struct {
+	Token  token.Token
+	Start  string // first line to delete
+	Lrange string // "-" when a range was given
+	Stop   string // last line to delete
+	Trash  []TrashStatement
+}
+
+func (del *DeleteCommand) statementNode()       {}
+func (del *DeleteCommand) TokenLiteral() string { return strings.ToUpper(del.Token.Literal) }
+func (del *DeleteCommand) HasTrash() bool       { return len(del.Trash) > 0 }
+func (del *DeleteCommand) String() string {
+	out := del.TokenLiteral()
+
+	if rng := del.Start + del.Lrange + del.Stop; len(rng) > 0 {
+		out = out + " " + rng
+	}
+
+	return out + Trash(del.Trash)
+}
+
+// MergeCommand overlays an ASCII program file onto the current program
+// MERGE filename
+type MergeCommand struct {
+	Token token.Token
+	Path  Expression // file to merge in
+	Trash []TrashStatement
+}
+
+func (mrg *MergeCommand) statementNode()       {}
+func (mrg *MergeCommand) TokenLiteral() string { return strings.ToUpper(mrg.Token.Literal) }
+func (mrg *MergeCommand) HasTrash() bool       { return len(mrg.Trash) > 0 }
+func (mrg *MergeCommand) String() string {
+	return pathString(mrg.TokenLiteral(), mrg.Path) + Trash(mrg.Trash)
+}
+
+// EditCommand displays a program line for editing
+// EDIT line
+type EditCommand struct {
+	Token token.Token
+	Line  string // line to be edited
+	Trash []TrashStatement
+}
+
+func (ed *EditCommand) statementNode()       {}
+func (ed *EditCommand) TokenLiteral() string { return strings.ToUpper(ed.Token.Literal) }
+func (ed *EditCommand) HasTrash() bool       { return len(ed.Trash) > 0 }
+func (ed *EditCommand) String() string {
+	out := ed.TokenLiteral()
+
+	if len(ed.Line) > 0 {
+		out = out + " " + ed.Line
+	}
+
+	return out + Trash(ed.Trash)
+}
+
+// RenumCommand renumbers the program lines
+// RENUM [new][,[old][,increment]]
+type RenumCommand struct {
+	Token     token.Token
+	NewNum    string // first new line number
+	OldNum    string // first line to renumber
+	Increment string // step between new numbers
+	Trash     []TrashStatement
+}
+
+func (ren *RenumCommand) statementNode()       {}
+func (ren *RenumCommand) TokenLiteral() string { return strings.ToUpper(ren.Token.Literal) }
+func (ren *RenumCommand) HasTrash() bool       { return len(ren.Trash) > 0 }
+func (ren *RenumCommand) String() string {
+	parms := strings.TrimRight(ren.NewNum+","+ren.OldNum+","+ren.Increment, ",")
+	out := ren.TokenLiteral()
+
+	if len(parms) > 0 {
+		out = out + " " + parms
+	}
+
+	return out + Trash(ren.Trash)
+}
@@ -305,11 +305,23 @@ func TestCodeMultiLines(t *testing.T) {
 		t.Fatal("Code.Exists failed to find line 10!")
 	}
 
+	// listing walks its own copy of the lines
+	it.Jump(20)
+	it.Next()
+	lst := program.ListIter()
+	for lst.Next() {
+	}
+	assert.Equal(t, 20, it.CurLine(), "ListIter moved the running line")
+	assert.Equal(t, "LET X = 6", it.Value().String(), "ListIter moved the running statement")
+
+	// jumping back into a line starts at its beginning
+	program.code.lines[0].curStmt = 1
 	err := program.code.Jump(10)
 
 	if err > 0 {
 		t.Fatalf("code.Jump to line 10 failed with %d!", err)
 	}
+	assert.Equal(t, 0, program.code.lines[0].curStmt, "code.Jump left the line part way thru")
 
 	err = program.code.Jump(400)
 
@@ -1480,6 +1492,58 @@ func Test_OnErrorGoto(t *testing.T) {
 	}
 }
 
+func Test_OnEventStatement(t *testing.T) {
+	tests := []struct {
+		stmt  OnEventStatement
+		exp   string
+		trash bool
+	}{
+		{stmt: OnEventStatement{Token: token.Token{Type: token.ON, Literal: "ON"}, Event: token.Token{Type: token.TIMER, Literal: "timer"},
+			Param: &IntegerLiteral{Value: 60}, Jump: 1000}, exp: "ON TIMER(60) GOSUB 1000"},
+		{stmt: OnEventStatement{Token: token.Token{Type: token.ON, Literal: "ON"}, Event: token.Token{Type: token.TIMER, Literal: "TIMER"},
+			Param: &IntegerLiteral{Value: 5}}, exp: "ON TIMER(5) GOSUB 0"},
+		{stmt: OnEventStatement{Token: token.Token{Type: token.ON, Literal: "ON"}, Event: token.Token{Type: token.TIMER, Literal: "TIMER"},
+			Trash: []TrashStatement{{Token: token.Token{Type: token.INT, Literal: "5"}}}}, exp: "ON TIMER 5", trash: true},
+		{stmt: OnEventStatement{Token: token.Token{Type: token.ON, Literal: "ON"}, Event: token.Token{Type: token.KEY, Literal: "key"},
+			Param: &IntegerLiteral{Value: 1}, Jump: 500}, exp: "ON KEY(1) GOSUB 500"},
+	}
+
+	for _, tt := range tests {
+		tt.stmt.statementNode()
+
+		assert.Equal(t, "ON", tt.stmt.TokenLiteral())
+		assert.Equal(t, tt.exp, tt.stmt.String())
+		assert.Equal(t, tt.trash, tt.stmt.HasTrash())
+	}
+}
+
+func Test_EventTrapStatement(t *testing.T) {
+	tests := []struct {
+		stmt  EventTrapStatement
+		tok   string
+		exp   string
+		trash bool
+	}{
+		{stmt: EventTrapStatement{Token: token.Token{Type: token.TIMER, Literal: "TIMER"}, State: token.Token{Type: token.ON, Literal: "on"}}, exp: "TIMER ON"},
+		{stmt: EventTrapStatement{Token: token.Token{Type: token.TIMER, Literal: "timer"}, State: token.Token{Type: token.STOP, Literal: "STOP"}}, exp: "TIMER STOP"},
+		{stmt: EventTrapStatement{Token: token.Token{Type: token.TIMER, Literal: "TIMER"},
+			Trash: []TrashStatement{{Token: token.Token{Type: token.IDENT, Literal: "X"}}}}, exp: "TIMER X", trash: true},
+		{stmt: EventTrapStatement{Token: token.Token{Type: token.KEY, Literal: "key"}, Param: &IntegerLiteral{Value: 3},
+			State: token.Token{Type: token.OFF, Literal: "off"}}, tok: "KEY", exp: "KEY(3) OFF"},
+	}
+
+	for _, tt := range tests {
+		tt.stmt.statementNode()
+
+		if len(tt.tok) == 0 {
+			tt.tok = "TIMER"
+		}
+		assert.Equal(t, tt.tok, tt.stmt.TokenLiteral())
+		assert.Equal(t, tt.exp, tt.stmt.String())
+		assert.Equal(t, tt.trash, tt.stmt.HasTrash())
+	}
+}
+
 func Test_OnGoStatement(t *testing.T) {
 	tests := []struct {
 		og  OnGoStatement
@@ -1533,6 +1597,7 @@ func Test_PrefixExpression(t *testing.T) {
 		val int16
 	}{
 		{exp: "-37", typ: token.MINUS, lit: "-", val: 37},
+		{exp: "NOT 37", typ: token.NOT, lit: "NOT", val: 37},
 	}
 
 	for _, tt := range tests {
@@ -1556,6 +1621,9 @@ func Test_PrintStatement(t *testing.T) {
 
 	assert.Equal(t, "PRINT", prt.TokenLiteral(), "Print statement has incorrect TokenLiteral")
 	assert.Equal(t, `PRINT 12,"Fred";`, prt.String(), "Print statement didn't build string correctly")
+
+	prt.File = &FileNumber{Token: token.Token{Type: token.HASHTAG, Literal: "#"}, Numbr: &IntegerLiteral{Value: 1}}
+	assert.Equal(t, `PRINT #1, 12,"Fred";`, prt.String(), "Print to file didn't build string correctly")
 }
 
 func Test_ReadStatement(t *testing.T) {
@@ -1680,6 +1748,15 @@ func Test_StopStatement(t *testing.T) {
 	assert.Equal(t, "STOP ", stop.String())
 }
 
+func Test_SystemCommand(t *testing.T) {
+	cmd := SystemCommand{Token: token.Token{Type: token.SYSTEM, Literal: "system"}}
+
+	cmd.statementNode()
+
+	assert.Equal(t, token.SYSTEM, cmd.TokenLiteral())
+	assert.Equal(t, "SYSTEM ", cmd.String())
+}
+
 func Test_StringLiteral(t *testing.T) {
 	str := &StringLiteral{Token: token.Token{Type: token.STRING, Literal: "STRING"}, Value: `Test String`}
 
@@ -1746,3 +1823,216 @@ func Test_ViewPrintStatement(t *testing.T) {
 	assert.Equal(t, "VIEW PRINT", vwp.TokenLiteral())
 	assert.Equal(t, "VIEW PRINT 3 TO 24", vwp.String())
 }
+
+func Test_WendStatement(t *testing.T) {
+	tests := []struct {
+		wnd   WendStatement
+		exp   string
+		Trash string
+	}{
+		{wnd: WendStatement{Token: token.Token{Type: token.WEND, Literal: "wend"}}, exp: "WEND"},
+		{wnd: WendStatement{Token: token.Token{Type: token.WEND, Literal: "WEND"}}, exp: "WEND X", Trash: "X"},
+	}
+
+	for _, tt := range tests {
+		tt.wnd.statementNode()
+		if len(tt.Trash) > 0 {
+			tt.wnd.Trash = append(tt.wnd.Trash, TrashStatement{Token: token.Token{Literal: tt.Trash}})
+
+			assert.True(t, tt.wnd.HasTrash(), "should have found trash")
+		}
+
+		assert.Equal(t, "WEND", tt.wnd.TokenLiteral())
+		assert.Equal(t, tt.exp, tt.wnd.String())
+	}
+}
+
+func Test_WhileStatement(t *testing.T) {
+	tests := []struct {
+		whl   WhileStatement
+		exp   string
+		Trash string
+	}{
+		{whl: WhileStatement{Token: token.Token{Type: token.WHILE, Literal: "WHILE"}}, exp: "WHILE"},
+		{whl: WhileStatement{Token: token.Token{Type: token.WHILE, Literal: "while"}, Condition: &Identifier{Token: token.Token{Literal: "X"}, Value: "X"}}, exp: "WHILE X"},
+		{whl: WhileStatement{Token: token.Token{Type: token.WHILE, Literal: "WHILE"}, Condition: &Identifier{Token: token.Token{Literal: "X"}, Value: "X"}}, exp: "WHILE X Y", Trash: "Y"},
+	}
+
+	for _, tt := range tests {
+		tt.whl.statementNode()
+		if len(tt.Trash) > 0 {
+			tt.whl.Trash = append(tt.whl.Trash, TrashStatement{Token: token.Token{Literal: tt.Trash}})
+
+			assert.True(t, tt.whl.HasTrash(), "should have found trash")
+		}
+
+		assert.Equal(t, "WHILE", tt.whl.TokenLiteral())
+		assert.Equal(t, tt.exp, tt.whl.String())
+	}
+}
+
+func Test_InputStatement(t *testing.T) {
+	tests := []struct {
+		inp   InputStatement
+		exp   string
+		Trash string
+	}{
+		{inp: InputStatement{Token: token.Token{Type: token.INPUT, Literal: "input"}, QMark: true, Vars: []Expression{&Identifier{Token: token.Token{Literal: "X"}, Value: "X"}}}, exp: "INPUT X"},
+		{inp: InputStatement{Token: token.Token{Type: token.INPUT, Literal: "INPUT"}, SameLine: true, Prompt: &StringLiteral{Value: "Hi"}, QMark: true,
+			Vars: []Expression{&Identifier{Token: token.Token{Literal: "X"}, Value: "X"}, &Identifier{Token: token.Token{Literal: "Y$"}, Value: "Y$"}}}, exp: `INPUT; "Hi"; X, Y$`},
+		{inp: InputStatement{Token: token.Token{Type: token.INPUT, Literal: "INPUT"}, Prompt: &StringLiteral{Value: "Hi"},
+			Vars: []Expression{&Identifier{Token: token.Token{Literal: "X"}, Value: "X"}}}, exp: `INPUT "Hi", X Z`, Trash: "Z"},
+		{inp: InputStatement{Token: token.Token{Type: token.INPUT, Literal: "INPUT"}, File: &FileNumber{Token: token.Token{Type: token.HASHTAG, Literal: "#"}, Numbr: &IntegerLiteral{Value: 1}},
+			Vars: []Expression{&Identifier{Token: token.Token{Literal: "X"}, Value: "X"}}}, exp: `INPUT #1, X`},
+	}
+
+	for _, tt := range tests {
+		tt.inp.statementNode()
+		if len(tt.Trash) > 0 {
+			tt.inp.Trash = append(tt.inp.Trash, TrashStatement{Token: token.Token{Literal: tt.Trash}})
+
+			assert.True(t, tt.inp.HasTrash(), "should have found trash")
+		}
+
+		assert.Equal(t, "INPUT", tt.inp.TokenLiteral())
+		assert.Equal(t, tt.exp, tt.inp.String())
+	}
+}
+
+func Test_LineInputStatement(t *testing.T) {
+	tests := []struct {
+		li    LineInputStatement
+		exp   string
+		Trash string
+	}{
+		{li: LineInputStatement{Token: token.Token{Type: token.LINE, Literal: "LINE INPUT"}}, exp: "LINE INPUT "},
+		{li: LineInputStatement{Token: token.Token{Type: token.LINE, Literal: "LINE INPUT"}, Var: &Identifier{Token: token.Token{Literal: "X$"}, Value: "X$"}}, exp: "LINE INPUT X$"},
+		{li: LineInputStatement{Token: token.Token{Type: token.LINE, Literal: "LINE INPUT"}, SameLine: true, Prompt: &StringLiteral{Value: "Hi"},
+			Var: &Identifier{Token: token.Token{Literal: "X$"}, Value: "X$"}}, exp: `LINE INPUT; "Hi"; X$ Z`, Trash: "Z"},
+		{li: LineInputStatement{Token: token.Token{Type: token.LINE, Literal: "LINE INPUT"}, File: &FileNumber{Token: token.Token{Type: token.HASHTAG, Literal: "#"}, Numbr: &IntegerLiteral{Value: 2}},
+			Var: &Identifier{Token: token.Token{Literal: "X$"}, Value: "X$"}}, exp: `LINE INPUT #2, X$`},
+	}
+
+	for _, tt := range tests {
+		tt.li.statementNode()
+		if len(tt.Trash) > 0 {
+			tt.li.Trash = append(tt.li.Trash, TrashStatement{Token: token.Token{Literal: tt.Trash}})
+
+			assert.True(t, tt.li.HasTrash(), "should have found trash")
+		}
+
+		assert.Equal(t, "LINE INPUT", tt.li.TokenLiteral())
+		assert.Equal(t, tt.exp, tt.li.String())
+	}
+}
+
+func Test_WriteStatement(t *testing.T) {
+	tests := []struct {
+		inp   WriteStatement
+		exp   string
+		Trash string
+	}{
+		{inp: WriteStatement{Token: token.Token{Type: token.WRITE, Literal: "WRITE"}}, exp: "WRITE "},
+		{inp: WriteStatement{Token: token.Token{Type: token.WRITE, Literal: "WRITE"}, Items: []Expression{&IntegerLiteral{Value: 12}, &StringLiteral{Value: "Fred"}}}, exp: `WRITE 12, "Fred"`},
+		{inp: WriteStatement{Token: token.Token{Type: token.WRITE, Literal: "WRITE"}, File: &FileNumber{Token: token.Token{Type: token.HASHTAG, Literal: "#"}, Numbr: &IntegerLiteral{Value: 1}},
+			Items: []Expression{&Identifier{Token: token.Token{Literal: "X"}, Value: "X"}}}, exp: `WRITE #1, X Z`, Trash: "Z"},
+	}
+
+	for _, tt := range tests {
+		tt.inp.statementNode()
+		if len(tt.Trash) > 0 {
+			tt.inp.Trash = append(tt.inp.Trash, TrashStatement{Token: token.Token{Literal: tt.Trash}})
+
+			assert.True(t, tt.inp.HasTrash(), "should have found trash")
+		}
+
+		assert.Equal(t, "WRITE", tt.inp.TokenLiteral())
+		assert.Equal(t, tt.exp, tt.inp.String())
+	}
+}
+
+func Test_RandomFileStatements(t *testing.T) {
+	fn := FileNumber{Token: token.Token{Type: token.HASHTAG, Literal: "#"}, Numbr: &IntegerLiteral{Value: 1}}
+	tests := []struct {
+		inp   Statement
+		lit   string
+		exp   string
+		trash bool
+	}{
+		{inp: &FieldStatement{Token: token.Token{Type: token.FIELD, Literal: "field"}, File: fn,
+			Widths: []Expression{&IntegerLiteral{Value: 20}, &IntegerLiteral{Value: 4}},
+			Vars:   []*Identifier{{Token: token.Token{Literal: "N$"}, Value: "N$"}, {Token: token.Token{Literal: "A$"}, Value: "A$"}}},
+			lit: "FIELD", exp: "FIELD #1, 20 AS N$, 4 AS A$"},
+		{inp: &FieldStatement{Token: token.Token{Type: token.FIELD, Literal: "FIELD"}, File: fn, Widths: []Expression{&IntegerLiteral{Value: 20}},
+			Trash: []TrashStatement{{Token: token.Token{Literal: "Z"}}}},
+			lit: "FIELD", exp: "FIELD #1, 20 AS  Z", trash: true},
+		{inp: &GetStatement{Token: token.Token{Type: token.GET, Literal: "GET"}, File: fn}, lit: "GET", exp: "GET #1"},
+		{inp: &GetStatement{Token: token.Token{Type: token.GET, Literal: "get"}, File: fn, Record: &IntegerLiteral{Value: 5},
+			Trash: []TrashStatement{{Token: token.Token{Literal: "Z"}}}}, lit: "GET", exp: "GET #1, 5 Z", trash: true},
+		{inp: &PutStatement{Token: token.Token{Type: token.PUT, Literal: "PUT"}, File: fn, Record: &IntegerLiteral{Value: 5}}, lit: "PUT", exp: "PUT #1, 5"},
+		{inp: &LsetStatement{Token: token.Token{Type: token.LSET, Literal: "LSET"}, Name: &Identifier{Token: token.Token{Literal: "N$"}, Value: "N$"},
+			Value: &StringLiteral{Value: "Fred"}}, lit: "LSET", exp: `LSET N$ = "Fred"`},
+		{inp: &RsetStatement{Token: token.Token{Type: token.RSET, Literal: "rset"}, Trash: []TrashStatement{{Token: token.Token{Literal: "Z"}}}},
+			lit: "RSET", exp: `RSET  =  Z`, trash: true},
+	}
+
+	for _, tt := range tests {
+		tt.inp.statementNode()
+
+		assert.Equal(t, tt.lit, tt.inp.TokenLiteral())
+		assert.Equal(t, tt.exp, tt.inp.String())
+		assert.Equal(t, tt.trash, tt.inp.(TrashCan).HasTrash())
+	}
+}
+
+func Test_SaveCommand(t *testing.T) {
+	tests := []struct {
+		cmd SaveCommand
+		exp string
+	}{
+		{cmd: SaveCommand{Token: token.Token{Type: token.SAVE, Literal: "save"}}, exp: `SAVE`},
+		{cmd: SaveCommand{Token: token.Token{Type: token.SAVE, Literal: "save"}, Path: &StringLiteral{Value: `PROG.BAS`}}, exp: `SAVE "PROG.BAS"`},
+		{cmd: SaveCommand{Token: token.Token{Type: token.SAVE, Literal: "SAVE"}, Path: &StringLiteral{Value: `PROG.BAS`}, Format: "A"}, exp: `SAVE "PROG.BAS",A`},
+		{cmd: SaveCommand{Token: token.Token{Type: token.SAVE, Literal: "SAVE"}, Path: &StringLiteral{Value: `PROG.BAS`}, Format: "P"}, exp: `SAVE "PROG.BAS",P`},
+	}
+
+	for _, tt := range tests {
+		cmd := &tt.cmd
+		cmd.statementNode()
+
+		assert.Equal(t, "SAVE", cmd.TokenLiteral(), "Save command has incorrect TokenLiteral")
+		assert.Equal(t, tt.exp, cmd.String(), "Save command didn't build string correctly")
+		assert.False(t, cmd.HasTrash(), "trash can should be empty")
+	}
+}
+
+func Test_FileManagement(t *testing.T) {
+	tests := []struct {
+		inp   Statement
+		tok   string
+		exp   string
+		trash bool
+	}{
+		{inp: &KillStatement{Token: token.Token{Type: token.KILL, Literal: "kill"}}, tok: "KILL", exp: `KILL`},
+		{inp: &KillStatement{Token: token.Token{Type: token.KILL, Literal: "KILL"}, Path: &StringLiteral{Value: `*.DAT`}}, tok: "KILL", exp: `KILL "*.DAT"`},
+		{inp: &KillStatement{Token: token.Token{Type: token.KILL, Literal: "KILL"}, Path: &StringLiteral{Value: `A.DAT`}, Trash: []TrashStatement{{Token: token.Token{Type: token.IDENT, Literal: "X"}}}}, tok: "KILL", exp: `KILL "A.DAT" X`, trash: true},
+		{inp: &MkDirStatement{Token: token.Token{Type: token.MKDIR, Literal: "mkdir"}, Path: &StringLiteral{Value: `SUB`}}, tok: "MKDIR", exp: `MKDIR "SUB"`},
+		{inp: &RmDirStatement{Token: token.Token{Type: token.RMDIR, Literal: "rmdir"}, Path: &StringLiteral{Value: `SUB`}}, tok: "RMDIR", exp: `RMDIR "SUB"`},
+		{inp: &NameStatement{Token: token.Token{Type: token.NAME, Literal: "name"}, OldName: &StringLiteral{Value: `A.DAT`}}, tok: "NAME", exp: `NAME "A.DAT"`},
+		{inp: &NameStatement{Token: token.Token{Type: token.NAME, Literal: "NAME"}, OldName: &StringLiteral{Value: `A.DAT`}, NewName: &StringLiteral{Value: `B.DAT`}}, tok: "NAME", exp: `NAME "A.DAT" AS "B.DAT"`},
+		{inp: &ResetStatement{Token: token.Token{Type: token.RESET, Literal: "reset"}}, tok: "RESET", exp: `RESET`},
+		{inp: &MergeCommand{Token: token.Token{Type: token.MERGE, Literal: "merge"}, Path: &StringLiteral{Value: `SUBS.BAS`}}, tok: "MERGE", exp: `MERGE "SUBS.BAS"`},
+		{inp: &DeleteCommand{Token: token.Token{Type: token.DELETE, Literal: "delete"}}, tok: "DELETE", exp: `DELETE`},
+		{inp: &DeleteCommand{Token: token.Token{Type: token.DELETE, Literal: "DELETE"}, Start: "100", Lrange: "-", Stop: "200"}, tok: "DELETE", exp: `DELETE 100-200`},
+		{inp: &DeleteCommand{Token: token.Token{Type: token.DELETE, Literal: "DELETE"}, Lrange: "-", Stop: "50"}, tok: "DELETE", exp: `DELETE -50`},
+		{inp: &DeleteCommand{Token: token.Token{Type: token.DELETE, Literal: "DELETE"}, Start: "300", Lrange: "-", Trash: []TrashStatement{{Token: token.Token{Type: token.IDENT, Literal: "X"}}}}, tok: "DELETE", exp: `DELETE 300- X`, trash: true},
+	}
+
+	for _, tt := range tests {
+		tt.inp.statementNode()
+
+		assert.Equal(t, tt.tok, tt.inp.TokenLiteral())
+		assert.Equal(t, tt.exp, tt.inp.String())
+		assert.Equal(t, tt.trash, tt.inp.(TrashCan).HasTrash())
+	}
+}
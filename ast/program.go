@@ -3,6 +3,7 @@ package ast
 import (
 	"bytes"
 	"errors"
+	"strings"
 
 	"github.com/navionguy/basicwasm/berrors"
 )
@@ -131,6 +132,15 @@ func (p *Program) StatementIter() *Code {
 	return p.code
 }
 
+// ListIter iterates over a copy of the lines, listing or saving the
+// program doesn't move the statement it is running
+func (p *Program) ListIter() *Code {
+	cd := &Code{lines: append([]codeLine(nil), p.code.lines...)}
+	cd.Restart()
+
+	return cd
+}
+
 // CmdLineIter iterates over the command line
 func (p *Program) CmdLineIter() *Code {
 	if p.cmdLine.Len() > 0 {
@@ -192,6 +202,24 @@ func (cd *Code) addLine(lineNum int) {
 	cd.currLine = lineNum
 }
 
+// DeleteLines removes every line numbered from start thru stop
+// returns the number of lines removed
+func (cd *Code) DeleteLines(start int, stop int) int {
+	kept := cd.lines[:0]
+
+	for _, ln := range cd.lines {
+		if (ln.lineNum < start) || (ln.lineNum > stop) {
+			kept = append(kept, ln)
+		}
+	}
+
+	gone := len(cd.lines) - len(kept)
+	cd.lines = kept
+	cd.currIndex = 0
+
+	return gone
+}
+
 // CurLine returns the current executing line number or zero if there isn't one
 func (cd *Code) CurLine() int {
 	if cd.currIndex > len(cd.lines)-1 {
@@ -293,6 +321,7 @@ func (cd *Code) Jump(target int) int {
 
 	if ok {
 		cd.currIndex = i
+		cd.lines[i].curStmt = 0 // it may have been left mid line
 		return 0
 	}
 	// stop execution
@@ -441,3 +470,77 @@ func (data *ConstData) nextStmt() {
 	data.stmt = 0
 	data.line++
 }
+
+// Source gives the text of each line the way it was typed in,
+// lines that weren't, or have been renumbered since, are listed instead
+// it moves the iterator the same as Listing does
+func (cd *Code) Source() []string {
+	lines := cd.Listing(0, cd.MaxLineNum())
+	if len(lines) != len(cd.lines) {
+		return lines
+	}
+
+	for i, ln := range cd.lines {
+		if len(ln.stmts) == 0 {
+			continue
+		}
+		if lns, ok := ln.stmts[0].(*LineNumStmt); ok && (len(lns.Source) > 0) {
+			lines[i] = lns.Source
+		}
+	}
+
+	return lines
+}
+
+// Listing builds the text of each line from start to stop, the way LIST shows it
+// it moves the iterator, use one from ListIter while a program is running
+func (cd *Code) Listing(start int, stop int) []string {
+	var out bytes.Buffer
+	var lines []string
+
+	// couple of flags to control the listing loop
+	midLine := false // tells me I've printed a line # and the first statement (need to insert colons)
+	bList := false   // set true when I see a line # in the printing range
+
+	// roll through lines until I'm done
+	for more := true; more; {
+		stmt := cd.Value() // fetch the next statment
+
+		// check to see if we are starting a new line
+		lnm, ok := stmt.(*LineNumStmt)
+
+		if ok {
+			if int(lnm.Value) > stop {
+				// I've passed the end line #, I'm done
+				break
+			}
+
+			// output anything in the buffer from a previous line, if I'm printing yet
+			if bList {
+				lines = append(lines, strings.TrimRight(out.String(), " "))
+				out.Truncate(0)
+			}
+			bList = (int(lnm.Value) >= start)
+			midLine = false // just wrote a line number, not in the middle of a line
+		}
+
+		if bList {
+			if midLine {
+				// seperate the statements
+				out.WriteString(": ")
+			}
+			out.WriteString(stmt.String())
+			if lnm == nil {
+				midLine = true // in a line until your not
+			}
+		}
+
+		more = cd.Next()
+	}
+
+	if out.Len() > 0 {
+		lines = append(lines, strings.TrimRight(out.String(), " "))
+	}
+
+	return lines
+}
@@ -3,6 +3,8 @@ package ast
 import (
 	"testing"
 
+	"github.com/navionguy/basicwasm/berrors"
+	"github.com/navionguy/basicwasm/token"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -125,3 +127,106 @@ func Test_Value(t *testing.T) {
 		assert.Equal(t, tt.res, res, "cd.value() unexpected result")
 	}
 }
+
+func Test_DeleteLines(t *testing.T) {
+	tests := []struct {
+		start int
+		stop  int
+		gone  int
+		left  []int
+	}{
+		{start: 20, stop: 20, gone: 1, left: []int{10, 30, 40, 50}},
+		{start: 20, stop: 40, gone: 3, left: []int{10, 50}},
+		{start: 0, stop: 25, gone: 2, left: []int{30, 40, 50}},
+		{start: 35, stop: 65529, gone: 2, left: []int{10, 20, 30}},
+		{start: 11, stop: 19, gone: 0, left: []int{10, 20, 30, 40, 50}},
+		{start: 0, stop: 65529, gone: 5},
+	}
+
+	for _, tt := range tests {
+		cd := &Code{}
+		for _, ln := range []int{10, 20, 30, 40, 50} {
+			cd.addLine(ln)
+			cd.lines[cd.currIndex].stmts = append(cd.lines[cd.currIndex].stmts, &LineNumStmt{Value: int32(ln)})
+		}
+
+		assert.Equal(t, tt.gone, cd.DeleteLines(tt.start, tt.stop), "DeleteLines(%d, %d) removed wrong count", tt.start, tt.stop)
+
+		var left []int
+		for _, ln := range cd.lines {
+			left = append(left, ln.lineNum)
+		}
+		assert.Equal(t, tt.left, left, "DeleteLines(%d, %d) left the wrong lines", tt.start, tt.stop)
+
+		for _, ln := range tt.left {
+			assert.True(t, cd.Exists(ln), "line %d can't be found", ln)
+		}
+	}
+}
+
+func Test_Source(t *testing.T) {
+	cd := &Code{}
+	cd.addLine(10)
+	cd.lines[0].stmts = append(cd.lines[0].stmts, &LineNumStmt{Value: 10, Source: "10 END:END"}, &EndStatement{Token: token.Token{Type: token.END, Literal: "END"}})
+	cd.addLine(20)
+	cd.lines[1].stmts = append(cd.lines[1].stmts, &LineNumStmt{Value: 20}, &EndStatement{Token: token.Token{Type: token.END, Literal: "END"}})
+
+	cd.Restart()
+	assert.Equal(t, []string{"10 END:END", "20 END"}, cd.Source(), "Source() lines wrong")
+}
+
+func Test_Renumber(t *testing.T) {
+	tests := []struct {
+		newNum int
+		old    int
+		inc    int
+		lines  []int
+		refs   []string
+		timer  string
+		undef  []string
+		err    int
+	}{
+		{newNum: 10, inc: 10, lines: []int{10, 20, 30, 40}, refs: []string{" GOTO 30", "RUN 20", "RETURN 10", "ON X GOTO 40, 10"}, timer: "ON TIMER(1) GOSUB 30", undef: []string{"Undefined line 99 in 10"}},
+		{newNum: 100, old: 30, inc: 20, lines: []int{5, 20, 100, 120}, refs: []string{" GOTO 100", "RUN 20", "RETURN 5", "ON X GOTO 120, 5"}, timer: "ON TIMER(1) GOSUB 100", undef: []string{"Undefined line 99 in 5"}},
+		{newNum: 30000, old: 30, inc: 10000, lines: []int{5, 20, 30000, 40000}, refs: []string{" GOTO 30000", "RUN 20", "RETURN 5", "ON X GOTO 40000, 5"}, timer: "ON TIMER(1) GOSUB 30000", undef: []string{"Undefined line 99 in 5"}},
+		{newNum: 20, old: 30, inc: 10, err: berrors.IllegalFuncCallErr},
+		{newNum: 100, old: 50, inc: 10, err: berrors.IllegalFuncCallErr},
+		{newNum: 65000, inc: 200, err: berrors.IllegalFuncCallErr},
+		{newNum: 10, inc: 0, err: berrors.IllegalFuncCallErr},
+	}
+
+	for _, tt := range tests {
+		cd := &Code{}
+		refs := []Statement{
+			&GotoStatement{Token: token.Token{Type: token.GOTO, Literal: "GOTO"}, JmpTo: []token.Token{{Type: token.INT, Literal: "30"}}},
+			&RunCommand{Token: token.Token{Type: token.RUN, Literal: "RUN"}, StartLine: 20},
+			&ReturnStatement{Token: token.Token{Type: token.RETURN, Literal: "RETURN"}, ReturnTo: "5"},
+			&OnGoStatement{Token: token.Token{Type: token.ON, Literal: "ON"}, Exp: &Identifier{Value: "X"}, MidTok: token.Token{Type: token.GOTO, Literal: "GOTO"},
+				Jumps: []Expression{&IntegerLiteral{Value: 40}, &IntegerLiteral{Value: 5}}},
+		}
+		for i, ln := range []int{5, 20, 30, 40} {
+			cd.addLine(ln)
+			cd.lines[cd.currIndex].stmts = append(cd.lines[cd.currIndex].stmts, &LineNumStmt{Value: int32(ln), Source: "as typed"}, refs[i])
+		}
+		cd.lines[0].stmts = append(cd.lines[0].stmts, &RestoreStatement{Line: 99})
+		cd.lines[1].stmts = append(cd.lines[1].stmts, &OnEventStatement{Token: token.Token{Type: token.ON, Literal: "ON"},
+			Event: token.Token{Type: token.TIMER, Literal: "TIMER"}, Param: &IntegerLiteral{Value: 1}, Jump: 30})
+
+		undef, err := cd.Renumber(tt.newNum, tt.old, tt.inc)
+
+		assert.Equal(t, tt.err, err, "Renumber(%d, %d, %d) gave wrong error", tt.newNum, tt.old, tt.inc)
+		if err != 0 {
+			assert.True(t, cd.Exists(40), "Renumber(%d, %d, %d) changed lines when it failed", tt.newNum, tt.old, tt.inc)
+			continue
+		}
+		assert.Equal(t, tt.undef, undef, "Renumber(%d, %d, %d) undefined lines wrong", tt.newNum, tt.old, tt.inc)
+		assert.Equal(t, tt.timer, cd.lines[1].stmts[2].String(), "Renumber(%d, %d, %d) ON TIMER wrong", tt.newNum, tt.old, tt.inc)
+
+		for i, ln := range tt.lines {
+			assert.Equal(t, ln, cd.lines[i].lineNum, "Renumber(%d, %d, %d) line %d wrong", tt.newNum, tt.old, tt.inc, i)
+			assert.Equal(t, int32(ln), cd.lines[i].stmts[0].(*LineNumStmt).Value, "Renumber(%d, %d, %d) LineNumStmt %d wrong", tt.newNum, tt.old, tt.inc, i)
+			assert.Empty(t, cd.lines[i].stmts[0].(*LineNumStmt).Source, "Renumber(%d, %d, %d) kept the old text of %d", tt.newNum, tt.old, tt.inc, i)
+			assert.Equal(t, tt.refs[i], cd.lines[i].stmts[1].String(), "Renumber(%d, %d, %d) reference %d wrong", tt.newNum, tt.old, tt.inc, i)
+		}
+	}
+}
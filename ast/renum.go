@@ -0,0 +1,221 @@
+package ast
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/navionguy/basicwasm/berrors"
+	"github.com/navionguy/basicwasm/token"
+)
+
+// highest line number basic will accept
+const maxLineNum = 65529
+
+// Renumber gives every line from old onward a new number, starting at
+// newNum and stepping by inc, then fixes up every reference to them
+// returns the "Undefined line" warnings and an error code, zero if it worked
+func (cd *Code) Renumber(newNum int, old int, inc int) ([]string, int) {
+	if len(cd.lines) == 0 {
+		return nil, 0
+	}
+
+	if (inc <= 0) || (newNum > maxLineNum) {
+		return nil, berrors.IllegalFuncCallErr
+	}
+
+	first, found := cd.findLine(old)
+	if !found && (old > cd.MaxLineNum()) {
+		return nil, berrors.IllegalFuncCallErr
+	}
+
+	// new numbers can't slide in front of lines that aren't moving
+	if (first > 0) && (cd.lines[first-1].lineNum >= newNum) {
+		return nil, berrors.IllegalFuncCallErr
+	}
+
+	if newNum+(len(cd.lines)-first-1)*inc > maxLineNum {
+		return nil, berrors.IllegalFuncCallErr
+	}
+
+	remap := map[int]int{}
+	for i := first; i < len(cd.lines); i++ {
+		remap[cd.lines[i].lineNum] = newNum + (i-first)*inc
+	}
+
+	var undef []string
+	for i := range cd.lines {
+		inLine := cd.lines[i].lineNum
+		if nl, ok := remap[inLine]; ok {
+			inLine = nl
+		}
+
+		fix := func(ref int) int {
+			if nl, ok := remap[ref]; ok {
+				return nl
+			}
+			if !cd.Exists(ref) {
+				undef = append(undef, fmt.Sprintf("Undefined line %d in %d", ref, inLine))
+			}
+			return ref
+		}
+
+		for _, stmt := range cd.lines[i].stmts {
+			renumStatement(stmt, fix)
+		}
+	}
+
+	// now the lines themselves can move
+	for i := first; i < len(cd.lines); i++ {
+		ln := &cd.lines[i]
+		ln.lineNum = remap[ln.lineNum]
+
+		if lns, ok := ln.stmts[0].(*LineNumStmt); ok {
+			lns.Value = int32(ln.lineNum)
+			lns.Token.Literal = strconv.Itoa(ln.lineNum)
+		}
+	}
+
+	// the lines as typed have the old numbers in them
+	for _, ln := range cd.lines {
+		if lns, ok := ln.stmts[0].(*LineNumStmt); ok {
+			lns.Source = ""
+		}
+	}
+	cd.currIndex = 0
+
+	return undef, 0
+}
+
+// renumStatement passes every line number the statement refers to thru fix
+func renumStatement(stmt Statement, fix func(int) int) {
+	switch stmt := stmt.(type) {
+	case *GosubStatement:
+		renumTokens(stmt.Gosub, fix)
+
+	case *GotoStatement:
+		renumTokens(stmt.JmpTo, fix)
+
+	case *IfStatement:
+		stmt.Condition = renumExpression(stmt.Condition, fix)
+		renumStatement(stmt.Consequence, fix)
+		renumStatement(stmt.Alternative, fix)
+
+	case *OnErrorGoto:
+		if stmt.Jump > 0 {
+			stmt.Jump = fix(stmt.Jump)
+		}
+
+	case *OnEventStatement:
+		if stmt.Jump > 0 {
+			stmt.Jump = fix(stmt.Jump)
+		}
+
+	case *OnGoStatement:
+		for i := range stmt.Jumps {
+			stmt.Jumps[i] = renumLiteral(stmt.Jumps[i], fix)
+		}
+
+	case *RestoreStatement:
+		if stmt.Line > 0 {
+			stmt.Line = fix(stmt.Line)
+		}
+
+	case *ResumeStatement:
+		if len(stmt.ResmDir) == 1 {
+			stmt.ResmDir[0] = renumLiteral(stmt.ResmDir[0], fix)
+		}
+
+	case *ReturnStatement:
+		if ln, err := strconv.Atoi(stmt.ReturnTo); err == nil {
+			stmt.ReturnTo = strconv.Itoa(fix(ln))
+		}
+
+	case *RunCommand:
+		if stmt.StartLine > 0 {
+			stmt.StartLine = fix(stmt.StartLine)
+		}
+
+	case *WhileStatement:
+		stmt.Condition = renumExpression(stmt.Condition, fix)
+	}
+}
+
+// a GOTO or GOSUB target is a single integer token
+func renumTokens(tks []token.Token, fix func(int) int) {
+	if (len(tks) != 1) || (tks[0].Type != token.INT) {
+		return
+	}
+
+	ln, err := strconv.Atoi(tks[0].Literal)
+	if err != nil {
+		return
+	}
+	tks[0].Literal = strconv.Itoa(fix(ln))
+}
+
+// look for ERL being compared to a line number, on either side
+func renumExpression(exp Expression, fix func(int) int) Expression {
+	switch exp := exp.(type) {
+	case *GroupedExpression:
+		exp.Exp = renumExpression(exp.Exp, fix)
+
+	case *PrefixExpression:
+		exp.Right = renumExpression(exp.Right, fix)
+
+	case *InfixExpression:
+		if strings.ContainsAny(exp.Operator, "<=>") {
+			if isErl(exp.Left) {
+				exp.Right = renumLiteral(exp.Right, fix)
+				return exp
+			}
+			if isErl(exp.Right) {
+				exp.Left = renumLiteral(exp.Left, fix)
+				return exp
+			}
+		}
+		exp.Left = renumExpression(exp.Left, fix)
+		exp.Right = renumExpression(exp.Right, fix)
+	}
+
+	return exp
+}
+
+// isErl is true if the expression is the ERL variable
+func isErl(exp Expression) bool {
+	id, ok := exp.(*Identifier)
+
+	return ok && (id.Value == "ERL")
+}
+
+// swap a line number literal for its new value
+// zero is never a line reference, it always means something special
+func renumLiteral(exp Expression, fix func(int) int) Expression {
+	var ln int
+	switch lit := exp.(type) {
+	case *IntegerLiteral:
+		if lit.HasTrash() {
+			return exp
+		}
+		ln = int(lit.Value)
+	case *DblIntegerLiteral:
+		if lit.HasTrash() {
+			return exp
+		}
+		ln = int(lit.Value)
+	default:
+		return exp
+	}
+
+	if ln <= 0 {
+		return exp
+	}
+
+	ln = fix(ln)
+	if ln > math.MaxInt16 {
+		return &DblIntegerLiteral{Token: token.Token{Type: token.INTD, Literal: strconv.Itoa(ln)}, Value: int32(ln)}
+	}
+
+	return &IntegerLiteral{Token: token.Token{Type: token.INT, Literal: strconv.Itoa(ln)}, Value: int16(ln)}
+}
@@ -54,17 +54,17 @@ const (
 	InternalErr
 	BadFileNum
 	FileNotFound
-	_
-	_
+	BadFileMode
+	FileAlreadyOpen
 	_
 	DeviceIOError
-	_
+	FileAlreadyExists
 	_
 	_ // 60
 	_
-	_
-	_
-	_
+	InputPastEnd
+	BadRecordNum
+	BadFileName
 	_
 	_
 	_
@@ -74,8 +74,8 @@ const (
 	_
 	_
 	_
-	_
-	_
+	RenameAcrossDisks
+	PathFileAccess
 	PathNotFound
 	ServerError
 )
@@ -83,10 +83,24 @@ const (
 // TextForError returns the error text based on error number
 func TextForError(err int) string {
 	switch err {
+	case BadFileMode:
+		return "Bad file mode"
+	case BadFileName:
+		return "Bad file name"
+	case BadFileNum:
+		return "Bad file number"
+	case BadRecordNum:
+		return "Bad record number"
 	case CantContinue:
 		return "Can't continue"
 	case DivByZero:
 		return "Division by zero"
+	case FieldOverflow:
+		return "FIELD overflow"
+	case FileAlreadyExists:
+		return "File already exists"
+	case FileAlreadyOpen:
+		return "File already open"
 	case FileNotFound:
 		return "File not found"
 	case DeviceIOError:
@@ -95,12 +109,18 @@ func TextForError(err int) string {
 		return "Illegal direct"
 	case IllegalFuncCallErr:
 		return "Illegal function call"
+	case InputPastEnd:
+		return "Input past end"
+	case MissingOp:
+		return "Missing operand"
 	case NextWithoutFor:
 		return "NEXT without FOR"
 	case OutOfData:
 		return "Out of DATA"
 	case Overflow:
 		return "Overflow"
+	case RenameAcrossDisks:
+		return "Rename across disks"
 	case ReturnWoGosub:
 		return "RETURN without GOSUB"
 	case Syntax:
@@ -111,8 +131,14 @@ func TextForError(err int) string {
 		return "Undefined user function"
 	case UnDefinedLineNumber:
 		return "Undefined line number"
+	case WhileWoWend:
+		return "WHILE without WEND"
+	case WendWoWhile:
+		return "WEND without WHILE"
 	case PermissionDenied:
 		return "Permission Denied"
+	case PathFileAccess:
+		return "Path/File access error"
 	case PathNotFound:
 		return "Path not found"
 	case ServerError:
@@ -12,24 +12,37 @@ func TestTextForError(t *testing.T) {
 		val int
 		exp string
 	}{
+		{inp: BadFileMode, val: 54, exp: "Bad file mode"},
+		{inp: BadFileName, val: 64, exp: "Bad file name"},
+		{inp: BadFileNum, val: 52, exp: "Bad file number"},
+		{inp: BadRecordNum, val: 63, exp: "Bad record number"},
 		{inp: CantContinue, val: 17, exp: "Can't continue"},
 		{inp: DivByZero, val: 11, exp: "Division by zero"},
+		{inp: FieldOverflow, val: 50, exp: "FIELD overflow"},
+		{inp: FileAlreadyExists, val: 58, exp: "File already exists"},
+		{inp: FileAlreadyOpen, val: 55, exp: "File already open"},
 		{inp: FileNotFound, val: 53, exp: "File not found"},
 		{inp: DeviceIOError, val: 57, exp: "Device I/O Error"},
 		{inp: IllegalDirect, val: 12, exp: "Illegal direct"},
 		{inp: IllegalFuncCallErr, val: 5, exp: "Illegal function call"},
+		{inp: InputPastEnd, val: 62, exp: "Input past end"},
+		{inp: MissingOp, val: 22, exp: "Missing operand"},
 		{inp: NextWithoutFor, val: 1, exp: "NEXT without FOR"},
 		{inp: OutOfData, val: 4, exp: "Out of DATA"},
 		{inp: Overflow, val: 6, exp: "Overflow"},
+		{inp: RenameAcrossDisks, val: 74, exp: "Rename across disks"},
 		{inp: ReturnWoGosub, val: 3, exp: "RETURN without GOSUB"},
 		{inp: Syntax, val: 2, exp: "Syntax error"},
 		{inp: TypeMismatch, val: 13, exp: "Type mismatch"},
 		{inp: UndefinedFunction, val: 18, exp: "Undefined user function"},
 		{inp: UnDefinedLineNumber, val: 8, exp: "Undefined line number"},
 		{inp: PermissionDenied, val: 70, exp: "Permission Denied"},
+		{inp: PathFileAccess, val: 75, exp: "Path/File access error"},
 		{inp: PathNotFound, val: 76, exp: "Path not found"},
 		{inp: 100, val: 100, exp: "Unprintable error"},
 		{inp: ServerError, val: 77, exp: "Server error"},
+		{inp: WhileWoWend, val: 29, exp: "WHILE without WEND"},
+		{inp: WendWoWhile, val: 30, exp: "WEND without WHILE"},
 	}
 
 	for _, tt := range tests {
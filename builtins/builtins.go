@@ -8,6 +8,7 @@ import (
 	"strconv"
 
 	"github.com/navionguy/basicwasm/berrors"
+	"github.com/navionguy/basicwasm/gwtypes"
 	"github.com/navionguy/basicwasm/object"
 )
 
@@ -286,6 +287,34 @@ var Builtins = map[string]*object.Builtin{
 			return FixType(env, int32(binary.LittleEndian.Uint32(str[:4])))
 		},
 	},
+	"EOF": { // -1 if the end of an input file has been reached
+		Fn: func(env *object.Environment, fn *object.Builtin, args ...object.Object) object.Object {
+			file, err := extractFile(env, args)
+
+			if err != nil {
+				return err
+			}
+
+			// only makes sense for a file you can read from
+			if (file.AccessMode() == gwtypes.Output) || (file.AccessMode() == gwtypes.Append) {
+				return object.StdError(env, berrors.BadFileMode)
+			}
+
+			// a random file is at the end when the last GET came up short
+			if rf, ok := file.(gwtypes.RandomFile); ok && (file.AccessMode() == gwtypes.Random) {
+				if rf.CurRecord()*rf.RecLen() > file.Lof() {
+					return &object.Integer{Value: -1}
+				}
+				return &object.Integer{Value: 0}
+			}
+
+			if file.EOF() {
+				return &object.Integer{Value: -1}
+			}
+
+			return &object.Integer{Value: 0}
+		},
+	},
 	"EXP": { // e^^x
 		Fn: func(env *object.Environment, fn *object.Builtin, args ...object.Object) object.Object {
 			if len(args) != 1 {
@@ -359,14 +388,7 @@ var Builtins = map[string]*object.Builtin{
 				return object.StdError(env, berrors.Syntax)
 			}
 
-			bt := env.Terminal().ReadKeys(1)
-
-			//convert it to a string and send it
-
-			st := &object.String{Value: string(bt)}
-			tv := &object.TypedVar{Value: st, TypeID: "$"}
-
-			return tv
+			return &object.String{Value: env.InKey()}
 		},
 	},
 	"INPUT$": { // read keystrokes from the keyboard
@@ -387,7 +409,7 @@ var Builtins = map[string]*object.Builtin{
 				return object.StdError(env, berrors.IllegalFuncCallErr)
 			}
 
-			bt := env.Terminal().ReadKeys(int(rc))
+			bt := env.ReadKeys(int(rc))
 
 			st := &object.String{Value: string(bt)}
 			tv := &object.TypedVar{Value: st, TypeID: "$"}
@@ -519,6 +541,33 @@ var Builtins = map[string]*object.Builtin{
 			return &object.Integer{Value: int16(len(bstr))}
 		},
 	},
+	"LOC": { // number of 128 byte records read or written so far
+		Fn: func(env *object.Environment, fn *object.Builtin, args ...object.Object) object.Object {
+			file, err := extractFile(env, args)
+
+			if err != nil {
+				return err
+			}
+
+			// random files report the last record read or written
+			if rf, ok := file.(gwtypes.RandomFile); ok && (file.AccessMode() == gwtypes.Random) {
+				return FixType(env, rf.CurRecord())
+			}
+
+			return FixType(env, (file.Loc()+127)/128)
+		},
+	},
+	"LOF": { // length of the file in bytes
+		Fn: func(env *object.Environment, fn *object.Builtin, args ...object.Object) object.Object {
+			file, err := extractFile(env, args)
+
+			if err != nil {
+				return err
+			}
+
+			return FixType(env, file.Lof())
+		},
+	},
 	"LOG": { // return the natural log of a number
 		Fn: func(env *object.Environment, fn *object.Builtin, args ...object.Object) object.Object {
 			if len(args) != 1 {
@@ -634,6 +683,17 @@ var Builtins = map[string]*object.Builtin{
 			return object.StdError(env, berrors.Overflow)
 		},
 	},
+	"POS": { // return the cursor column, the argument is a dummy
+		Fn: func(env *object.Environment, fn *object.Builtin, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return object.StdError(env, berrors.Syntax)
+			}
+
+			_, col := env.Terminal().GetCursor()
+
+			return &object.Integer{Value: int16(col + 1)}
+		},
+	},
 	"RIGHT$": { // return the rightmost n characters of the string
 		Fn: func(env *object.Environment, fn *object.Builtin, args ...object.Object) object.Object {
 			if len(args) != 2 {
@@ -686,25 +746,36 @@ var Builtins = map[string]*object.Builtin{
 		},
 	},
 	"SCREEN": { // read the ascii value at a position on the screen
-		// ToDo: add support for screen color
+		// SCREEN(row,col[,z]) a non-zero z returns the color attribute instead
 		Fn: func(env *object.Environment, fn *object.Builtin, args ...object.Object) object.Object {
-			if len(args) != 2 {
+			if (len(args) < 2) || (len(args) > 3) {
 				return object.StdError(env, berrors.IllegalFuncCallErr)
 			}
 
-			rowarg, ok := extractNumeric(args[0])
-			colarg, ok2 := extractNumeric(args[1])
+			var parms []int
+			for _, arg := range args {
+				v, ok := extractNumeric(arg)
+				if !ok {
+					return object.StdError(env, berrors.TypeMismatch)
+				}
+				parms = append(parms, int(math.Round(v)))
+			}
+
+			row := parms[0]
+			col := parms[1]
+			scrn := env.Screen()
 
-			if !ok || !ok2 {
-				return object.StdError(env, berrors.TypeMismatch)
+			if (row < 1) || (row > object.ScreenRows) || (col < 1) || (col > scrn.Width()) {
+				return object.StdError(env, berrors.IllegalFuncCallErr)
 			}
 
-			row := int(rowarg)
-			col := int(colarg)
+			ch, attr := scrn.Cell(row-1, col-1)
 
-			bt := []byte(env.Terminal().Read(col, row, 1))
+			if (len(parms) == 3) && (parms[2] != 0) {
+				return &object.Integer{Value: int16(attr)}
+			}
 
-			return &object.Integer{Value: int16(bt[0])}
+			return &object.Integer{Value: int16(ch)}
 		},
 	},
 	"SGN": { // return the sign of the argument -1 = neg, 0 = zero, 1 = pos
@@ -1014,6 +1085,31 @@ func extractNumeric(obj object.Object) (float64, bool) {
 	}
 }
 
+// find the open file for the file number passed in
+func extractFile(env *object.Environment, args []object.Object) (gwtypes.AnOpenFile, object.Object) {
+	if len(args) != 1 {
+		return nil, object.StdError(env, berrors.Syntax)
+	}
+
+	num, ok := extractNumeric(args[0])
+
+	if !ok {
+		return nil, object.StdError(env, berrors.TypeMismatch)
+	}
+
+	if (num < 0) || (num > math.MaxInt16) {
+		return nil, object.StdError(env, berrors.BadFileNum)
+	}
+
+	file := env.GetOpenFile(int16(num))
+
+	if file == nil {
+		return nil, object.StdError(env, berrors.BadFileNum)
+	}
+
+	return file, nil
+}
+
 // returns []bytes, extractedYN, isString
 func extractString(obj object.Object) ([]byte, bool, bool) {
 
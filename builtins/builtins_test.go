@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/navionguy/basicwasm/decimal"
+	"github.com/navionguy/basicwasm/gwtypes"
 	"github.com/navionguy/basicwasm/mocks"
 	"github.com/navionguy/basicwasm/object"
 	"github.com/navionguy/basicwasm/token"
@@ -129,6 +130,7 @@ type test struct {
 	inp  []object.Object
 	exp  interface{}
 	scrn string
+	file *mocks.MockFile // opened as file #1
 }
 
 func runTests(t *testing.T, bltin string, tests []test) {
@@ -140,10 +142,14 @@ func runTests(t *testing.T, bltin string, tests []test) {
 
 		var mt mocks.MockTerm
 		mocks.InitMockTerm(&mt)
+		env := object.NewTermEnvironment(mt)
 		if len(tt.scrn) > 0 {
-			mt.StrVal = &tt.scrn
+			env.Terminal().Print(tt.scrn)
+		}
+
+		if tt.file != nil {
+			env.AddOpenFile(1, tt.file)
 		}
-		env := object.NewTermEnvironment(mt)
 
 		if tt.lnum != 0 {
 			env.Set(token.LINENUM, &object.IntDbl{Value: int32(tt.lnum)})
@@ -321,6 +327,21 @@ func TestCvs(t *testing.T) {
 	runTests(t, "CVS", tests)
 }
 
+func TestEof(t *testing.T) {
+	tests := []test{
+		{cmd: `10 EOF(1, 2)`, lnum: 10, inp: []object.Object{&object.Integer{Value: 1}, &object.Integer{Value: 2}}, exp: &object.Error{Message: "Syntax error in 10"}},
+		{cmd: `20 EOF("A")`, lnum: 20, inp: []object.Object{&object.String{Value: "A"}}, exp: &object.Error{Message: "Type mismatch in 20"}},
+		{cmd: `30 EOF(2)`, lnum: 30, inp: []object.Object{&object.Integer{Value: 2}}, exp: &object.Error{Message: "Bad file number in 30"}},
+		{cmd: `40 EOF(-1)`, lnum: 40, inp: []object.Object{&object.Integer{Value: -1}}, exp: &object.Error{Message: "Bad file number in 40"}},
+		{cmd: `50 EOF(1)`, lnum: 50, inp: []object.Object{&object.Integer{Value: 1}}, file: &mocks.MockFile{AccMode: gwtypes.Output}, exp: &object.Error{Message: "Bad file mode in 50"}},
+		{cmd: `60 EOF(1)`, inp: []object.Object{&object.Integer{Value: 1}}, file: &mocks.MockFile{AccMode: gwtypes.Input, Data: []byte("ABC")}, exp: 0},
+		{cmd: `70 EOF(1)`, inp: []object.Object{&object.Integer{Value: 1}}, file: &mocks.MockFile{AccMode: gwtypes.Input, Data: []byte("ABC"), Pos: 3}, exp: -1},
+		{cmd: `80 F% = 1 : EOF(F%)`, inp: []object.Object{&object.TypedVar{TypeID: "%", Value: &object.Integer{Value: 1}}}, file: &mocks.MockFile{AccMode: gwtypes.Input}, exp: -1},
+	}
+
+	runTests(t, "EOF", tests)
+}
+
 func TestExp(t *testing.T) {
 	tests := []test{
 		{cmd: `10 EXP(2, 3)`, lnum: 10, inp: []object.Object{&object.Integer{Value: 2}, &object.Integer{Value: 3}}, exp: &object.Error{Message: "Syntax error in 10"}},
@@ -461,6 +482,30 @@ func TestLeft(t *testing.T) {
 	runTests(t, "LEFT$", tests)
 }
 
+func TestLoc(t *testing.T) {
+	tests := []test{
+		{cmd: `10 LOC(1, 2)`, lnum: 10, inp: []object.Object{&object.Integer{Value: 1}, &object.Integer{Value: 2}}, exp: &object.Error{Message: "Syntax error in 10"}},
+		{cmd: `20 LOC(3)`, lnum: 20, inp: []object.Object{&object.Integer{Value: 3}}, exp: &object.Error{Message: "Bad file number in 20"}},
+		{cmd: `30 LOC(1)`, inp: []object.Object{&object.Integer{Value: 1}}, file: &mocks.MockFile{AccMode: gwtypes.Input, Data: make([]byte, 300)}, exp: 0},
+		{cmd: `40 LOC(1)`, inp: []object.Object{&object.Integer{Value: 1}}, file: &mocks.MockFile{AccMode: gwtypes.Input, Data: make([]byte, 300), Pos: 1}, exp: 1},
+		{cmd: `50 LOC(1)`, inp: []object.Object{&object.Integer{Value: 1}}, file: &mocks.MockFile{AccMode: gwtypes.Input, Data: make([]byte, 300), Pos: 129}, exp: 2},
+	}
+
+	runTests(t, "LOC", tests)
+}
+
+func TestLof(t *testing.T) {
+	tests := []test{
+		{cmd: `10 LOF("1")`, lnum: 10, inp: []object.Object{&object.String{Value: "1"}}, exp: &object.Error{Message: "Type mismatch in 10"}},
+		{cmd: `20 LOF(3)`, lnum: 20, inp: []object.Object{&object.Integer{Value: 3}}, exp: &object.Error{Message: "Bad file number in 20"}},
+		{cmd: `30 LOF(1)`, inp: []object.Object{&object.Integer{Value: 1}}, file: &mocks.MockFile{AccMode: gwtypes.Output}, exp: 0},
+		{cmd: `40 LOF(1)`, inp: []object.Object{&object.Integer{Value: 1}}, file: &mocks.MockFile{AccMode: gwtypes.Input, Data: make([]byte, 300)}, exp: 300},
+		{cmd: `50 LOF(1)`, inp: []object.Object{&object.Integer{Value: 1}}, file: &mocks.MockFile{AccMode: gwtypes.Input, Data: make([]byte, 40000)}, exp: &object.IntDbl{Value: 40000}},
+	}
+
+	runTests(t, "LOF", tests)
+}
+
 func TestLen(t *testing.T) {
 	tests := []test{
 		{cmd: `30 LEN("hello world")`, inp: []object.Object{&object.String{Value: "hello world"}}, exp: 11},
@@ -592,11 +637,27 @@ func TestScreen(t *testing.T) {
 		{cmd: `20 SCREEN(5, "fred")`, lnum: 20, inp: []object.Object{&object.Integer{Value: 5}, &object.String{Value: "fred"}}, scrn: "", exp: &object.Error{Message: "Type mismatch in 20"}},
 		{cmd: `30 SCREEN(1,1)`, inp: []object.Object{&object.Integer{Value: 1}, &object.Integer{Value: 1}}, scrn: "470", exp: &object.Integer{Value: 52}},
 		{cmd: `40 SCREEN(1,2)`, inp: []object.Object{&object.Integer{Value: 1}, &object.Integer{Value: 2}}, scrn: "470", exp: &object.Integer{Value: 55}},
+		{cmd: `50 SCREEN(1,5)`, inp: []object.Object{&object.Integer{Value: 1}, &object.Integer{Value: 5}}, scrn: "470", exp: &object.Integer{Value: 32}},
+		{cmd: `60 SCREEN(1,1,1)`, inp: []object.Object{&object.Integer{Value: 1}, &object.Integer{Value: 1}, &object.Integer{Value: 1}}, scrn: "\x1b[34m\x1b[47m4", exp: &object.Integer{Value: 0x71}},
+		{cmd: `70 SCREEN(2,1,0)`, inp: []object.Object{&object.Integer{Value: 2}, &object.Integer{Value: 1}, &object.Integer{Value: 0}}, scrn: "470\r\nA", exp: &object.Integer{Value: 65}},
+		{cmd: `80 SCREEN(26,1)`, lnum: 80, inp: []object.Object{&object.Integer{Value: 26}, &object.Integer{Value: 1}}, exp: &object.Error{Message: "Illegal function call in 80"}},
+		{cmd: `90 SCREEN(1,81)`, lnum: 90, inp: []object.Object{&object.Integer{Value: 1}, &object.Integer{Value: 81}}, exp: &object.Error{Message: "Illegal function call in 90"}},
 	}
 
 	runTests(t, "SCREEN", tests)
 }
 
+func TestPos(t *testing.T) {
+	tests := []test{
+		{cmd: `10 POS(0, 1)`, lnum: 10, inp: []object.Object{&object.Integer{Value: 0}, &object.Integer{Value: 1}}, exp: &object.Error{Message: "Syntax error in 10"}},
+		{cmd: `20 POS(0)`, inp: []object.Object{&object.Integer{Value: 0}}, exp: &object.Integer{Value: 1}},
+		{cmd: `30 POS(0)`, inp: []object.Object{&object.Integer{Value: 0}}, scrn: "HELLO", exp: &object.Integer{Value: 6}},
+		{cmd: `40 POS(0)`, inp: []object.Object{&object.Integer{Value: 0}}, scrn: "HELLO\r\n", exp: &object.Integer{Value: 1}},
+	}
+
+	runTests(t, "POS", tests)
+}
+
 func TestSgn(t *testing.T) {
 	tests := []test{
 		{cmd: `10 SGN(5, 2)`, lnum: 10, inp: []object.Object{&object.Integer{Value: 5}, &object.Integer{Value: 2}}, exp: &object.Error{Message: "Syntax error in 10"}},
@@ -691,8 +752,8 @@ func TestTab(t *testing.T) {
 
 		var mt mocks.MockTerm
 		mocks.InitMockTerm(&mt)
-		*mt.Col = tt.col
 		env := object.NewTermEnvironment(mt)
+		env.Terminal().Locate(1, tt.col+1)
 		res := fn.Fn(env, fn, tt.inp...)
 
 		assert.EqualValuesf(t, tt.exp, res, "call to TAB(%s) returned %T", tt.inp[0].Inspect(), res)
@@ -1,65 +1,175 @@
 package cli
 
 import (
+	"context"
 	"fmt"
-	"time"
+	"sync"
 
 	"github.com/navionguy/basicwasm/ast"
 	"github.com/navionguy/basicwasm/evaluator"
+	"github.com/navionguy/basicwasm/keybuffer"
 	"github.com/navionguy/basicwasm/lexer"
 	"github.com/navionguy/basicwasm/object"
 	"github.com/navionguy/basicwasm/parser"
 	"github.com/navionguy/basicwasm/settings"
 )
 
-var stopTerminal bool
+// exit status given back by Run
+const (
+	ExitEnd   = 0 // the program ran off the end or hit an END
+	ExitError = 1 // an error the program didn't handle stopped it
+	ExitStop  = 2 // a STOP, or a break, halted the program
+)
+
+// Session is a command line interface running in its own goroutine
+type Session struct {
+	env    *object.Environment
+	ctx    context.Context    // done once the session is stopped
+	cancel context.CancelFunc //
+	done   chan struct{}      // closed when the session is over
+
+	mtx     sync.Mutex
+	restart context.CancelFunc // interrupts the command line for a reset
+	reset   bool               // power back on rather than ending
+}
 
-// Start begins interacting with the user
-func Start(env *object.Environment) {
-	stopTerminal = false
-	go runLoop(env, &stopTerminal)
+// Start begins interacting with the user, the session runs
+// until it is stopped, ctx is done or SYSTEM is entered
+func Start(ctx context.Context, env *object.Environment) *Session {
+	ses := &Session{env: env, done: make(chan struct{})}
+	ses.ctx, ses.cancel = context.WithCancel(ctx)
+
+	go ses.runLoop()
+	return ses
 }
 
-// Stop shuts down the command line interface
-func Stop() {
-	stopTerminal = true
+// Stop tells the session to end, a program that is running
+// gets interrupted, Wait says when it is over
+func (ses *Session) Stop() {
+	ses.cancel()
 }
 
-// runLoop reads key presses and send them off to be processed
-// basically, just loop until the main routine exits
-func runLoop(env *object.Environment, done *bool) {
+// Wait blocks until the session is over
+func (ses *Session) Wait() {
+	<-ses.done
+}
+
+// Reset interrupts whatever is running and puts the session back
+// the way it was when the machine was switched on
+func (ses *Session) Reset() {
+	ses.mtx.Lock()
+	defer ses.mtx.Unlock()
+
+	ses.reset = true
+	if ses.restart != nil {
+		ses.restart()
+	}
+}
+
+// Run loads a program file and runs it with no prompt and
+// no command line, returns the exit status
+func Run(file string, env *object.Environment) int {
+	load := &ast.LoadCommand{Path: &ast.StringLiteral{Value: file}, KeepOpen: true}
+	rc := evaluator.Eval(load, env.CmdLineIter(), env)
+
+	switch msg := rc.(type) {
+	case *object.Error:
+		env.Terminal().Println(msg.Message)
+		return ExitError
+	case *object.HaltSignal:
+		if len(msg.Msg) > 0 {
+			env.Terminal().Println(msg.Msg)
+			return ExitStop
+		}
+	}
+
+	return ExitEnd
+}
+
+// runLoop runs the command line, starting it over after a reset
+func (ses *Session) runLoop() {
+	defer close(ses.done)
+	defer ses.cancel()
 
-	// send the boot-up "OK" to the console
-	env.Terminal().Println("OK")
 	for {
-		keys := env.Terminal().ReadKeys(1)
+		ctx, cancel := context.WithCancel(ses.ctx)
+
+		ses.mtx.Lock()
+		ses.restart = cancel
+		if ses.reset {
+			cancel()
+		}
+		ses.mtx.Unlock()
+
+		ses.env.SetContext(ctx)
+		ses.commandLine(ctx)
+		cancel()
+
+		if (ses.ctx.Err() != nil) || !ses.powerOn() {
+			return
+		}
+	}
+}
+
+// powerOn carries out a reset, returns false if one wasn't asked for
+func (ses *Session) powerOn() bool {
+	ses.mtx.Lock()
+	defer ses.mtx.Unlock()
 
-		evalKeyCodes(keys, env)
+	if !ses.reset {
+		return false
+	}
 
-		if *done {
+	ses.reset = false
+	ses.env.PowerOn()
+	return true
+}
+
+// commandLine reads key presses and sends them off to be processed
+// until ctx is done, the keyboard goes away or SYSTEM is entered
+func (ses *Session) commandLine(ctx context.Context) {
+	env := ses.env
+
+	// send the boot-up "OK" to the console
+	env.Terminal().Println("OK")
+	kb := env.KeyBuffer()
+	for ctx.Err() == nil {
+		// function keys type their macros at the prompt
+		kb.ExpandMacros(true)
+		keys := env.ReadKeys(1)
+		kb.ExpandMacros(false)
+
+		if (len(keys) == 0) || evalKeyCodes(keys, env) {
 			return
 		}
 	}
 }
 
 // given one or more key codes, turn them into action
-func evalKeyCodes(keys []byte, env *object.Environment) {
+// returns true if SYSTEM was entered
+func evalKeyCodes(keys []byte, env *object.Environment) bool {
 	k := keys[0]
 	switch k {
-	case '\r':
-		row, col := env.Terminal().GetCursor()
-		//		fmt.Printf("cursor at %d:%d\n", row, col)
-		env.Terminal().Print("\r\n")
-		nr, nc := env.Terminal().GetCursor()
-		for (nr == row) && (nc == col) {
-			time.Sleep(time.Millisecond)
-			nr, nc = env.Terminal().GetCursor()
-			//env.Terminal().Log(fmt.Sprintf("cursor at %d:%d\n", nr, nc))
+	case keybuffer.ExtendedKey:
+		// the scan code is right behind it
+		if len(keys) < 2 {
+			keys = append(keys, env.ReadKeys(1)...)
+		}
+		if len(keys) > 1 {
+			editKey(keys[1], env)
 		}
-		execCommand(env.Terminal().Read(0, row, 80), env)
+	case 0x1b: // escape
+		abandonLine(env)
+	case '\r':
+		env.Screen().SetInsertMode(false)
+		row, _ := env.Terminal().GetCursor()
 
-		//fmt.Println(term.Read(0, row, 80))
-	case 0x7F: // down arrow
+		// the whole logical line gets entered, even if it wrapped
+		text, last := env.Screen().LogicalLine(row)
+		env.Terminal().Locate(last+1, 1)
+		env.Terminal().Print("\r\n")
+		return execCommand(text, env)
+	case 0x7F: // backspace
 		row, col := env.Terminal().GetCursor()
 		env.Terminal().Locate(row+1, col)
 		env.Terminal().Print("\x1b[P")
@@ -76,16 +186,18 @@ func evalKeyCodes(keys []byte, env *object.Environment) {
 		msg = append(msg, k)
 		hex := hex.EncodeToString(msg)
 		env.Terminal().Print(hex)*/
-		env.Terminal().Print(string(k))
+		typeKey(k, env)
 		//cmd = append(cmd, k)
 		//fmt.Printf("%s\n", hex.EncodeToString(cmd[len(cmd)-1:]))
 	}
 
+	return false
 }
 
 // we have input terminated with a return key
 // should be either a command or a line of source code
-func execCommand(input string, env *object.Environment) {
+// returns true if it was SYSTEM
+func execCommand(input string, env *object.Environment) bool {
 
 	// go parse the input
 	parseCmdLine(input, env)
@@ -98,10 +210,10 @@ func execCommand(input string, env *object.Environment) {
 		if env.GetSetting(settings.Auto) != nil {
 			prompt(env)
 		}
-		return
+		return false
 	}
 
-	parseCmdExecute(iter, env)
+	return parseCmdExecute(iter, env)
 }
 
 // see if I can successfully parse the command line entered
@@ -113,7 +225,8 @@ func parseCmdLine(input string, env *object.Environment) {
 }
 
 // once you have a parsed command line, go execute it
-func parseCmdExecute(iter *ast.Code, env *object.Environment) {
+// returns true if SYSTEM ended the session
+func parseCmdExecute(iter *ast.Code, env *object.Environment) bool {
 
 	for iter.Value() != nil {
 		cmd := iter.Value()
@@ -121,12 +234,13 @@ func parseCmdExecute(iter *ast.Code, env *object.Environment) {
 		obj := evaluator.Eval(cmd, srcIter, env)
 
 		if handleExitMsgs(obj, env) {
-			return
+			return isSystemExit(obj)
 		}
 		iter.Next()
 	}
 	env.CmdComplete()
 	prompt(env)
+	return false
 }
 
 // some special objects that can come back from command execution
@@ -143,11 +257,26 @@ func handleExitMsgs(rc object.Object, env *object.Environment) bool {
 	}
 
 	env.CmdComplete()
-	prompt(env)
+	if !isSystemExit(rc) {
+		prompt(env)
+	}
 	return true
 }
 
+// did SYSTEM get executed
+func isSystemExit(rc object.Object) bool {
+	hs, ok := rc.(*object.HaltSignal)
+	return ok && hs.Exit
+}
+
 func prompt(env *object.Environment) {
+	// EDIT puts the line up instead of OK
+	if ed := env.GetSetting(settings.Edit); ed != nil {
+		env.SaveSetting(settings.Edit, nil)
+		editLine(ed.(*ast.StringLiteral).Value, env)
+		return
+	}
+
 	// get the auto settings if they exist
 	a := env.GetSetting(settings.Auto)
 
@@ -1,11 +1,17 @@
 package cli
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"io"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/navionguy/basicwasm/ast"
+	"github.com/navionguy/basicwasm/console"
+	"github.com/navionguy/basicwasm/keybuffer"
 	"github.com/navionguy/basicwasm/mocks"
 	"github.com/navionguy/basicwasm/object"
 	"github.com/navionguy/basicwasm/settings"
@@ -24,40 +30,120 @@ func Test_StartStop(t *testing.T) {
 	//key := []byte("\r")
 	//	evalKeyCodes(key, env)
 	env := object.NewTermEnvironment(trm)
-	Start(env)
+	ses := Start(context.Background(), env)
 	time.Sleep(5000)
-	Stop()
+	ses.Stop()
+	ses.Wait()
+}
+
+func Test_Session(t *testing.T) {
+	tests := []struct {
+		keys  string
+		stop  bool // stop the session instead of typing SYSTEM
+		reset bool // reset before typing the keys
+		exp   string
+	}{
+		{keys: "SYSTEM\r", exp: "OK\nSYSTEM\n"},
+		{keys: "PRINT 5\rSYSTEM\r", exp: "OK\nPRINT 5\n5\nOK\nSYSTEM\n"},
+		{keys: "10 SYSTEM\rRUN\r", exp: "OK\n10 SYSTEM\nRUN\n"},
+		{keys: "", exp: "OK\n"},
+		{stop: true, exp: "OK\n"},
+		{reset: true, keys: "LIST\rSYSTEM\r", exp: "OK\nOK\nLIST\nOK\nSYSTEM\n"},
+	}
+
+	for _, tt := range tests {
+		rdr, wtr := io.Pipe()
+		var out bytes.Buffer
+		env := object.NewTermEnvironment(console.New(rdr, &out))
+		env.SetClient(&mocks.MockClient{Err: errors.New("no server")})
+		env.Set("A", &object.Integer{Value: 7})
+		parseCmdLine("10 PRINT A", env)
+
+		ses := Start(context.Background(), env)
+		if tt.reset {
+			ses.Reset()
+		}
+
+		switch {
+		case tt.stop:
+			ses.Stop()
+		case len(tt.keys) > 0:
+			io.WriteString(wtr, tt.keys)
+		default:
+			wtr.Close()
+		}
+
+		ses.Wait()
+		assert.Equal(t, tt.exp, out.String(), "%q printed the wrong thing", tt.keys)
+
+		if tt.reset {
+			assert.Equal(t, 0, env.StatementIter().Len(), "reset kept the program")
+			assert.Equal(t, &object.Integer{Value: 0}, env.Get("A"), "reset kept the variables")
+		}
+		wtr.Close()
+	}
+}
+
+func Test_Run(t *testing.T) {
+	tests := []struct {
+		src  string
+		url  string // give an incorrect url so the load fails
+		keys string
+		exp  []string
+		rc   int
+	}{
+		{src: "10 PRINT \"HELLO\"", exp: []string{"HELLO", ""}, rc: ExitEnd},
+		{src: "10 PRINT 1\n20 END\n30 PRINT 2", exp: []string{"1", ""}, rc: ExitEnd},
+		{src: "10 PRINT 1\n20 STOP\n30 PRINT 2", exp: []string{"1", "", "Break in line 20"}, rc: ExitStop},
+		{src: "10 PRINT 1\n20 SYSTEM\n30 PRINT 2", exp: []string{"1", ""}, rc: ExitEnd},
+		{src: "10 X = 1/0", exp: []string{"Division by zero in 10"}, rc: ExitError},
+		{src: "10 ON ERROR GOTO 30\n20 ERROR 5\n30 END", rc: ExitEnd},
+		{src: "10 INPUT A\n20 PRINT A", keys: "7\r", exp: []string{"? ", "7", "", "7", ""}, rc: ExitEnd},
+		{src: "10 INPUT A\n20 PRINT A", keys: "\x03", exp: []string{"? ", "", "Break in line 10"}, rc: ExitStop},
+		{src: "10 PRINT 1", url: "http://localhost:8080/drivec/other.bas", exp: []string{"Server error"}, rc: ExitError},
+	}
+
+	for _, tt := range tests {
+		trm := mocks.MockTerm{}
+		mocks.InitMockTerm(&trm)
+		trm.StrVal = &tt.keys
+		trm.ExpMsg = &mocks.Expector{Exp: tt.exp}
+		env := object.NewTermEnvironment(trm)
+		env.SetClient(&mocks.MockClient{Contents: tt.src, Url: tt.url})
+
+		rc := Run(`C:\PROG.BAS`, env)
+
+		assert.Equal(t, tt.rc, rc, "%s gave the wrong exit status", tt.src)
+		assert.False(t, trm.ExpMsg.Failed, "%s printed the wrong thing", tt.src)
+		assert.Empty(t, trm.ExpMsg.Exp, "%s didn't print everything", tt.src)
+	}
 }
 
 func Test_EvalKeyCodes(t *testing.T) {
 	tests := []struct {
-		inp   string
-		key   []byte
-		exp   []string
-		auto  bool
-		delay int
+		inp  string
+		key  []byte
+		scrn string // text on the screen
+		exp  []string
+		auto bool
 	}{
-		{inp: "", key: []byte("\r"), delay: 2},
-		{inp: "PRINT", key: []byte("\r"), exp: []string{"\r\n", "", "OK"}},           // output a blank line
-		{inp: "Down arrow", key: []byte{0x7f}, exp: []string{"\x1b[P"}},              // move cursor down
-		{inp: "F", key: []byte("F"), exp: []string{"F"}},                             // just echo the key
-		{inp: "ctrl-c", key: []byte{0x03}, exp: []string{""}},                        // nothing visibile, need to check state ToDo
-		{inp: "ctrl-c auto", key: []byte{0x03}, exp: []string{"", "OK"}, auto: true}, // should turn off auto ToDo check that
+		{inp: "", key: []byte("\r")},
+		{inp: "PRINT", key: []byte("\r"), scrn: "PRINT", exp: []string{"\r\n", "", "OK"}}, // output a blank line
+		{inp: "Down arrow", key: []byte{0x7f}, exp: []string{"\x1b[P"}},                   // move cursor down
+		{inp: "F", key: []byte("F"), exp: []string{"F"}},                                  // just echo the key
+		{inp: "ctrl-c", key: []byte{0x03}, exp: []string{""}},                             // nothing visibile, need to check state ToDo
+		{inp: "ctrl-c auto", key: []byte{0x03}, exp: []string{"", "OK"}, auto: true},      // should turn off auto ToDo check that
 	}
 
 	for _, tt := range tests {
 		trm := mocks.MockTerm{}
 		mocks.InitMockTerm(&trm)
-		*trm.Row = 1
-		*trm.Col = len(tt.inp)
-		*trm.Delay = tt.delay
 		trm.StrVal = &tt.inp
 		trm.ExpMsg = &mocks.Expector{}
-		if len(tt.exp) > 0 {
-			trm.ExpMsg.Exp = tt.exp
-		}
-
 		env := object.NewTermEnvironment(trm)
+		env.Terminal().Print(tt.scrn)
+		trm.ExpMsg.Exp = tt.exp
+
 		if tt.auto {
 			ato := ast.AutoCommand{Params: []ast.Expression{&ast.DblIntegerLiteral{Value: int32(10)}, &ast.DblIntegerLiteral{Value: int32(10)}}}
 			env.SaveSetting(settings.Auto, &ato)
@@ -151,6 +237,7 @@ func Test_HandleExitMsgs(t *testing.T) {
 	}{
 		{rc: &object.Error{Message: "Syntax error in line 10"}, exp: "Syntax error in line 10", fail: true},
 		{rc: &object.HaltSignal{Msg: "Halt at line 10"}, exp: "Halt at line 10", fail: true},
+		{rc: &object.HaltSignal{Exit: true}, fail: true},
 		{rc: &object.Integer{Value: 0}, exp: "", fail: false},
 	}
 
@@ -165,3 +252,110 @@ func Test_HandleExitMsgs(t *testing.T) {
 		assert.Equal(t, tt.fail, err)
 	}
 }
+
+func Test_EditKeys(t *testing.T) {
+	// wraps onto a second row, which starts with YZ
+	long := "10 REM " + strings.Repeat("X", 73) + "YZ"
+
+	tests := []struct {
+		key    []byte
+		row    int
+		col    int
+		scrn   string // rest of the keystroke
+		line   string // text on screen at row
+		lrow   int    // row the text starts on, if it isn't row
+		ins    bool   // insert mode going in
+		exp    []string
+		erow   int
+		ecol   int
+		insert bool // insert mode coming out
+		cls    bool
+		after  string // logical line at row coming out
+	}{
+		{key: []byte{keybuffer.ExtendedKey, keybuffer.ScanUp}, row: 5, col: 3, erow: 4, ecol: 3},
+		{key: []byte{keybuffer.ExtendedKey, keybuffer.ScanUp}, row: 0, col: 3, erow: 0, ecol: 3},
+		{key: []byte{keybuffer.ExtendedKey}, scrn: string([]byte{keybuffer.ScanUp}), row: 5, col: 3, erow: 4, ecol: 3},
+		{key: []byte{keybuffer.ExtendedKey, keybuffer.ScanDown}, row: 5, col: 3, ins: true, erow: 6, ecol: 3},
+		{key: []byte{keybuffer.ExtendedKey, keybuffer.ScanDown}, row: 24, col: 3, erow: 24, ecol: 3},
+		{key: []byte{keybuffer.ExtendedKey, keybuffer.ScanLeft}, row: 5, col: 3, erow: 5, ecol: 2},
+		{key: []byte{keybuffer.ExtendedKey, keybuffer.ScanLeft}, row: 5, col: 0, erow: 4, ecol: 79},
+		{key: []byte{keybuffer.ExtendedKey, keybuffer.ScanLeft}, row: 0, col: 0, erow: 0, ecol: 0},
+		{key: []byte{keybuffer.ExtendedKey, keybuffer.ScanRight}, row: 5, col: 3, erow: 5, ecol: 4},
+		{key: []byte{keybuffer.ExtendedKey, keybuffer.ScanRight}, row: 5, col: 79, erow: 6, ecol: 0},
+		{key: []byte{keybuffer.ExtendedKey, keybuffer.ScanRight}, row: 24, col: 79, erow: 24, ecol: 79},
+		{key: []byte{keybuffer.ExtendedKey, keybuffer.ScanHome}, row: 5, col: 3, erow: 0, ecol: 0},
+		{key: []byte{keybuffer.ExtendedKey, keybuffer.ScanEnd}, line: "10 PRINT X", row: 5, col: 0, erow: 5, ecol: 10},
+		{key: []byte{keybuffer.ExtendedKey, keybuffer.ScanInsert}, row: 5, col: 3, erow: 5, ecol: 3, insert: true},
+		{key: []byte{keybuffer.ExtendedKey, keybuffer.ScanInsert}, row: 5, col: 3, ins: true, erow: 5, ecol: 3},
+		{key: []byte{keybuffer.ExtendedKey, keybuffer.ScanDelete}, row: 5, col: 3, ins: true, exp: []string{"\x1b[P"}, erow: 5, ecol: 3, insert: true},
+		{key: []byte{keybuffer.ExtendedKey, keybuffer.ScanCtrlEnd}, row: 5, col: 3, exp: []string{"\x1b[K"}, erow: 5, ecol: 3},
+		{key: []byte{keybuffer.ExtendedKey, keybuffer.ScanCtrlHome}, row: 5, col: 3, ins: true, erow: 0, ecol: 0, cls: true},
+		{key: []byte{keybuffer.ExtendedKey, keybuffer.ScanDelete}, line: long, row: 5, col: 3, exp: []string{"\x1b[P", "Y", "\x1b[P"}, erow: 5, ecol: 3, after: "10 EM " + strings.Repeat("X", 73) + "YZ"},
+		{key: []byte{keybuffer.ExtendedKey, keybuffer.ScanDelete}, line: long, lrow: 5, row: 6, col: 0, exp: []string{"\x1b[P"}, erow: 6, ecol: 0, after: "10 REM " + strings.Repeat("X", 73) + "Z"},
+		{key: []byte{0x1b}, row: 5, col: 3, ins: true, exp: []string{"\x1b[2K"}, erow: 5, ecol: 0},
+		{key: []byte{0x1b}, line: long, lrow: 5, row: 6, col: 1, exp: []string{"\x1b[2K", "\x1b[2K"}, erow: 5, ecol: 0},
+		{key: []byte("A"), row: 5, col: 3, exp: []string{"A"}, erow: 5, ecol: 4},
+		{key: []byte("A"), row: 5, col: 3, ins: true, exp: []string{"\x1b[@A"}, erow: 5, ecol: 4, insert: true},
+	}
+
+	for _, tt := range tests {
+		trm := mocks.MockTerm{}
+		mocks.InitMockTerm(&trm)
+		trm.StrVal = &tt.scrn
+		trm.ExpMsg = &mocks.Expector{}
+		env := object.NewTermEnvironment(trm)
+		lrow := tt.row
+		if tt.lrow > 0 {
+			lrow = tt.lrow
+		}
+		env.Terminal().Locate(lrow+1, 1)
+		env.Terminal().Print(tt.line)
+		env.Terminal().Locate(tt.row+1, tt.col+1)
+		trm.ExpMsg.Exp = tt.exp
+		env.Screen().SetInsertMode(tt.ins)
+
+		evalKeyCodes(tt.key, env)
+
+		row, col := env.Terminal().GetCursor()
+		assert.False(t, trm.ExpMsg.Failed, "%x printed the wrong thing", tt.key)
+		assert.Empty(t, trm.ExpMsg.Exp, "%x didn't print everything", tt.key)
+		assert.Equal(t, tt.erow, row, "%x left cursor on wrong row", tt.key)
+		assert.Equal(t, tt.ecol, col, "%x left cursor in wrong column", tt.key)
+		assert.Equal(t, tt.insert, env.Screen().InsertMode(), "%x insert mode wrong", tt.key)
+		assert.Equal(t, tt.cls, *trm.SawCls, "%x screen clear wrong", tt.key)
+
+		text, _ := env.Screen().LogicalLine(tt.row)
+		if len(tt.after) > 0 {
+			assert.Equal(t, tt.after, text, "%x left the wrong line", tt.key)
+		}
+	}
+}
+
+func Test_EditPrompt(t *testing.T) {
+	long := "10 PRINT \"" + strings.Repeat("X", 100) + "\""
+
+	tests := []struct {
+		prog string
+		cmd  string
+		exp  []string
+	}{
+		{prog: "10 PRINT X", cmd: "EDIT 10", exp: []string{"10 PRINT X\r"}},
+		{prog: long, cmd: "EDIT 10", exp: []string{long + "\r", "\x1b[1A"}},
+		{prog: "10 PRINT X", cmd: "EDIT 20", exp: []string{"Undefined line number", "OK"}},
+	}
+
+	for _, tt := range tests {
+		trm := mocks.MockTerm{}
+		mocks.InitMockTerm(&trm)
+		trm.ExpMsg = &mocks.Expector{}
+		env := object.NewTermEnvironment(trm)
+		execCommand(tt.prog, env)
+
+		trm.ExpMsg.Exp = tt.exp
+		execCommand(tt.cmd, env)
+
+		assert.False(t, trm.ExpMsg.Failed, "%s printed the wrong thing", tt.cmd)
+		assert.Empty(t, trm.ExpMsg.Exp, "%s didn't print everything", tt.cmd)
+		assert.Nil(t, env.GetSetting(settings.Edit), "%s left the edit setting", tt.cmd)
+	}
+}
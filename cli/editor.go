@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/navionguy/basicwasm/keybuffer"
+	"github.com/navionguy/basicwasm/object"
+)
+
+// editKey performs the action for one of the extended editing keys
+func editKey(scan byte, env *object.Environment) {
+	row, col := env.Terminal().GetCursor()
+	cols := env.Screen().Width()
+
+	switch scan {
+	case keybuffer.ScanUp:
+		if row > 0 {
+			row--
+		}
+	case keybuffer.ScanDown:
+		if row < object.ScreenRows-1 {
+			row++
+		}
+	case keybuffer.ScanLeft:
+		row, col = cursorLeft(row, col, cols)
+	case keybuffer.ScanRight:
+		row, col = cursorRight(row, col, cols)
+	case keybuffer.ScanHome:
+		row, col = 0, 0
+	case keybuffer.ScanEnd:
+		col = len(env.Terminal().Read(0, row, cols))
+		if col >= cols {
+			col = cols - 1
+		}
+	case keybuffer.ScanInsert:
+		env.Screen().SetInsertMode(!env.Screen().InsertMode())
+		return
+	case keybuffer.ScanDelete:
+		deleteChar(row, col, env)
+		return
+	case keybuffer.ScanCtrlEnd:
+		env.Terminal().Print("\x1b[K") // erase to end of line
+		return
+	case keybuffer.ScanCtrlHome:
+		env.Screen().SetInsertMode(false)
+		env.Terminal().Cls()
+		return
+	default:
+		return
+	}
+
+	// any cursor movement ends insert mode
+	env.Screen().SetInsertMode(false)
+	env.Terminal().Locate(row+1, col+1)
+}
+
+// move back one column, wrapping to the end of the row above
+func cursorLeft(row, col, cols int) (int, int) {
+	if col > 0 {
+		return row, col - 1
+	}
+
+	if row > 0 {
+		return row - 1, cols - 1
+	}
+
+	return row, col
+}
+
+// move forward one column, wrapping to the start of the row below
+func cursorRight(row, col, cols int) (int, int) {
+	if col < cols-1 {
+		return row, col + 1
+	}
+
+	if row < object.ScreenRows-1 {
+		return row + 1, 0
+	}
+
+	return row, col
+}
+
+// deleteChar removes the character under the cursor, the rest of
+// the logical line moves back, pulling up from the rows it wrapped onto
+func deleteChar(row, col int, env *object.Environment) {
+	cols := env.Screen().Width()
+	_, last := env.Screen().LogicalLine(row)
+
+	env.Terminal().Print("\x1b[P") // delete character under the cursor
+	for r := row; r < last; r++ {
+		// the first character of the next row fills the end of this one
+		env.Terminal().Locate(r+1, cols)
+		env.Terminal().Print(padRead(env.Terminal().Read(0, r+1, 1)))
+		env.Terminal().Locate(r+2, 1)
+		env.Terminal().Print("\x1b[P")
+	}
+
+	env.Terminal().Locate(row+1, col+1)
+}
+
+// padRead gives back a blank where the screen read came up empty
+func padRead(s string) string {
+	if len(s) == 0 {
+		return " "
+	}
+	return s
+}
+
+// typeKey puts a character on the screen at the cursor
+func typeKey(k byte, env *object.Environment) {
+	if env.Screen().InsertMode() {
+		env.Terminal().Print("\x1b[@" + string(k)) // open up a space first
+		return
+	}
+
+	env.Terminal().Print(string(k))
+}
+
+// abandonLine handles the Esc key, the whole logical line is wiped
+// from the screen and nothing gets entered
+func abandonLine(env *object.Environment) {
+	row, _ := env.Terminal().GetCursor()
+	first := env.Screen().LineStart(row)
+	_, last := env.Screen().LogicalLine(row)
+
+	env.Screen().SetInsertMode(false)
+	for r := first; r <= last; r++ {
+		env.Terminal().Locate(r+1, 1)
+		env.Terminal().Print("\x1b[2K")
+	}
+	env.Terminal().Locate(first+1, 1)
+}
+
+// editLine displays a program line and leaves the cursor on its first
+// character so it can be changed and re-entered
+func editLine(text string, env *object.Environment) {
+	env.Terminal().Print(text + "\r")
+
+	// long lines wrap, get back up to where it started
+	if up := (len(text) - 1) / env.Screen().Width(); up > 0 {
+		env.Terminal().Print(fmt.Sprintf("\x1b[%dA", up))
+	}
+}
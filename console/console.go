@@ -0,0 +1,172 @@
+package console
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync/atomic"
+
+	"github.com/navionguy/basicwasm/keybuffer"
+)
+
+// Console runs the interpreter without a browser.
+// Screen output goes out as plain text, keystrokes come
+// from a reader, usually stdin
+type Console struct {
+	out   io.Writer
+	kbuff *keybuffer.KeyBuffer // closed once the input is used up
+	brk   int32                // set to one when a ctrl-c comes in
+}
+
+// New creates a Console reading keys from in and writing to out
+func New(in io.Reader, out io.Writer) *Console {
+	con := &Console{out: out, kbuff: keybuffer.New()}
+
+	go con.feedKeys(in)
+	return con
+}
+
+// feedKeys passes the input to the key buffer one key at a time
+// line feeds become the Enter key
+func (con *Console) feedKeys(in io.Reader) {
+	defer con.kbuff.Close()
+
+	rdr := bufio.NewReader(in)
+	prev := byte(0)
+	for {
+		bt, err := rdr.ReadByte()
+		if err != nil {
+			return
+		}
+
+		switch {
+		case bt == '\n' && prev == '\r':
+			// already sent the Enter
+		case bt == '\n':
+			con.saveKey([]byte{'\r'})
+		default:
+			con.saveKey([]byte{bt})
+		}
+		prev = bt
+	}
+}
+
+// saveKey hands one keystroke to the key buffer
+// noting a ctrl-c for BreakCheck
+func (con *Console) saveKey(key []byte) {
+	if bytes.IndexByte(key, 0x03) >= 0 {
+		atomic.StoreInt32(&con.brk, 1)
+	}
+	con.kbuff.SaveKeyStroke(key)
+}
+
+// KeyBuffer gives the environment the keys typed at this console
+func (con *Console) KeyBuffer() *keybuffer.KeyBuffer {
+	return con.kbuff
+}
+
+// Cls has nothing to clear on a stream
+func (con *Console) Cls() {
+}
+
+// Print writes the text with any escape sequences removed
+func (con *Console) Print(msg string) {
+	io.WriteString(con.out, plainText(msg))
+}
+
+// Println prints the string followed by a new line
+func (con *Console) Println(msg string) {
+	con.Print(msg + "\n")
+}
+
+// Locate can't move around in a stream
+func (con *Console) Locate(row, col int) {
+}
+
+// Log is dropped, it would mix in with the program's output
+func (con *Console) Log(msg string) {
+}
+
+// GetCursor is always home, the screen buffer tracks the real position
+func (con *Console) GetCursor() (int, int) {
+	return 0, 0
+}
+
+// Read has no screen to read from, the screen buffer does that
+func (con *Console) Read(col, row, len int) string {
+	return ""
+}
+
+// ReadKeys waits for the requested number of keystrokes
+// once the input runs dry it returns what it has
+func (con *Console) ReadKeys(count int) []byte {
+	var keys []byte
+
+	for len(keys) < count {
+		bt, err := con.kbuff.ReadKey(context.Background())
+		if err != nil {
+			return keys
+		}
+		keys = append(keys, bt)
+	}
+
+	return keys
+}
+
+// SoundBell is silent, a bell would just clutter the output
+func (con *Console) SoundBell() {
+}
+
+// BreakCheck returns true if a ctrl-c was entered
+// the flag is cleared before returning
+func (con *Console) BreakCheck() bool {
+	return atomic.SwapInt32(&con.brk, 0) == 1
+}
+
+// plainText drops escape sequences and carriage returns
+func plainText(msg string) string {
+	if !strings.ContainsAny(msg, "\x1b\r") {
+		return msg
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(msg); i++ {
+		switch msg[i] {
+		case '\r':
+		case 0x1b:
+			i = skipEscape(msg, i)
+		default:
+			out.WriteByte(msg[i])
+		}
+	}
+
+	return out.String()
+}
+
+// returns the index of the last byte of the escape sequence starting at i
+func skipEscape(msg string, i int) int {
+	if i+1 >= len(msg) {
+		return i
+	}
+
+	switch msg[i+1] {
+	case '[': // control sequence, ends with a final byte
+		for j := i + 2; j < len(msg); j++ {
+			if (msg[j] >= 0x40) && (msg[j] <= 0x7e) {
+				return j
+			}
+		}
+	case ']': // operating system command, ends with a bell
+		for j := i + 2; j < len(msg); j++ {
+			if msg[j] == 0x07 {
+				return j
+			}
+		}
+	default: // two character sequence
+		return i + 1
+	}
+
+	return len(msg) - 1
+}
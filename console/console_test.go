@@ -0,0 +1,105 @@
+package console
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Print(t *testing.T) {
+	tests := []struct {
+		inp string
+		exp string
+	}{
+		{inp: "HELLO", exp: "HELLO"},
+		{inp: "HELLO\r\nWORLD", exp: "HELLO\nWORLD"},
+		{inp: "\x1b[31mRED\x1b[0m", exp: "RED"},
+		{inp: "\x1b[5d\x1b[10`X", exp: "X"},
+		{inp: "\x1b]0;title\x07TEXT", exp: "TEXT"},
+		{inp: "\x1b7SAVED\x1b8", exp: "SAVED"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		con := New(strings.NewReader(""), &out)
+
+		con.Print(tt.inp)
+		assert.Equal(t, tt.exp, out.String(), "%q printed wrong", tt.inp)
+
+		out.Reset()
+		con.Println(tt.inp)
+		assert.Equal(t, tt.exp+"\n", out.String(), "%q println wrong", tt.inp)
+	}
+}
+
+func Test_ReadKeys(t *testing.T) {
+	tests := []struct {
+		inp   string
+		count int
+		exp   []byte
+		brk   bool
+	}{
+		{inp: "A", count: 1, exp: []byte("A")},
+		{inp: "42\n", count: 3, exp: []byte("42\r")},
+		{inp: "42\r\n", count: 3, exp: []byte("42\r")},
+		{inp: "short", count: 10, exp: []byte("short")},
+		{inp: "", count: 1},
+		{inp: "1\x03", count: 2, exp: []byte("1\x03"), brk: true},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		con := New(strings.NewReader(tt.inp), &out)
+
+		keys := con.ReadKeys(tt.count)
+		assert.Equal(t, tt.exp, keys, "%q read the wrong keys", tt.inp)
+		assert.Equal(t, tt.brk, con.BreakCheck(), "%q break check wrong", tt.inp)
+		assert.False(t, con.BreakCheck(), "%q break check didn't clear", tt.inp)
+	}
+}
+
+func Test_SplitKeys(t *testing.T) {
+	tests := []struct {
+		inp  string
+		keys []string
+	}{
+		{inp: "A", keys: []string{"A"}},
+		{inp: "AB\r", keys: []string{"A", "B", "\r"}},
+		{inp: "\x1b", keys: []string{"\x1b"}},
+		{inp: "\x1bX", keys: []string{"\x1b", "X"}},
+		{inp: "A\x1b[DB", keys: []string{"A", "\x1b[D", "B"}},
+		{inp: "\x1b[15~\x1bOP", keys: []string{"\x1b[15~", "\x1bOP"}},
+		{inp: "\x1b[1;5H", keys: []string{"\x1b[1;5H"}},
+		{inp: "\x1b[1;", keys: []string{"\x1b[1;"}},
+		{inp: "\x1bO", keys: []string{"\x1bO"}},
+	}
+
+	for _, tt := range tests {
+		var keys []string
+		for _, k := range splitKeys([]byte(tt.inp)) {
+			keys = append(keys, string(k))
+		}
+
+		assert.Equal(t, tt.keys, keys, "%q split wrong", tt.inp)
+	}
+}
+
+func Test_Terminal(t *testing.T) {
+	var out bytes.Buffer
+	trm := newTerminal(strings.NewReader("\x1b[A\x03"), &out)
+
+	trm.Print("\x1b[31mRED")
+	trm.Println("")
+	trm.Locate(5, 10)
+	trm.Cls()
+	trm.SoundBell()
+	trm.Log("not shown")
+	trm.Restore()
+	assert.Equal(t, "\x1b[31mRED\r\n\x1b[5d\x1b[10`\x1b[2J\x1b[H\a", out.String(), "terminal output wrong")
+
+	keys := trm.ReadKeys(3)
+	assert.Equal(t, []byte{0x00, 0x48, 0x03}, keys, "cursor up and ctrl-c read wrong")
+	assert.True(t, trm.BreakCheck(), "ctrl-c not seen")
+}
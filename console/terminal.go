@@ -0,0 +1,185 @@
+package console
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/navionguy/basicwasm/keybuffer"
+	"github.com/navionguy/basicwasm/object"
+)
+
+// Terminal runs the interpreter in a terminal window.
+// The tty is put in raw mode so every key comes straight in and
+// output, colors included, goes to it untouched
+type Terminal struct {
+	*Console
+	tty  *os.File // the tty in raw mode, nil if there isn't one
+	mode *ttyMode // how the tty was set before going raw
+}
+
+// NewTerminal switches the tty to raw mode and starts reading keys from it
+// call Restore before exiting to give the tty back like it was
+func NewTerminal(tty *os.File) (*Terminal, error) {
+	mode, err := makeRaw(tty)
+	if err != nil {
+		return nil, err
+	}
+
+	trm := newTerminal(tty, tty)
+	trm.tty = tty
+	trm.mode = mode
+	trm.watchSize()
+
+	return trm, nil
+}
+
+// builds the Terminal without touching the tty mode
+func newTerminal(in io.Reader, out io.Writer) *Terminal {
+	trm := &Terminal{Console: &Console{out: out, kbuff: keybuffer.New()}}
+
+	go trm.feedKeys(in)
+	return trm
+}
+
+// Restore puts the tty back the way it was found
+func (trm *Terminal) Restore() {
+	if trm.mode == nil {
+		return
+	}
+
+	trm.write(fmt.Sprintf("\x1b[r\x1b[%dd\r\n", object.ScreenRows))
+	restoreMode(trm.tty, trm.mode)
+	trm.mode = nil
+}
+
+// feedKeys splits what the tty sends into keystrokes
+// escape sequences have to stay together to be recognized
+func (trm *Terminal) feedKeys(in io.Reader) {
+	defer trm.kbuff.Close()
+
+	buf := make([]byte, 256)
+	for {
+		n, err := in.Read(buf)
+		for _, key := range splitKeys(buf[:n]) {
+			trm.saveKey(key)
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// splitKeys breaks the input into single keystrokes
+func splitKeys(inp []byte) [][]byte {
+	var keys [][]byte
+
+	for i := 0; i < len(inp); {
+		l := keyLength(inp[i:])
+		keys = append(keys, inp[i:i+l])
+		i += l
+	}
+
+	return keys
+}
+
+// how many bytes make up the key at the start of inp
+func keyLength(inp []byte) int {
+	if (inp[0] != 0x1b) || (len(inp) < 2) {
+		return 1
+	}
+
+	switch inp[1] {
+	case '[': // control sequence, runs to the final byte
+		for i := 2; i < len(inp); i++ {
+			if (inp[i] >= 0x40) && (inp[i] <= 0x7e) {
+				return i + 1
+			}
+		}
+		return len(inp)
+	case 'O': // single shift, F1 thru F4 and some cursor keys
+		if len(inp) > 2 {
+			return 3
+		}
+		return len(inp)
+	}
+
+	// just the escape key
+	return 1
+}
+
+// Cls clears the screen and homes the cursor
+func (trm *Terminal) Cls() {
+	trm.write("\x1b[2J\x1b[H")
+}
+
+// Print sends the string to the tty as is
+func (trm *Terminal) Print(msg string) {
+	trm.write(msg)
+}
+
+// Println prints the string followed by a CR/LF
+func (trm *Terminal) Println(msg string) {
+	trm.write(msg + "\r\n")
+}
+
+// Locate moves the cursor, upper left is 1,1
+func (trm *Terminal) Locate(row, col int) {
+	trm.write(fmt.Sprintf("\x1b[%dd\x1b[%d`", row, col))
+}
+
+// Log is dropped, it would scribble over the screen
+func (trm *Terminal) Log(msg string) {
+}
+
+// SoundBell rings the tty bell
+func (trm *Terminal) SoundBell() {
+	trm.write("\a")
+}
+
+func (trm *Terminal) write(msg string) {
+	io.WriteString(trm.out, msg)
+}
+
+// WaitQuit blocks until ctrl-\ is pressed or the tty goes away
+func (trm *Terminal) WaitQuit() {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, sigQuit...)
+	<-sig
+	signal.Stop(sig)
+}
+
+// watchSize keeps the scrolling region at the screen size
+// as the window changes
+func (trm *Terminal) watchSize() {
+	trm.fitWindow()
+	if len(sigWinch) == 0 {
+		return
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, sigWinch...)
+	go func() {
+		for range sig {
+			trm.fitWindow()
+		}
+	}()
+}
+
+// a window taller than the screen has to scroll at the bottom
+// of the screen, not the bottom of the window
+func (trm *Terminal) fitWindow() {
+	rows, _, err := windowSize(trm.tty)
+	if (err != nil) || (rows <= 0) {
+		return
+	}
+
+	if rows > object.ScreenRows {
+		rows = object.ScreenRows
+	}
+
+	// save and restore the cursor, setting the region homes it
+	trm.write(fmt.Sprintf("\x1b7\x1b[1;%dr\x1b8", rows))
+}
@@ -0,0 +1,76 @@
+//go:build linux
+// +build linux
+
+package console
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// the tty settings, saved so they can be put back
+type ttyMode syscall.Termios
+
+// resizing the window sends SIGWINCH
+var sigWinch = []os.Signal{syscall.SIGWINCH}
+
+// ctrl-\ sends SIGQUIT, closing the window SIGHUP
+var sigQuit = []os.Signal{syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGHUP}
+
+func ioctl(tty *os.File, req uintptr, arg unsafe.Pointer) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, tty.Fd(), req, uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+// makeRaw turns off line editing and echo so keys come in as typed
+// ctrl-c and ctrl-z come thru as keys, ctrl-\ still quits
+func makeRaw(tty *os.File) (*ttyMode, error) {
+	var mode syscall.Termios
+	if err := ioctl(tty, syscall.TCGETS, unsafe.Pointer(&mode)); err != nil {
+		return nil, err
+	}
+	saved := ttyMode(mode)
+
+	mode.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	mode.Oflag &^= syscall.OPOST
+	mode.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.IEXTEN
+	mode.Cflag &^= syscall.CSIZE | syscall.PARENB
+	mode.Cflag |= syscall.CS8
+	mode.Cc[syscall.VMIN] = 1
+	mode.Cc[syscall.VTIME] = 0
+	mode.Cc[syscall.VINTR] = 0 // disabled
+	mode.Cc[syscall.VSUSP] = 0
+
+	if err := ioctl(tty, syscall.TCSETS, unsafe.Pointer(&mode)); err != nil {
+		return nil, err
+	}
+
+	return &saved, nil
+}
+
+// restoreMode puts back the saved tty settings
+func restoreMode(tty *os.File, mode *ttyMode) {
+	ioctl(tty, syscall.TCSETS, unsafe.Pointer(mode))
+}
+
+// windowSize returns the rows and columns of the terminal window
+func windowSize(tty *os.File) (int, int, error) {
+	var ws struct {
+		Row, Col, X, Y uint16
+	}
+
+	if tty == nil {
+		return 0, 0, os.ErrInvalid
+	}
+
+	if err := ioctl(tty, syscall.TIOCGWINSZ, unsafe.Pointer(&ws)); err != nil {
+		return 0, 0, err
+	}
+
+	return int(ws.Row), int(ws.Col), nil
+}
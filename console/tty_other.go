@@ -0,0 +1,28 @@
+//go:build !linux
+// +build !linux
+
+package console
+
+import (
+	"errors"
+	"os"
+)
+
+type ttyMode struct{}
+
+var sigWinch []os.Signal
+
+var sigQuit = []os.Signal{os.Interrupt}
+
+var errNoRaw = errors.New("raw terminal mode is only supported on linux")
+
+func makeRaw(tty *os.File) (*ttyMode, error) {
+	return nil, errNoRaw
+}
+
+func restoreMode(tty *os.File, mode *ttyMode) {
+}
+
+func windowSize(tty *os.File) (int, int, error) {
+	return 0, 0, errNoRaw
+}
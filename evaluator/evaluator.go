@@ -7,15 +7,22 @@ import (
 	"bytes"
 	"fmt"
 	"math"
+	"path"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/navionguy/basicwasm/afile"
 	"github.com/navionguy/basicwasm/ast"
 	"github.com/navionguy/basicwasm/berrors"
 	"github.com/navionguy/basicwasm/builtins"
 	"github.com/navionguy/basicwasm/decimal"
 	"github.com/navionguy/basicwasm/filelist"
 	"github.com/navionguy/basicwasm/fileserv"
+	"github.com/navionguy/basicwasm/gwtoken"
+	"github.com/navionguy/basicwasm/gwtypes"
+	"github.com/navionguy/basicwasm/keybuffer"
 	"github.com/navionguy/basicwasm/lexer"
 	"github.com/navionguy/basicwasm/localfiles"
 	"github.com/navionguy/basicwasm/object"
@@ -76,6 +83,9 @@ func Eval(tnode ast.Node, code *ast.Code, env *object.Environment) object.Object
 	case *ast.DataStatement:
 		return nil
 
+	case *ast.DeleteCommand:
+		return evalDeleteCommand(node, env)
+
 	case *ast.DimStatement:
 		evalDimStatement(node, code, env)
 
@@ -88,12 +98,18 @@ func Eval(tnode ast.Node, code *ast.Code, env *object.Environment) object.Object
 	case *ast.CommonStatement:
 		evalCommonStatement(node, env)
 
+	case *ast.EditCommand:
+		return evalEditCommand(node, env)
+
 	case *ast.EndStatement:
 		return evalEndStatement(env)
 
 	case *ast.ErrorStatement:
 		return evalErrorStatement(node, code, env)
 
+	case *ast.EventTrapStatement:
+		return evalEventTrapStatement(node, code, env)
+
 	case *ast.ExpressionStatement:
 		if node.Token.Literal == ":" {
 			return nil
@@ -103,9 +119,15 @@ func Eval(tnode ast.Node, code *ast.Code, env *object.Environment) object.Object
 	case *ast.FilesCommand:
 		return evalFilesCommand(node, code, env)
 
+	case *ast.FieldStatement:
+		return evalFieldStatement(node, code, env)
+
 	case *ast.ForStatement:
 		return evalForStatement(node, code, env)
 
+	case *ast.GetStatement:
+		return evalGetStatement(node, code, env)
+
 	case *ast.GosubStatement:
 		return evalGosubStatement(node, code, env)
 
@@ -121,9 +143,15 @@ func Eval(tnode ast.Node, code *ast.Code, env *object.Environment) object.Object
 	case *ast.InkeyExpression:
 		return evalInKeyExpression(env)
 
+	case *ast.InputStatement:
+		return evalInputStatement(node, code, env)
+
 	case *ast.KeyStatement:
 		return evalKeyStatement(node, code, env)
 
+	case *ast.KillStatement:
+		return evalKillStatement(node, code, env)
+
 	case *ast.LetStatement:
 		val := Eval(node.Value, code, env)
 		if isError(val) {
@@ -131,11 +159,13 @@ func Eval(tnode ast.Node, code *ast.Code, env *object.Environment) object.Object
 		}
 		// life gets more complicated, not less
 		if !strings.ContainsAny(node.Name.Token.Literal, "[($%!#") {
-			env.Set(node.Name.Token.Literal, val)
-			return nil
+			return env.Set(node.Name.Token.Literal, val)
 		}
 		return saveVariable(code, env, node.Name, val)
 
+	case *ast.LineInputStatement:
+		return evalLineInputStatement(node, code, env)
+
 	case *ast.LineNumStmt:
 		ln := &object.IntDbl{Value: node.Value}
 		env.Set(token.LINENUM, ln)
@@ -152,6 +182,18 @@ func Eval(tnode ast.Node, code *ast.Code, env *object.Environment) object.Object
 	case *ast.LocateStatement:
 		return evalLocateStatement(node, code, env)
 
+	case *ast.LsetStatement:
+		return evalJustifyStatement(node.Name, node.Value, true, code, env)
+
+	case *ast.MergeCommand:
+		return evalMergeCommand(node, code, env)
+
+	case *ast.MkDirStatement:
+		return evalMkDirStatement(node, code, env)
+
+	case *ast.NameStatement:
+		return evalNameStatement(node, code, env)
+
 	case *ast.NextStatement:
 		return evalNextStatement(node, code, env)
 
@@ -164,15 +206,21 @@ func Eval(tnode ast.Node, code *ast.Code, env *object.Environment) object.Object
 	case *ast.OnErrorGoto:
 		return evalOnErrorStatement(node, code, env)
 
+	case *ast.OnEventStatement:
+		return evalOnEventStatement(node, code, env)
+
 	case *ast.OnGoStatement:
 		return evalOnGoStatement(node, code, env)
 
 	case *ast.OpenStatement:
-		return evalOpenStatement(*node, env)
+		return evalOpenStatement(*node, code, env)
 
 	case *ast.PrintStatement:
 		return evalPrintStatement(node, code, env)
 
+	case *ast.PutStatement:
+		return evalPutStatement(node, code, env)
+
 		// Expressions
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
@@ -222,6 +270,12 @@ func Eval(tnode ast.Node, code *ast.Code, env *object.Environment) object.Object
 	case *ast.ReadStatement:
 		return evalReadStatement(node, code, env)
 
+	case *ast.RenumCommand:
+		return evalRenumCommand(node, env)
+
+	case *ast.ResetStatement:
+		env.CloseAllFiles()
+
 	case *ast.RestoreStatement:
 		return evalRestoreStatement(node, env)
 
@@ -234,15 +288,27 @@ func Eval(tnode ast.Node, code *ast.Code, env *object.Environment) object.Object
 	case *ast.ReturnStatement:
 		return evalReturnStatement(code, env)
 
+	case *ast.RmDirStatement:
+		return evalRmDirStatement(node, code, env)
+
+	case *ast.RsetStatement:
+		return evalJustifyStatement(node.Name, node.Value, false, code, env)
+
 	case *ast.RunCommand:
 		return evalRunCommand(node, code, env)
 
+	case *ast.SaveCommand:
+		return evalSaveCommand(node, code, env)
+
 	case *ast.ScreenStatement:
 		return evalScreenStatement(node, code, env)
 
 	case *ast.StopStatement:
 		return evalStopStatement(code, env)
 
+	case *ast.SystemCommand:
+		return evalSystemCommand(env)
+
 	case *ast.CallExpression:
 		function := Eval(node.Function, code, env)
 		if isError(function) {
@@ -269,6 +335,15 @@ func Eval(tnode ast.Node, code *ast.Code, env *object.Environment) object.Object
 	case *ast.ViewStatement:
 		return evalViewStatement()
 
+	case *ast.WendStatement:
+		return evalWendStatement(code, env)
+
+	case *ast.WhileStatement:
+		return evalWhileStatement(node, code, env)
+
+	case *ast.WriteStatement:
+		return evalWriteStatement(node, code, env)
+
 	default:
 		msg := fmt.Sprintf("unsupported codepoint at line %d, %T", code.CurLine(), node)
 		env.Terminal().Println(msg)
@@ -441,7 +516,7 @@ func evalChainStatement(chain *ast.ChainStatement, code *ast.Code, env *object.E
 
 // attempt to pull down the  desired file
 func evalChainLoad(file string, env *object.Environment) object.Object {
-	rdr, err := fileserv.GetFile(file, env)
+	rdr, err := evalGetProgFile(file, env)
 
 	if err != nil {
 		return err
@@ -477,6 +552,7 @@ func evalChainStart(env *object.Environment) object.Object {
 func evalChainExecute(env *object.Environment) object.Object {
 	pcode := env.StatementIter()
 	env.ConstData().Restore()
+	env.ClearTraps()
 
 	rc := evalRunStart(pcode, env)
 
@@ -510,10 +586,17 @@ func evalClearCommand(env *object.Environment) {
 	env.ClearVars() // environment handles all the details
 	env.CloseAllFiles()
 	env.ClearCommon()
+	env.ClearTraps()
 }
 
 // close one or more files
 func evalCloseStatement(close *ast.CloseStatement, code *ast.Code, env *object.Environment) object.Object {
+	// CLOSE all by itself closes every open file
+	if len(close.Files) == 0 {
+		env.CloseAllFiles()
+		return nil
+	}
+
 	for _, fnum := range close.Files {
 		rc := evalExpressionNode(fnum.Numbr, code, env)
 		switch val := rc.(type) {
@@ -720,11 +803,22 @@ func evalStatements(code *ast.Code, env *object.Environment) object.Object {
 				halt = !code.Next()
 			}
 		} else {
-			if env.Terminal().BreakCheck() {
+			// give the front-end a turn so key presses get in
+			env.Spend()
+
+			// ending the session breaks into the program too
+			if env.Terminal().BreakCheck() || (env.Context().Err() != nil) {
 				rc = evalStatementsBreakChk(code, env)
 				halt = true
 			} else {
-				halt = !code.Next()
+				rc = evalStatementsEventChk(code, env)
+				if rc != nil {
+					halt, code, rc = evalStatementResult(rc, code, env)
+				}
+
+				if !halt {
+					halt = !code.Next()
+				}
 			}
 		}
 	}
@@ -752,7 +846,11 @@ func evalStatementResult(rc object.Object, code *ast.Code, env *object.Environme
 	case object.ObjectType("HALT"):
 		halt = true
 		env.SaveSetting(settings.Restart, code)
-		rc = nil
+		// a STOP needs to say where it happened, END is silent
+		// and a SYSTEM has to make it back to the session
+		if hs := rc.(*object.HaltSignal); (len(hs.Msg) == 0) && !hs.Exit {
+			rc = nil
+		}
 	default:
 		halt = true
 		rc = object.StdError(env, berrors.Syntax)
@@ -796,6 +894,32 @@ func evalStatementsBreakChk(code *ast.Code, env *object.Environment) object.Obje
 	return &hlt
 }
 
+// evalStatementsEventChk GOSUBs to the handler of a trapped event
+// the return address is the statement that just finished
+func evalStatementsEventChk(code *ast.Code, env *object.Environment) object.Object {
+	if !env.ProgramRunning() {
+		return nil
+	}
+
+	line := env.NextEvent()
+	if line == 0 {
+		return nil
+	}
+
+	env.Push(code.GetReturnPoint())
+
+	err := code.Jump(line)
+	if err > 0 {
+		return object.StdError(env, err)
+	}
+
+	if env.GetTrace() {
+		env.Terminal().Print(fmt.Sprintf("[%d]", line))
+	}
+
+	return nil
+}
+
 // read constant values out of data statements into variables
 func evalReadStatement(rd *ast.ReadStatement, code *ast.Code, env *object.Environment) object.Object {
 	var value object.Object
@@ -961,7 +1085,7 @@ func evalRunLoad(run *ast.RunCommand, code *ast.Code, env *object.Environment) o
 }
 
 func evalRunFetch(file string, run *ast.RunCommand, env *object.Environment) object.Object {
-	rdr, err := fileserv.GetFile(file, env)
+	rdr, err := evalGetProgFile(file, env)
 
 	if err != nil {
 		object.StdError(env, berrors.Syntax)
@@ -988,6 +1112,7 @@ func evalRunCheckStartLineNum(run *ast.RunCommand, env *object.Environment) obje
 	//	env.Terminal().Println("evalRunCheckStartLineNum")
 	pcode := env.StatementIter()
 	env.ConstData().Restore()
+	env.ClearTraps()
 
 	if run.StartLine > 0 {
 		err := pcode.Jump(run.StartLine)
@@ -1035,9 +1160,26 @@ func evalScreenStatement(scrn *ast.ScreenStatement, code *ast.Code, env *object.
 
 	// save the new SCREEN settings
 	env.SaveSetting(settings.Screen, cur)
+	evalScreenWidth(cur.Settings[ast.ScrnMode], env)
 	return nil
 }
 
+// the medium resolution modes only have room for 40 columns
+// mode 0 keeps whatever width it has
+func evalScreenWidth(mode int, env *object.Environment) {
+	scrn := env.Screen()
+	if scrn == nil {
+		return
+	}
+
+	switch mode {
+	case 1, 7:
+		scrn.SetWidth(40)
+	case 2, 8, 9, 10:
+		scrn.SetWidth(object.ScreenCols)
+	}
+}
+
 // get the current setting, if it exists
 func evalScreenGetCurrent(env *object.Environment) *ast.ScreenStatement {
 	cur := env.GetSetting(settings.Screen)
@@ -1067,6 +1209,12 @@ func evalStopStatement(code *ast.Code, env *object.Environment) object.Object {
 	return &halt
 }
 
+// close any open files and leave the interpreter
+func evalSystemCommand(env *object.Environment) object.Object {
+	env.CloseAllFiles()
+	return &object.HaltSignal{Exit: true}
+}
+
 // turn off tracing
 func evalTroffCommand(env *object.Environment) {
 	env.SetTrace(false)
@@ -1160,6 +1308,62 @@ func evalEndStatement(env *object.Environment) object.Object {
 	return &object.HaltSignal{}
 }
 
+// TIMER ON, OFF or STOP and KEY(n) ON, OFF or STOP
+func evalEventTrapStatement(node *ast.EventTrapStatement, code *ast.Code, env *object.Environment) object.Object {
+	// only KEY says which one
+	if node.HasTrash() || ((node.Param != nil) != (node.Token.Type == token.KEY)) {
+		return object.StdError(env, berrors.Syntax)
+	}
+
+	state := object.TrapOn
+	switch node.State.Type {
+	case token.OFF:
+		state = object.TrapOff
+	case token.STOP:
+		state = object.TrapStop
+	}
+
+	if node.Token.Type != token.KEY {
+		env.SetTrapState(node.TokenLiteral(), state)
+		return nil
+	}
+
+	key, err := evalEventKeyNum(node.Param, code, env)
+	if err != nil {
+		return err
+	}
+
+	env.SetKeyState(key, state)
+	return nil
+}
+
+// evaluates the key number for KEY(n), it has to be 1 thru 20
+func evalEventKeyNum(param ast.Expression, code *ast.Code, env *object.Environment) (int, object.Object) {
+	key, err := evalEventParam(param, code, env)
+	if err != nil {
+		return 0, err
+	}
+
+	if (key < 1) || (key > keybuffer.LastKey) {
+		return 0, object.StdError(env, berrors.IllegalFuncCallErr)
+	}
+
+	return int(key), nil
+}
+
+// evaluates the number in parens for an event
+func evalEventParam(param ast.Expression, code *ast.Code, env *object.Environment) (int32, object.Object) {
+	rc := Eval(param, code, env)
+	switch rc.(type) {
+	case *object.Error:
+		return 0, rc
+	case *object.String:
+		return 0, object.StdError(env, berrors.TypeMismatch)
+	}
+
+	return coerceDblInteger(rc, env)
+}
+
 // ERROR statement, user wants to signal an error has occurred
 func evalErrorStatement(ers *ast.ErrorStatement, code *ast.Code, env *object.Environment) object.Object {
 	rc := evalExpressionNode(ers.ErrNum, code, env)
@@ -1232,6 +1436,248 @@ func evalFilesCommand(files *ast.FilesCommand, code *ast.Code, env *object.Envir
 	return nil
 }
 
+// KILL deletes every file matching the filespec
+// none of them can be deleted while they are open
+func evalKillStatement(stmt *ast.KillStatement, code *ast.Code, env *object.Environment) object.Object {
+	fp, err := evalFileSpec(stmt.Path, code, env)
+	if err != nil {
+		return err
+	}
+
+	dir, spec := splitFilePath(fp)
+
+	// collect the matching files, remembering which the server holds
+	files := make(map[string]bool)
+	for _, fn := range localfiles.For(env).List(dir) {
+		_, name := splitFilePath(fn)
+		if matchFileSpec(spec, name) {
+			files[fn] = false
+		}
+	}
+
+	for _, ent := range evalServerDir(dir, env).Files {
+		if !ent.Subdir && matchFileSpec(spec, strings.ToLower(ent.Name)) {
+			files[dir+strings.ToLower(ent.Name)+`\`] = true
+		}
+	}
+
+	if len(files) == 0 {
+		return object.StdError(env, berrors.FileNotFound)
+	}
+
+	names := make([]string, 0, len(files))
+	for fn := range files {
+		if len(env.FindOpenFiles(fn)) > 0 {
+			return object.StdError(env, berrors.FileAlreadyOpen)
+		}
+		names = append(names, fn)
+	}
+	sort.Strings(names)
+
+	for _, fn := range names {
+		if files[fn] && (fileserv.DeleteFile(fn, env) != nil) {
+			// read-only drive, the file would just come back
+			return object.StdError(env, berrors.PathFileAccess)
+		}
+		localfiles.For(env).Remove(fn)
+	}
+
+	return nil
+}
+
+// MKDIR creates a directory, the server is asked to create
+// it as well but a read-only drive can't hold it
+func evalMkDirStatement(stmt *ast.MkDirStatement, code *ast.Code, env *object.Environment) object.Object {
+	fp, err := evalFileSpec(stmt.Path, code, env)
+	if err != nil {
+		return err
+	}
+
+	if localfiles.For(env).DirExists(fp) || evalServerDirExists(fp, env) {
+		return object.StdError(env, berrors.PathFileAccess)
+	}
+
+	localfiles.For(env).MakeDir(fp)
+
+	err = fileserv.MakeDir(fp, env)
+	if (err != nil) && (env.Terminal() != nil) {
+		env.Terminal().Log(err.Inspect())
+	}
+
+	return nil
+}
+
+// NAME changes the name of a file, the new name can't be in use
+func evalNameStatement(stmt *ast.NameStatement, code *ast.Code, env *object.Environment) object.Object {
+	if stmt.NewName == nil {
+		return object.StdError(env, berrors.Syntax)
+	}
+
+	oldfp, err := evalFileSpec(stmt.OldName, code, env)
+	if err != nil {
+		return err
+	}
+
+	newfp, err := evalFileSpec(stmt.NewName, code, env)
+	if err != nil {
+		return err
+	}
+
+	if oldfp[:2] != newfp[:2] {
+		return object.StdError(env, berrors.RenameAcrossDisks)
+	}
+
+	local, remote := evalFileExists(oldfp, env)
+	if !local && !remote {
+		return object.StdError(env, berrors.FileNotFound)
+	}
+
+	if l, r := evalFileExists(newfp, env); l || r {
+		return object.StdError(env, berrors.FileAlreadyExists)
+	}
+
+	if len(env.FindOpenFiles(oldfp)) > 0 {
+		return object.StdError(env, berrors.FileAlreadyOpen)
+	}
+
+	if remote && (fileserv.RenameFile(oldfp, newfp, env) != nil) {
+		return object.StdError(env, berrors.PathFileAccess)
+	}
+
+	localfiles.For(env).Rename(oldfp, newfp)
+
+	return nil
+}
+
+// RMDIR removes an empty directory, you can't remove the one you are in
+func evalRmDirStatement(stmt *ast.RmDirStatement, code *ast.Code, env *object.Environment) object.Object {
+	fp, err := evalFileSpec(stmt.Path, code, env)
+	if err != nil {
+		return err
+	}
+
+	local := localfiles.For(env).DirExists(fp)
+	remote := evalServerDirExists(fp, env)
+	if !local && !remote {
+		return object.StdError(env, berrors.PathNotFound)
+	}
+
+	if (fp == fileserv.GetCWD(env)) || !localfiles.For(env).DirEmpty(fp) {
+		return object.StdError(env, berrors.PathFileAccess)
+	}
+
+	if remote {
+		if len(evalServerDir(fp, env).Files) > 0 {
+			return object.StdError(env, berrors.PathFileAccess)
+		}
+
+		if fileserv.DeleteFile(fp, env) != nil {
+			return object.StdError(env, berrors.PathFileAccess)
+		}
+	}
+
+	localfiles.For(env).RemoveDir(fp)
+
+	return nil
+}
+
+// evaluate the path given to a file management statement
+// and build the full path to it
+func evalFileSpec(path ast.Expression, code *ast.Code, env *object.Environment) (string, object.Object) {
+	if path == nil {
+		return "", object.StdError(env, berrors.Syntax)
+	}
+
+	res := Eval(path, code, env)
+	if isError(res) {
+		return "", res
+	}
+
+	str, ok := res.(*object.String)
+	if !ok {
+		return "", object.StdError(env, berrors.TypeMismatch)
+	}
+
+	if len(strings.TrimSpace(str.Value)) == 0 {
+		return "", object.StdError(env, berrors.BadFileName)
+	}
+
+	return fileserv.BuildFullPath(str.Value, env), nil
+}
+
+// split a full path into the directory and the name
+func splitFilePath(fp string) (string, string) {
+	fp = strings.TrimSuffix(fp, `\`)
+	i := strings.LastIndex(fp, `\`) + 1
+
+	return fp[:i], fp[i:]
+}
+
+// check if the file is held locally and/or by the server
+func evalFileExists(fp string, env *object.Environment) (bool, bool) {
+	local := localfiles.For(env).Find(fp) != nil
+
+	dir, name := splitFilePath(fp)
+	for _, ent := range evalServerDir(dir, env).Files {
+		if !ent.Subdir && strings.EqualFold(ent.Name, name) {
+			return local, true
+		}
+	}
+
+	return local, false
+}
+
+// check if the server holds the directory
+func evalServerDirExists(fp string, env *object.Environment) bool {
+	dir, name := splitFilePath(fp)
+	for _, ent := range evalServerDir(dir, env).Files {
+		if ent.Subdir && strings.EqualFold(ent.Name, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// get the list of files the server holds in a directory
+// any failure just gives an empty list
+func evalServerDir(dir string, env *object.Environment) *filelist.FileList {
+	list := filelist.NewFileList()
+
+	rdr, err := fileserv.GetFile(dir, env)
+	if err != nil {
+		return list
+	}
+
+	if list.Build(rdr, env) != nil {
+		list.Files = nil
+	}
+
+	return list
+}
+
+// matchFileSpec checks a name against a filespec with wildcards
+// like DOS, the name and extension are matched separately
+func matchFileSpec(spec, name string) bool {
+	sbase, sext := splitFileExt(strings.ToLower(spec))
+	nbase, next := splitFileExt(strings.ToLower(name))
+
+	bok, _ := path.Match(sbase, nbase)
+	eok, _ := path.Match(sext, next)
+
+	return bok && eok
+}
+
+// split a file name at the extension
+func splitFileExt(name string) (string, string) {
+	i := strings.Index(name, ".")
+	if i < 0 {
+		return name, ""
+	}
+
+	return name[:i], name[i+1:]
+}
+
 func catchNotDir(path string, err error, env *object.Environment) {
 	if err.Error() != "NotDir" {
 		env.Terminal().Println(err.Error())
@@ -1455,529 +1901,1516 @@ func evalImpliedLetStatement(node *ast.Identifier, env *object.Environment) obje
 }
 
 func evalInKeyExpression(env *object.Environment) object.Object {
-	rKey := env.Terminal().ReadKeys(1)
-
-	key := &object.String{Value: string(rKey[0])}
+	// the keyboard may have nothing more to give
+	key := &object.String{Value: env.InKey()}
 
 	return key
 }
 
-// defines, enables, disables and lists keyboard macros
-func evalKeyStatement(node *ast.KeyStatement, code *ast.Code, env *object.Environment) object.Object {
-	// get the current key definitions
-	keyDefs := evalKeyStatementGetKeyDefs(env)
+// INPUT prompts the user and reads the values typed into variables
+func evalInputStatement(stmt *ast.InputStatement, code *ast.Code, env *object.Environment) object.Object {
+	if len(stmt.Vars) == 0 {
+		return object.StdError(env, berrors.Syntax)
+	}
 
-	switch node.Param.(type) {
-	case *ast.OnExpression:
-		keyDefs.Disp = true
-	case *ast.OffExpression:
-		keyDefs.Disp = false
-	case *ast.ListExpression: // list out the current defined values
-		env.Terminal().Print(keyDefs.String())
-	default:
-		err := evalKeyStatementFKeyParams(keyDefs, node, code, env)
-		if err != nil {
-			return err
-		}
+	if stmt.File != nil {
+		return evalInputFileStatement(stmt, code, env)
 	}
 
-	// save any changes made
-	env.SaveSetting(settings.KeyMacs, keyDefs)
+	for {
+		evalInputPrompt(stmt.Prompt, stmt.QMark, env)
 
-	return nil
-}
+		line, brk := evalInputReadLine(stmt.SameLine, env)
+		if brk {
+			return evalInputBreak(code, env)
+		}
 
-// retrieve or create the current key definitions
-func evalKeyStatementGetKeyDefs(env *object.Environment) *ast.KeySettings {
-	// create empty settings
-	keyDefs := &ast.KeySettings{}
-	keyDefs.Keys = make(map[string]string)
+		vals, rc := evalInputValues(line, stmt.Vars, code, env)
+		if rc != nil {
+			return rc
+		}
 
-	// go get the keyboard macro settings
-	keys := env.GetSetting(settings.KeyMacs)
+		if vals != nil {
+			return evalInputSaveValues(stmt.Vars, vals, code, env)
+		}
 
-	if keys != nil {
-		// settings have been saved before, use saved values
-		keyDefs = keys.(*ast.KeySettings)
+		// he gets to try again
+		env.Terminal().Println("?Redo from start")
 	}
+}
 
-	return keyDefs
+// display the prompt, if there is one, and the question mark
+func evalInputPrompt(prompt *ast.StringLiteral, qmark bool, env *object.Environment) {
+	if prompt != nil {
+		env.Terminal().Print(prompt.Value)
+	}
+
+	if qmark {
+		env.Terminal().Print("? ")
+	}
 }
 
-// user wants to redefine macro for F1 to F10
-func evalKeyStatementFKeyParams(keys *ast.KeySettings, node *ast.KeyStatement, code *ast.Code, env *object.Environment) object.Object {
-	// do the error checking first
-	// one and only one parameter for this form
-	if len(node.Data) != 1 {
-		return object.StdError(env, berrors.Syntax)
-	}
-	ind := evalExpressionNodeTyped(node.Param, code, env, &object.Integer{})
-
-	if ind == nil {
-		return object.StdError(env, berrors.Syntax)
-	}
+// evalInputReadLine reads key strokes, echoing them, until the user presses enter
+// returns true if the user hit ctrl-c or the keyboard has nothing more to give
+func evalInputReadLine(sameLine bool, env *object.Environment) (string, bool) {
+	var line []byte
 
-	i := ind.(*object.Integer).Value
-	// index 1-10 is a keybord function key
-	if (i >= 1) && (i <= 10) {
-		return evalKeyStatementMapFKey(i, keys, node.Data[0], code, env)
-	}
-	// index 15-20 are user defined keys for the ON KEY statements
-	if (i >= 15) && (i <= 20) {
-		return evalKeyStatmentCustomKey(node.Data[0], code, env)
-	}
+	// function keys type their macros
+	kb := env.KeyBuffer()
+	kb.ExpandMacros(true)
+	defer kb.ExpandMacros(false)
 
-	// index is invalid
-	return object.StdError(env, berrors.IllegalFuncCallErr)
-}
+	for {
+		keys := env.ReadKeys(1)
 
-// define a string to insert for a function key
-func evalKeyStatementMapFKey(key int16, keys *ast.KeySettings, val ast.Expression, code *ast.Code, env *object.Environment) object.Object {
-	s := evalExpressionNodeTyped(val, code, env, &object.String{})
+		if len(keys) == 0 {
+			return string(line), true
+		}
 
-	if s == nil {
-		return object.StdError(env, berrors.Syntax)
+		switch k := keys[0]; k {
+		case '\r':
+			if !sameLine {
+				env.Terminal().Println("")
+			}
+			return string(line), false
+		case 0x03: // ctrl-c
+			env.Terminal().Println("")
+			return string(line), true
+		case 0x08, 0x7f: // backspace
+			if len(line) > 0 {
+				line = line[:len(line)-1]
+				env.Terminal().Print("\b \b")
+			}
+		case keybuffer.ExtendedKey: // editing keys do nothing, drop the scan code
+			if len(keys) < 2 {
+				env.ReadKeys(1)
+			}
+		default:
+			if k >= ' ' {
+				line = append(line, k)
+				env.Terminal().Print(string(k))
+			}
+		}
 	}
+}
 
-	k := fmt.Sprintf("F%d", key)
-	keys.Keys[k] = s.(*object.String).Value
+// user wants out, stop execution
+func evalInputBreak(code *ast.Code, env *object.Environment) object.Object {
+	// clear the flag so we don't see it twice
+	env.Terminal().BreakCheck()
 
-	return nil
+	return evalStatementsBreakChk(code, env)
 }
 
-func evalKeyStatmentCustomKey(val ast.Expression, code *ast.Code, env *object.Environment) object.Object {
-	b := evalExpressionNodeTyped(val, code, env, &object.String{})
+// evalInputValues splits the line up and converts each field to fit his variable
+// returns nil values if the user needs to redo his input
+func evalInputValues(line string, vars []ast.Expression, code *ast.Code, env *object.Environment) ([]object.Object, object.Object) {
+	fields, quoted := evalInputFields(line)
 
-	if b == nil {
-		return object.StdError(env, berrors.Syntax)
+	if len(fields) != len(vars) {
+		return nil, nil
 	}
-	return nil
-}
-
-// list some or all of the current program
-func evalListStatement(stmt *ast.ListStatement, env *object.Environment) {
-	var out bytes.Buffer
-	// get a code iterator
-	cd := env.StatementIter()
 
-	// assume my default limits
-	start := 0
-	stop := cd.MaxLineNum()
+	var vals []object.Object
+	for i, v := range vars {
+		id, ok := v.(*ast.Identifier)
 
-	// figure out any limits to the listing
-	// is there a starting line?
-	if len(stmt.Start) > 0 {
-		start, _ = strconv.Atoi(stmt.Start)
-		if len(stmt.Lrange) == 0 {
-			stop = start
+		if !ok {
+			return nil, object.StdError(env, berrors.Syntax)
 		}
-	}
 
-	// is there a stopping point
-	if len(stmt.Stop) > 0 {
-		stop, _ = strconv.Atoi(stmt.Stop)
+		val := evalInputValue(fields[i], quoted[i], id, code, env)
+		if val == nil {
+			return nil, nil
+		}
+		vals = append(vals, val)
 	}
 
-	// couple of flags to control the listing loop
-	midLine := false // tells me I've printed a line # and the first statement (need to insert colons)
-	bList := false   // set true when I see a line # in the printing range
-
-	// roll through lines until I'm done
-	for more := true; more; {
-		stmt := cd.Value() // fetch the next statment
+	return vals, nil
+}
 
-		// check to see if we are starting a new line
-		lnm, ok := stmt.(*ast.LineNumStmt)
+// break the input line into comma separated fields
+// a field can be a quoted string, which allows commas inside of it
+func evalInputFields(line string) ([]string, []bool) {
+	var fields []string
+	var quoted []bool
 
-		if ok {
-			if int(lnm.Value) > stop {
-				// I've passed the end line #, I'm done
-				break
-			}
+	for i := 0; i <= len(line); i++ {
+		// skip leading spaces
+		for (i < len(line)) && (line[i] == ' ') {
+			i++
+		}
 
-			// output anything in the buffer from a previous line, if I'm printing yet
-			if bList {
-				env.Terminal().Println(strings.TrimRight(out.String(), " "))
-				out.Truncate(0)
+		// quoted string runs to the closing quote
+		if (i < len(line)) && (line[i] == '"') {
+			end := strings.IndexByte(line[i+1:], '"')
+			if end < 0 {
+				end = len(line[i+1:])
 			}
-			bList = (int(lnm.Value) >= start)
-			midLine = false // just wrote a line number, not in the middle of a line
-		}
+			fields = append(fields, line[i+1:i+1+end])
+			quoted = append(quoted, true)
+			i += end + 2
 
-		if bList {
-			if midLine {
-				// seperate the statements
-				out.WriteString(": ")
+			// nothing but spaces allowed before the comma
+			for (i < len(line)) && (line[i] == ' ') {
+				i++
 			}
-			out.WriteString(stmt.String())
-			if lnm == nil {
-				midLine = true // in a line until your not
+			if (i < len(line)) && (line[i] != ',') {
+				// force a redo
+				return nil, nil
 			}
+			continue
 		}
 
-		more = cd.Next()
+		end := strings.IndexByte(line[i:], ',')
+		if end < 0 {
+			end = len(line[i:])
+		}
+		fields = append(fields, strings.TrimRight(line[i:i+end], " "))
+		quoted = append(quoted, false)
+		i += end
 	}
-	env.Terminal().Println(out.String())
+
+	return fields, quoted
 }
 
-// evalLoadCommand - load and parse the target program
-func evalLoadCommand(stmt *ast.LoadCommand, code *ast.Code, env *object.Environment) object.Object {
-	// get the target file name
-	res := Eval(stmt.Path, code, env)
-	str, ok := res.(*object.String)
+// evalInputValue converts the field into a value for the variable
+// returns nil if it just won't fit
+func evalInputValue(field string, quoted bool, id *ast.Identifier, code *ast.Code, env *object.Environment) object.Object {
+	typeid, _ := parseVarName(id.Value)
 
-	if !ok {
-		return object.StdError(env, berrors.TypeMismatch)
+	if typeid == "$" {
+		return &object.String{Value: field}
 	}
 
-	return evalLoadGetFile(str.Value, stmt, env)
-}
-
-// calls the file server looking for a source file
-func evalLoadGetFile(file string, stmt *ast.LoadCommand, env *object.Environment) object.Object {
-	rdr, err := fileserv.GetFile(file, env)
-
-	if err != nil {
-		// server sent an error, get out
-		return err
+	if quoted {
+		return nil
 	}
 
-	return evalLoadParse(rdr, stmt, env)
-}
+	// nothing entered means zero
+	if len(field) == 0 {
+		return &object.Integer{Value: 0}
+	}
 
-// parse in the loaded file
-func evalLoadParse(rdr *bufio.Reader, stmt *ast.LoadCommand, env *object.Environment) object.Object {
-	// flush the old program
-	env.NewProgram()
-	fileserv.ParseFile(rdr, env)
+	l := lexer.New(field)
+	p := parser.New(l)
+	exp := p.ParseConstant()
 
-	if !stmt.KeepOpen {
-		// he does not want to start execution
+	if !evalInputConstant(exp) {
 		return nil
 	}
 
-	newCode := env.StatementIter()
-	return evalRunStart(newCode, env)
+	return evalInputFitType(typeid, Eval(exp, code, env), env)
 }
 
-// eval where to LOCATE the cursor
-func evalLocateStatement(stmt *ast.LocateStatement, code *ast.Code, env *object.Environment) object.Object {
-	// check if I have too many parameters or not enough
-	if (len(stmt.Parms) > 5) || (len(stmt.Parms) == 0) {
-		return object.StdError(env, berrors.Syntax)
+// make sure the user entered a numeric constant, and only a numeric constant
+func evalInputConstant(exp ast.Expression) bool {
+	if tc, ok := exp.(ast.TrashCan); ok && tc.HasTrash() {
+		return false
 	}
 
-	// evaluate movement of cursor
-	return evalLocateCursorMove(stmt, code, env)
-}
+	switch node := exp.(type) {
+	case *ast.IntegerLiteral, *ast.DblIntegerLiteral, *ast.FixedLiteral, *ast.FloatSingleLiteral,
+		*ast.FloatDoubleLiteral, *ast.HexConstant, *ast.OctalConstant:
+		return true
+	case *ast.PrefixExpression:
+		return evalInputConstant(node.Right)
+	}
 
-// figure out if/how the cursor needs to move
-func evalLocateCursorMove(stmt *ast.LocateStatement, code *ast.Code, env *object.Environment) object.Object {
-	row, col := env.Terminal().GetCursor()
+	return false
+}
 
-	// if no params for new position, I'm done here
-	if (stmt.Parms[0] == nil) && (stmt.Parms[1] == nil) {
+// coerce the value into the type of the variable
+func evalInputFitType(typeid string, val object.Object, env *object.Environment) object.Object {
+	if isError(val) {
 		return nil
 	}
 
-	// calculate new row
-	if stmt.Parms[0] != nil {
-		nr := evalExpressions(stmt.Parms[0:1], code, env)
-		newRow, err := coerceIndex(nr[0], env)
+	if checkTypes(typeid, val) {
+		return val
+	}
 
-		if err != nil {
-			return err
+	switch typeid {
+	case "%":
+		i, err := coerceDblInteger(val, env)
+		if (err != nil) || (i > math.MaxInt16) || (i < math.MinInt16) {
+			return nil
+		}
+		return &object.Integer{Value: int16(i)}
+	case "#":
+		if id, ok := val.(*object.IntDbl); ok {
+			return &object.FloatDbl{Value: float64(id.Value)}
 		}
-		row = int(newRow)
 	}
 
-	// calculte new col
-	if (len(stmt.Parms) > 1) && (stmt.Parms[1] != nil) {
-		nc := evalExpressions(stmt.Parms[1:2], code, env)
-		newCol, err := coerceIndex(nc[0], env)
+	return nil
+}
 
-		if err != nil {
-			return err
+// all the values are good, save them into the variables
+func evalInputSaveValues(vars []ast.Expression, vals []object.Object, code *ast.Code, env *object.Environment) object.Object {
+	for i, v := range vars {
+		rc := saveVariable(code, env, v.(*ast.Identifier), vals[i])
+		if rc != nil {
+			return rc
 		}
-		col = int(newCol)
 	}
 
-	env.Terminal().Locate(row, col)
-
-	return evalLocateCursorShow(stmt, env)
+	return nil
 }
 
-// check if he set the cursor visibility
-func evalLocateCursorShow(stmt *ast.LocateStatement, env *object.Environment) object.Object {
-	// did he specify the parmeter?
-	if (len(stmt.Parms) < 3) || (stmt.Parms[2] == nil) {
-		return nil
+// INPUT # reads the values from a file rather than the keyboard
+func evalInputFileStatement(stmt *ast.InputStatement, code *ast.Code, env *object.Environment) object.Object {
+	file, err := evalFileHandle(stmt.File, code, env, gwtypes.Input)
+	if err != nil {
+		return err
 	}
 
-	return object.StdError(env, berrors.Syntax)
-}
+	for _, v := range stmt.Vars {
+		id, ok := v.(*ast.Identifier)
+		if !ok {
+			return object.StdError(env, berrors.Syntax)
+		}
 
-// convert a string octal constant into an integer
-func evalOctalConstant(stmt *ast.OctalConstant, env *object.Environment) object.Object {
+		typeid, _ := parseVarName(id.Value)
+		field, quoted, rc := evalInputFileField(file, typeid == "$", env)
+		if rc != nil {
+			return rc
+		}
 
-	dst, err := strconv.ParseInt(stmt.Value, 8, 16)
+		val := evalInputValue(field, quoted, id, code, env)
+		if val == nil {
+			return object.StdError(env, berrors.TypeMismatch)
+		}
 
-	if err != nil {
-		st := err.Error()
-		if strings.Contains(st, "value out of range") {
-			return object.StdError(env, berrors.Overflow)
+		rc = saveVariable(code, env, id, val)
+		if rc != nil {
+			return rc
 		}
-		return object.StdError(env, berrors.Syntax)
 	}
 
-	return &object.Integer{Value: int16(dst)}
+	return nil
 }
 
-// evalNewCommand clears the code space and all the variables
-func evalNewCommand(env *object.Environment) object.Object {
-	env.NewProgram()
-	env.ClearVars()
+// evalInputFileField reads the next field from the file
+// a string ends at a comma or the end of the line, a number also ends at a space
+// returns true if the field was a quoted string
+func evalInputFileField(file gwtypes.AnOpenFile, str bool, env *object.Environment) (string, bool, object.Object) {
+	// leading spaces and line ends are skipped
+	for b, ok := file.PeekByte(); ok && strings.IndexByte(" \t\r\n", b) >= 0; b, ok = file.PeekByte() {
+		file.GetByte()
+	}
 
-	// send a halt signal if we are executing a program
-	var htl object.HaltSignal
+	if file.EOF() {
+		return "", false, object.StdError(env, berrors.InputPastEnd)
+	}
 
-	return &htl
+	var field []byte
+	quoted := false
+
+	if b, _ := file.PeekByte(); str && (b == '"') {
+		// quoted string runs to the closing quote
+		quoted = true
+		file.GetByte()
+		for b, ok := file.GetByte(); ok && (b != '"'); b, ok = file.GetByte() {
+			field = append(field, b)
+		}
+	} else {
+		ends := ",\r\n\x1a"
+		if !str {
+			ends += " "
+		}
+		for b, ok := file.PeekByte(); ok && (strings.IndexByte(ends, b) < 0); b, ok = file.PeekByte() {
+			field = append(field, b)
+			file.GetByte()
+		}
+	}
+
+	evalInputFileDelimiter(file)
+
+	if quoted {
+		return string(field), true, nil
+	}
+	return strings.TrimRight(string(field), " "), false, nil
 }
 
-// evalNextStatement decides if I should do it all over again
-func evalNextStatement(stmt *ast.NextStatement, code *ast.Code, env *object.Environment) object.Object {
-	// make sure we are actually in a FOR loop
-	if len(env.ForLoops) == 0 {
-		return object.StdError(env, berrors.NextWithoutFor)
+// evalInputFileDelimiter skips trailing spaces and the comma or line end after a field
+func evalInputFileDelimiter(file gwtypes.AnOpenFile) {
+	for b, ok := file.PeekByte(); ok && (b == ' '); b, ok = file.PeekByte() {
+		file.GetByte()
 	}
 
-	blk := env.ForLoops[len(env.ForLoops)-1:]
+	b, ok := file.PeekByte()
+	if !ok {
+		return
+	}
 
-	// does the next specify the variable?
-	if stmt.Id.Token.Literal != "" {
-		// make sure they match
-		if !strings.EqualFold(stmt.Id.Token.Literal, blk[0].Four.Init.Name.Token.Literal) {
-			return object.StdError(env, berrors.NextWithoutFor)
+	switch b {
+	case ',', '\n':
+		file.GetByte()
+	case '\r':
+		file.GetByte()
+		if nb, ok := file.PeekByte(); ok && (nb == '\n') {
+			file.GetByte()
 		}
 	}
-
-	return evalNextStep(blk[0], code, env)
 }
 
-// time to bump the counter by the step value
-func evalNextStep(four object.ForBlock, code *ast.Code, env *object.Environment) object.Object {
-	// get the counter variable
-	cntr := env.Get(four.Four.Init.Name.Token.Literal)
+// evalFileReadLine reads up to the end of the line, the CR/LF is dropped
+func evalFileReadLine(file gwtypes.AnOpenFile) string {
+	var line []byte
 
-	// counter for loop wasn't saved, how did that happen
-	if cntr == nil {
-		return object.StdError(env, berrors.InternalErr)
+	for {
+		b, ok := file.PeekByte()
+		if !ok || (b == 0x1a) {
+			return string(line)
+		}
+		file.GetByte()
+
+		switch b {
+		case '\n':
+			return string(line)
+		case '\r':
+			if nb, ok := file.PeekByte(); ok && (nb == '\n') {
+				file.GetByte()
+			}
+			return string(line)
+		}
+		line = append(line, b)
 	}
+}
 
-	// get the step value or set it to one if nothing specified
-	var step []object.Object
-	var pos, zero bool
-	if four.Four.Step != nil {
-		step = evalExpressions(four.Four.Step, code, env)
-		pos, zero = evalNextStepSign(step[0], env)
-	} else {
-		step = append(step, &object.Integer{Value: 1})
-		pos = true
+// LINE INPUT reads everything typed into a string variable
+func evalLineInputStatement(stmt *ast.LineInputStatement, code *ast.Code, env *object.Environment) object.Object {
+	id, ok := stmt.Var.(*ast.Identifier)
+
+	if !ok {
+		return object.StdError(env, berrors.Syntax)
 	}
 
-	// if step is zero, stop
-	if zero {
-		return nil
+	typeid, _ := parseVarName(id.Value)
+	if typeid != "$" {
+		return object.StdError(env, berrors.TypeMismatch)
 	}
 
-	// add step to the cntr
-	cntr = evalInfixExpression("+", cntr, step[0], env)
-	// save off the counter variable
-	env.Set(four.Four.Init.Name.Token.Literal, cntr)
+	// LINE INPUT # reads the line from a file
+	if stmt.File != nil {
+		file, err := evalFileHandle(stmt.File, code, env, gwtypes.Input)
+		if err != nil {
+			return err
+		}
 
-	obj, jump := evalNextComplete(pos, cntr, four.Four, code, env)
+		if file.EOF() {
+			return object.StdError(env, berrors.InputPastEnd)
+		}
 
-	if jump {
-		// go back to where the four loop started
-		code.JumpToRetPoint(four.Code)
-		return nil
+		return saveVariable(code, env, id, &object.String{Value: evalFileReadLine(file)})
 	}
 
-	return obj
+	evalInputPrompt(stmt.Prompt, false, env)
+
+	line, brk := evalInputReadLine(stmt.SameLine, env)
+	if brk {
+		return evalInputBreak(code, env)
+	}
+
+	return saveVariable(code, env, id, &object.String{Value: line})
 }
 
-// evalNextStepSign returns step>0, step == zero and step value
+// defines, enables, disables and lists keyboard macros
+func evalKeyStatement(node *ast.KeyStatement, code *ast.Code, env *object.Environment) object.Object {
+	// get the current key definitions
+	keyDefs := evalKeyStatementGetKeyDefs(env)
+
+	switch node.Param.(type) {
+	case *ast.OnExpression:
+		keyDefs.Disp = true
+	case *ast.OffExpression:
+		keyDefs.Disp = false
+	case *ast.ListExpression: // list out the current defined values
+		env.Terminal().Print(keyDefs.String())
+	default:
+		err := evalKeyStatementFKeyParams(keyDefs, node, code, env)
+		if err != nil {
+			return err
+		}
+	}
+
+	// save any changes made
+	env.SaveSetting(settings.KeyMacs, keyDefs)
+
+	return nil
+}
+
+// retrieve or create the current key definitions
+func evalKeyStatementGetKeyDefs(env *object.Environment) *ast.KeySettings {
+	// create empty settings
+	keyDefs := &ast.KeySettings{}
+	keyDefs.Keys = make(map[string]string)
+
+	// go get the keyboard macro settings
+	keys := env.GetSetting(settings.KeyMacs)
+
+	if keys != nil {
+		// settings have been saved before, use saved values
+		keyDefs = keys.(*ast.KeySettings)
+	}
+
+	return keyDefs
+}
+
+// user wants to redefine macro for F1 to F10
+func evalKeyStatementFKeyParams(keys *ast.KeySettings, node *ast.KeyStatement, code *ast.Code, env *object.Environment) object.Object {
+	// do the error checking first
+	// one and only one parameter for this form
+	if len(node.Data) != 1 {
+		return object.StdError(env, berrors.Syntax)
+	}
+	ind := evalExpressionNodeTyped(node.Param, code, env, &object.Integer{})
+
+	if ind == nil {
+		return object.StdError(env, berrors.Syntax)
+	}
+
+	i := ind.(*object.Integer).Value
+	// index 1-10 is a keybord function key
+	if (i >= 1) && (i <= 10) {
+		return evalKeyStatementMapFKey(i, keys, node.Data[0], code, env)
+	}
+	// index 15-20 are user defined keys for the ON KEY statements
+	if (i >= 15) && (i <= 20) {
+		return evalKeyStatmentCustomKey(i, keys, node.Data[0], code, env)
+	}
+
+	// index is invalid
+	return object.StdError(env, berrors.IllegalFuncCallErr)
+}
+
+// define a string to insert for a function key
+func evalKeyStatementMapFKey(key int16, keys *ast.KeySettings, val ast.Expression, code *ast.Code, env *object.Environment) object.Object {
+	s := evalExpressionNodeTyped(val, code, env, &object.String{})
+
+	if s == nil {
+		return object.StdError(env, berrors.Syntax)
+	}
+
+	k := fmt.Sprintf("F%d", key)
+	keys.Keys[k] = s.(*object.String).Value
+
+	return nil
+}
+
+// define a key for ON KEY to trap, the string holds
+// the shift key flags followed by the scan code
+func evalKeyStatmentCustomKey(key int16, keys *ast.KeySettings, val ast.Expression, code *ast.Code, env *object.Environment) object.Object {
+	b := evalExpressionNodeTyped(val, code, env, &object.String{})
+
+	if b == nil {
+		return object.StdError(env, berrors.Syntax)
+	}
+
+	def := []byte(b.(*object.String).Value)
+	if len(def) != 2 {
+		return object.StdError(env, berrors.IllegalFuncCallErr)
+	}
+
+	// each user key gets a flags and scan code pair
+	ind := int(key-keybuffer.FirstUserKey) * 2
+	onKeys := make([]byte, (keybuffer.LastKey-keybuffer.FirstUserKey+1)*2)
+	copy(onKeys, keys.OnKeys)
+	copy(onKeys[ind:], def)
+	keys.OnKeys = onKeys
+
+	return nil
+}
+
+// list some or all of the current program
+func evalListStatement(stmt *ast.ListStatement, env *object.Environment) {
+	// get a code iterator
+	cd := env.ListIter()
+
+	// assume my default limits
+	start := 0
+	stop := cd.MaxLineNum()
+
+	// figure out any limits to the listing
+	// is there a starting line?
+	if len(stmt.Start) > 0 {
+		start, _ = strconv.Atoi(stmt.Start)
+		if len(stmt.Lrange) == 0 {
+			stop = start
+		}
+	}
+
+	// is there a stopping point
+	if len(stmt.Stop) > 0 {
+		stop, _ = strconv.Atoi(stmt.Stop)
+	}
+
+	for _, line := range evalListLines(start, stop, env) {
+		env.Terminal().Println(line)
+	}
+}
+
+// remove a range of lines from the program
+// the range has to hold at least one line
+func evalDeleteCommand(cmd *ast.DeleteCommand, env *object.Environment) object.Object {
+	if (len(cmd.Start) == 0) && (len(cmd.Stop) == 0) {
+		return object.StdError(env, berrors.IllegalFuncCallErr)
+	}
+
+	cd := env.StatementIter()
+	start := 0
+	stop := cd.MaxLineNum()
+
+	if len(cmd.Start) > 0 {
+		start, _ = strconv.Atoi(cmd.Start)
+		if len(cmd.Lrange) == 0 {
+			stop = start
+		}
+	}
+
+	if len(cmd.Stop) > 0 {
+		stop, _ = strconv.Atoi(cmd.Stop)
+	}
+
+	if cd.DeleteLines(start, stop) == 0 {
+		return object.StdError(env, berrors.IllegalFuncCallErr)
+	}
+	env.ConstData().Restore()
+
+	// the program has changed, can't keep running it
+	if env.ProgramRunning() {
+		return &object.HaltSignal{}
+	}
+
+	return nil
+}
+
+// EDIT line
+// the cli puts the line on screen in place of the OK prompt
+func evalEditCommand(cmd *ast.EditCommand, env *object.Environment) object.Object {
+	if len(cmd.Line) == 0 {
+		return object.StdError(env, berrors.IllegalFuncCallErr)
+	}
+
+	line, _ := strconv.Atoi(cmd.Line)
+	if !env.StatementIter().Exists(line) {
+		return object.StdError(env, berrors.UnDefinedLineNumber)
+	}
+
+	text := evalListLines(line, line, env)
+	env.SaveSetting(settings.Edit, &ast.StringLiteral{Value: text[0]})
+
+	return nil
+}
+
+// RENUM [new][,[old][,increment]]
+func evalRenumCommand(cmd *ast.RenumCommand, env *object.Environment) object.Object {
+	newNum := 10
+	old := 0
+	inc := 10
+
+	if len(cmd.NewNum) > 0 {
+		newNum, _ = strconv.Atoi(cmd.NewNum)
+	}
+
+	if len(cmd.OldNum) > 0 {
+		old, _ = strconv.Atoi(cmd.OldNum)
+	}
+
+	if len(cmd.Increment) > 0 {
+		inc, _ = strconv.Atoi(cmd.Increment)
+	}
+
+	undef, err := env.StatementIter().Renumber(newNum, old, inc)
+	if err != 0 {
+		return object.StdError(env, err)
+	}
+
+	for _, msg := range undef {
+		env.Terminal().Println(msg)
+	}
+	env.ConstData().Restore()
+
+	// the program has changed, can't keep running it
+	if env.ProgramRunning() {
+		return &object.HaltSignal{}
+	}
+
+	return nil
+}
+
+// build the text of each program line from start to stop
+func evalListLines(start int, stop int, env *object.Environment) []string {
+	return env.ListIter().Listing(start, stop)
+}
+
+// evalLoadCommand - load and parse the target program
+func evalLoadCommand(stmt *ast.LoadCommand, code *ast.Code, env *object.Environment) object.Object {
+	// get the target file name
+	res := Eval(stmt.Path, code, env)
+	str, ok := res.(*object.String)
+
+	if !ok {
+		return object.StdError(env, berrors.TypeMismatch)
+	}
+
+	return evalLoadGetFile(str.Value, stmt, env)
+}
+
+// calls the file server looking for a source file
+func evalLoadGetFile(file string, stmt *ast.LoadCommand, env *object.Environment) object.Object {
+	rdr, err := evalGetProgFile(file, env)
+
+	if err != nil {
+		// server sent an error, get out
+		return err
+	}
+
+	return evalLoadParse(rdr, stmt, env)
+}
+
+// fetch a program file, a copy held locally (like one just SAVEd)
+// is used before asking the server
+func evalGetProgFile(file string, env *object.Environment) (*bufio.Reader, object.Object) {
+	if len(file) > 0 {
+		alf := localfiles.For(env).Find(fileserv.BuildFullPath(evalProgFileName(file), env))
+		if alf != nil {
+			return bufio.NewReader(bytes.NewReader(*alf.(gwtypes.FileData).Data())), nil
+		}
+	}
+
+	return fileserv.GetFile(file, env)
+}
+
+// program files default to a .BAS extension
+func evalProgFileName(file string) string {
+	base := file[strings.LastIndex(file, `\`)+1:]
+
+	if (len(base) > 0) && !strings.Contains(base, ".") {
+		return file + ".BAS"
+	}
+
+	return file
+}
+
+// MERGE an ASCII program into the current one
+// lines in the file replace any with the same number
+func evalMergeCommand(cmd *ast.MergeCommand, code *ast.Code, env *object.Environment) object.Object {
+	if cmd.Path == nil {
+		return object.StdError(env, berrors.Syntax)
+	}
+
+	res := Eval(cmd.Path, code, env)
+	str, ok := res.(*object.String)
+
+	if !ok {
+		return object.StdError(env, berrors.TypeMismatch)
+	}
+
+	rdr, err := evalGetProgFile(str.Value, env)
+	if err != nil {
+		return err
+	}
+
+	// only ASCII files can be merged
+	bt, _ := rdr.Peek(1)
+	if (len(bt) > 0) && ((bt[0] == gwtoken.TOKEN_FILE) || (bt[0] == gwtoken.PROTECTED_FILE)) {
+		return object.StdError(env, berrors.BadFileMode)
+	}
+
+	fileserv.ParseFile(rdr, env)
+	env.ConstData().Restore()
+
+	// the program has changed, can't keep running it
+	if env.ProgramRunning() {
+		return &object.HaltSignal{}
+	}
+
+	return nil
+}
+
+// parse in the loaded file
+func evalLoadParse(rdr *bufio.Reader, stmt *ast.LoadCommand, env *object.Environment) object.Object {
+	// flush the old program
+	env.NewProgram()
+	fileserv.ParseFile(rdr, env)
+
+	if !stmt.KeepOpen {
+		// he does not want to start execution
+		return nil
+	}
+
+	newCode := env.StatementIter()
+	return evalRunStart(newCode, env)
+}
+
+// write the current program out to a file
+// tokenized is the default, ASCII and protected are optional
+func evalSaveCommand(stmt *ast.SaveCommand, code *ast.Code, env *object.Environment) object.Object {
+	if stmt.Path == nil {
+		return object.StdError(env, berrors.Syntax)
+	}
+
+	res := Eval(stmt.Path, code, env)
+	str, ok := res.(*object.String)
+
+	if !ok {
+		return object.StdError(env, berrors.TypeMismatch)
+	}
+
+	if len(strings.TrimSpace(str.Value)) == 0 {
+		return object.StdError(env, berrors.BadFileName)
+	}
+
+	img := evalSaveImage(stmt.Format, env)
+	fn := fileserv.BuildFullPath(evalProgFileName(str.Value), env)
+	localfiles.For(env).Store(fn, img)
+
+	// the drive may well be read only, the local copy is still good
+	err := fileserv.PutFile(fn, img, env)
+	if (err != nil) && (env.Terminal() != nil) {
+		env.Terminal().Log(err.Inspect())
+	}
+
+	return nil
+}
+
+// build the contents of the program file in the requested format
+func evalSaveImage(format string, env *object.Environment) []byte {
+	switch format {
+	case "A":
+		var img []byte
+		for _, line := range evalListLines(0, env.ListIter().MaxLineNum(), env) {
+			img = append(img, object.EncodeBytes(line+"\r\n")...)
+		}
+		// GW-BASIC always ends the file with a ctrl-Z
+		return append(img, 0x1a)
+	case "P":
+		return gwtoken.Protect(gwtoken.Encode(env.ListIter()))
+	}
+
+	return gwtoken.Encode(env.ListIter())
+}
+
+// eval where to LOCATE the cursor
+func evalLocateStatement(stmt *ast.LocateStatement, code *ast.Code, env *object.Environment) object.Object {
+	// check if I have too many parameters or not enough
+	if (len(stmt.Parms) > 5) || (len(stmt.Parms) == 0) {
+		return object.StdError(env, berrors.Syntax)
+	}
+
+	// evaluate movement of cursor
+	return evalLocateCursorMove(stmt, code, env)
+}
+
+// figure out if/how the cursor needs to move
+func evalLocateCursorMove(stmt *ast.LocateStatement, code *ast.Code, env *object.Environment) object.Object {
+	row, col := env.Terminal().GetCursor()
+
+	// if no params for new position, I'm done here
+	if (stmt.Parms[0] == nil) && (stmt.Parms[1] == nil) {
+		return nil
+	}
+
+	// calculate new row
+	if stmt.Parms[0] != nil {
+		nr := evalExpressions(stmt.Parms[0:1], code, env)
+		newRow, err := coerceIndex(nr[0], env)
+
+		if err != nil {
+			return err
+		}
+		row = int(newRow)
+	}
+
+	// calculte new col
+	if (len(stmt.Parms) > 1) && (stmt.Parms[1] != nil) {
+		nc := evalExpressions(stmt.Parms[1:2], code, env)
+		newCol, err := coerceIndex(nc[0], env)
+
+		if err != nil {
+			return err
+		}
+		col = int(newCol)
+	}
+
+	env.Terminal().Locate(row, col)
+
+	return evalLocateCursorShow(stmt, env)
+}
+
+// check if he set the cursor visibility
+func evalLocateCursorShow(stmt *ast.LocateStatement, env *object.Environment) object.Object {
+	// did he specify the parmeter?
+	if (len(stmt.Parms) < 3) || (stmt.Parms[2] == nil) {
+		return nil
+	}
+
+	return object.StdError(env, berrors.Syntax)
+}
+
+// convert a string octal constant into an integer
+func evalOctalConstant(stmt *ast.OctalConstant, env *object.Environment) object.Object {
+
+	dst, err := strconv.ParseInt(stmt.Value, 8, 16)
+
+	if err != nil {
+		st := err.Error()
+		if strings.Contains(st, "value out of range") {
+			return object.StdError(env, berrors.Overflow)
+		}
+		return object.StdError(env, berrors.Syntax)
+	}
+
+	return &object.Integer{Value: int16(dst)}
+}
+
+// evalNewCommand clears the code space and all the variables
+func evalNewCommand(env *object.Environment) object.Object {
+	env.NewProgram()
+	env.ClearVars()
+	env.ClearTraps()
+
+	// send a halt signal if we are executing a program
+	var htl object.HaltSignal
+
+	return &htl
+}
+
+// evalNextStatement decides if I should do it all over again
+func evalNextStatement(stmt *ast.NextStatement, code *ast.Code, env *object.Environment) object.Object {
+	// make sure we are actually in a FOR loop
+	if len(env.ForLoops) == 0 {
+		return object.StdError(env, berrors.NextWithoutFor)
+	}
+
+	blk := env.ForLoops[len(env.ForLoops)-1:]
+
+	// does the next specify the variable?
+	if stmt.Id.Token.Literal != "" {
+		// make sure they match
+		if !strings.EqualFold(stmt.Id.Token.Literal, blk[0].Four.Init.Name.Token.Literal) {
+			return object.StdError(env, berrors.NextWithoutFor)
+		}
+	}
+
+	return evalNextStep(blk[0], code, env)
+}
+
+// time to bump the counter by the step value
+func evalNextStep(four object.ForBlock, code *ast.Code, env *object.Environment) object.Object {
+	// get the counter variable
+	cntr := env.Get(four.Four.Init.Name.Token.Literal)
+
+	// counter for loop wasn't saved, how did that happen
+	if cntr == nil {
+		return object.StdError(env, berrors.InternalErr)
+	}
+
+	// get the step value or set it to one if nothing specified
+	var step []object.Object
+	var pos, zero bool
+	if four.Four.Step != nil {
+		step = evalExpressions(four.Four.Step, code, env)
+		pos, zero = evalNextStepSign(step[0], env)
+	} else {
+		step = append(step, &object.Integer{Value: 1})
+		pos = true
+	}
+
+	// if step is zero, stop
+	if zero {
+		return nil
+	}
+
+	// add step to the cntr
+	cntr = evalInfixExpression("+", cntr, step[0], env)
+	// save off the counter variable
+	env.Set(four.Four.Init.Name.Token.Literal, cntr)
+
+	obj, jump := evalNextComplete(pos, cntr, four.Four, code, env)
+
+	if jump {
+		// go back to where the four loop started
+		code.JumpToRetPoint(four.Code)
+		return nil
+	}
+
+	return obj
+}
+
+// evalNextStepSign returns step>0, step == zero and step value
 func evalNextStepSign(stepper object.Object, env *object.Environment) (bool, bool) {
 	pos := evalInfixBooleanExpression(">", stepper, &object.Integer{Value: 0}, env)
 	neg := evalInfixBooleanExpression("<", stepper, &object.Integer{Value: 0}, env)
 
-	if !pos && !neg {
-		return true, true
+	if !pos && !neg {
+		return true, true
+	}
+
+	return pos, false
+}
+
+// return possible err and keep going true/false
+func evalNextComplete(pos bool, cntr object.Object, four *ast.ForStatement, code *ast.Code, env *object.Environment) (object.Object, bool) {
+	// compute the final value
+	fnl := evalExpressions(four.Final, code, env)
+
+	if len(fnl) == 0 {
+		return object.StdError(env, berrors.Syntax), false
+	}
+
+	_, ok := fnl[0].(*object.Error)
+	if ok {
+		return fnl[0], false
+	}
+
+	// check if counter has passed final value
+	var res bool
+	if pos {
+		res = evalInfixBooleanExpression(">", cntr, fnl[0], env)
+	} else {
+		res = evalInfixBooleanExpression("<", cntr, fnl[0], env)
+	}
+
+	return nil, !res
+}
+
+// make sure the line is valid and then save it in the settings
+func evalOnErrorStatement(node *ast.OnErrorGoto, code *ast.Code, env *object.Environment) object.Object {
+	// make sure the statment is complete
+	if (!strings.EqualFold(node.Token.Literal, "ON ERROR GOTO")) || (node.Jump < 0) {
+		return object.StdError(env, berrors.Syntax)
+	}
+
+	// if the jump to line is zero, he is turning off the error trap
+	if node.Jump == 0 {
+		env.ClrSetting(settings.OnError)
+		return nil
+	}
+
+	// make sure the error handler actually exists
+	if !code.Exists(node.Jump) {
+		return object.StdError(env, berrors.UnDefinedLineNumber)
+	}
+
+	// save the node to the settings
+	env.SaveSetting(settings.OnError, node)
+	return nil
+}
+
+// ON TIMER(n) GOSUB line, n is 1 to 86400 seconds
+// ON KEY(n) GOSUB line, n is 1 to 20
+func evalOnEventStatement(node *ast.OnEventStatement, code *ast.Code, env *object.Environment) object.Object {
+	if node.HasTrash() || (node.Param == nil) {
+		return object.StdError(env, berrors.Syntax)
+	}
+
+	if node.Event.Type == token.KEY {
+		key, err := evalEventKeyNum(node.Param, code, env)
+		if err != nil {
+			return err
+		}
+
+		if err = evalOnEventHandler(node, code, env); err != nil {
+			return err
+		}
+
+		env.SetKeyTrap(key, node.Jump)
+		return nil
+	}
+
+	secs, err := evalEventParam(node.Param, code, env)
+	if err != nil {
+		return err
+	}
+
+	if (secs < 1) || (secs > 86400) {
+		return object.StdError(env, berrors.IllegalFuncCallErr)
 	}
 
-	return pos, false
+	if err = evalOnEventHandler(node, code, env); err != nil {
+		return err
+	}
+
+	env.SetTimer(time.Duration(secs)*time.Second, node.Jump)
+	return nil
 }
 
-// return possible err and keep going true/false
-func evalNextComplete(pos bool, cntr object.Object, four *ast.ForStatement, code *ast.Code, env *object.Environment) (object.Object, bool) {
-	// compute the final value
-	fnl := evalExpressions(four.Final, code, env)
+// make sure the handler actually exists
+func evalOnEventHandler(node *ast.OnEventStatement, code *ast.Code, env *object.Environment) object.Object {
+	if (node.Jump > 0) && !code.Exists(node.Jump) {
+		return object.StdError(env, berrors.UnDefinedLineNumber)
+	}
 
-	if len(fnl) == 0 {
-		return object.StdError(env, berrors.Syntax), false
+	return nil
+}
+
+// evalOnGoStatement, can be ON x GOTO or ON x GOSUB
+func evalOnGoStatement(node *ast.OnGoStatement, code *ast.Code, env *object.Environment) object.Object {
+	// make sure I have an expression
+	if node.Exp == nil {
+		return object.StdError(env, berrors.Syntax)
 	}
 
-	_, ok := fnl[0].(*object.Error)
+	// figure out the index
+	rc := evalExpressionNode(node.Exp, code, env)
+
+	// did I get an error?
+	_, ok := rc.(*object.Error)
 	if ok {
-		return fnl[0], false
+		return rc
 	}
 
-	// check if counter has passed final value
-	var res bool
-	if pos {
-		res = evalInfixBooleanExpression(">", cntr, fnl[0], env)
-	} else {
-		res = evalInfixBooleanExpression("<", cntr, fnl[0], env)
+	// Get the result as an index
+	ind, rc := coerceDblInteger(rc, env)
+
+	// if rc is not nil, it is an error
+	if rc != nil {
+		return rc
 	}
 
-	return nil, !res
+	return evalOnGoJump(ind, node, code, env)
 }
 
-// make sure the line is valid and then save it in the settings
-func evalOnErrorStatement(node *ast.OnErrorGoto, code *ast.Code, env *object.Environment) object.Object {
-	// make sure the statment is complete
-	if (!strings.EqualFold(node.Token.Literal, "ON ERROR GOTO")) || (node.Jump < 0) {
-		return object.StdError(env, berrors.Syntax)
+// figure out where to jump, how to jump then jump
+func evalOnGoJump(ind int32, node *ast.OnGoStatement, code *ast.Code, env *object.Environment) object.Object {
+	// if index to small/large, just continue
+	if (ind <= 0) || (int(ind) > len(node.Jumps)) {
+		return nil
 	}
 
-	// if the jump to line is zero, he is turning off the error trap
-	if node.Jump == 0 {
-		env.ClrSetting(settings.OnError)
-		return nil
+	// pull the destination expression
+	jmpto := evalExpressionNode(node.Jumps[ind-1], code, env)
+
+	// extract out the line number
+	jmp, rc := coerceDblInteger(jmpto, env)
+
+	if rc != nil {
+		return rc
 	}
 
-	// make sure the error handler actually exists
-	if !code.Exists(node.Jump) {
+	if !code.Exists(int(jmp)) {
 		return object.StdError(env, berrors.UnDefinedLineNumber)
 	}
 
-	// save the node to the settings
-	env.SaveSetting(settings.OnError, node)
+	switch node.MidTok.Literal {
+	case "GOTO":
+		return Eval(&ast.GotoStatement{JmpTo: []token.Token{{Type: token.INT, Literal: strconv.Itoa(int(jmp))}}}, code, env)
+	case "GOSUB":
+		return Eval(&ast.GosubStatement{Gosub: []token.Token{{Type: token.INT, Literal: strconv.Itoa(int(jmp))}}}, code, env)
+	}
+	return object.StdError(env, berrors.Syntax)
+}
+
+// opens a data file
+// todo: support open device
+// note: node is a *copy* of the OpenStatement in the AST, not a pointer to it.
+//
+//	this way, I can modify the fields for the current environment and not
+//	affect later evaluations of the statement.
+func evalOpenStatement(node ast.OpenStatement, code *ast.Code, env *object.Environment) object.Object {
+	// get the target file name and build a fully qualified file name
+	// based on current virtual drive and directory
+	node.FileName = fileserv.BuildFullPath(node.FileName, env)
+
+	if node.Verbose {
+		return evalVerboseOpen(&node, code, env)
+	}
+
+	return evalConciseOpen(&node, code, env)
+}
+
+// it's gwbasic, so they have two statement formats to open a file
+// this is the verbose form
+func evalVerboseOpen(node *ast.OpenStatement, code *ast.Code, env *object.Environment) object.Object {
+	// any missing parameters, fill in the defaults
+	if len(node.Mode) == 0 {
+		node.Mode = token.RANDOM
+	}
+
+	if len(node.Access) == 0 {
+		node.Access = token.READ + " " + token.WRITE
+	}
+
+	if len(node.RecLen) == 0 {
+		node.RecLen = "128"
+	}
+
+	return evalOpenFile(node, code, env)
+}
+
+// fill in defaults as need for concise form of open
+func evalConciseOpen(node *ast.OpenStatement, code *ast.Code, env *object.Environment) object.Object {
+	// concise form assumes access mode
+	node.Access = token.READ + " " + token.WRITE
+
+	if len(node.RecLen) == 0 {
+		node.RecLen = "128"
+	}
+
+	return evalOpenFile(node, code, env)
+}
+
+// GW-BASIC allows file numbers 1 thru 15
+const maxOpenFiles = 15
+
+// evalOpenFile gets the file into local storage and assigns it the file number
+func evalOpenFile(node *ast.OpenStatement, code *ast.Code, env *object.Environment) object.Object {
+	fnum, err := evalFileNumber(&node.FileNumber, code, env)
+	if err != nil {
+		return err
+	}
+
+	if (fnum < 1) || (fnum > maxOpenFiles) {
+		return object.StdError(env, berrors.BadFileNum)
+	}
+
+	if env.GetOpenFile(fnum) != nil {
+		return object.StdError(env, berrors.FileAlreadyOpen)
+	}
+
+	if rl, err := strconv.Atoi(node.RecLen); (err != nil) || (rl < 1) || (rl > math.MaxInt16) {
+		return object.StdError(env, berrors.IllegalFuncCallErr)
+	}
+
+	rc := evalOpenLocalFile(node, env)
+	fd, ok := rc.(gwtypes.FileData)
+	if !ok {
+		// couldn't get the file
+		return rc
+	}
+
+	af, err := afile.OpenFile(node.FileName, fd.Data(), *node, env)
+	if err != nil {
+		return err
+	}
+
+	env.AddOpenFile(fnum, af)
 	return nil
 }
 
-// evalOnGoStatement, can be ON x GOTO or ON x GOSUB
-func evalOnGoStatement(node *ast.OnGoStatement, code *ast.Code, env *object.Environment) object.Object {
-	// make sure I have an expression
-	if node.Exp == nil {
+// evalOpenLocalFile gets the contents of the file based on the mode
+func evalOpenLocalFile(node *ast.OpenStatement, env *object.Environment) object.Object {
+	lf := localfiles.For(env)
+
+	switch strings.ToUpper(node.Mode) {
+	case "O", token.OUTPUT:
+		// output always starts with an empty file
+		return lf.Create(node.FileName)
+	case "A", token.APPEND:
+		// appending to a file that doesn't exist creates it
+		rc := lf.Open(node.FileName, env)
+		if isError(rc) {
+			return lf.Create(node.FileName)
+		}
+		return rc
+	case "R", token.RANDOM:
+		// a random file that doesn't exist yet gets created
+		rc := lf.Open(node.FileName, env)
+		if er, ok := rc.(*object.Error); ok && (er.Code == berrors.FileNotFound) {
+			return lf.Create(node.FileName)
+		}
+		return rc
+	case "I", token.INPUT:
+		// Open checks if the file is held in the local file system
+		// if not, it tries to pull the file from the file server
+		// returns an error if it can't be found
+		return lf.Open(node.FileName, env)
+	}
+
+	return object.StdError(env, berrors.BadFileMode)
+}
+
+// evalFileNumber evaluates the file number, it must be a numeric
+func evalFileNumber(fn *ast.FileNumber, code *ast.Code, env *object.Environment) (int16, object.Object) {
+	if fn.Numbr == nil {
+		return 0, object.StdError(env, berrors.BadFileNum)
+	}
+
+	rc := evalExpressionNode(fn.Numbr, code, env)
+	if isError(rc) {
+		return 0, rc
+	}
+
+	if tv, ok := rc.(*object.TypedVar); ok {
+		rc = tv.Value
+	}
+
+	num, err := coerceDblInteger(rc, env)
+	if err != nil {
+		return 0, object.StdError(env, berrors.TypeMismatch)
+	}
+
+	if (num < 0) || (num > math.MaxInt16) {
+		return 0, object.StdError(env, berrors.BadFileNum)
+	}
+
+	return int16(num), nil
+}
+
+// evalFileHandle finds the file a statement has been redirected to
+// the file has to be open in one of the modes allowed
+func evalFileHandle(fn *ast.FileNumber, code *ast.Code, env *object.Environment, modes ...gwtypes.AccessMode) (gwtypes.AnOpenFile, object.Object) {
+	fnum, err := evalFileNumber(fn, code, env)
+	if err != nil {
+		return nil, err
+	}
+
+	return evalFileMode(fnum, env, modes...)
+}
+
+// evalFileMode finds file number fnum, it has to be open in one of the modes
+func evalFileMode(fnum int16, env *object.Environment, modes ...gwtypes.AccessMode) (gwtypes.AnOpenFile, object.Object) {
+	file := env.GetOpenFile(fnum)
+	if file == nil {
+		return nil, object.StdError(env, berrors.BadFileNum)
+	}
+
+	for _, mode := range modes {
+		if file.AccessMode() == mode {
+			return file, nil
+		}
+	}
+
+	return nil, object.StdError(env, berrors.BadFileMode)
+}
+
+// GW-BASIC limits on random file access
+const (
+	maxFieldWidth = 255   // a field is held in a string
+	maxRecordNum  = 32767 // highest record GET or PUT will accept
+)
+
+// evalRandomFile finds a file that is open for random access
+func evalRandomFile(fn *ast.FileNumber, code *ast.Code, env *object.Environment) (int16, gwtypes.RandomFile, object.Object) {
+	fnum, err := evalFileNumber(fn, code, env)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	file := env.GetOpenFile(fnum)
+	if file == nil {
+		return 0, nil, object.StdError(env, berrors.BadFileNum)
+	}
+
+	rf, ok := file.(gwtypes.RandomFile)
+	if !ok || (file.AccessMode() != gwtypes.Random) {
+		return 0, nil, object.StdError(env, berrors.BadFileMode)
+	}
+
+	return fnum, rf, nil
+}
+
+// evalRecordInteger evaluates a field width or record number, it must be numeric
+func evalRecordInteger(exp ast.Expression, code *ast.Code, env *object.Environment) (int, object.Object) {
+	rc := evalExpressionNode(exp, code, env)
+	if isError(rc) {
+		return 0, rc
+	}
+
+	if tv, ok := rc.(*object.TypedVar); ok {
+		rc = tv.Value
+	}
+
+	num, err := coerceDblInteger(rc, env)
+	if err != nil {
+		return 0, object.StdError(env, berrors.TypeMismatch)
+	}
+
+	return int(num), nil
+}
+
+// evalRecordNumber works out which record GET or PUT should access
+// if none is given, it is the one after the last one accessed
+func evalRecordNumber(exp ast.Expression, file gwtypes.RandomFile, code *ast.Code, env *object.Environment) (int, object.Object) {
+	if exp == nil {
+		return file.CurRecord() + 1, nil
+	}
+
+	rec, err := evalRecordInteger(exp, code, env)
+	if err != nil {
+		return 0, err
+	}
+
+	if (rec < 1) || (rec > maxRecordNum) {
+		return 0, object.StdError(env, berrors.BadRecordNum)
+	}
+
+	return rec, nil
+}
+
+// FIELD binds string variables to slices of the record buffer
+func evalFieldStatement(node *ast.FieldStatement, code *ast.Code, env *object.Environment) object.Object {
+	if (len(node.Widths) == 0) || (len(node.Widths) != len(node.Vars)) || node.HasTrash() {
+		return object.StdError(env, berrors.Syntax)
+	}
+
+	fnum, file, err := evalRandomFile(&node.File, code, env)
+	if err != nil {
+		return err
+	}
+
+	offset := 0
+	for i, w := range node.Widths {
+		width, err := evalRecordInteger(w, code, env)
+		if err != nil {
+			return err
+		}
+
+		if (width < 0) || (width > maxFieldWidth) {
+			return object.StdError(env, berrors.IllegalFuncCallErr)
+		}
+
+		// the fields have to fit in the record
+		if offset+width > file.RecLen() {
+			return object.StdError(env, berrors.FieldOverflow)
+		}
+
+		typeid, isarray := parseVarName(node.Vars[i].Value)
+		if typeid != "$" || isarray {
+			return object.StdError(env, berrors.TypeMismatch)
+		}
+
+		fv := &object.FieldVar{Name: node.Vars[i].Value, Offset: offset, Width: width}
+		env.AddField(fnum, fv)
+		evalFieldLoad(fv, file, env)
+
+		offset += width
+	}
+
+	return nil
+}
+
+// evalFieldLoad gives a FIELD variable the current contents of its slice of the buffer
+func evalFieldLoad(fv *object.FieldVar, file gwtypes.RandomFile, env *object.Environment) {
+	fv.Value = &object.String{Value: string(file.Record()[fv.Offset : fv.Offset+fv.Width])}
+	env.Set(fv.Name, fv.Value)
+}
+
+// evalFieldRefresh reloads every variable still bound to the buffer of file fnum
+func evalFieldRefresh(fnum int16, file gwtypes.RandomFile, env *object.Environment) {
+	for _, fv := range env.Fields(fnum) {
+		evalFieldLoad(fv, file, env)
+	}
+}
+
+// GET reads a record into the buffer, FIELD variables pick up the new contents
+func evalGetStatement(node *ast.GetStatement, code *ast.Code, env *object.Environment) object.Object {
+	if node.HasTrash() {
+		return object.StdError(env, berrors.Syntax)
+	}
+
+	fnum, file, err := evalRandomFile(&node.File, code, env)
+	if err != nil {
+		return err
+	}
+
+	rec, err := evalRecordNumber(node.Record, file, code, env)
+	if err != nil {
+		return err
+	}
+
+	file.GetRecord(rec)
+	evalFieldRefresh(fnum, file, env)
+
+	return nil
+}
+
+// PUT writes the buffer out as a record, the file grows as needed
+func evalPutStatement(node *ast.PutStatement, code *ast.Code, env *object.Environment) object.Object {
+	if node.HasTrash() {
 		return object.StdError(env, berrors.Syntax)
 	}
 
-	// figure out the index
-	rc := evalExpressionNode(node.Exp, code, env)
-
-	// did I get an error?
-	_, ok := rc.(*object.Error)
-	if ok {
-		return rc
+	_, file, err := evalRandomFile(&node.File, code, env)
+	if err != nil {
+		return err
 	}
 
-	// Get the result as an index
-	ind, rc := coerceDblInteger(rc, env)
-
-	// if rc is not nil, it is an error
-	if rc != nil {
-		return rc
+	rec, err := evalRecordNumber(node.Record, file, code, env)
+	if err != nil {
+		return err
 	}
 
-	return evalOnGoJump(ind, node, code, env)
+	file.PutRecord(rec)
+
+	return nil
 }
 
-// figure out where to jump, how to jump then jump
-func evalOnGoJump(ind int32, node *ast.OnGoStatement, code *ast.Code, env *object.Environment) object.Object {
-	// if index to small/large, just continue
-	if (ind <= 0) || (int(ind) > len(node.Jumps)) {
-		return nil
+// LSET and RSET move a string into a variable, padding with spaces or truncating it
+// to fit the space the variable already holds.  For a FIELD variable, that is
+// its slice of the record buffer.
+func evalJustifyStatement(name *ast.Identifier, value ast.Expression, left bool, code *ast.Code, env *object.Environment) object.Object {
+	if (name == nil) || (value == nil) {
+		return object.StdError(env, berrors.Syntax)
 	}
 
-	// pull the destination expression
-	jmpto := evalExpressionNode(node.Jumps[ind-1], code, env)
-
-	// extract out the line number
-	jmp, rc := coerceDblInteger(jmpto, env)
-
-	if rc != nil {
-		return rc
+	typeid, _ := parseVarName(name.Value)
+	if typeid != "$" {
+		return object.StdError(env, berrors.TypeMismatch)
 	}
 
-	if !code.Exists(int(jmp)) {
-		return object.StdError(env, berrors.UnDefinedLineNumber)
+	str, err := evalJustifyValue(value, code, env)
+	if err != nil {
+		return err
 	}
 
-	switch node.MidTok.Literal {
-	case "GOTO":
-		return Eval(&ast.GotoStatement{JmpTo: []token.Token{{Type: token.INT, Literal: strconv.Itoa(int(jmp))}}}, code, env)
-	case "GOSUB":
-		return Eval(&ast.GosubStatement{Gosub: []token.Token{{Type: token.INT, Literal: strconv.Itoa(int(jmp))}}}, code, env)
+	fnum, fv := env.FindField(name.Value)
+	if fv == nil {
+		// not bound to a buffer, it keeps its current length
+		cur, err := evalJustifyValue(name, code, env)
+		if err != nil {
+			return err
+		}
+		return saveVariable(code, env, name, &object.String{Value: justifyString(str, len(cur), left)})
 	}
-	return object.StdError(env, berrors.Syntax)
-}
 
-// opens a data file
-// todo: support open device
-// note: node is a *copy* of the OpenStatement in the AST, not a pointer to it.
-//
-//	this way, I can modify the fields for the current environment and not
-//	affect later evaluations of the statement.
-func evalOpenStatement(node ast.OpenStatement, env *object.Environment) object.Object {
-	// get the target file name and build a fully qualified file name
-	// based on current virtual drive and directory
-	node.FileName = fileserv.BuildFullPath(node.FileName, env)
+	file := env.GetOpenFile(fnum).(gwtypes.RandomFile)
+	copy(file.Record()[fv.Offset:fv.Offset+fv.Width], justifyString(str, fv.Width, left))
 
-	if node.Verbose {
-		return evalVerboseOpen(&node, env)
-	}
+	// fields can overlap, they all see the change
+	evalFieldRefresh(fnum, file, env)
 
-	return evalConciseOpen(&node, env)
+	return nil
 }
 
-// it's gwbasic, so they have two statement formats to open a file
-// this is the verbose form
-func evalVerboseOpen(node *ast.OpenStatement, env *object.Environment) object.Object {
-	// any missing parameters, fill in the defaults
-	if len(node.Mode) == 0 {
-		node.Mode = token.RANDOM
+// evalJustifyValue evaluates an expression that must be a string
+func evalJustifyValue(exp ast.Expression, code *ast.Code, env *object.Environment) (string, object.Object) {
+	rc := evalExpressionNode(exp, code, env)
+	if isError(rc) {
+		return "", rc
 	}
 
-	if len(node.Access) == 0 {
-		node.Access = token.READ + " " + token.WRITE
+	if tv, ok := rc.(*object.TypedVar); ok {
+		rc = tv.Value
 	}
 
-	if len(node.RecLen) == 0 {
-		node.RecLen = "128"
+	switch str := rc.(type) {
+	case *object.String:
+		return str.Value, nil
+	case *object.BStr:
+		// MKI$ and friends build these
+		return string(str.Value), nil
 	}
 
-	// localfiles.Open checks if the file is held in the local file system
-	// if not, it tries to pull the file from the file server
-	// once the file is local, it proceeds to open it
-	// returns an error if it can't be found
-	return localfiles.Open(node.FileName, env)
+	return "", object.StdError(env, berrors.TypeMismatch)
 }
 
-// fill in defaults as need for concise form of open
-func evalConciseOpen(node *ast.OpenStatement, env *object.Environment) object.Object {
-	// concise form assumes access mode
-	node.Access = token.READ + " " + token.WRITE
+// justifyString pads str with spaces to width, on the right if left justifying
+// a string that is too long loses characters on the right either way
+func justifyString(str string, width int, left bool) string {
+	if len(str) >= width {
+		return str[:width]
+	}
 
-	if len(node.RecLen) == 0 {
-		node.RecLen = "128"
+	pad := strings.Repeat(" ", width-len(str))
+	if left {
+		return str + pad
 	}
 
-	// localfiles.Open checks if the file is held in the local file system
-	// if not, it tries to pull the file from the file server
-	// once the file is local, it proceeds to open it
-	// returns an error if it can't be found
-	return localfiles.Open(node.FileName, env)
+	return pad + str
 }
 
 // Build the default Palette struct
@@ -2032,9 +3465,28 @@ func evalPaletteDefault(scrmode int) *ast.PaletteStatement {
 // Process parameters of a Print statement
 func evalPrintStatement(node *ast.PrintStatement, code *ast.Code, env *object.Environment) object.Object {
 	var rc object.Object
+	var out printer = env.Terminal()
+
+	// LPRINT goes to the line printer, never to a file
+	if node.Token.Type == token.LPRINT {
+		if node.File != nil {
+			return object.StdError(env, berrors.Syntax)
+		}
+		out = env.LinePrinter()
+	}
+
+	// PRINT # sends the output to a file
+	if node.File != nil {
+		fp, err := evalFilePrinter(node.File, code, env)
+		if err != nil {
+			return err
+		}
+		out = fp
+	}
+
 	// go print items, if there are any
 	if len(node.Items) > 0 {
-		rc = evalPrintItems(node, code, env)
+		rc = evalPrintItems(node, out, code, env)
 	}
 
 	// if I got anything, it is an error
@@ -2048,15 +3500,73 @@ func evalPrintStatement(node *ast.PrintStatement, code *ast.Code, env *object.En
 	}
 
 	// end with a newline
-	env.Terminal().Println("")
+	out.Println("")
 
 	return nil
 }
 
+// printer is where PRINT and WRITE send their output, the screen or a file
+type printer interface {
+	Print(string)
+	Println(string)
+}
+
+// filePrinter sends output to an open file
+// the column is kept with the open file, a line can be built by several statements
+type filePrinter struct {
+	file gwtypes.AnOpenFile
+	num  int16 // the file number
+	env  *object.Environment
+}
+
+// evalFilePrinter sets up PRINT # or WRITE # to an open sequential output file
+func evalFilePrinter(fn *ast.FileNumber, code *ast.Code, env *object.Environment) (*filePrinter, object.Object) {
+	fnum, err := evalFileNumber(fn, code, env)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := evalFileMode(fnum, env, gwtypes.Output, gwtypes.Append)
+	if err != nil {
+		return nil, err
+	}
+
+	return &filePrinter{file: file, num: fnum, env: env}, nil
+}
+
+// Print writes the string to the file, commas tab out to the next print zone
+func (fp *filePrinter) Print(s string) {
+	var out []byte
+	col := fp.env.FileCol(fp.num)
+
+	for _, b := range []byte(s) {
+		if b == '\t' {
+			for pad := object.PrintZone - (col % object.PrintZone); pad > 0; pad-- {
+				out = append(out, ' ')
+				col++
+			}
+			continue
+		}
+		out = append(out, b)
+		col++
+	}
+
+	fp.file.Write(out)
+	fp.env.SetFileCol(fp.num, col)
+}
+
+// Println ends the line with a CR/LF like GW-BASIC does
+func (fp *filePrinter) Println(s string) {
+	fp.Print(s)
+	fp.file.Write([]byte("\r\n"))
+	fp.env.SetFileCol(fp.num, 0)
+}
+
 // Print the individual items
-func evalPrintItems(node *ast.PrintStatement, code *ast.Code, env *object.Environment) object.Object {
+func evalPrintItems(node *ast.PrintStatement, out printer, code *ast.Code, env *object.Environment) object.Object {
 	var obj object.Object
-	fmt := ""
+	var uf *usingFormat
+	used := false
 
 	for i, item := range node.Items {
 		switch node := item.(type) {
@@ -2083,12 +3593,14 @@ func evalPrintItems(node *ast.PrintStatement, code *ast.Code, env *object.Enviro
 			fval := Eval(node, code, env)
 			obj = fval.(*object.Fixed)
 		case *ast.UsingExpression:
-			obj = evalUsingExpression(node, code, env)
-			form, ok := obj.(*object.String)
-			if ok {
-				fmt = form.Value
-				continue // skip any printing
+			var err object.Object
+			uf, err = evalUsingExpression(node, code, env)
+			if err != nil {
+				return err
 			}
+			continue // the format itself doesn't get printed
+		default:
+			obj = evalExpressionNode(node, code, env)
 		}
 		_, ok := obj.(*object.Error)
 
@@ -2096,48 +3608,58 @@ func evalPrintItems(node *ast.PrintStatement, code *ast.Code, env *object.Enviro
 			return obj
 		}
 
-		if len(fmt) == 0 {
-			evalPrintItemValue(obj, env)
+		if uf == nil {
+			evalPrintItemValue(obj, out)
 		} else {
-			err := evalPrintItemUsing(fmt, obj, env)
+			err := evalPrintItemUsing(uf, obj, out, env)
 			if err != nil {
 				return err
 			}
+			used = true
+			continue // the format does the spacing, commas don't tab
 		}
 
 		// if seperated by a comma, that means tab
 		if node.Seperators[i] == "," {
-			env.Terminal().Print("\t")
+			out.Print("\t")
 		}
 	}
 
+	if uf == nil {
+		return nil
+	}
+
+	// USING needs something to format
+	if !used {
+		return object.StdError(env, berrors.MissingOp)
+	}
+
+	// any literal text following the last field used
+	if tail := uf.finish(); len(tail) > 0 {
+		out.Print(tail)
+	}
+
 	return nil
 }
 
-// evalPrintItemUsing uses the suppllied format string to Sprintf the object into a string
-// and then prints it.
-// TODO support more than just numerics
-func evalPrintItemUsing(form string, item object.Object, env *object.Environment) object.Object {
-	out := fmt.Sprintf("fmt snap %T", item)
-	switch val := item.(type) {
-	case *object.Integer:
-		out = fmt.Sprintf(form, float32(val.Value))
-	case *object.IntDbl:
-		out = fmt.Sprintf(form, float32(val.Value))
-	case *object.Fixed:
-		v, _ := val.Value.Float64()
-		out = fmt.Sprintf(form, v)
-	case *object.FloatSgl:
-		out = fmt.Sprintf(form, val.Value)
-	case *object.FloatDbl:
-		out = fmt.Sprintf(form, val.Value)
+// evalPrintItemUsing formats the item into the next field of the format and prints it
+func evalPrintItemUsing(uf *usingFormat, item object.Object, out printer, env *object.Environment) object.Object {
+	res, err := uf.print(item, env)
+	if err != nil {
+		return err
 	}
-	env.Terminal().Print(out)
+
+	out.Print(res)
 	return nil
 }
 
-// figure out what a print item is, and turn it into a string
-func evalPrintItemValue(item object.Object, env *object.Environment) {
+// figure out what a print item is, and print it
+func evalPrintItemValue(item object.Object, out printer) {
+	out.Print(evalPrintItemString(item))
+}
+
+// turn a print item into a string
+func evalPrintItemString(item object.Object) string {
 	out := fmt.Sprintf("oh snap %T", item)
 	switch val := item.(type) {
 	case *object.String:
@@ -2152,8 +3674,10 @@ func evalPrintItemValue(item object.Object, env *object.Environment) {
 		out = val.Inspect()
 	case *object.IntDbl:
 		out = val.Inspect()
+	case *object.TypedVar:
+		out = evalPrintItemString(val.Value)
 	}
-	env.Terminal().Print(out)
+	return out
 }
 
 // get the value of the identifier
@@ -2166,6 +3690,8 @@ func evalPrefixExpression(operator string, right object.Object, env *object.Envi
 	switch operator {
 	case "-":
 		return evalMinusPrefixOperatorExpression(right, env)
+	case "NOT":
+		return evalNotPrefixOperatorExpression(right, env)
 	default:
 		return newError(env, "unknown operator: %s%s", operator, right.Type())
 	}
@@ -2190,6 +3716,35 @@ func evalMinusPrefixOperatorExpression(right object.Object, env *object.Environm
 	return right
 }
 
+// NOT flips all the bits of the 16 bit integer
+func evalNotPrefixOperatorExpression(right object.Object, env *object.Environment) object.Object {
+	var val float64
+
+	switch obj := right.(type) {
+	case *object.Integer:
+		val = float64(obj.Value)
+	case *object.IntDbl:
+		val = float64(obj.Value)
+	case *object.FloatSgl:
+		val = float64(obj.Value)
+	case *object.FloatDbl:
+		val = obj.Value
+	case *object.Fixed:
+		val, _ = obj.Value.Float64()
+	case *object.Error:
+		return obj
+	default:
+		return object.StdError(env, berrors.TypeMismatch)
+	}
+
+	i, err := evalLogicalOperand(val, env)
+	if err != nil {
+		return err
+	}
+
+	return &object.Integer{Value: ^i}
+}
+
 // pass parms to evalInfixExpression if result is zero, return false otherwise return true
 func evalInfixBooleanExpression(operator string, left, right object.Object, env *object.Environment) bool {
 	exp := evalInfixExpression(operator, left, right, env)
@@ -2229,6 +3784,9 @@ func evalStringInfixExpression(operator string, left, right object.Object, env *
 	case "=":
 		return &object.Integer{Value: bool2int16(leftVal == rightVal)}
 
+	case "AND", "OR", "XOR", "EQV", "IMP":
+		return object.StdError(env, berrors.TypeMismatch)
+
 	default:
 		return newError(env, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
@@ -2249,9 +3807,25 @@ func evalIntegerInfixExpression(operator string, leftVal, rightVal int, env *obj
 		return builtins.FixType(env, float64(leftVal)/float64(rightVal))
 	case "\\":
 		// I'm learning stuff I never knew about GWBasic
-		return &object.Integer{Value: int16(leftVal) / int16(rightVal)}
+		// both sides have to fit in an integer
+		l, err := evalLogicalOperand(float64(leftVal), env)
+		if err != nil {
+			return err
+		}
+		r, err := evalLogicalOperand(float64(rightVal), env)
+		if err != nil {
+			return err
+		}
+		if r == 0 {
+			return object.StdError(env, berrors.DivByZero)
+		}
+		return &object.Integer{Value: l / r}
 	case "MOD":
 		return builtins.FixType(env, leftVal%rightVal)
+	case "^":
+		return evalPowerExpression(float64(leftVal), float64(rightVal), false, env)
+	case "AND", "OR", "XOR", "EQV", "IMP":
+		return evalLogicalInfixExpression(operator, float64(leftVal), float64(rightVal), env)
 	case "<":
 		return &object.Integer{Value: bool2int16(leftVal < rightVal)}
 	case "<=":
@@ -2285,6 +3859,13 @@ func evalFixedInfixExpression(operator string, left, right decimal.Decimal, env
 			return object.StdError(env, err)
 		}
 		return &object.Fixed{Value: val}
+	case "^", "AND", "OR", "XOR", "EQV", "IMP":
+		l, _ := left.Float64()
+		r, _ := right.Float64()
+		if operator == "^" {
+			return evalPowerExpression(l, r, false, env)
+		}
+		return evalLogicalInfixExpression(operator, l, r, env)
 	case "<":
 		return &object.Integer{Value: bool2int16(left.Cmp(right) == -1)}
 	case "<=":
@@ -2316,6 +3897,10 @@ func evalFloatInfixExpression(operator string, leftVal, rightVal float32, env *o
 			return object.StdError(env, berrors.DivByZero)
 		}
 		return builtins.FixType(env, leftVal/rightVal)
+	case "^":
+		return evalPowerExpression(float64(leftVal), float64(rightVal), false, env)
+	case "AND", "OR", "XOR", "EQV", "IMP":
+		return evalLogicalInfixExpression(operator, float64(leftVal), float64(rightVal), env)
 	case "<":
 		return &object.Integer{Value: bool2int16(leftVal < rightVal)}
 	case "<=":
@@ -2347,6 +3932,10 @@ func evalFloatDblInfixExpression(operator string, leftVal, rightVal float64, env
 			return object.StdError(env, berrors.DivByZero)
 		}
 		return builtins.FixType(env, leftVal/rightVal)
+	case "^":
+		return evalPowerExpression(leftVal, rightVal, true, env)
+	case "AND", "OR", "XOR", "EQV", "IMP":
+		return evalLogicalInfixExpression(operator, leftVal, rightVal, env)
 	case "<":
 		return &object.Integer{Value: bool2int16(leftVal < rightVal)}
 	case "<=":
@@ -2364,6 +3953,70 @@ func evalFloatDblInfixExpression(operator string, leftVal, rightVal float64, env
 	}
 }
 
+// exponentiation is always done in floating point
+func evalPowerExpression(leftVal, rightVal float64, dbl bool, env *object.Environment) object.Object {
+	if (leftVal == 0) && (rightVal < 0) {
+		return object.StdError(env, berrors.DivByZero)
+	}
+
+	res := math.Pow(leftVal, rightVal)
+
+	if math.IsNaN(res) {
+		return object.StdError(env, berrors.IllegalFuncCallErr)
+	}
+
+	if !dbl {
+		if math.IsInf(float64(float32(res)), 0) {
+			return object.StdError(env, berrors.Overflow)
+		}
+		return builtins.FixType(env, float32(res))
+	}
+
+	if math.IsInf(res, 0) {
+		return object.StdError(env, berrors.Overflow)
+	}
+	return builtins.FixType(env, res)
+}
+
+// logical operators work bitwise on 16 bit integers
+func evalLogicalInfixExpression(operator string, leftVal, rightVal float64, env *object.Environment) object.Object {
+	l, err := evalLogicalOperand(leftVal, env)
+	if err != nil {
+		return err
+	}
+
+	r, err := evalLogicalOperand(rightVal, env)
+	if err != nil {
+		return err
+	}
+
+	switch operator {
+	case "AND":
+		return &object.Integer{Value: l & r}
+	case "OR":
+		return &object.Integer{Value: l | r}
+	case "XOR":
+		return &object.Integer{Value: l ^ r}
+	case "EQV":
+		return &object.Integer{Value: ^(l ^ r)}
+	case "IMP":
+		return &object.Integer{Value: ^l | r}
+	}
+
+	return newError(env, "unsupported operator %s", operator)
+}
+
+// operands get rounded to an integer, and it had better fit
+func evalLogicalOperand(val float64, env *object.Environment) (int16, object.Object) {
+	val = math.Round(val)
+
+	if (val > math.MaxInt16) || (val < math.MinInt16) {
+		return 0, object.StdError(env, berrors.Overflow)
+	}
+
+	return int16(val), nil
+}
+
 func evalIfStatement(ie *ast.IfStatement, code *ast.Code, env *object.Environment) object.Object {
 	condition := Eval(ie.Condition, code, env)
 	if isError(condition) {
@@ -2525,8 +4178,7 @@ func saveVariable(code *ast.Code, env *object.Environment, name *ast.Identifier,
 
 	// if not dealing with an array, just save the new value
 	if !isarray {
-		env.Set(sname, val)
-		return nil
+		return env.Set(sname, val)
 	}
 
 	cvarray, ok := cv.(*object.Array)
@@ -2671,33 +4323,38 @@ func isError(obj object.Object) bool {
 	return checkType(obj, object.ERROR_OBJ)
 }
 
+// true is all bits set, -1
 func bool2int16(b bool) int16 {
 	// The compiler currently only optimizes this form.
 	// See issue 6011.
 	var i int16
 	if b {
-		i = 1
+		i = -1
 	} else {
 		i = 0
 	}
 	return i
 }
 
-// evalUsingExpression and return string object with format string
-func evalUsingExpression(stmt *ast.UsingExpression, code *ast.Code, env *object.Environment) object.Object {
+// evalUsingExpression evaluates the format string and gets it ready for printing
+func evalUsingExpression(stmt *ast.UsingExpression, code *ast.Code, env *object.Environment) (*usingFormat, object.Object) {
+	if stmt.Format == nil {
+		return nil, object.StdError(env, berrors.MissingOp)
+	}
+
 	frm := evalExpressionNode(stmt.Format, code, env)
-	inp := ""
+	if tv, ok := frm.(*object.TypedVar); ok {
+		frm = tv.Value
+	}
+
 	switch obj := frm.(type) {
+	case *object.Error:
+		return nil, obj
 	case *object.String:
-		inp = obj.Value
-	default:
-		return object.StdError(env, berrors.Syntax)
+		return newUsingFormat(obj.Value, env)
 	}
-	l := lexer.New(inp)
-	p := parser.New(l)
-	fmt := p.ParseUsingRunTime()
 
-	return &object.String{Value: fmt}
+	return nil, object.StdError(env, berrors.TypeMismatch)
 }
 
 func evalViewPrintStatement(stmt *ast.ViewPrintStatement, code *ast.Code, env *object.Environment) object.Object {
@@ -2765,6 +4422,120 @@ func evalViewStatement() object.Object {
 	return nil
 }
 
+// WEND sends us back to the WHILE to test the condition again
+func evalWendStatement(code *ast.Code, env *object.Environment) object.Object {
+	// make sure we are actually in a WHILE loop
+	if len(env.WhileLoops) == 0 {
+		return object.StdError(env, berrors.WendWoWhile)
+	}
+
+	blk := env.WhileLoops[len(env.WhileLoops)-1]
+	env.WhileLoops = env.WhileLoops[:len(env.WhileLoops)-1]
+
+	// WHILE will be the next statement executed
+	code.JumpBeforeRetPoint(blk.Code)
+
+	return nil
+}
+
+// WHILE tests the condition and either starts the loop or skips it
+func evalWhileStatement(whl *ast.WhileStatement, code *ast.Code, env *object.Environment) object.Object {
+	if whl.Condition == nil {
+		return object.StdError(env, berrors.Syntax)
+	}
+
+	// if he jumped out of this loop and came back, forget the old one
+	// along with anything nested inside of it
+	rp := code.GetReturnPoint()
+	entering := true
+	for i := len(env.WhileLoops) - 1; i >= 0; i-- {
+		if env.WhileLoops[i].Code == rp {
+			env.WhileLoops = env.WhileLoops[:i]
+			entering = false
+			break
+		}
+	}
+
+	cond := Eval(whl.Condition, code, env)
+	if isError(cond) {
+		return cond
+	}
+
+	if cond.Type() == object.STRING_OBJ {
+		return object.StdError(env, berrors.TypeMismatch)
+	}
+
+	if evalInfixBooleanExpression("<>", cond, &object.Integer{Value: 0}, env) {
+		// the loop needs a WEND even if it never gets there
+		if entering {
+			err := evalWhileSkipLoop(code, env)
+			code.JumpToRetPoint(rp)
+			if err != nil {
+				return err
+			}
+		}
+		env.WhileLoops = append(env.WhileLoops, object.WhileBlock{Code: rp, While: whl})
+		return nil
+	}
+
+	return evalWhileSkipLoop(code, env)
+}
+
+// evalWhileSkipLoop condition is false
+// skip over statements until you find the matching WEND
+func evalWhileSkipLoop(code *ast.Code, env *object.Environment) object.Object {
+	depth := 0
+	for more := code.Next(); more; more = code.Next() {
+		switch code.Value().(type) {
+		case *ast.WhileStatement:
+			// found an inner WHILE loop, need to skip his WEND too
+			depth++
+		case *ast.WendStatement:
+			if depth == 0 {
+				return nil
+			}
+			depth--
+		}
+	}
+	return object.StdError(env, berrors.WhileWoWend)
+}
+
+// WRITE outputs the items seperated by commas, with strings in quotes
+func evalWriteStatement(node *ast.WriteStatement, code *ast.Code, env *object.Environment) object.Object {
+	var out printer = env.Terminal()
+
+	// WRITE # sends the output to a file
+	if node.File != nil {
+		fp, err := evalFilePrinter(node.File, code, env)
+		if err != nil {
+			return err
+		}
+		out = fp
+	}
+
+	var items []string
+	for _, item := range node.Items {
+		obj := evalExpressionNode(item, code, env)
+		if isError(obj) {
+			return obj
+		}
+
+		if tv, ok := obj.(*object.TypedVar); ok {
+			obj = tv.Value
+		}
+
+		if str, ok := obj.(*object.String); ok {
+			items = append(items, `"`+str.Value+`"`)
+		} else {
+			items = append(items, evalPrintItemString(obj))
+		}
+	}
+
+	out.Println(strings.Join(items, ","))
+
+	return nil
+}
+
 // checkForTrash checks to see if the node has any trash
 func checkForTrash(node ast.Node, env *object.Environment) object.Object {
 
@@ -1,18 +1,23 @@
 package evaluator
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"time"
 
 	"github.com/navionguy/basicwasm/afile"
 	"github.com/navionguy/basicwasm/ast"
 	"github.com/navionguy/basicwasm/berrors"
 	"github.com/navionguy/basicwasm/decimal"
 	"github.com/navionguy/basicwasm/fileserv"
+	"github.com/navionguy/basicwasm/gwtypes"
 	"github.com/navionguy/basicwasm/lexer"
+	"github.com/navionguy/basicwasm/localfiles"
 	"github.com/navionguy/basicwasm/mocks"
 	"github.com/navionguy/basicwasm/object"
 	"github.com/navionguy/basicwasm/parser"
@@ -602,7 +607,7 @@ func Test_CloseStatement(t *testing.T) {
 		var mt mocks.MockTerm
 		initMockTerm(&mt)
 		env := object.NewTermEnvironment(mt)
-		file, err := afile.OpenFile("d:\\data\\users.txt", ast.OpenStatement{FileName: "USERS.TXT", FileNumber: ast.FileNumber{Numbr: &ast.IntegerLiteral{Value: tt.open}}, Mode: "SHARED", Access: "RANDOM"}, env)
+		file, err := afile.OpenFile("d:\\data\\users.txt", nil, ast.OpenStatement{FileName: "USERS.TXT", FileNumber: ast.FileNumber{Numbr: &ast.IntegerLiteral{Value: tt.open}}, Mode: "SHARED", Access: "RANDOM"}, env)
 
 		assert.Nil(t, err, "file did not open")
 
@@ -725,8 +730,8 @@ func Test_CsrLinExpression(t *testing.T) {
 	var mt mocks.MockTerm
 	initMockTerm(&mt)
 	row := 5
-	mt.Row = &row
 	env := object.NewTermEnvironment(mt)
+	env.Terminal().Locate(row+1, 1)
 	p.ParseProgram(env)
 
 	rc := Eval(&ast.Program{}, env.StatementIter(), env)
@@ -740,6 +745,49 @@ func Test_CsrLinExpression(t *testing.T) {
 	assert.Equal(t, row+1, int(newRow.Value), "CSRLIN returned %d, expected %d", newRow.Value, row+1)
 }
 
+func Test_DateTime(t *testing.T) {
+	tests := []struct {
+		inp  string
+		exp  string
+		err  int
+		adv  time.Duration
+		chkX string
+	}{
+		{inp: `10 X$ = DATE$ + " " + TIME$`, chkX: "X$", exp: "07-04-1996 09:30:15"},
+		{inp: `10 X = TIMER`, chkX: "X", exp: "34215"},
+		{inp: `10 X = TIMER`, adv: 500 * time.Millisecond, chkX: "X", exp: "34215.49"},
+		{inp: `10 DATE$ = "12-25-2001" : X$ = DATE$ + " " + TIME$`, chkX: "X$", exp: "12-25-2001 09:30:15"},
+		{inp: `10 TIME$ = "18:05" : X$ = DATE$ + " " + TIME$ : T = TIMER`, chkX: "X$", exp: "07-04-1996 18:05:00"},
+		{inp: `10 DATE$ = "2001-12-25"`, err: berrors.IllegalFuncCallErr},
+		{inp: `10 TIME$ = "25:00"`, err: berrors.IllegalFuncCallErr},
+		{inp: `10 TIME$ = 12`, err: berrors.Syntax},
+		{inp: `10 TIMER = 5`, err: berrors.Syntax},
+	}
+
+	for _, tt := range tests {
+		var mt mocks.MockTerm
+		initMockTerm(&mt)
+		env := object.NewTermEnvironment(mt)
+		clk := &mocks.MockClock{Time: time.Date(1996, time.July, 4, 9, 30, 15, 0, time.Local)}
+		env.SetClock(clk)
+		clk.Advance(tt.adv)
+
+		p := parser.New(lexer.New(tt.inp))
+		p.ParseProgram(env)
+		rc := Eval(&ast.Program{}, env.StatementIter(), env)
+
+		if tt.err != 0 {
+			assert.Equal(t, object.StdError(env, tt.err).Inspect(), rc.Inspect(), "%s didn't fail", tt.inp)
+			continue
+		}
+
+		assert.Nil(t, rc, "%s failed", tt.inp)
+		if len(tt.chkX) > 0 {
+			assert.Equal(t, tt.exp, env.Get(tt.chkX).Inspect(), "%s gave the wrong value", tt.inp)
+		}
+	}
+}
+
 func Test_ErrorStatement(t *testing.T) {
 	tests := []struct {
 		inp string
@@ -1035,13 +1083,13 @@ func Test_GosubGotoStatements(t *testing.T) {
 	}{
 		{inp: "10 GOSUB", err: &object.Error{Code: 2, Message: "Syntax error in 10"}},
 		{inp: "10 GOSUB 30", err: &object.Error{Code: 8, Message: "Undefined line number in 10"}},
-		{inp: "10 GOSUB 30\n20 END\n30 STOP", line: 0},
-		{inp: "10 GOSUB 30\n20 END\n30 STOP", trace: true, line: 0},
+		{inp: "10 GOSUB 30\n20 END\n30 STOP", err: &object.HaltSignal{Msg: "Break in line 30"}},
+		{inp: "10 GOSUB 30\n20 END\n30 STOP", trace: true, err: &object.HaltSignal{Msg: "Break in line 30"}},
 		{inp: "20 GOTO", err: &object.Error{Code: 2, Message: "Syntax error in 20"}},
 		{inp: "20 GOTO X", err: &object.Error{Code: 2, Message: "Syntax error in 20"}},
 		{inp: "20 GOTO 30", err: &object.Error{Code: 8, Message: "Undefined line number in 20"}},
-		{inp: "20 GOTO 40\n30 END\n40 STOP", line: 0},
-		{inp: "20 GOTO 40\n30 END\n40 STOP", trace: true, line: 0},
+		{inp: "20 GOTO 40\n30 END\n40 STOP", err: &object.HaltSignal{Msg: "Break in line 40"}},
+		{inp: "20 GOTO 40\n30 END\n40 STOP", trace: true, err: &object.HaltSignal{Msg: "Break in line 40"}},
 		{inp: "20 GOTO 40\n30 END", err: &object.Error{Code: 8, Message: "Undefined line number in 20"}},
 	}
 
@@ -1117,9 +1165,9 @@ func Test_EvalIntegerExpression(t *testing.T) {
 	}{
 		{"10 X = -5", -5},
 		{"50 X=5 + 5", 10},
-		{"70 X=5 < 10", 1},
+		{"70 X=5 < 10", -1},
 		{"80 x=5 > 10", 0},
-		{"110 x=10 > 1", 1},
+		{"110 x=10 > 1", -1},
 		{"120 x=10 < 1", 0},
 		{"130 x=10 / 2", 5},
 		{"160 X=10 \\ 2", 5},
@@ -1178,6 +1226,65 @@ func TestDblInetegerExpression(t *testing.T) {
 	}
 }
 
+func Test_LogicalOperators(t *testing.T) {
+	tests := []struct {
+		inp string
+		x   interface{}
+		err object.Object
+	}{
+		{inp: "10 X = 12 AND 10", x: 8},
+		{inp: "10 X = 12 OR 10", x: 14},
+		{inp: "10 X = 12 XOR 10", x: 6},
+		{inp: "10 X = 12 EQV 10", x: -7},
+		{inp: "10 X = 12 IMP 10", x: -5},
+		{inp: "10 X = NOT 0", x: -1},
+		{inp: "10 X = NOT 1 = 2", x: -1},
+		{inp: "10 X = NOT 1 + 1", x: -3},
+		{inp: "10 X = 1 < 2 AND 3 < 2", x: 0},
+		{inp: "10 X = 1 OR 2 AND 0", x: 1},
+		{inp: "10 X = -1 XOR -1 OR 4", x: 0},
+		{inp: "10 X = 4.6 AND 7", x: 5},
+		{inp: "10 X = 99999 AND 1", err: &object.Error{Code: berrors.Overflow, Message: "Overflow in 10"}},
+		{inp: "10 X = 40000 OR 1", err: &object.Error{Code: berrors.Overflow, Message: "Overflow in 10"}},
+		{inp: "10 X = NOT 32768", err: &object.Error{Code: berrors.Overflow, Message: "Overflow in 10"}},
+		{inp: "10 X = NOT \"A\"", err: &object.Error{Code: berrors.TypeMismatch, Message: "Type mismatch in 10"}},
+		{inp: "10 X = \"A\" AND \"B\"", err: &object.Error{Code: berrors.TypeMismatch, Message: "Type mismatch in 10"}},
+		{inp: "10 X = \"A\" OR \"B\"", err: &object.Error{Code: berrors.TypeMismatch, Message: "Type mismatch in 10"}},
+		{inp: "10 X = 40000 \\ 2", err: &object.Error{Code: berrors.Overflow, Message: "Overflow in 10"}},
+		{inp: "10 X = 7 \\ 40000", err: &object.Error{Code: berrors.Overflow, Message: "Overflow in 10"}},
+		{inp: "10 X = 7 \\ 0", err: &object.Error{Code: berrors.DivByZero, Message: "Division by zero in 10"}},
+		{inp: "10 X = 32767 \\ 2", x: 16383},
+		{inp: "10 X = 2 ^ 3", x: 8},
+		{inp: "10 X = -2 ^ 2", x: -4},
+		{inp: "10 X = 2 ^ 3 ^ 2", x: 64},
+		{inp: "10 X = 2 * 3 ^ 2", x: 18},
+		{inp: "10 X = 4 ^ 0.5", x: 2},
+		{inp: "10 X = 0 ^ -1", err: &object.Error{Code: berrors.DivByZero, Message: "Division by zero in 10"}},
+		{inp: "10 X = (-8) ^ 0.5", err: &object.Error{Code: berrors.IllegalFuncCallErr, Message: "Illegal function call in 10"}},
+		{inp: "10 X = 10 ^ 39", err: &object.Error{Code: berrors.Overflow, Message: "Overflow in 10"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.inp)
+		p := parser.New(l)
+		var mt mocks.MockTerm
+		initMockTerm(&mt)
+		env := object.NewTermEnvironment(mt)
+
+		p.ParseProgram(env)
+		env.SetRun(true)
+		rc := Eval(&ast.Program{}, env.StatementIter(), env)
+
+		if tt.err != nil {
+			assert.EqualValuesf(t, tt.err, rc, "%s got the wrong error", tt.inp)
+			continue
+		}
+
+		assert.Nilf(t, rc, "%s returned unexpectedly with a %T", tt.inp, rc)
+		compareObjects(tt.inp, env.Get("X"), tt.x, t)
+	}
+}
+
 func TestDim_Statements(t *testing.T) {
 	tests := []struct {
 		inp string
@@ -1315,18 +1422,67 @@ func Test_EndStatement(t *testing.T) {
 	}
 }
 
-func Test_InkeyExpression(t *testing.T) {
+func Test_SystemCommand(t *testing.T) {
 	tests := []struct {
 		inp string
+		cmd bool
+	}{
+		{inp: "10 OPEN \"SYS.DAT\" FOR OUTPUT AS #1\n20 SYSTEM\n30 X = 5"},
+		{inp: "SYSTEM", cmd: true},
+	}
+
+	for _, tt := range tests {
+		var mt mocks.MockTerm
+		initMockTerm(&mt)
+		env := object.NewTermEnvironment(mt)
+		p := parser.New(lexer.New(tt.inp))
+
+		var rc object.Object
+		if tt.cmd {
+			p.ParseCmd(env)
+			rc = Eval(&ast.Program{}, env.CmdLineIter(), env)
+		} else {
+			p.ParseProgram(env)
+			rc = evalRunCheckStartLineNum(&ast.RunCommand{}, env)
+		}
+
+		assert.Equal(t, &object.HaltSignal{Exit: true}, rc, "%s didn't exit", tt.inp)
+		assert.Nil(t, env.GetOpenFile(1), "%s left a file open", tt.inp)
+		assert.Equal(t, &object.Integer{Value: 0}, env.Get("X"), "%s kept running", tt.inp)
+	}
+}
+
+func Test_SessionEnded(t *testing.T) {
+	var mt mocks.MockTerm
+	initMockTerm(&mt)
+	env := object.NewTermEnvironment(mt)
+	parser.New(lexer.New("10 GOTO 10")).ParseProgram(env)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	env.SetContext(ctx)
+	rc := evalRunCheckStartLineNum(&ast.RunCommand{}, env)
+
+	assert.Equal(t, &object.HaltSignal{Msg: "Break in line 10"}, rc, "program kept running")
+}
+
+func Test_InkeyExpression(t *testing.T) {
+	tests := []struct {
+		inp  string
+		keys string
+		exp  string
 	}{
 		//{inp: `X = ABS(-5) : END`},
 		//{inp: `X$ = HEX$(35) : END`},
 		{inp: `X$ = INKEY$ : END`},
+		{inp: `X$ = INKEY$ : END`, keys: "AB", exp: "A"},
+		{inp: `X$ = INKEY$ : END`, keys: "\x00H", exp: "\x00H"},
 	}
 
 	for _, tt := range tests {
 		mt := mocks.MockTerm{}
 		mocks.InitMockTerm(&mt)
+		*mt.StrVal = tt.keys
 		env := object.NewTermEnvironment(mt)
 
 		l := lexer.New(tt.inp)
@@ -1343,11 +1499,45 @@ func Test_InkeyExpression(t *testing.T) {
 
 		assert.True(t, ok)
 		assert.False(t, let.HasTrash())
+		assert.Equal(t, tt.exp, env.Get("X$").Inspect(), "%q read wrong", tt.keys)
+	}
+}
+
+func Test_InkeyCompare(t *testing.T) {
+	tests := []struct {
+		keys string
+		exp  int
+	}{
+		{exp: 1},
+		{keys: "\x00H", exp: 2},
+		{keys: "\x00P", exp: 3},
+		{keys: "A", exp: 4},
+	}
+
+	prog := `10 A$ = INKEY$ : X = 1
+20 IF A$ = "" THEN 60
+30 X = 2 : IF A$ = CHR$(0) + "H" THEN 60
+40 X = 3 : IF INKEY$ + A$ = CHR$(0) + "P" THEN 60
+50 X = 4
+60 END`
+
+	for _, tt := range tests {
+		mt := mocks.MockTerm{}
+		mocks.InitMockTerm(&mt)
+		*mt.StrVal = tt.keys
+		env := object.NewTermEnvironment(mt)
+
+		parser.New(lexer.New(prog)).ParseProgram(env)
+		env.SetRun(true)
+		rc := Eval(&ast.Program{}, env.StatementIter(), env)
+
+		assert.Nil(t, rc, "%q failed", tt.keys)
+		compareObjects(tt.keys, env.Get("X"), tt.exp, t)
 	}
 }
 
 func Test_KeyStatement(t *testing.T) {
-	const keydef = 14
+	const keydef = 10
 	tests := []struct {
 		inp string
 		len int
@@ -1356,9 +1546,9 @@ func Test_KeyStatement(t *testing.T) {
 	}{
 		{inp: `10 KEY OFF`, len: keydef},
 		{inp: `10 KEY ON`, len: keydef},
-		{inp: `10 KEY LIST`, len: keydef, exp: "F1 LIST\r\nF2 RUN\r\nF3 LOAD \"\r\nF4 SAVE \"\r\nF5 CONT\r\r\nF6 , \"LPT1:\" \r\r\nF7 TRON\r\r\nF8 TROFF\r\r\nF9 KEY\r\nF10 SCREEN 0,0,0\r\r\n"},
+		{inp: `10 KEY LIST`, len: keydef, exp: "F1 LIST \r\nF2 RUN\r\r\nF3 LOAD\"\r\nF4 SAVE\"\r\nF5 CONT\r\r\nF6 ,\"LPT1:\"\r\r\nF7 TRON\r\r\nF8 TROFF\r\r\nF9 KEY \r\nF10 SCREEN 0,0,0\r\r\n"},
 		{inp: `10 KEY 4,"FILES"`, len: keydef},
-		{inp: `10 KEY 4,"FILES" : KEY LIST`, len: keydef, exp: "F1 LIST\r\nF2 RUN\r\nF3 LOAD \"\r\nF4 FILES\r\nF5 CONT\r\r\nF6 , \"LPT1:\" \r\r\nF7 TRON\r\r\nF8 TROFF\r\r\nF9 KEY\r\nF10 SCREEN 0,0,0\r\r\n"},
+		{inp: `10 KEY 4,"FILES" : KEY LIST`, len: keydef, exp: "F1 LIST \r\nF2 RUN\r\r\nF3 LOAD\"\r\nF4 FILES\r\nF5 CONT\r\r\nF6 ,\"LPT1:\"\r\r\nF7 TRON\r\r\nF8 TROFF\r\r\nF9 KEY \r\nF10 SCREEN 0,0,0\r\r\n"},
 		{inp: `10 KEY 1`, err: true},
 		{inp: `20 KEY 25,"FILES"`, err: true},
 		{inp: `20 KEY "25","FILES"`, err: true},
@@ -1588,6 +1778,157 @@ func Test_OnErrorGotoStatement(t *testing.T) {
 	}
 }
 
+func Test_OnTimerStatement(t *testing.T) {
+	tests := []struct {
+		inp string
+		err int
+		chk string
+		exp int16
+	}{
+		{inp: `10 ON TIMER(1) GOSUB 100
+20 TIMER ON
+30 FOR I = 1 TO 50 : NEXT I
+40 END
+100 N = N + 1 : RETURN`, chk: "N", exp: 6},
+		{inp: `10 ON TIMER(1) GOSUB 100
+30 FOR I = 1 TO 50 : NEXT I
+40 END
+100 N = N + 1 : RETURN`, chk: "N", exp: 0},
+		{inp: `10 ON TIMER(2) GOSUB 100
+20 TIMER ON : TIMER STOP
+30 FOR I = 1 TO 50 : NEXT I
+40 TIMER ON : X = N : TIMER OFF
+50 END
+100 N = N + 1 : RETURN`, chk: "X", exp: 1},
+		{inp: `10 ON TIMER(1) GOSUB 100
+20 TIMER ON
+30 FOR I = 1 TO 50 : NEXT I
+40 END
+100 D = D + 1 : IF D > M THEN M = D
+110 K = K + 1 : IF K < 20 THEN 110
+120 D = D - 1 : RETURN`, chk: "M", exp: 1},
+		{inp: `10 ON TIMER(1) GOSUB 100
+20 TIMER ON
+30 FOR I = 1 TO 50 : NEXT I
+40 END
+100 N = N + 1 : TIMER OFF : RETURN`, chk: "N", exp: 1},
+		{inp: `10 ON TIMER(1) GOSUB 0
+20 TIMER ON
+30 FOR I = 1 TO 50 : NEXT I
+40 END`},
+		{inp: `10 ON TIMER(0) GOSUB 100`, err: berrors.IllegalFuncCallErr},
+		{inp: `10 ON TIMER(86401) GOSUB 100`, err: berrors.IllegalFuncCallErr},
+		{inp: `10 ON TIMER("A") GOSUB 100`, err: berrors.TypeMismatch},
+		{inp: `10 ON TIMER(5) GOSUB 100`, err: berrors.UnDefinedLineNumber},
+		{inp: `10 ON TIMER 5 GOSUB 100`, err: berrors.Syntax},
+		{inp: `10 TIMER`, err: berrors.Syntax},
+		{inp: `10 TIMER ON 5`, err: berrors.Syntax},
+	}
+
+	for _, tt := range tests {
+		var mt mocks.MockTerm
+		initMockTerm(&mt)
+		env := object.NewTermEnvironment(mt)
+		env.SetClock(&mocks.MockClock{Time: time.Date(1996, time.July, 4, 9, 30, 15, 0, time.Local), Step: 100 * time.Millisecond})
+
+		p := parser.New(lexer.New(tt.inp))
+		p.ParseProgram(env)
+		rc := evalRunCheckStartLineNum(&ast.RunCommand{}, env)
+
+		if tt.err != 0 {
+			assert.Equal(t, object.StdError(env, tt.err).Code, rc.(*object.Error).Code, "%s didn't fail", tt.inp)
+			continue
+		}
+
+		assert.Nil(t, rc, "%s failed", tt.inp)
+		if len(tt.chk) > 0 {
+			assert.Equal(t, &object.Integer{Value: tt.exp}, env.Get(tt.chk), "%s gave the wrong %s", tt.inp, tt.chk)
+		}
+	}
+}
+
+func Test_OnKeyStatement(t *testing.T) {
+	tests := []struct {
+		inp  string
+		key  string
+		err  int
+		n    int16
+		kept bool
+	}{
+		{inp: `10 ON KEY(1) GOSUB 100
+20 KEY(1) ON
+30 STOP
+40 X = N
+50 END
+100 N = N + 1 : RETURN`, key: "\x1bOP", n: 1},
+		{inp: `10 ON KEY(1) GOSUB 100
+20 KEY(1) ON : KEY(1) OFF
+30 STOP
+40 X = N
+50 END
+100 N = N + 1 : RETURN`, key: "\x1bOP", kept: true},
+		{inp: `10 ON KEY(11) GOSUB 100
+20 KEY(11) STOP
+30 STOP
+40 X = N : KEY(11) ON
+50 END
+100 N = N + 1 : RETURN`, key: "\x1b[A", n: 1},
+		{inp: `10 ON KEY(15) GOSUB 100
+20 KEY 15, CHR$(1)+CHR$(30) : KEY(15) ON
+30 STOP
+40 X = N
+50 END
+100 N = N + 1 : RETURN`, key: "A", n: 1},
+		{inp: `10 ON KEY(2) GOSUB 100
+20 KEY(2) ON
+30 STOP
+40 X = N
+50 END
+100 N = N + 1 : RETURN`, key: "B", kept: true},
+		{inp: `10 ON KEY(21) GOSUB 100`, err: berrors.IllegalFuncCallErr},
+		{inp: `10 KEY(0) ON`, err: berrors.IllegalFuncCallErr},
+		{inp: `10 KEY("A") ON`, err: berrors.TypeMismatch},
+		{inp: `10 ON KEY(1) GOSUB 900`, err: berrors.UnDefinedLineNumber},
+		{inp: `10 KEY 15, "A"`, err: berrors.IllegalFuncCallErr},
+		{inp: `10 KEY(1)`, err: berrors.Syntax},
+	}
+
+	for _, tt := range tests {
+		var mt mocks.MockTerm
+		initMockTerm(&mt)
+		env := object.NewTermEnvironment(mt)
+		kb := env.KeyBuffer()
+
+		p := parser.New(lexer.New(tt.inp))
+		p.ParseProgram(env)
+		rc := evalRunCheckStartLineNum(&ast.RunCommand{}, env)
+
+		if tt.err != 0 {
+			assert.Equal(t, object.StdError(env, tt.err).Code, rc.(*object.Error).Code, "%s didn't fail", tt.inp)
+			continue
+		}
+
+		// press the key as if the program was still going, then carry on
+		env.SetRun(true)
+		kb.SaveKeyStroke([]byte(tt.key))
+		env.SetRun(false)
+		p = parser.New(lexer.New("CONT"))
+		p.ParseCmd(env)
+		Eval(&ast.Program{}, env.CmdLineIter(), env)
+
+		assert.Equal(t, &object.Integer{Value: tt.n}, env.Get("N"), "%s handled the key wrong", tt.inp)
+		// untrapped keys wait in the buffer
+		_, err := kb.ReadByte()
+		assert.Equal(t, tt.kept, err == nil, "%s buffered the key wrong", tt.inp)
+
+		// with the program over, the key goes to the command line
+		kb.SaveKeyStroke([]byte(tt.key))
+		assert.Empty(t, kb.KeyHits(), "%s trapped the key at the prompt", tt.inp)
+		_, err = kb.ReadByte()
+		assert.Nil(t, err, "%s kept the key from the prompt", tt.inp)
+	}
+}
+
 func Test_OnGoStatement(t *testing.T) {
 	tests := []struct {
 		inp string
@@ -1649,8 +1990,8 @@ func Test_OpenStatement(t *testing.T) {
 		inp string
 		exp object.Object
 	}{
-		{inp: `10 OPEN "test.dat" FOR OUTPUT AS #1`,
-			exp: &object.Error{Message: "Path not found in 10", Code: 76}},
+		// output creates the file locally
+		{inp: `10 OPEN "test.out" FOR OUTPUT AS #1`},
 		{inp: `20 OPEN "test.dat" AS #1`,
 			exp: &object.Error{Message: "Path not found in 20", Code: 76}},
 		// test his trash detection
@@ -2092,11 +2433,15 @@ func Test_ScreenStatement(t *testing.T) {
 	tests := []struct {
 		inp   string
 		exp   [4]int
+		width int
 		err   bool
 		ecode int
 	}{
-		{inp: "SCREEN 0,1", exp: [4]int{0, 1, -1, -1}},
-		{inp: "SCREEN 0,1 : SCREEN ,2", exp: [4]int{0, 2, -1, -1}},
+		{inp: "SCREEN 0,1", exp: [4]int{0, 1, -1, -1}, width: 80},
+		{inp: "SCREEN 0,1 : SCREEN ,2", exp: [4]int{0, 2, -1, -1}, width: 80},
+		{inp: "SCREEN 1", exp: [4]int{1, 1, -1, -1}, width: 40},
+		{inp: "SCREEN 1 : SCREEN 0", exp: [4]int{0, 1, -1, -1}, width: 40},
+		{inp: "SCREEN 7 : SCREEN 2", exp: [4]int{2, 1, -1, -1}, width: 80},
 		{inp: "SCREEN 3", err: true, ecode: berrors.IllegalFuncCallErr},
 	}
 
@@ -2115,6 +2460,7 @@ func Test_ScreenStatement(t *testing.T) {
 			scrn := set.(*ast.ScreenStatement)
 
 			assert.NotNil(t, scrn, "Screen settings failed to save!")
+			assert.Equal(t, tt.width, env.Screen().Width(), "%s left screen the wrong width", tt.inp)
 
 			for i := range tt.exp {
 				// -1 means it should be nil
@@ -2247,22 +2593,22 @@ func ExamplePrint() {
 	// Hello World!
 	// This is a test
 	// Another test program.
-	// Test of tab	due to comma
+	// Test of tab   due to comma
 	// Test of a run on sentence
 	// 45.12
 	// 57.12
 	// 90.24
 	// 22.56
-	// 1
+	// -1
 	// 32.52
 	// 0
-	// 1
+	// -1
 	// 0
 	// 0
-	// 1
+	// -1
 	// 0
-	// 1
-	// 1
+	// -1
+	// -1
 	// 0
 	// 153.408
 	// 13.27059
@@ -2294,8 +2640,8 @@ func ExampleT_int() {
 	// 1.092233E+04
 	// 2
 	// 0
-	// 1
-	// 1
+	// -1
+	// -1
 	// 0
 }
 
@@ -2338,25 +2684,25 @@ func ExampleT_fixed() {
 	// 57.12
 	// 90.24
 	// 22.56
-	// 1
+	// -1
 	// 32.52
 	// 0
-	// 1
+	// -1
 	// 0
 	// 0
-	// 1
+	// -1
 	// 0
-	// 1
-	// 1
+	// -1
+	// -1
 	// 0
 	// 153.408
 	// 13.27059
 	// 2.361234E+04
-	// 1
+	// -1
 	// 0
-	// 1
+	// -1
 	// 0
-	// 1
+	// -1
 	// 0
 }
 
@@ -2397,12 +2743,12 @@ func ExampleT_float() {
 	// 0
 	// 0
 	// 0
-	// 1
-	// 1
-	// 1
-	// 1
-	// 1
-	// 1
+	// -1
+	// -1
+	// -1
+	// -1
+	// -1
+	// -1
 }
 
 func ExampleT_floatDbl() {
@@ -2444,17 +2790,17 @@ func ExampleT_floatDbl() {
 	// 2.035850E+04
 	// 70500
 	// 5.253191E-03
-	// 1
+	// -1
 	// 0
 	// 0
 	// 0
-	// 1
-	// 1
-	// 1
-	// 1
-	// 1
-	// 1
-	// 1
+	// -1
+	// -1
+	// -1
+	// -1
+	// -1
+	// -1
+	// -1
 	// 0
 	// -2.351234E+04
 	// -2.351234E+04
@@ -2834,12 +3180,50 @@ func Test_UsingStatement(t *testing.T) {
 		inp string
 		err object.Object
 		exp []string
+		lpt string // what comes out of the printer
 	}{
 		{inp: `PRINT USING "###.##"; 23.45`, err: nil, exp: []string{" 23.45"}},
 		{inp: `PRINT "Totals:"; USING "###.##"; 23.45`, err: nil, exp: []string{"Totals:", " 23.45"}},
 		{inp: `PRINT USING "##.##"; X`, err: nil, exp: []string{" 0.00"}},
 		{inp: `PRINT USING "##.##"; X#`, err: nil, exp: []string{" 0.00"}},
 		{inp: `X=2.134E1 : PRINT USING "##.##"; X`, err: nil, exp: []string{"21.34"}},
+		{inp: `PRINT USING "##.##"; 0.78`, exp: []string{" 0.78"}},
+		{inp: `PRINT USING "#.##"; -0.5`, exp: []string{"-.50"}},
+		{inp: `PRINT USING "###"; -5`, exp: []string{" -5"}},
+		{inp: `PRINT USING "#.#"; 0`, exp: []string{"0.0"}},
+		{inp: `PRINT USING "##.#"; -0.04`, exp: []string{" 0.0"}},
+		{inp: `PRINT USING "+##.##"; -68.95, 2.4, 55.6, -0.9`, exp: []string{"-68.95", " +2.40", "+55.60", " -0.90"}},
+		{inp: `PRINT USING "##.##-"; -68.95, 22.449, -7.01`, exp: []string{"68.95-", "22.45 ", " 7.01-"}},
+		{inp: `PRINT USING "##.##+"; 5, -5`, exp: []string{" 5.00+", " 5.00-"}},
+		{inp: `PRINT USING "**#.#"; 12.39, -0.9, 765.1`, exp: []string{"*12.4", "*-0.9", "765.1"}},
+		{inp: `PRINT USING "$$###.##"; 456.78`, exp: []string{" $456.78"}},
+		{inp: `PRINT USING "**$##.##"; 2.34`, exp: []string{"***$2.34"}},
+		{inp: `PRINT USING "####,.##"; 1234.5`, exp: []string{"1,234.50"}},
+		{inp: `PRINT USING "##,###"; 1234567`, exp: []string{"%1,234,567"}},
+		{inp: `PRINT USING "##.##^^^^"; 234.56`, exp: []string{" 2.35E+02"}},
+		{inp: `PRINT USING ".####^^^^-"; -88888`, exp: []string{".8889E+05-"}},
+		{inp: `PRINT USING "+.##^^^^"; 123`, exp: []string{"+.12E+03"}},
+		{inp: `PRINT USING "##.##^^^^^"; 1D-100`, exp: []string{" 1.00E-100"}},
+		{inp: `PRINT USING "#.#^^^^"; 0`, exp: []string{"0.0E+00"}},
+		{inp: `PRINT USING "##"; 123`, exp: []string{"%123"}},
+		{inp: `PRINT USING "##.##"; 99.999`, exp: []string{"%100.00"}},
+		{inp: `PRINT USING "!"; "ABC"; "DEF"`, exp: []string{"A", "D"}},
+		{inp: `PRINT USING "\  \"; "LOOK"; "OUT"; "SEEKER"`, exp: []string{"LOOK", "OUT ", "SEEK"}},
+		{inp: `PRINT USING "&!"; "LOOK"; "OUT"`, exp: []string{"LOOK", "O"}},
+		{inp: `PRINT USING "_!##.##_!"; 12.34`, exp: []string{"!12.34", "!"}},
+		{inp: `PRINT USING "Total: ## each_"; 5`, exp: []string{"Total:  5", " each_"}},
+		{inp: `PRINT USING "[##] "; 1, 2;`, exp: []string{"[ 1", "] [ 2", "] "}},
+		{inp: `A$ = "#.#" : PRINT USING A$; 1.25; 0.15`, exp: []string{"1.3", "0.2"}},
+		{inp: `PRINT USING "##"; -5 + 2`, exp: []string{"-3"}},
+		{inp: `PRINT USING "##"; "A"`, err: &object.Error{Code: berrors.TypeMismatch, Message: "Type mismatch"}},
+		{inp: `PRINT USING "!"; 5`, err: &object.Error{Code: berrors.TypeMismatch, Message: "Type mismatch"}},
+		{inp: `PRINT USING 5; 1`, err: &object.Error{Code: berrors.TypeMismatch, Message: "Type mismatch"}},
+		{inp: `PRINT USING "ABC"; 5`, err: &object.Error{Code: berrors.IllegalFuncCallErr, Message: "Illegal function call"}},
+		{inp: `PRINT USING "##########.###############"; 5`, err: &object.Error{Code: berrors.IllegalFuncCallErr, Message: "Illegal function call"}},
+		{inp: `PRINT USING "##"`, err: &object.Error{Code: berrors.MissingOp, Message: "Missing operand"}},
+		{inp: `LPRINT USING "##.##"; 1.5`, lpt: " 1.50\r\n"},
+		{inp: `LPRINT "A", "B" : LPRINT "C";`, lpt: "A             B\r\n"},
+		{inp: `LPRINT #1, 5`, err: &object.Error{Code: berrors.Syntax, Message: "Syntax error"}},
 	}
 
 	for _, tt := range tests {
@@ -2853,6 +3237,8 @@ func Test_UsingStatement(t *testing.T) {
 			mt.ExpMsg = exp
 		}
 		env := object.NewTermEnvironment(mt)
+		var paper bytes.Buffer
+		env.SetPrinterOutput(&paper)
 
 		p.ParseCmd(env)
 
@@ -2863,6 +3249,7 @@ func Test_UsingStatement(t *testing.T) {
 		} else {
 			assert.Equalf(t, tt.err, rc, "%s returned %T", tt.inp, rc)
 		}
+		assert.Equalf(t, tt.lpt, paper.String(), "%s printed wrong", tt.inp)
 
 		if len(tt.exp) != 0 {
 			assert.Falsef(t, mt.ExpMsg.Failed, "%s didn't get %s", tt.inp, tt.exp)
@@ -2910,3 +3297,676 @@ func Test_ViewPrintStatement(t *testing.T) {
 	}
 
 }
+
+func Test_WhileWendStatements(t *testing.T) {
+	tests := []struct {
+		inp  string
+		err  object.Object
+		x    int
+		open int // loops still active
+	}{
+		{inp: "10 X = 0\n20 WHILE X < 5\n30 X = X + 1\n40 WEND\n50 END", x: 5},
+		{inp: "10 X = 9\n20 WHILE X < 5 : X = X + 1 : WEND\n30 END", x: 9},
+		{inp: "10 X = 0 : Y = 0\n20 WHILE X < 3 : X = X + 1 : Z = 0\n30 WHILE Z < 4 : Z = Z + 1 : Y = Y + 1 : WEND\n40 WEND\n50 X = Y", x: 12},
+		{inp: "10 X = 9\n20 WHILE X < 5 : WHILE X < 4 : WEND : X = 3 : WEND\n30 END", x: 9},
+		{inp: "10 X = 0\n20 WHILE X < 5\n30 X = X + 1\n40 IF X = 3 THEN GOTO 20\n50 WEND\n60 X = X * 10", x: 50},
+		{inp: "10 X = 0\n20 WHILE -1\n30 X = X + 1\n40 IF X = 7 THEN GOTO 60\n50 WEND\n60 END", x: 7, open: 1},
+		{inp: "10 X = 9\n20 WHILE X < 5\n30 X = X + 1", err: &object.Error{Code: berrors.WhileWoWend, Message: "WHILE without WEND in 20"}},
+		{inp: "10 WHILE 1\n20 X = X + 1\n30 END", err: &object.Error{Code: berrors.WhileWoWend, Message: "WHILE without WEND in 10"}},
+		{inp: "10 X = 0\n20 WHILE X < 5 : WHILE 1\n30 X = X + 1\n40 WEND", err: &object.Error{Code: berrors.WhileWoWend, Message: "WHILE without WEND in 20"}},
+		{inp: "10 X = 9\n20 WEND", err: &object.Error{Code: berrors.WendWoWhile, Message: "WEND without WHILE in 20"}},
+		{inp: "10 WHILE\n20 WEND", err: &object.Error{Code: berrors.Syntax, Message: "Syntax error in 10"}},
+		{inp: "10 WHILE \"X\"\n20 WEND", err: &object.Error{Code: berrors.TypeMismatch, Message: "Type mismatch in 10"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.inp)
+		p := parser.New(l)
+		var mt mocks.MockTerm
+		initMockTerm(&mt)
+		env := object.NewTermEnvironment(mt)
+
+		p.ParseProgram(env)
+		itr := env.StatementIter()
+		env.SetRun(true)
+		rc := Eval(&ast.Program{}, itr, env)
+
+		if tt.err != nil {
+			assert.EqualValuesf(t, tt.err, rc, "%s got the wrong error", tt.inp)
+			continue
+		}
+
+		assert.Nilf(t, rc, "%s returned unexpectedly with a %T", tt.inp, rc)
+		compareObjects(tt.inp, env.Get("X"), tt.x, t)
+		assert.Lenf(t, env.WhileLoops, tt.open, "%s left the wrong number of WHILE loops running", tt.inp)
+	}
+}
+
+func Test_InputStatement(t *testing.T) {
+	tests := []struct {
+		inp  string
+		keys string
+		vars map[string]interface{}
+		exp  []string
+		err  object.Object
+		brk  bool
+	}{
+		{inp: `10 INPUT A`, keys: "42\r", vars: map[string]interface{}{"A": 42}, exp: []string{"? ", "4", "2", ""}},
+		{inp: `10 INPUT "Name"; N$`, keys: "Bob\r", vars: map[string]interface{}{"N$": &object.String{Value: "Bob"}}, exp: []string{"Name", "? "}},
+		{inp: `10 INPUT "Name", N$`, keys: "Bob\r", vars: map[string]interface{}{"N$": &object.String{Value: "Bob"}}, exp: []string{"Name", "B"}},
+		{inp: `10 INPUT; A%, B$`, keys: "-7, \"x, y\"\r", vars: map[string]interface{}{"A%": -7, "B$": &object.String{Value: "x, y"}}},
+		{inp: `10 INPUT A%, B`, keys: "1\r2,3\r", vars: map[string]interface{}{"A%": 2, "B": 3}},
+		{inp: `10 INPUT A%`, keys: "X\r5\r", vars: map[string]interface{}{"A%": 5}, exp: []string{"? ", "X", "", "?Redo from start"}},
+		{inp: `10 INPUT A%`, keys: "2.6\r", vars: map[string]interface{}{"A%": 3}},
+		{inp: `10 INPUT A%`, keys: "40000\r\"1\"\r&H10\r", vars: map[string]interface{}{"A%": 16}},
+		{inp: `10 INPUT A`, keys: "12\x083\r", vars: map[string]interface{}{"A": 13}},
+		{inp: `10 INPUT A`, keys: "1\x00H2\x00;\r", vars: map[string]interface{}{"A": 12}},
+		{inp: `10 INPUT A,B`, keys: "\r", brk: true},
+		{inp: `10 INPUT A`, keys: "1\x03", brk: true},
+		{inp: `10 INPUT`, err: &object.Error{Code: berrors.Syntax, Message: "Syntax error in 10"}},
+		{inp: `10 INPUT "Name" N$`, err: &object.Error{Code: berrors.Syntax, Message: "Syntax error in 10"}},
+		{inp: `10 LINE INPUT "Line? "; L$`, keys: " a, \"b\" \r", vars: map[string]interface{}{"L$": &object.String{Value: " a, \"b\" "}}, exp: []string{"Line? "}},
+		{inp: `10 LINE INPUT L`, keys: "x\r", err: &object.Error{Code: berrors.TypeMismatch, Message: "Type mismatch in 10"}},
+		{inp: `10 LINE INPUT L$`, keys: "\x03", brk: true},
+		{inp: `10 LINE INPUT "Line", L$`, err: &object.Error{Code: berrors.Syntax, Message: "Syntax error in 10"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.inp)
+		p := parser.New(l)
+		var mt mocks.MockTerm
+		initMockTerm(&mt)
+		*mt.StrVal = tt.keys
+		if tt.exp != nil {
+			mt.ExpMsg = &mocks.Expector{Exp: tt.exp}
+		}
+		env := object.NewTermEnvironment(mt)
+
+		p.ParseProgram(env)
+		itr := env.StatementIter()
+		env.SetRun(true)
+		rc := Eval(&ast.Program{}, itr, env)
+
+		if tt.err != nil {
+			assert.EqualValuesf(t, tt.err, rc, "%s got the wrong result", tt.inp)
+			continue
+		}
+
+		if tt.brk {
+			assert.EqualValuesf(t, &object.HaltSignal{Msg: "Break in line 10"}, rc, "%s didn't report the break", tt.inp)
+			assert.NotNilf(t, env.GetSetting(settings.Restart), "%s didn't stop for a break", tt.inp)
+			continue
+		}
+		assert.Nilf(t, rc, "%s returned unexpectedly with a %T", tt.inp, rc)
+		assert.Falsef(t, mt.ExpMsg.Failed, "%s didn't display what was expected", tt.inp)
+		for k, v := range tt.vars {
+			compareObjects(tt.inp, env.Get(k), v, t)
+		}
+	}
+}
+
+func Test_SequentialFiles(t *testing.T) {
+	tests := []struct {
+		inp  string
+		file string
+		data string
+		vars map[string]interface{}
+		exp  []string
+		err  object.Object
+	}{
+		{inp: "10 OPEN \"SEQ1.DAT\" FOR OUTPUT AS #1\n20 WRITE #1, \"Hello, World\", 42, -3.5\n30 PRINT #1, \"Line\";\" two\"\n40 CLOSE #1\n50 OPEN \"SEQ1.DAT\" FOR INPUT AS #2\n60 INPUT #2, A$, B, C\n70 LINE INPUT #2, D$\n80 X = EOF(2)",
+			file: "SEQ1.DAT", data: "\"Hello, World\",42,-3.5\r\nLine two\r\n",
+			vars: map[string]interface{}{"A$": &object.String{Value: "Hello, World"}, "B": 42, "C": &object.Fixed{Value: decimal.New(-35, -1)}, "D$": &object.String{Value: "Line two"}, "X": -1}},
+		{inp: "10 OPEN \"O\", #1, \"SEQ2.DAT\" : PRINT #1, \"A\" : CLOSE #1\n20 OPEN \"A\", #1, \"SEQ2.DAT\" : PRINT #1, 1, 2 : CLOSE 1\n30 OPEN \"I\", #1, \"SEQ2.DAT\" : INPUT #1, A$, B, C\n40 X = LOF(1) : Y = LOC(1) : Z = EOF(1)",
+			file: "SEQ2.DAT", data: "A\r\n1             2\r\n",
+			vars: map[string]interface{}{"A$": &object.String{Value: "A"}, "B": 1, "C": 2, "X": 20, "Y": 1, "Z": -1}},
+		{inp: "10 OPEN \"SEQ3.DAT\" FOR OUTPUT AS #1 : F = 1\n20 WRITE #F, \"\", 7 : PRINT #F, \" padded \" : CLOSE\n30 OPEN \"SEQ3.DAT\" FOR INPUT AS #F\n40 INPUT #F, A$, B, C$",
+			vars: map[string]interface{}{"A$": &object.String{Value: ""}, "B": 7, "C$": &object.String{Value: "padded"}}},
+		{inp: "10 OPEN \"SEQ4.DAT\" FOR OUTPUT AS #1 : WRITE #1, \"A\"\n20 CLOSE #1 : OPEN \"SEQ4.DAT\" FOR INPUT AS #1\n30 INPUT #1, A$, B$",
+			err: &object.Error{Code: berrors.InputPastEnd, Message: "Input past end in 30"}},
+		{inp: "10 OPEN \"SEQ5.DAT\" FOR OUTPUT AS #1 : CLOSE #1\n20 OPEN \"SEQ5.DAT\" FOR INPUT AS #1\n30 LINE INPUT #1, A$",
+			err: &object.Error{Code: berrors.InputPastEnd, Message: "Input past end in 30"}},
+		{inp: "10 OPEN \"SEQ6.DAT\" FOR OUTPUT AS #1 : PRINT #1, \"ABC\" : CLOSE #1\n20 OPEN \"SEQ6.DAT\" FOR INPUT AS #1\n30 INPUT #1, A",
+			err: &object.Error{Code: berrors.TypeMismatch, Message: "Type mismatch in 30"}},
+		{inp: "10 OPEN \"SEQ7.DAT\" FOR OUTPUT AS #1\n20 INPUT #1, A",
+			err: &object.Error{Code: berrors.BadFileMode, Message: "Bad file mode in 20"}},
+		{inp: "10 OPEN \"SEQ7.DAT\" FOR OUTPUT AS #1 : CLOSE #1\n20 OPEN \"SEQ7.DAT\" FOR INPUT AS #1\n30 PRINT #1, A",
+			err: &object.Error{Code: berrors.BadFileMode, Message: "Bad file mode in 30"}},
+		{inp: "10 OPEN \"SEQ7.DAT\" FOR OUTPUT AS #1 : CLOSE #1\n20 OPEN \"SEQ7.DAT\" FOR INPUT AS #1\n30 WRITE #1, A",
+			err: &object.Error{Code: berrors.BadFileMode, Message: "Bad file mode in 30"}},
+		{inp: "10 OPEN \"SEQ7.DAT\" FOR OUTPUT AS #1\n20 LINE INPUT #1, A$",
+			err: &object.Error{Code: berrors.BadFileMode, Message: "Bad file mode in 20"}},
+		{inp: "10 OPEN \"SEQ7.DAT\" FOR OUTPUT AS #1\n20 X = EOF(1)",
+			err: &object.Error{Code: berrors.BadFileMode, Message: "Bad file mode in 20"}},
+		{inp: "10 OPEN \"X\", #1, \"SEQ7.DAT\"",
+			err: &object.Error{Code: berrors.BadFileMode, Message: "Bad file mode in 10"}},
+		{inp: "10 PRINT #3, A",
+			err: &object.Error{Code: berrors.BadFileNum, Message: "Bad file number in 10"}},
+		{inp: "10 WRITE #3, A",
+			err: &object.Error{Code: berrors.BadFileNum, Message: "Bad file number in 10"}},
+		{inp: "10 INPUT #3, A",
+			err: &object.Error{Code: berrors.BadFileNum, Message: "Bad file number in 10"}},
+		{inp: "10 LINE INPUT #3, A$",
+			err: &object.Error{Code: berrors.BadFileNum, Message: "Bad file number in 10"}},
+		{inp: "10 OPEN \"SEQ7.DAT\" FOR OUTPUT AS #16",
+			err: &object.Error{Code: berrors.BadFileNum, Message: "Bad file number in 10"}},
+		{inp: "10 OPEN \"SEQ7.DAT\" FOR OUTPUT AS #\"A\"",
+			err: &object.Error{Code: berrors.TypeMismatch, Message: "Type mismatch in 10"}},
+		{inp: "10 OPEN \"SEQ7.DAT\" FOR OUTPUT AS #1\n20 OPEN \"SEQ8.DAT\" FOR OUTPUT AS #1",
+			err: &object.Error{Code: berrors.FileAlreadyOpen, Message: "File already open in 20"}},
+		{inp: "10 WRITE \"A\", 1", exp: []string{"\"A\",1"}},
+		{inp: "10 OPEN \"SEQ10.DAT\" FOR OUTPUT AS #1\n20 PRINT #1, \"C\";\n30 PRINT #1, \"D\", \"E\" : CLOSE #1",
+			file: "SEQ10.DAT", data: "CD            E\r\n"},
+		{inp: "10 OPEN \"SEQ11.DAT\" FOR OUTPUT AS #1 : PRINT #1, \"AB\"; : CLOSE #1\n20 OPEN \"SEQ11.DAT\" FOR APPEND AS #1 : PRINT #1, \"X\", \"Y\" : CLOSE #1",
+			file: "SEQ11.DAT", data: "ABX             Y\r\n"},
+		{inp: "10 OPEN \"SEQ9.DAT\" FOR OUTPUT AS #1\n20 PRINT #1, USING \"$$#.##\"; 1.5, 2 : CLOSE #1",
+			file: "SEQ9.DAT", data: " $1.50 $2.00\r\n"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.inp)
+		p := parser.New(l)
+		var mt mocks.MockTerm
+		initMockTerm(&mt)
+		if tt.exp != nil {
+			mt.ExpMsg = &mocks.Expector{Exp: tt.exp}
+		}
+		env := object.NewTermEnvironment(mt)
+		mc := &mocks.MockClient{Err: errors.New("404 Not Found"), StatusCode: 404}
+		env.SetClient(mc)
+
+		p.ParseProgram(env)
+		env.SetRun(true)
+		rc := Eval(&ast.Program{}, env.StatementIter(), env)
+
+		if tt.err != nil {
+			assert.EqualValuesf(t, tt.err, rc, "%s got the wrong result", tt.inp)
+			continue
+		}
+
+		assert.Nilf(t, rc, "%s returned unexpectedly with a %T", tt.inp, rc)
+		assert.Falsef(t, mt.ExpMsg.Failed, "%s didn't display what was expected", tt.inp)
+		for k, v := range tt.vars {
+			compareObjects(tt.inp, env.Get(k), v, t)
+		}
+
+		if len(tt.file) > 0 {
+			fd, ok := localfiles.For(env).Open(fileserv.BuildFullPath(tt.file, env), env).(gwtypes.FileData)
+			assert.Truef(t, ok, "%s not saved locally", tt.file)
+			assert.Equal(t, tt.data, string(*fd.Data()))
+		}
+	}
+}
+
+func Test_RandomFiles(t *testing.T) {
+	tests := []struct {
+		inp  string
+		file string
+		data string
+		vars map[string]interface{}
+		err  object.Object
+	}{
+		{inp: "10 OPEN \"RND1.DAT\" AS #1 LEN = 16\n20 FIELD #1, 10 AS N$, 2 AS Q$, 4 AS P$\n30 LSET N$ = \"WIDGET\" : LSET Q$ = MKI$(12) : LSET P$ = MKS$(15)\n40 PUT #1, 1\n" +
+			"50 LSET N$ = \"SPROCKET\" : RSET Q$ = MKI$(7) : LSET P$ = MKS$(22)\n60 PUT #1\n70 GET #1, 1\n80 A$ = N$ : B = CVI(Q$) : C = CVS(P$)\n90 GET #1 : D$ = N$ : E = CVI(Q$)\n100 X = LOF(1) : Y = LOC(1) : Z = EOF(1)",
+			vars: map[string]interface{}{"A$": &object.String{Value: "WIDGET    "}, "B": 12, "C": 15, "D$": &object.String{Value: "SPROCKET  "}, "E": 7, "X": 32, "Y": 2, "Z": 0}},
+		{inp: "10 OPEN \"R\", #1, \"RND2.DAT\", 4 : FIELD 1, 4 AS A$\n20 RSET A$ = \"XY\" : PUT 1, 3\n30 GET 1, 2 : B$ = A$ : GET 1, 4 : X = EOF(1) : L = LOF(1)",
+			file: "RND2.DAT", data: "\x00\x00\x00\x00\x00\x00\x00\x00  XY",
+			vars: map[string]interface{}{"A$": &object.String{Value: "\x00\x00\x00\x00"}, "B$": &object.String{Value: "\x00\x00\x00\x00"}, "X": -1, "L": 12}},
+		{inp: "10 OPEN \"RND3.DAT\" AS #1 LEN = 4 : FIELD #1, 4 AS A$ : LSET A$ = \"ABCDEF\"\n20 A$ = \"XY\" : GET #1, 1 : B$ = \"ABCDEF\" : LSET B$ = \"XY\" : C$ = \"ABC\" : RSET C$ = \"Z\"",
+			vars: map[string]interface{}{"A$": &object.String{Value: "XY"}, "B$": &object.String{Value: "XY    "}, "C$": &object.String{Value: "  Z"}}},
+		{inp: "10 OPEN \"RND4.DAT\" AS #1 LEN = 4 : FIELD #1, 2 AS A$, 2 AS B$ : FIELD #1, 4 AS C$\n20 LSET C$ = \"WXYZ\" : CLOSE #1 : LSET C$ = \"A\"",
+			vars: map[string]interface{}{"A$": &object.String{Value: "WX"}, "B$": &object.String{Value: "YZ"}, "C$": &object.String{Value: "A   "}}},
+		{inp: "10 OPEN \"RND5.DAT\" AS #1 LEN = 10\n20 FIELD #1, 6 AS A$, 6 AS B$",
+			err: &object.Error{Code: berrors.FieldOverflow, Message: "FIELD overflow in 20"}},
+		{inp: "10 OPEN \"RND5.DAT\" AS #1\n20 FIELD #1, 256 AS A$",
+			err: &object.Error{Code: berrors.IllegalFuncCallErr, Message: "Illegal function call in 20"}},
+		{inp: "10 OPEN \"RND5.DAT\" AS #1\n20 FIELD #1, 4 AS A",
+			err: &object.Error{Code: berrors.TypeMismatch, Message: "Type mismatch in 20"}},
+		{inp: "10 OPEN \"RND5.DAT\" AS #1\n20 FIELD #1, 4",
+			err: &object.Error{Code: berrors.Syntax, Message: "Syntax error in 20"}},
+		{inp: "10 OPEN \"RND5.DAT\" AS #1\n20 GET #1, 0",
+			err: &object.Error{Code: berrors.BadRecordNum, Message: "Bad record number in 20"}},
+		{inp: "10 OPEN \"RND5.DAT\" AS #1\n20 PUT #1, 32768",
+			err: &object.Error{Code: berrors.BadRecordNum, Message: "Bad record number in 20"}},
+		{inp: "10 OPEN \"RND5.DAT\" AS #1\n20 PUT #1, \"A\"",
+			err: &object.Error{Code: berrors.TypeMismatch, Message: "Type mismatch in 20"}},
+		{inp: "10 PUT #2, 1",
+			err: &object.Error{Code: berrors.BadFileNum, Message: "Bad file number in 10"}},
+		{inp: "10 OPEN \"RND6.DAT\" FOR OUTPUT AS #1\n20 GET #1",
+			err: &object.Error{Code: berrors.BadFileMode, Message: "Bad file mode in 20"}},
+		{inp: "10 OPEN \"RND6.DAT\" FOR OUTPUT AS #1\n20 FIELD #1, 4 AS A$",
+			err: &object.Error{Code: berrors.BadFileMode, Message: "Bad file mode in 20"}},
+		{inp: "10 LSET A = \"X\"",
+			err: &object.Error{Code: berrors.TypeMismatch, Message: "Type mismatch in 10"}},
+		{inp: "10 RSET A$ = 5",
+			err: &object.Error{Code: berrors.TypeMismatch, Message: "Type mismatch in 10"}},
+		{inp: "10 OPEN \"RND7.DAT\" AS #1 LEN = 0",
+			err: &object.Error{Code: berrors.IllegalFuncCallErr, Message: "Illegal function call in 10"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.inp)
+		p := parser.New(l)
+		var mt mocks.MockTerm
+		initMockTerm(&mt)
+		env := object.NewTermEnvironment(mt)
+		mc := &mocks.MockClient{StatusCode: 404}
+		env.SetClient(mc)
+
+		p.ParseProgram(env)
+		env.SetRun(true)
+		rc := Eval(&ast.Program{}, env.StatementIter(), env)
+
+		if tt.err != nil {
+			assert.EqualValuesf(t, tt.err, rc, "%s got the wrong result", tt.inp)
+			continue
+		}
+
+		assert.Nilf(t, rc, "%s returned unexpectedly with a %T", tt.inp, rc)
+		for k, v := range tt.vars {
+			compareObjects(tt.inp, env.Get(k), v, t)
+		}
+
+		if len(tt.file) > 0 {
+			fd, ok := localfiles.For(env).Open(fileserv.BuildFullPath(tt.file, env), env).(gwtypes.FileData)
+			assert.Truef(t, ok, "%s not saved locally", tt.file)
+			assert.Equal(t, tt.data, string(*fd.Data()))
+		}
+	}
+}
+
+func Test_SaveCommand(t *testing.T) {
+	tests := []struct {
+		prog  string
+		cmd   string
+		file  string
+		start byte
+		ascii string
+		scode int
+		err   object.Object
+	}{
+		{prog: "10 PRINT \"HELLO\": X = 5\n20 IF X > 3 THEN 40 ELSE 10\n30 ' just a comment\n40 END", cmd: `SAVE "SAVE1"`, file: `SAVE1.BAS`, start: 0xFF},
+		{prog: "10 PRINT \"HELLO\": X = 5\n20 IF X > 3 THEN 40 ELSE 10\n30 ' just a comment\n40 END", cmd: `SAVE "SAVE2.BAS",A`, file: `SAVE2.BAS`, start: '1',
+			ascii: "10 PRINT \"HELLO\" :  X = 5\r\n20 IF X > 3 THEN 40 ELSE 10\r\n30 ' just a comment\r\n40 END\r\n\x1a"},
+		{prog: "10 PRINT \"HELLO\": X = 5\n20 IF X > 3 THEN 40 ELSE 10\n30 ' just a comment\n40 END", cmd: `SAVE "SAVE3.PRT",P`, file: `SAVE3.PRT`, start: 0xFE},
+		{prog: "10 DIM A(10): FOR I = 1 TO 10: A(I) = I * 1000: NEXT I\n20 DATA 1,\"TWO\",3", cmd: `SAVE "SAVE4"`, file: `SAVE4.BAS`, start: 0xFF, scode: 405},
+		{prog: "10 X = 1.5 + 2.5# + 3! + 40000 + 1E+10\n20 Y = &H1F + &O17: Z$ = MKI$(Y)", cmd: `SAVE "SAVE6"`, file: `SAVE6.BAS`, start: 0xFF},
+		{prog: "10 END", cmd: `SAVE 5`, err: &object.Error{Code: berrors.TypeMismatch, Message: "Type mismatch"}},
+		{prog: "10 END", cmd: `SAVE ""`, err: &object.Error{Code: berrors.BadFileName, Message: "Bad file name"}},
+		{prog: "10 END", cmd: `SAVE "SAVE5",Q`, err: &object.Error{Code: berrors.Syntax, Message: "Syntax error"}},
+	}
+
+	for _, tt := range tests {
+		var mt mocks.MockTerm
+		initMockTerm(&mt)
+		env := object.NewTermEnvironment(mt)
+		env.SetClient(&mocks.MockClient{StatusCode: tt.scode})
+
+		parser.New(lexer.New(tt.prog)).ParseProgram(env)
+		parser.New(lexer.New(tt.cmd)).ParseCmd(env)
+		rc := Eval(&ast.Program{}, env.CmdLineIter(), env)
+
+		if tt.err != nil {
+			assert.EqualValuesf(t, tt.err, rc, "%s got the wrong result", tt.cmd)
+			continue
+		}
+		assert.Nil(t, rc, "%s failed", tt.cmd)
+
+		fl := localfiles.For(env).Find(fileserv.BuildFullPath(tt.file, env))
+		if !assert.NotNil(t, fl, "%s didn't store %s", tt.cmd, tt.file) {
+			continue
+		}
+		data := *fl.(gwtypes.FileData).Data()
+		assert.Equal(t, tt.start, data[0], "%s wrote the wrong file format", tt.cmd)
+		if len(tt.ascii) > 0 {
+			assert.Equal(t, tt.ascii, string(data), "%s wrote the wrong text", tt.cmd)
+		}
+
+		// load it back and it should list the same
+		var mt2 mocks.MockTerm
+		initMockTerm(&mt2)
+		env2 := object.NewTermEnvironment(mt2)
+		env2.SetClient(&mocks.MockClient{Err: errors.New("no server")})
+		env2.SetLocalFiles(env.LocalFiles())
+		parser.New(lexer.New(`LOAD "` + tt.file + `"`)).ParseCmd(env2)
+		rc = Eval(&ast.Program{}, env2.CmdLineIter(), env2)
+
+		assert.Nil(t, rc, "LOAD of %s failed", tt.file)
+		assert.Equal(t, evalListLines(0, 100, env), evalListLines(0, 100, env2), "%s didn't reload the same", tt.file)
+	}
+}
+
+func Test_SaveWhileRunning(t *testing.T) {
+	var mt mocks.MockTerm
+	initMockTerm(&mt)
+	env := object.NewTermEnvironment(mt)
+	env.SetClient(&mocks.MockClient{})
+
+	parser.New(lexer.New("10 X = 1\n20 SAVE \"SAVE7\",A : X = X + 1\n30 LIST 10 : X = X + 1\n40 Y = X")).ParseProgram(env)
+	env.SetRun(true)
+	rc := Eval(&ast.Program{}, env.StatementIter(), env)
+
+	assert.Nil(t, rc, "program failed")
+	compareObjects("SAVE while running", env.Get("Y"), 3, t)
+	assert.NotNil(t, localfiles.For(env).Find(fileserv.BuildFullPath("SAVE7.BAS", env)), "program not saved")
+}
+
+func Test_FileManagement(t *testing.T) {
+	const srv = "http://localhost:8080/drivec"
+	tests := []struct {
+		local []string          // files held locally before the command
+		dirs  []string          // directories created locally before the command
+		pages map[string]string // directory listings the server sends back
+		scode int               // status the server gives a change request
+		pre   string            // command run first to set things up
+		cmd   string
+		err   int      // error code expected, 0 for none
+		gone  []string // files that should be gone after
+		kept  []string // files that should still be there after
+		sent  []string // change requests the server should get
+	}{
+		{local: []string{`c:\killt\a.dat\`, `c:\killt\b.dat\`, `c:\killt\c.txt\`}, pages: map[string]string{srv + "/killt": `[{"name":"D.DAT","isdir":false},{"name":"SUB.DAT","isdir":true}]`},
+			cmd: `KILL "KILLT\*.DAT"`, gone: []string{`c:\killt\a.dat\`, `c:\killt\b.dat\`}, kept: []string{`c:\killt\c.txt\`}, sent: []string{"DELETE " + srv + "/killt/d.dat"}},
+		{local: []string{`c:\killt\c.txt\`}, cmd: `KILL "KILLT\*"`, err: berrors.FileNotFound, kept: []string{`c:\killt\c.txt\`}},
+		{local: []string{`c:\killt\c.txt\`}, cmd: `KILL "KILLT\NONE.TXT"`, err: berrors.FileNotFound},
+		{local: []string{`c:\killt\e.txt\`}, pre: `OPEN "KILLT\E.TXT" FOR INPUT AS #1`, cmd: `KILL "KILLT\E.TXT"`, err: berrors.FileAlreadyOpen, kept: []string{`c:\killt\e.txt\`}},
+		{pages: map[string]string{srv + "/killt": `[{"name":"F.DAT","isdir":false}]`}, scode: http.StatusMethodNotAllowed,
+			cmd: `KILL "KILLT\F.DAT"`, err: berrors.PathFileAccess, sent: []string{"DELETE " + srv + "/killt/f.dat"}},
+		{cmd: `KILL`, err: berrors.Syntax},
+		{cmd: `KILL 5`, err: berrors.TypeMismatch},
+		{cmd: `KILL ""`, err: berrors.BadFileName},
+		{local: []string{`c:\namet\old.dat\`}, cmd: `NAME "NAMET\OLD.DAT" AS "NAMET\NEW.DAT"`, gone: []string{`c:\namet\old.dat\`}, kept: []string{`c:\namet\new.dat\`}},
+		{pages: map[string]string{srv + "/namet": `[{"name":"SRV.DAT","isdir":false}]`}, cmd: `NAME "NAMET\SRV.DAT" AS "NAMET\SRV2.DAT"`,
+			sent: []string{"MOVE " + srv + "/namet/srv.dat " + srv + "/namet/srv2.dat"}},
+		{pages: map[string]string{srv + "/namet": `[{"name":"SRV.DAT","isdir":false}]`}, scode: http.StatusMethodNotAllowed, cmd: `NAME "NAMET\SRV.DAT" AS "NAMET\SRV2.DAT"`,
+			err: berrors.PathFileAccess, sent: []string{"MOVE " + srv + "/namet/srv.dat " + srv + "/namet/srv2.dat"}},
+		{local: []string{`c:\namet\one.dat\`, `c:\namet\two.dat\`}, cmd: `NAME "NAMET\ONE.DAT" AS "NAMET\TWO.DAT"`, err: berrors.FileAlreadyExists, kept: []string{`c:\namet\one.dat\`, `c:\namet\two.dat\`}},
+		{cmd: `NAME "NAMET\NONE.DAT" AS "NAMET\TWO.DAT"`, err: berrors.FileNotFound},
+		{local: []string{`c:\namet\three.dat\`}, cmd: `NAME "NAMET\THREE.DAT" AS "D:\THREE.DAT"`, err: berrors.RenameAcrossDisks},
+		{local: []string{`c:\namet\four.dat\`}, pre: `OPEN "NAMET\FOUR.DAT" FOR INPUT AS #2`, cmd: `NAME "NAMET\FOUR.DAT" AS "NAMET\FIVE.DAT"`, err: berrors.FileAlreadyOpen},
+		{cmd: `NAME "NAMET\FOUR.DAT"`, err: berrors.Syntax},
+		{cmd: `MKDIR "MKT1"`, sent: []string{"MKCOL " + srv + "/mkt1"}},
+		{cmd: `MKDIR "MKT2"`, scode: http.StatusMethodNotAllowed, sent: []string{"MKCOL " + srv + "/mkt2"}},
+		{pages: map[string]string{srv: `[{"name":"MKT3","isdir":true}]`}, cmd: `MKDIR "MKT3"`, err: berrors.PathFileAccess},
+		{dirs: []string{`c:\mkt4\`}, cmd: `MKDIR "MKT4"`, err: berrors.PathFileAccess},
+		{dirs: []string{`c:\rmt1\`}, cmd: `RMDIR "RMT1"`},
+		{pages: map[string]string{srv: `[{"name":"RMT2","isdir":true}]`}, cmd: `RMDIR "RMT2"`, sent: []string{"DELETE " + srv + "/rmt2"}},
+		{pages: map[string]string{srv: `[{"name":"RMT2","isdir":true}]`}, scode: http.StatusMethodNotAllowed, cmd: `RMDIR "RMT2"`, err: berrors.PathFileAccess, sent: []string{"DELETE " + srv + "/rmt2"}},
+		{pages: map[string]string{srv: `[{"name":"RMT3","isdir":true}]`, srv + "/rmt3": `[{"name":"X.DAT","isdir":false}]`}, cmd: `RMDIR "RMT3"`, err: berrors.PathFileAccess},
+		{local: []string{`c:\rmt4\x.dat\`}, dirs: []string{`c:\rmt4\`}, cmd: `RMDIR "RMT4"`, err: berrors.PathFileAccess},
+		{dirs: []string{`c:\`}, cmd: `RMDIR "\"`, err: berrors.PathFileAccess},
+		{cmd: `RMDIR "NONE"`, err: berrors.PathNotFound},
+		{local: []string{`c:\resett.dat\`}, pre: `OPEN "RESETT.DAT" FOR INPUT AS #3`, cmd: `RESET`, kept: []string{`c:\resett.dat\`}},
+	}
+
+	for _, tt := range tests {
+		var mt mocks.MockTerm
+		initMockTerm(&mt)
+		env := object.NewTermEnvironment(mt)
+
+		for _, fn := range tt.local {
+			localfiles.For(env).Store(fn, []byte("data"))
+		}
+		for _, dir := range tt.dirs {
+			localfiles.For(env).MakeDir(dir)
+		}
+
+		if len(tt.pre) > 0 {
+			env.SetClient(&mocks.MockClient{Err: errors.New("no server")})
+			parser.New(lexer.New(tt.pre)).ParseCmd(env)
+			assert.Nil(t, Eval(&ast.Program{}, env.CmdLineIter(), env), "%s failed", tt.pre)
+			env.CmdComplete()
+		}
+
+		clnt := &mocks.MockClient{Pages: tt.pages, StatusCode: tt.scode}
+		if clnt.Pages == nil {
+			clnt.Pages = map[string]string{}
+		}
+		env.SetClient(clnt)
+
+		parser.New(lexer.New(tt.cmd)).ParseCmd(env)
+		rc := Eval(&ast.Program{}, env.CmdLineIter(), env)
+
+		if tt.err == 0 {
+			assert.Nil(t, rc, "%s failed", tt.cmd)
+		} else if assert.NotNil(t, rc, "%s should have failed", tt.cmd) {
+			assert.Equal(t, tt.err, rc.(*object.Error).Code, "%s gave %s", tt.cmd, rc.Inspect())
+		}
+
+		for _, fn := range tt.gone {
+			assert.Nil(t, localfiles.For(env).Find(fn), "%s didn't remove %s", tt.cmd, fn)
+		}
+		for _, fn := range tt.kept {
+			assert.NotNil(t, localfiles.For(env).Find(fn), "%s lost %s", tt.cmd, fn)
+		}
+		assert.Equal(t, tt.sent, clnt.Sent, "%s sent the wrong requests", tt.cmd)
+
+		if strings.HasPrefix(tt.cmd, "RESET") {
+			assert.Nil(t, env.GetOpenFile(3), "RESET didn't close the files")
+		}
+	}
+}
+
+func Test_MergeCommand(t *testing.T) {
+	tests := []struct {
+		prog  string
+		cmd   string
+		file  string // contents of the file being merged
+		scode int
+		run   bool // run the program rather than the command
+		exp   []string
+		err   int
+	}{
+		{prog: "10 PRINT 1\n20 PRINT 2\n30 END", cmd: `MERGE "SUBS"`, file: "20 PRINT \"TWO\"\r\n25 X = 5\r\n\x1a",
+			exp: []string{"10 PRINT 1", `20 PRINT "TWO"`, "25  X = 5", "30 END"}},
+		{prog: "10 PRINT 1\n20 PRINT 2", cmd: `MERGE "SUBS.BAS"`, file: "5 REM start\n40 GOTO 10\n",
+			exp: []string{"5 REM start", "10 PRINT 1", "20 PRINT 2", "40  GOTO 10"}},
+		{prog: "10 MERGE \"SUBS\"\n20 PRINT 2", file: "20 PRINT 3\r\n30 END\r\n", run: true,
+			exp: []string{`10 MERGE "SUBS"`, "20 PRINT 3", "30 END"}},
+		{prog: "10 PRINT 1", cmd: `MERGE "SUBS"`, file: "\xff\x7c\x12\x0a\x00\x91\x00\x00\x00\x1a", err: berrors.BadFileMode, exp: []string{"10 PRINT 1"}},
+		{prog: "10 PRINT 1", cmd: `MERGE "SUBS"`, file: "\xfe\x7c\x12\x0a\x00\x91\x00\x00\x00\x1a", err: berrors.BadFileMode, exp: []string{"10 PRINT 1"}},
+		{prog: "10 PRINT 1", cmd: `MERGE "SUBS"`, scode: http.StatusNotFound, err: berrors.FileNotFound, exp: []string{"10 PRINT 1"}},
+		{prog: "10 PRINT 1", cmd: `MERGE 5`, err: berrors.TypeMismatch, exp: []string{"10 PRINT 1"}},
+		{prog: "10 PRINT 1", cmd: `MERGE`, err: berrors.Syntax, exp: []string{"10 PRINT 1"}},
+	}
+
+	for _, tt := range tests {
+		var mt mocks.MockTerm
+		initMockTerm(&mt)
+		env := object.NewTermEnvironment(mt)
+		env.SetClient(&mocks.MockClient{Contents: tt.file, StatusCode: tt.scode})
+
+		parser.New(lexer.New(tt.prog)).ParseProgram(env)
+
+		var rc object.Object
+		if tt.run {
+			env.SetRun(true)
+			rc = Eval(&ast.Program{}, env.StatementIter(), env)
+		} else {
+			parser.New(lexer.New(tt.cmd)).ParseCmd(env)
+			rc = Eval(&ast.Program{}, env.CmdLineIter(), env)
+		}
+
+		if tt.err == 0 {
+			assert.Nil(t, rc, "%s failed", tt.cmd)
+		} else if assert.NotNil(t, rc, "%s should have failed", tt.cmd) {
+			assert.Equal(t, tt.err, rc.(*object.Error).Code, "%s gave %s", tt.cmd, rc.Inspect())
+		}
+
+		assert.Equal(t, tt.exp, evalListLines(0, env.StatementIter().MaxLineNum(), env), "%s merged badly", tt.cmd)
+	}
+}
+
+func Test_DeleteCommand(t *testing.T) {
+	tests := []struct {
+		cmd string
+		run bool // DELETE is in the program
+		exp []string
+		err int
+	}{
+		{cmd: `DELETE 20`, exp: []string{"10 DATA 1", "30 READ X", "40 PRINT X", "50 END"}},
+		{cmd: `DELETE 20-40`, exp: []string{"10 DATA 1", "50 END"}},
+		{cmd: `DELETE -30`, exp: []string{"40 PRINT X", "50 END"}},
+		{cmd: `DELETE 30-`, exp: []string{"10 DATA 1", "20 PRINT 2"}},
+		{cmd: `DELETE 15-19`, err: berrors.IllegalFuncCallErr},
+		{cmd: `DELETE 25`, err: berrors.IllegalFuncCallErr},
+		{cmd: `DELETE 60-`, err: berrors.IllegalFuncCallErr},
+		{cmd: `DELETE`, err: berrors.IllegalFuncCallErr},
+		{cmd: `DELETE 10-20 X`, err: berrors.Syntax},
+		{cmd: `45 DELETE 10-20`, run: true, exp: []string{"30 READ X", "40 PRINT X", "45 DELETE 10-20", "50 END"}},
+	}
+
+	for _, tt := range tests {
+		var mt mocks.MockTerm
+		initMockTerm(&mt)
+		env := object.NewTermEnvironment(mt)
+		prog := []string{"10 DATA 1", "20 PRINT 2", "30 READ X", "40 PRINT X", "50 END"}
+
+		parser.New(lexer.New(strings.Join(prog, "\n"))).ParseProgram(env)
+
+		var rc object.Object
+		if tt.run {
+			parser.New(lexer.New(tt.cmd)).ParseCmd(env)
+			env.SetRun(true)
+			rc = Eval(&ast.Program{}, env.StatementIter(), env)
+		} else {
+			parser.New(lexer.New(tt.cmd)).ParseCmd(env)
+			rc = Eval(&ast.Program{}, env.CmdLineIter(), env)
+		}
+
+		if tt.err == 0 {
+			assert.Nil(t, rc, "%s failed", tt.cmd)
+		} else if assert.NotNil(t, rc, "%s should have failed", tt.cmd) {
+			assert.Equal(t, tt.err, rc.(*object.Error).Code, "%s gave %s", tt.cmd, rc.Inspect())
+			tt.exp = prog
+		}
+
+		assert.Equal(t, tt.exp, evalListLines(0, env.StatementIter().MaxLineNum(), env), "%s deleted badly", tt.cmd)
+	}
+}
+
+func Test_RenumCommand(t *testing.T) {
+	prog := []string{
+		"10 ON ERROR GOTO 70",
+		"20 IF X THEN 40 ELSE 50",
+		"30 ON X GOSUB 40, 50",
+		"40  GOTO 20",
+		"50 RESTORE 60",
+		"60 DATA 1",
+		"70 IF ERL = 20 THEN 40",
+		"80 RESUME 40",
+		"90 GOSUB 900",
+		"100 IF X THEN RESUME 40 ELSE RESUME 950",
+		"110 IF 70 = ERL THEN 80",
+	}
+
+	tests := []struct {
+		cmd   string
+		exp   []string
+		undef string
+		err   int
+	}{
+		{cmd: `RENUM`, undef: "Undefined line 900 in 90Undefined line 950 in 100", exp: []string{
+			"10 ON ERROR GOTO 70",
+			"20 IF X THEN 40 ELSE 50",
+			"30 ON X GOSUB 40, 50",
+			"40  GOTO 20",
+			"50 RESTORE 60",
+			"60 DATA 1",
+			"70 IF ERL = 20 THEN 40",
+			"80 RESUME 40",
+			"90  GOSUB 900",
+			"100 IF X THEN RESUME 40 ELSE RESUME 950",
+			"110 IF 70 = ERL THEN 80",
+		}},
+		{cmd: `RENUM 100,,100`, undef: "Undefined line 900 in 900Undefined line 950 in 1000", exp: []string{
+			"100 ON ERROR GOTO 700",
+			"200 IF X THEN 400 ELSE 500",
+			"300 ON X GOSUB 400, 500",
+			"400  GOTO 200",
+			"500 RESTORE 600",
+			"600 DATA 1",
+			"700 IF ERL = 200 THEN 400",
+			"800 RESUME 400",
+			"900  GOSUB 900",
+			"1000 IF X THEN RESUME 400 ELSE RESUME 950",
+			"1100 IF 700 = ERL THEN 800",
+		}},
+		{cmd: `RENUM 1000,40,5`, undef: "Undefined line 900 in 1025Undefined line 950 in 1030", exp: []string{
+			"10 ON ERROR GOTO 1015",
+			"20 IF X THEN 1000 ELSE 1005",
+			"30 ON X GOSUB 1000, 1005",
+			"1000  GOTO 20",
+			"1005 RESTORE 1010",
+			"1010 DATA 1",
+			"1015 IF ERL = 20 THEN 1000",
+			"1020 RESUME 1000",
+			"1025  GOSUB 900",
+			"1030 IF X THEN RESUME 1000 ELSE RESUME 950",
+			"1035 IF 1015 = ERL THEN 1020",
+		}},
+		{cmd: `RENUM 30,40`, err: berrors.IllegalFuncCallErr},
+		{cmd: `RENUM 200,150`, err: berrors.IllegalFuncCallErr},
+		{cmd: `RENUM 65000,,80`, err: berrors.IllegalFuncCallErr},
+		{cmd: `RENUM ,,0`, err: berrors.IllegalFuncCallErr},
+		{cmd: `RENUM 10 X`, err: berrors.Syntax},
+	}
+
+	for _, tt := range tests {
+		var mt mocks.MockTerm
+		initMockTerm(&mt)
+		saw := ""
+		mt.SawStr = &saw
+		env := object.NewTermEnvironment(mt)
+
+		parser.New(lexer.New(strings.Join(prog, "\n"))).ParseProgram(env)
+		parser.New(lexer.New(tt.cmd)).ParseCmd(env)
+		rc := Eval(&ast.Program{}, env.CmdLineIter(), env)
+
+		if tt.err == 0 {
+			assert.Nil(t, rc, "%s failed", tt.cmd)
+		} else if assert.NotNil(t, rc, "%s should have failed", tt.cmd) {
+			assert.Equal(t, tt.err, rc.(*object.Error).Code, "%s gave %s", tt.cmd, rc.Inspect())
+			continue
+		}
+
+		assert.Equal(t, tt.undef, saw, "%s warnings wrong", tt.cmd)
+		assert.Equal(t, tt.exp, evalListLines(0, env.StatementIter().MaxLineNum(), env), "%s renumbered badly", tt.cmd)
+	}
+}
+
+func Test_EditCommand(t *testing.T) {
+	tests := []struct {
+		cmd string
+		exp string
+		err int
+	}{
+		{cmd: `EDIT 20`, exp: `20 PRINT "HELLO"`},
+		{cmd: `EDIT 25`, err: berrors.UnDefinedLineNumber},
+		{cmd: `EDIT`, err: berrors.IllegalFuncCallErr},
+		{cmd: `EDIT 20 X`, err: berrors.Syntax},
+	}
+
+	for _, tt := range tests {
+		var mt mocks.MockTerm
+		initMockTerm(&mt)
+		env := object.NewTermEnvironment(mt)
+
+		parser.New(lexer.New("10 X = 5\n20 PRINT \"HELLO\"")).ParseProgram(env)
+		parser.New(lexer.New(tt.cmd)).ParseCmd(env)
+		rc := Eval(&ast.Program{}, env.CmdLineIter(), env)
+
+		if tt.err != 0 {
+			if assert.NotNil(t, rc, "%s should have failed", tt.cmd) {
+				assert.Equal(t, tt.err, rc.(*object.Error).Code, "%s gave %s", tt.cmd, rc.Inspect())
+			}
+			assert.Nil(t, env.GetSetting(settings.Edit), "%s saved a line to edit", tt.cmd)
+			continue
+		}
+
+		assert.Nil(t, rc, "%s failed", tt.cmd)
+		if ed, ok := env.GetSetting(settings.Edit).(*ast.StringLiteral); assert.True(t, ok, "%s didn't save the line", tt.cmd) {
+			assert.Equal(t, tt.exp, ed.Value, "%s saved the wrong line", tt.cmd)
+		}
+	}
+}
@@ -89,6 +89,46 @@ var typeConverters = map[string]expression{
 		return evalFloatDblInfixExpression(operator, float64(left.(*object.FloatSgl).Value), right.(*object.FloatDbl).Value, env)
 	},
 
+	// Double integers, mostly the result of integer math that overflowed
+
+	object.INTEGER_DBL + object.INTEGER_DBL: func(operator string, left, right object.Object, env *object.Environment) object.Object {
+		return evalIntegerInfixExpression(operator, int(left.(*object.IntDbl).Value), int(right.(*object.IntDbl).Value), env)
+	},
+
+	object.INTEGER_DBL + object.INTEGER_OBJ: func(operator string, left, right object.Object, env *object.Environment) object.Object {
+		return evalIntegerInfixExpression(operator, int(left.(*object.IntDbl).Value), int(right.(*object.Integer).Value), env)
+	},
+
+	object.INTEGER_OBJ + object.INTEGER_DBL: func(operator string, left, right object.Object, env *object.Environment) object.Object {
+		return evalIntegerInfixExpression(operator, int(left.(*object.Integer).Value), int(right.(*object.IntDbl).Value), env)
+	},
+
+	object.INTEGER_DBL + object.FIXED_OBJ: func(operator string, left, right object.Object, env *object.Environment) object.Object {
+		dleft := decimal.NewFromInt32(left.(*object.IntDbl).Value)
+		return evalFixedInfixExpression(operator, dleft, right.(*object.Fixed).Value, env)
+	},
+
+	object.FIXED_OBJ + object.INTEGER_DBL: func(operator string, left, right object.Object, env *object.Environment) object.Object {
+		dright := decimal.NewFromInt32(right.(*object.IntDbl).Value)
+		return evalFixedInfixExpression(operator, left.(*object.Fixed).Value, dright, env)
+	},
+
+	object.INTEGER_DBL + object.FLOATSGL_OBJ: func(operator string, left, right object.Object, env *object.Environment) object.Object {
+		return evalFloatInfixExpression(operator, float32(left.(*object.IntDbl).Value), right.(*object.FloatSgl).Value, env)
+	},
+
+	object.FLOATSGL_OBJ + object.INTEGER_DBL: func(operator string, left, right object.Object, env *object.Environment) object.Object {
+		return evalFloatInfixExpression(operator, left.(*object.FloatSgl).Value, float32(right.(*object.IntDbl).Value), env)
+	},
+
+	object.INTEGER_DBL + object.FLOATDBL_OBJ: func(operator string, left, right object.Object, env *object.Environment) object.Object {
+		return evalFloatDblInfixExpression(operator, float64(left.(*object.IntDbl).Value), right.(*object.FloatDbl).Value, env)
+	},
+
+	object.FLOATDBL_OBJ + object.INTEGER_DBL: func(operator string, left, right object.Object, env *object.Environment) object.Object {
+		return evalFloatDblInfixExpression(operator, left.(*object.FloatDbl).Value, float64(right.(*object.IntDbl).Value), env)
+	},
+
 	/*
 
 		object. + object.: func(operator string, left, right object.Object, env *object.Environment) object.Object {
@@ -0,0 +1,444 @@
+package evaluator
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/navionguy/basicwasm/berrors"
+	"github.com/navionguy/basicwasm/object"
+)
+
+// the most digits a numeric field can hold
+const maxUsingDigits = 24
+
+// usingFormat steps thru a PRINT USING format string, each item
+// printed takes the next field, starting over at the end of the string
+type usingFormat struct {
+	form string
+	pos  int // where the next field is looked for
+}
+
+// usingField is one field of a format string
+type usingField struct {
+	width  int  // characters in the field, zero for & which takes the whole string
+	str    bool // string field, !, & or \  \
+	lead   bool // leading + always shows the sign
+	trail  byte // trailing + or - shows the sign after the number
+	dollar bool // $$ puts a dollar sign in front of the number
+	fill   byte // ** fills the leading spaces with asterisks
+	commas bool // comma left of the decimal point, digits get grouped in threes
+	point  bool // the field has a decimal point
+	before int  // digit positions left of the decimal point
+	places int  // digit positions right of the decimal point
+	exp    int  // length of the ^^^^ exponent, zero if there isn't one
+}
+
+// newUsingFormat makes sure the format has a field to print in
+func newUsingFormat(form string, env *object.Environment) (*usingFormat, object.Object) {
+	for i := 0; i < len(form); {
+		if fld, _ := scanUsingField(form[i:]); fld != nil {
+			if fld.before+fld.places > maxUsingDigits {
+				return nil, object.StdError(env, berrors.IllegalFuncCallErr)
+			}
+			return &usingFormat{form: form}, nil
+		}
+		_, n := scanUsingLiteral(form[i:])
+		i += n
+	}
+
+	return nil, object.StdError(env, berrors.IllegalFuncCallErr)
+}
+
+// print formats one item, along with any literal text ahead of its field
+func (uf *usingFormat) print(item object.Object, env *object.Environment) (string, object.Object) {
+	var out strings.Builder
+
+	for {
+		if uf.pos >= len(uf.form) {
+			uf.pos = 0
+		}
+
+		fld, n := scanUsingField(uf.form[uf.pos:])
+		if fld == nil {
+			lit, n := scanUsingLiteral(uf.form[uf.pos:])
+			out.WriteString(lit)
+			uf.pos += n
+			continue
+		}
+
+		uf.pos += n
+		res, err := fld.format(item, env)
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(res)
+		return out.String(), nil
+	}
+}
+
+// finish gives back the literal text after the last field used
+// up to the next field or the end of the format
+func (uf *usingFormat) finish() string {
+	var out strings.Builder
+
+	for uf.pos < len(uf.form) {
+		if fld, _ := scanUsingField(uf.form[uf.pos:]); fld != nil {
+			break
+		}
+		lit, n := scanUsingLiteral(uf.form[uf.pos:])
+		out.WriteString(lit)
+		uf.pos += n
+	}
+
+	return out.String()
+}
+
+// a character that isn't part of a field, an underscore
+// makes the character after it literal
+func scanUsingLiteral(form string) (string, int) {
+	if (form[0] == '_') && (len(form) > 1) {
+		return form[1:2], 2
+	}
+	return form[:1], 1
+}
+
+// scanUsingField returns the field at the start of the form
+// and its length, nil if there isn't one
+func scanUsingField(form string) (*usingField, int) {
+	switch form[0] {
+	case '!':
+		return &usingField{width: 1, str: true}, 1
+	case '&':
+		return &usingField{str: true}, 1
+	case '\\':
+		i := 1
+		for (i < len(form)) && (form[i] == ' ') {
+			i++
+		}
+		if (i < len(form)) && (form[i] == '\\') {
+			return &usingField{width: i + 1, str: true}, i + 1
+		}
+		return nil, 0
+	}
+
+	return scanUsingNumber(form)
+}
+
+// a numeric field, [+][**|$$|**$]#,#.#[^^^^][+|-]
+func scanUsingNumber(form string) (*usingField, int) {
+	fld := &usingField{fill: ' '}
+	i := 0
+
+	if form[0] == '+' {
+		fld.lead = true
+		i++
+	}
+
+	switch {
+	case strings.HasPrefix(form[i:], "**$"):
+		fld.fill, fld.dollar, fld.before = '*', true, 2
+		i += 3
+	case strings.HasPrefix(form[i:], "**"):
+		fld.fill, fld.before = '*', 2
+		i += 2
+	case strings.HasPrefix(form[i:], "$$"):
+		fld.dollar, fld.before = true, 1
+		i += 2
+	}
+
+	// a decimal point only starts a field if a digit follows it
+	if (fld.before == 0) && !strings.HasPrefix(form[i:], "#") && !strings.HasPrefix(form[i:], ".#") {
+		return nil, 0
+	}
+
+	for ; i < len(form); i++ {
+		c := form[i]
+		switch {
+		case (c == '#') && fld.point:
+			fld.places++
+		case c == '#':
+			fld.before++
+		case (c == ',') && !fld.point:
+			fld.before++
+			fld.commas = true
+		case (c == '.') && !fld.point:
+			fld.point = true
+		default:
+			return fld.finish(form, i)
+		}
+	}
+
+	return fld.finish(form, i)
+}
+
+// pick up the exponent and trailing sign following the digits
+func (fld *usingField) finish(form string, i int) (*usingField, int) {
+	switch {
+	case strings.HasPrefix(form[i:], "^^^^^"):
+		fld.exp = 5
+	case strings.HasPrefix(form[i:], "^^^^"):
+		fld.exp = 4
+	}
+	i += fld.exp
+
+	if !fld.lead && (i < len(form)) && ((form[i] == '+') || (form[i] == '-')) {
+		fld.trail = form[i]
+		i++
+	}
+
+	fld.width = i
+	return fld, i
+}
+
+// format the item to fit the field
+func (fld *usingField) format(item object.Object, env *object.Environment) (string, object.Object) {
+	if tv, ok := item.(*object.TypedVar); ok {
+		item = tv.Value
+	}
+
+	str, isStr := item.(*object.String)
+	if isStr != fld.str {
+		return "", object.StdError(env, berrors.TypeMismatch)
+	}
+
+	if fld.str {
+		return fld.text(str.Value), nil
+	}
+
+	neg, digits, exp, ok := usingDigits(item)
+	if !ok {
+		return "", object.StdError(env, berrors.TypeMismatch)
+	}
+
+	return fld.number(neg, digits, exp), nil
+}
+
+// strings are left justified, cut off if they don't fit
+func (fld *usingField) text(s string) string {
+	if fld.width == 0 {
+		return s
+	}
+
+	if len(s) > fld.width {
+		return s[:fld.width]
+	}
+
+	return s + strings.Repeat(" ", fld.width-len(s))
+}
+
+// number lays out a value of 0.digits times ten to the exp
+func (fld *usingField) number(neg bool, digits string, exp int) string {
+	var body string
+	if fld.exp > 0 {
+		digits, exp = fld.scientific(digits, exp)
+		body = digits
+	} else {
+		digits, exp = roundDigits(digits, exp, exp+fld.places)
+		body = fld.fixed(digits, exp)
+	}
+
+	// a value that rounds to zero has no sign
+	if !strings.ContainsAny(strings.Split(body, "E")[0], "123456789") {
+		neg = false
+	}
+
+	sign, post := "", ""
+	switch {
+	case fld.lead && neg:
+		sign = "-"
+	case fld.lead:
+		sign = "+"
+	case fld.trail == '-' && neg, fld.trail == '+' && neg:
+		post = "-"
+	case fld.trail == '-':
+		post = " "
+	case fld.trail == '+':
+		post = "+"
+	case neg:
+		sign = "-"
+	}
+
+	if fld.dollar {
+		sign += "$"
+	}
+
+	// a zero goes in front of the decimal point if it fits
+	if strings.HasPrefix(body, ".") || (len(body) == 0) {
+		if (len(body) == 0) || (len(sign)+len(body)+len(post) < fld.width) {
+			body = "0" + body
+		}
+	}
+
+	res := sign + body + post
+	if len(res) > fld.width {
+		return "%" + res
+	}
+
+	return strings.Repeat(string(fld.fill), fld.width-len(res)) + res
+}
+
+// the digits of a fixed point number, without the sign
+func (fld *usingField) fixed(digits string, exp int) string {
+	whole := ""
+	if exp > 0 {
+		whole = digitsAt(digits, 0, exp)
+	}
+
+	if fld.commas {
+		whole = groupDigits(whole)
+	}
+
+	if !fld.point {
+		return whole
+	}
+
+	return whole + "." + digitsAt(digits, exp, fld.places)
+}
+
+// the mantissa and exponent, without the sign
+// unless the field shows the sign elsewhere it takes a digit position
+func (fld *usingField) scientific(digits string, exp int) (string, int) {
+	before := fld.before
+	if !fld.lead && (fld.trail == 0) && (before > 0) {
+		before--
+	}
+
+	if before+fld.places == 0 {
+		before = 1
+	}
+
+	digits, exp = roundDigits(digits, exp, before+fld.places)
+	if len(digits) > 0 {
+		exp -= before
+	}
+
+	res := digitsAt(digits, 0, before)
+	if fld.point {
+		res += "." + digitsAt(digits, before, fld.places)
+	}
+
+	es := "+"
+	if exp < 0 {
+		es = "-"
+		exp = -exp
+	}
+
+	num := strconv.Itoa(exp)
+	if len(num) < fld.exp-2 {
+		num = strings.Repeat("0", fld.exp-2-len(num)) + num
+	}
+
+	return res + "E" + es + num, exp
+}
+
+// count digits starting at from, zeros fill in past the end
+func digitsAt(digits string, from int, count int) string {
+	var out strings.Builder
+
+	for i := from; i < from+count; i++ {
+		if (i >= 0) && (i < len(digits)) {
+			out.WriteByte(digits[i])
+		} else {
+			out.WriteByte('0')
+		}
+	}
+
+	return out.String()
+}
+
+// put a comma between each group of three digits
+func groupDigits(whole string) string {
+	var out strings.Builder
+
+	for i := range whole {
+		if (i > 0) && ((len(whole)-i)%3 == 0) {
+			out.WriteByte(',')
+		}
+		out.WriteByte(whole[i])
+	}
+
+	return out.String()
+}
+
+// roundDigits keeps the first n digits, rounding half away from zero
+// trailing zeros are dropped, a value that rounds to zero has no digits
+func roundDigits(digits string, exp int, n int) (string, int) {
+	if n >= len(digits) {
+		return digits, exp
+	}
+
+	if n < 0 {
+		return "", 0
+	}
+
+	d := []byte(digits[:n])
+	if digits[n] >= '5' {
+		i := n - 1
+		for (i >= 0) && (d[i] == '9') {
+			d[i] = '0'
+			i--
+		}
+
+		if i < 0 {
+			d = append([]byte{'1'}, d...)
+			exp++
+		} else {
+			d[i]++
+		}
+	}
+
+	res := strings.TrimRight(string(d), "0")
+	if len(res) == 0 {
+		return "", 0
+	}
+
+	return res, exp
+}
+
+// usingDigits breaks a number down into its sign and the digits
+// of 0.digits times ten to the exp
+func usingDigits(item object.Object) (bool, string, int, bool) {
+	var s string
+
+	switch val := item.(type) {
+	case *object.Integer:
+		s = strconv.Itoa(int(val.Value))
+	case *object.IntDbl:
+		s = strconv.Itoa(int(val.Value))
+	case *object.Fixed:
+		s = val.Value.String()
+	case *object.FloatSgl:
+		s = strconv.FormatFloat(float64(val.Value), 'e', -1, 32)
+	case *object.FloatDbl:
+		s = strconv.FormatFloat(val.Value, 'e', -1, 64)
+	default:
+		return false, "", 0, false
+	}
+
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimLeft(s, "+-")
+
+	exp := 0
+	if i := strings.IndexByte(s, 'e'); i >= 0 {
+		exp, _ = strconv.Atoi(s[i+1:])
+		s = s[:i]
+	}
+
+	point := strings.IndexByte(s, '.')
+	if point < 0 {
+		point = len(s)
+	} else {
+		s = s[:point] + s[point+1:]
+	}
+
+	// drop the zeros on both ends
+	trimmed := strings.TrimLeft(s, "0")
+	point -= len(s) - len(trimmed)
+	s = strings.TrimRight(trimmed, "0")
+
+	if len(s) == 0 {
+		return false, "", 0, true
+	}
+
+	return neg, s, point + exp, true
+}
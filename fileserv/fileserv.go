@@ -2,11 +2,15 @@ package fileserv
 
 import (
 	"bufio"
+	"bytes"
 	"flag"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
 	"unicode"
 
@@ -27,7 +31,11 @@ import (
 // the results of those requests.
 
 type fileSource struct {
-	src http.FileSystem
+	src      http.FileSystem
+	root     string // host directory src serves up
+	drive    string // host directory of the drive root
+	route    string // route to the drive root
+	writable bool   // programs may change files on the drive
 }
 
 // These are the command line flags that tell where to find runtime resources
@@ -41,8 +49,17 @@ var (
 		"drived": flag.String("driveD", "/Users/don/Downloads/HCALC_129", "HamCalc source files"),
 		// TODO: add the rest of the possible drive letter flags
 	}
+	writable = map[string]*bool{
+		"drivea": flag.Bool("driveAWritable", false, "allow programs to change files on drive A"),
+		"driveb": flag.Bool("driveBWritable", false, "allow programs to change files on drive B"),
+		"drivec": flag.Bool("driveCWritable", false, "allow programs to change files on drive C"),
+		"drived": flag.Bool("driveDWritable", false, "allow programs to change files on drive D"),
+	}
 )
 
+// largest file a program can write to the server
+const maxPutSize = 1 << 20
+
 // WrapFileSources builds mux routes to all my resources
 // css files, images, javascript files and of course
 // the basic interpreter wasm file.
@@ -71,15 +88,24 @@ func WrapFileSources(rtr *mux.Router) {
 
 	for key, drv := range drives {
 		if len(*drv) > 0 {
-			ndrv := strings.ToLower(*drv)
-			fs := &fileSource{src: http.Dir(ndrv)}
 			path := "/" + key
+			fs := &fileSource{src: http.Dir(*drv), root: *drv, drive: *drv, route: path, writable: driveWritable(key)}
 			fs.fullyWrapSource(rtr, path)
 			fs.wrapSubDirs(rtr, *drv, path)
+			if fs.writable {
+				fs.wrapNewDirs(rtr, path)
+			}
 		}
 	}
 }
 
+// check the command line flag to see if the drive takes changes
+func driveWritable(key string) bool {
+	flg := writable[key]
+
+	return (flg != nil) && *flg
+}
+
 // given a path, create a handler function that will extract the
 // parts of the path and then call the source directory to work
 // on the file
@@ -95,11 +121,38 @@ func (fs *fileSource) wrapSource(rtr *mux.Router, path string, mimetype string)
 		if len(ext) > 0 {
 			file = file + "." + ext
 		}
-		fs.serveFile(rw, r, file, mimetype)
+		fs.handleRequest(rw, r, file, mimetype)
 	}).Name(path)
 
 }
 
+// Directories made after start-up don't have routes of their own.
+// On a writable drive, anything below the drive root that didn't
+// match a route is found relative to the drive root.
+func (fs *fileSource) wrapNewDirs(rtr *mux.Router, path string) {
+	rtr.PathPrefix(path + "/").HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		file := strings.TrimPrefix(r.URL.Path, path+"/")
+		fs.handleRequest(rw, r, file, "text/plain; charset=ASCII")
+	})
+}
+
+// handleRequest sends the request to the handler for its method
+// anything that isn't a change is served like a GET
+func (fs fileSource) handleRequest(w http.ResponseWriter, r *http.Request, fname string, mimetype string) {
+	switch r.Method {
+	case http.MethodPut:
+		fs.putFile(w, r, fname)
+	case http.MethodDelete:
+		fs.deleteFile(w, fname)
+	case MethodMkDir:
+		fs.makeDir(w, fname)
+	case MethodRename:
+		fs.renameFile(w, r, fname)
+	default:
+		fs.serveFile(w, r, fname, mimetype)
+	}
+}
+
 // Since the gorilla mux doesn't support wildcard routes I have to map
 // all the possibilities independantly.
 //
@@ -167,7 +220,7 @@ func (fs fileSource) wrapADir(rtr *mux.Router, dir string, path string, files []
 		fname := info.Name()
 		subdir := dir + "/" + fname
 		subpath := path + "/" + fname
-		nfs := &fileSource{src: http.Dir(subdir)}
+		nfs := &fileSource{src: http.Dir(subdir), root: subdir, drive: fs.drive, route: fs.route, writable: fs.writable}
 		nfs.fullyWrapSource(rtr, subpath)
 		nfs.wrapSubDirs(rtr, subdir, subpath)
 	}
@@ -244,7 +297,7 @@ func (fs fileSource) Open(name string) (hFile http.File, err error) {
 		return nil, os.ErrPermission
 	}
 
-	file, err := fs.src.Open(name)
+	file, err := fs.src.Open(matchCase(fs.src, name))
 	if err != nil {
 		return nil, err
 	}
@@ -253,6 +306,226 @@ func (fs fileSource) Open(name string) (hFile http.File, err error) {
 
 }
 
+// matchCase finds the host's spelling of name, requests come in
+// lower case but the host's file names may not be
+// anything not found on the host is left the way it was asked for
+func matchCase(src http.FileSystem, name string) string {
+	parts := strings.Split(name, "/")
+	dir := "/"
+	for i, part := range parts {
+		if len(part) == 0 {
+			continue
+		}
+		parts[i] = matchEntry(src, dir, part)
+		dir = path.Join(dir, parts[i])
+	}
+
+	return strings.Join(parts, "/")
+}
+
+// matchEntry looks in dir for name, an exact match wins
+func matchEntry(src http.FileSystem, dir string, name string) string {
+	hfile, err := src.Open(dir)
+	if err != nil {
+		return name
+	}
+	defer hfile.Close()
+
+	files, err := hfile.Readdir(-1)
+	if err != nil {
+		return name
+	}
+
+	found := name
+	for _, finfo := range files {
+		if finfo.Name() == name {
+			return name
+		}
+		if strings.EqualFold(finfo.Name(), name) {
+			found = finfo.Name()
+		}
+	}
+
+	return found
+}
+
+// putFile creates or replaces a file with the body of the request
+func (fs fileSource) putFile(w http.ResponseWriter, r *http.Request, fname string) {
+	hpath, rc := fs.changePath(fname)
+	if rc != 0 {
+		w.WriteHeader(rc)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPutSize))
+	if err != nil {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	st, err := os.Stat(hpath)
+	if (err == nil) && st.IsDir() {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	created := err != nil
+
+	if writeFileAtomic(hpath, data) != nil {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+
+	if created {
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteFile removes a file or an empty directory
+func (fs fileSource) deleteFile(w http.ResponseWriter, fname string) {
+	hpath, rc := fs.changePath(fname)
+	if rc != 0 {
+		w.WriteHeader(rc)
+		return
+	}
+
+	if _, err := os.Stat(hpath); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// a directory that isn't empty won't go away
+	if os.Remove(hpath) != nil {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// makeDir creates a new directory
+func (fs fileSource) makeDir(w http.ResponseWriter, fname string) {
+	hpath, rc := fs.changePath(fname)
+	if rc != 0 {
+		w.WriteHeader(rc)
+		return
+	}
+
+	if _, err := os.Stat(hpath); err == nil {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if os.Mkdir(hpath, 0755) != nil {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+// renameFile moves a file to the name in the Destination header
+// the destination must be on the same drive and not already exist
+func (fs fileSource) renameFile(w http.ResponseWriter, r *http.Request, fname string) {
+	hpath, rc := fs.changePath(fname)
+	if rc != 0 {
+		w.WriteHeader(rc)
+		return
+	}
+
+	dest, err := url.Parse(r.Header.Get(DestinationHeader))
+	if (err != nil) || !strings.HasPrefix(dest.Path, fs.route+"/") {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	dpath, ok := hostPath(fs.drive, matchCase(http.Dir(fs.drive), strings.TrimPrefix(dest.Path, fs.route+"/")))
+	if !ok {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if _, err := os.Stat(hpath); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if _, err := os.Stat(dpath); err == nil {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+
+	if os.Rename(hpath, dpath) != nil {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+// changePath checks that the drive takes changes and finds the
+// file on the host, a non-zero status is returned if it can't be changed
+func (fs fileSource) changePath(fname string) (string, int) {
+	if !fs.writable {
+		return "", http.StatusMethodNotAllowed
+	}
+
+	hpath, ok := hostPath(fs.root, matchCase(fs.src, fname))
+	if !ok {
+		return "", http.StatusForbidden
+	}
+
+	return hpath, 0
+}
+
+// hostPath builds the host file name for a name below root
+// names that could reach outside of root, or touch hidden files, are refused
+func hostPath(root string, name string) (string, bool) {
+	name = strings.Trim(name, "/")
+	if (len(name) == 0) || containsDotFile(name) || strings.ContainsAny(name, `\:`) {
+		return "", false
+	}
+
+	return filepath.Join(root, filepath.FromSlash(name)), true
+}
+
+// writeFileAtomic writes to a hidden temporary file and then renames it
+// so a failed write never leaves a partial file behind
+// the file keeps the permissions it had, new files get 0644
+func writeFileAtomic(name string, data []byte) error {
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(name); err == nil {
+		mode = fi.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".put-*")
+	if err != nil {
+		return err
+	}
+
+	// temp files are created private to the owner
+	err = tmp.Chmod(mode)
+	if err == nil {
+		_, err = tmp.Write(data)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), name)
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+
+	return err
+}
+
 // containsDotFile reports whether name contains a path element starting with a period.
 // The name is assumed to be a delimited by forward slashes, as guaranteed
 // by the http.FileSystem interface.
@@ -313,6 +586,94 @@ func GetFile(file string, env *object.Environment) (*bufio.Reader, object.Object
 	return rdr, nil
 }
 
+// PutFile sends the contents of a file to the remote server to be written
+// a read-only drive refuses with a 405
+func PutFile(file string, data []byte, env *object.Environment) object.Object {
+	rq := buildRequestURL(file, env)
+	t := env.Terminal()
+	if t != nil {
+		t.Log("PUT " + rq)
+	}
+
+	req, err := http.NewRequest(http.MethodPut, rq, bytes.NewReader(data))
+	if err != nil {
+		return object.StdError(env, berrors.PathNotFound)
+	}
+
+	return sendChange(req, env)
+}
+
+// DeleteFile asks the remote server to delete a file or an empty directory
+func DeleteFile(file string, env *object.Environment) object.Object {
+	return sendChangeRequest(http.MethodDelete, file, nil, env)
+}
+
+// MakeDir asks the remote server to create a directory
+func MakeDir(path string, env *object.Environment) object.Object {
+	return sendChangeRequest(MethodMkDir, path, nil, env)
+}
+
+// RenameFile asks the remote server to change the name of a file
+// the new name goes in the Destination header
+func RenameFile(oldName, newName string, env *object.Environment) object.Object {
+	hdr := http.Header{}
+	hdr.Set(DestinationHeader, buildRequestURL(newName, env))
+
+	return sendChangeRequest(MethodRename, oldName, hdr, env)
+}
+
+// Methods and headers used to change files on a writable drive
+// they follow the WebDAV names
+const (
+	MethodMkDir       = "MKCOL"
+	MethodRename      = "MOVE"
+	DestinationHeader = "Destination"
+)
+
+// build and send a request that changes a file on the server
+func sendChangeRequest(method, file string, hdr http.Header, env *object.Environment) object.Object {
+	rq := buildRequestURL(file, env)
+	t := env.Terminal()
+	if t != nil {
+		t.Log(method + " " + rq)
+	}
+
+	req, err := http.NewRequest(method, rq, nil)
+	if err != nil {
+		return object.StdError(env, berrors.PathNotFound)
+	}
+
+	for key, val := range hdr {
+		req.Header[key] = val
+	}
+
+	return sendChange(req, env)
+}
+
+// send a request that changes the server's files and convert
+// the status code into the matching error
+func sendChange(req *http.Request, env *object.Environment) object.Object {
+	res, _ := env.GetClient().Do(req)
+
+	if res == nil {
+		return object.StdError(env, berrors.PathNotFound)
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return nil
+	case http.StatusNotFound:
+		return object.StdError(env, berrors.PathNotFound)
+	case http.StatusForbidden, http.StatusMethodNotAllowed:
+		return object.StdError(env, berrors.PermissionDenied)
+	}
+
+	e := object.StdError(env, berrors.ServerError)
+	e.Message = e.Message + fmt.Sprintf(" %d", res.StatusCode)
+	return e
+}
+
 // execute a get via the current HTTPClient
 func sendRequest(rq string, env *object.Environment) (*http.Response, object.Object) {
 	res, err := env.GetClient().Get(rq)
@@ -369,7 +730,7 @@ func BuildFullPath(path string, env *object.Environment) string {
 		path = path + `\`
 	}
 	// is it a full path specification, case 1
-	if strings.EqualFold(path[1:3], ":\\") {
+	if (len(path) > 2) && strings.EqualFold(path[1:3], ":\\") {
 		return strings.ToLower(path)
 	}
 
@@ -538,6 +899,9 @@ func parseAsciiFile(inp *bufio.Reader, env *object.Environment) {
 func readLine(inp *bufio.Reader, env *object.Environment) bool {
 	bt, err := inp.ReadBytes(0x0a)
 
+	// files saved by GW-BASIC end in a ctrl-Z
+	bt = bytes.TrimRight(bt, "\r\n\x1a")
+
 	if len(bt) > 0 {
 		parseLine(string(bt), env)
 	}
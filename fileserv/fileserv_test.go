@@ -8,6 +8,8 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -280,6 +282,8 @@ func Test_BuildFullPath(t *testing.T) {
 		{path: "database", cwd: "C:\\", exp: "c:\\database\\"},
 		{path: "c:\\database", cwd: "C:\\", exp: "c:\\database\\"},
 		{path: "\\database", cwd: "C:\\", exp: "c:\\database\\"},
+		{path: "a", cwd: "C:\\sub\\", exp: "c:\\sub\\a\\"},
+		{path: "\\", cwd: "C:\\sub\\", exp: "c:\\"},
 	}
 
 	for _, tt := range tests {
@@ -581,6 +585,7 @@ func Test_ParseFile(t *testing.T) {
 			0x6D, 0x2E, 0x22, 0x0A, 0x32, 0x30, 0x20, 0x50, 0x52, 0x49, 0x4E, 0x54,
 			0x20, 0x22, 0x53, 0x61, 0x76, 0x65, 0x64, 0x20, 0x61, 0x73, 0x20, 0x41,
 			0x53, 0x43, 0x49, 0x49, 0x2E, 0x22}, stmts: 4},
+		{inp: []byte("10 PRINT \"Saved by GW-BASIC\"\r\n20 END\r\n\x1a"), stmts: 4},
 	}
 
 	for _, tt := range tests {
@@ -600,6 +605,223 @@ func Test_ParseFile(t *testing.T) {
 	}
 }
 
+func Test_PutFile(t *testing.T) {
+	tests := []struct {
+		file  string
+		data  string
+		scode int
+		err   error
+		exp   string
+	}{
+		{file: `C:\PROG.BAS`, data: "10 PRINT X\r\n", scode: 200},
+		{file: `C:\PROG.BAS`, data: "10 PRINT X\r\n", scode: 201},
+		{file: `C:\PROG.BAS`, data: "10 PRINT X\r\n", scode: 404, exp: "Path not found"},
+		{file: `C:\PROG.BAS`, data: "10 PRINT X\r\n", scode: 405, exp: "Permission Denied"},
+		{file: `C:\PROG.BAS`, data: "10 PRINT X\r\n", scode: 500, exp: "Server error 500"},
+		{file: `C:\PROG.BAS`, data: "10 PRINT X\r\n", err: errors.New("no server"), exp: "Path not found"},
+	}
+
+	for _, tt := range tests {
+		var trm object.Console
+		env := object.NewTermEnvironment(trm)
+		clnt := mocks.MockClient{StatusCode: tt.scode, Err: tt.err}
+		env.SetClient(&clnt)
+
+		rc := PutFile(tt.file, []byte(tt.data), env)
+
+		if len(tt.exp) == 0 {
+			assert.Nil(t, rc, "PutFile(%s) unexpected error", tt.file)
+			assert.Equal(t, tt.data, clnt.Contents, "PutFile(%s) sent wrong data", tt.file)
+			continue
+		}
+
+		assert.NotNil(t, rc, "PutFile(%s) expected an error", tt.file)
+		if rc != nil {
+			assert.Equal(t, tt.exp, rc.(*object.Error).Message)
+		}
+	}
+}
+
+func Test_ChangeRequests(t *testing.T) {
+	tests := []struct {
+		send  func(*object.Environment) object.Object
+		scode int
+		sent  string
+		exp   string
+	}{
+		{send: func(env *object.Environment) object.Object { return DeleteFile(`C:\DATA.TXT`, env) }, sent: "DELETE http://localhost:8080/drivec/data.txt"},
+		{send: func(env *object.Environment) object.Object { return DeleteFile(`C:\SUB\`, env) }, scode: 204, sent: "DELETE http://localhost:8080/drivec/sub"},
+		{send: func(env *object.Environment) object.Object { return MakeDir(`C:\SUB\`, env) }, scode: 201, sent: "MKCOL http://localhost:8080/drivec/sub"},
+		{send: func(env *object.Environment) object.Object { return RenameFile(`C:\A.DAT`, `C:\SUB\B.DAT`, env) },
+			sent: "MOVE http://localhost:8080/drivec/a.dat http://localhost:8080/drivec/sub/b.dat"},
+		{send: func(env *object.Environment) object.Object { return DeleteFile(`C:\DATA.TXT`, env) }, scode: 404, sent: "DELETE http://localhost:8080/drivec/data.txt", exp: "Path not found"},
+		{send: func(env *object.Environment) object.Object { return MakeDir(`C:\SUB\`, env) }, scode: 405, sent: "MKCOL http://localhost:8080/drivec/sub", exp: "Permission Denied"},
+		{send: func(env *object.Environment) object.Object { return RenameFile(`C:\A.DAT`, `C:\B.DAT`, env) }, scode: 409,
+			sent: "MOVE http://localhost:8080/drivec/a.dat http://localhost:8080/drivec/b.dat", exp: "Server error 409"},
+	}
+
+	for _, tt := range tests {
+		var trm object.Console
+		env := object.NewTermEnvironment(trm)
+		clnt := mocks.MockClient{StatusCode: tt.scode}
+		env.SetClient(&clnt)
+
+		rc := tt.send(env)
+
+		assert.Equal(t, []string{tt.sent}, clnt.Sent, "wrong request sent")
+		if len(tt.exp) == 0 {
+			assert.Nil(t, rc, "%s unexpected error", tt.sent)
+			continue
+		}
+
+		if assert.NotNil(t, rc, "%s expected an error", tt.sent) {
+			assert.Equal(t, tt.exp, rc.(*object.Error).Message)
+		}
+	}
+}
+
 func Test_SendRequest(t *testing.T) {
 
 }
+
+func Test_HostPath(t *testing.T) {
+	tests := []struct {
+		name string
+		exp  string
+		ok   bool
+	}{
+		{name: "data.txt", exp: filepath.Join("root", "data.txt"), ok: true},
+		{name: "/sub/data.txt", exp: filepath.Join("root", "sub", "data.txt"), ok: true},
+		{name: "sub/", exp: filepath.Join("root", "sub"), ok: true},
+		{name: "", ok: false},
+		{name: "/", ok: false},
+		{name: ".htaccess", ok: false},
+		{name: "../secret.txt", ok: false},
+		{name: "sub/../../secret.txt", ok: false},
+		{name: `..\secret.txt`, ok: false},
+		{name: `c:secret.txt`, ok: false},
+	}
+
+	for _, tt := range tests {
+		res, ok := hostPath("root", tt.name)
+
+		assert.Equal(t, tt.ok, ok, "hostPath(%s) check failed", tt.name)
+		assert.Equal(t, tt.exp, res, "hostPath(%s) gave wrong path", tt.name)
+	}
+}
+
+func Test_WritableDrive(t *testing.T) {
+	tests := []struct {
+		send func(*object.Environment) object.Object
+		exp  string // error message expected
+		file string // file that should hold data afterwards
+		data string
+		gone string // file that should be gone afterwards
+	}{
+		{send: func(env *object.Environment) object.Object { return PutFile(`C:\DATA.TXT`, []byte("first"), env) }, file: "data.txt", data: "first"},
+		{send: func(env *object.Environment) object.Object { return PutFile(`C:\DATA.TXT`, []byte("second"), env) }, file: "data.txt", data: "second"},
+		{send: func(env *object.Environment) object.Object { return MakeDir(`C:\NEWDIR\`, env) }, file: "newdir"},
+		{send: func(env *object.Environment) object.Object { return MakeDir(`C:\NEWDIR\`, env) }, exp: "Permission Denied"},
+		{send: func(env *object.Environment) object.Object { return PutFile(`C:\NEWDIR\NEW.DAT`, []byte("new"), env) }, file: "newdir/new.dat", data: "new"},
+		{send: func(env *object.Environment) object.Object {
+			return RenameFile(`C:\DATA.TXT`, `C:\NEWDIR\MOVED.TXT`, env)
+		}, file: "newdir/moved.txt", data: "second", gone: "data.txt"},
+		{send: func(env *object.Environment) object.Object { return RenameFile(`C:\NONE.TXT`, `C:\OTHER.TXT`, env) }, exp: "Path not found"},
+		{send: func(env *object.Environment) object.Object {
+			return RenameFile(`C:\NEWDIR\NEW.DAT`, `C:\NEWDIR\MOVED.TXT`, env)
+		}, exp: "Server error 412"},
+		{send: func(env *object.Environment) object.Object { return RenameFile(`C:\NEWDIR\NEW.DAT`, `D:\NEW.DAT`, env) }, exp: "Server error 400"},
+		{send: func(env *object.Environment) object.Object { return DeleteFile(`C:\NEWDIR\`, env) }, exp: "Server error 409"},
+		{send: func(env *object.Environment) object.Object { return DeleteFile(`C:\NEWDIR\NEW.DAT`, env) }, gone: "newdir/new.dat"},
+		{send: func(env *object.Environment) object.Object { return DeleteFile(`C:\NEWDIR\NEW.DAT`, env) }, exp: "Path not found"},
+		{send: func(env *object.Environment) object.Object { return PutFile(`C:\.HIDDEN`, []byte("sneaky"), env) }, exp: "Permission Denied", gone: ".hidden"},
+		{send: func(env *object.Environment) object.Object { return PutFile(`C:\NEWDIR`, []byte("oops"), env) }, exp: "Server error 409"},
+	}
+
+	dir := t.TempDir()
+	rt := mux.NewRouter()
+	fs := &fileSource{src: http.Dir(dir), root: dir, drive: dir, route: "/drivec", writable: true}
+	fs.fullyWrapSource(rt, "/drivec")
+	fs.wrapNewDirs(rt, "/drivec")
+	ts := httptest.NewServer(rt)
+	defer ts.Close()
+
+	for _, tt := range tests {
+		var trm object.Console
+		env := object.NewTermEnvironment(trm)
+		env.SetClient(http.DefaultClient)
+		env.SaveSetting(settings.ServerURL, &ast.StringLiteral{Value: ts.URL})
+
+		rc := tt.send(env)
+
+		if len(tt.exp) == 0 {
+			assert.Nil(t, rc, "unexpected error %v", rc)
+		} else if assert.NotNil(t, rc, "expected %s", tt.exp) {
+			assert.Equal(t, tt.exp, rc.(*object.Error).Message)
+		}
+
+		if len(tt.data) > 0 {
+			bt, err := os.ReadFile(filepath.Join(dir, tt.file))
+			assert.Nil(t, err, "%s wasn't written", tt.file)
+			assert.Equal(t, tt.data, string(bt), "%s has the wrong contents", tt.file)
+		} else if len(tt.file) > 0 {
+			_, err := os.Stat(filepath.Join(dir, tt.file))
+			assert.Nil(t, err, "%s wasn't created", tt.file)
+		}
+
+		if len(tt.gone) > 0 {
+			_, err := os.Stat(filepath.Join(dir, tt.gone))
+			assert.True(t, os.IsNotExist(err), "%s should be gone", tt.gone)
+		}
+	}
+
+	// the new directory is served up and no temp files were left behind
+	res, err := http.Get(ts.URL + "/drivec/newdir/moved.txt")
+	if assert.Nil(t, err) {
+		assert.Equal(t, http.StatusOK, res.StatusCode, "new directory wasn't served")
+	}
+	files, _ := os.ReadDir(dir)
+	for _, fl := range files {
+		assert.False(t, strings.HasPrefix(fl.Name(), "."), "%s left behind", fl.Name())
+	}
+}
+
+func Test_WriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "data.txt")
+
+	assert.Nil(t, writeFileAtomic(name, []byte("new")), "new file failed")
+	fi, err := os.Stat(name)
+	if assert.Nil(t, err, "new file missing") {
+		assert.Equal(t, os.FileMode(0644), fi.Mode().Perm(), "new file has wrong mode")
+	}
+
+	os.Chmod(name, 0640)
+	assert.Nil(t, writeFileAtomic(name, []byte("again")), "rewrite failed")
+	fi, err = os.Stat(name)
+	if assert.Nil(t, err, "rewritten file missing") {
+		assert.Equal(t, os.FileMode(0640), fi.Mode().Perm(), "rewrite changed the mode")
+	}
+}
+
+func Test_ReadOnlyDrive(t *testing.T) {
+	dir := t.TempDir()
+	rt := mux.NewRouter()
+	fs := &fileSource{src: http.Dir(dir), root: dir, drive: dir, route: "/drivec"}
+	fs.fullyWrapSource(rt, "/drivec")
+	ts := httptest.NewServer(rt)
+	defer ts.Close()
+
+	for _, method := range []string{http.MethodPut, http.MethodDelete, MethodMkDir, MethodRename} {
+		req, _ := http.NewRequest(method, ts.URL+"/drivec/data.txt", strings.NewReader("data"))
+		req.Header.Set(DestinationHeader, ts.URL+"/drivec/other.txt")
+		res, err := http.DefaultClient.Do(req)
+
+		if assert.Nil(t, err, "%s failed", method) {
+			assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode, "%s wasn't refused", method)
+		}
+	}
+
+	files, _ := os.ReadDir(dir)
+	assert.Empty(t, files, "read only drive was changed")
+}
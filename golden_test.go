@@ -0,0 +1,138 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/navionguy/basicwasm/cli"
+	"github.com/navionguy/basicwasm/gwtypes"
+	"github.com/navionguy/basicwasm/localfiles"
+	"github.com/navionguy/basicwasm/mocks"
+	"github.com/navionguy/basicwasm/object"
+	"github.com/stretchr/testify/assert"
+)
+
+// go test -run Test_Golden -update rewrites the golden files
+var update = flag.Bool("update", false, "rewrite the golden files with the current output")
+
+const (
+	progDir   = "."               // the programs that get run
+	goldenDir = "testdata/golden" // expected results, and keystrokes to type
+)
+
+// Every program in progDir gets run with the keys in NAME.keys typed
+// in, then the exit status, everything printed, what is left on the
+// screen and the data files it used get compared to NAME.golden
+func Test_Golden(t *testing.T) {
+	progs, err := filepath.Glob(filepath.Join(progDir, "*.BAS"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the programs come off drive C: the same way they do for run
+	l, err := serveDrives(progDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	for _, prog := range progs {
+		base := filepath.Base(prog)
+		name := strings.TrimSuffix(base, filepath.Ext(base))
+
+		t.Run(name, func(t *testing.T) {
+			got := runGolden(base, name, l)
+			gfile := filepath.Join(goldenDir, name+".golden")
+
+			if *update {
+				if err := os.WriteFile(gfile, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(gfile)
+			if err != nil {
+				t.Fatalf("no golden file for %s, run with -update to create it", base)
+			}
+			assert.Equal(t, string(want), got, "%s didn't match %s", base, gfile)
+		})
+	}
+}
+
+// run the program and build up the report on what it did
+func runGolden(prog string, name string, l net.Listener) string {
+	keys, _ := os.ReadFile(filepath.Join(goldenDir, name+".keys"))
+
+	// a new line in the script is the Enter key
+	keys = []byte(strings.ReplaceAll(string(keys), "\n", "\r"))
+
+	con := &mocks.MockConsole{Keys: keys}
+	env := nativeEnvironment(con, l)
+
+	rc := cli.Run(`C:\`+prog, env)
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "exit status: %d\n", rc)
+	out.WriteString("-- transcript --\n")
+	out.WriteString(goldenEscape(con.Transcript.String()))
+	out.WriteString("\n-- screen --\n")
+	out.WriteString(goldenScreen(env.Screen()))
+	out.WriteString(goldenFiles(env))
+
+	return out.String()
+}
+
+// the data files the program read or wrote
+func goldenFiles(env *object.Environment) string {
+	lf := localfiles.For(env)
+	files := lf.List(`C:\`)
+	sort.Strings(files)
+
+	var out strings.Builder
+	for _, fn := range files {
+		fmt.Fprintf(&out, "-- file %s --\n", strings.TrimSuffix(fn, `\`))
+		if fd, ok := lf.Find(fn).(gwtypes.FileData); ok {
+			out.WriteString(goldenEscape(string(*fd.Data())))
+			out.WriteString("\n")
+		}
+	}
+
+	return out.String()
+}
+
+// the screen rows, stopping after the last one with anything on it
+func goldenScreen(scrn *object.Screen) string {
+	var rows []string
+	for row := 0; row < object.ScreenRows; row++ {
+		rows = append(rows, scrn.Read(0, row, scrn.Width()))
+	}
+
+	for (len(rows) > 0) && (len(rows[len(rows)-1]) == 0) {
+		rows = rows[:len(rows)-1]
+	}
+
+	return strings.Join(rows, "\n") + "\n"
+}
+
+// make the control characters visible, keeping the line breaks
+func goldenEscape(msg string) string {
+	var out strings.Builder
+
+	for _, r := range strings.ReplaceAll(msg, "\r\n", "\n") {
+		if (r == '\n') || unicode.IsPrint(r) {
+			out.WriteRune(r)
+			continue
+		}
+		fmt.Fprintf(&out, `\x%02x`, r)
+	}
+
+	return out.String()
+}
@@ -0,0 +1,463 @@
+package gwtoken
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/navionguy/basicwasm/ast"
+	"github.com/navionguy/basicwasm/object"
+)
+
+// progStart is the memory address GW-BASIC loads the first program line into.
+// The line links stored in a tokenized file are addresses relative to it.
+const progStart = 0x126E
+
+// eofMark is the ctrl-Z GW-BASIC writes at the end of a program file
+const eofMark = 0x1A
+
+// eofMarks is how a saved program ends, GW-BASIC leaves three of them
+var eofMarks = []byte{eofMark, eofMark, eofMark}
+
+// keywords maps the text of a keyword to the bytes that represent it.
+// It is built by running every token through the decoder, so the
+// encoder and the decoder can never disagree.
+var keywords = buildKeywords()
+
+// keywords that may be followed by line numbers
+// those line numbers get stored as lineNum_TOK values
+var lineNumKeywords = map[string]bool{
+	"DELETE":  true,
+	"EDIT":    true,
+	"ELSE":    true,
+	"GOSUB":   true,
+	"GOTO":    true,
+	"LIST":    true,
+	"LLIST":   true,
+	"RENUM":   true,
+	"RESTORE": true,
+	"RESUME":  true,
+	"RETURN":  true,
+	"RUN":     true,
+	"THEN":    true,
+}
+
+// protWriter applies basic's obfuscation to the bytes of a program
+// it shares the key indexes with the protReader that removes it
+type protWriter struct {
+	protReader
+}
+
+// build the keyword map from the decoder tables
+// the token pages are processed in order, so FIX ends up
+// with its 0xff page value
+func buildKeywords() map[string][]byte {
+	kw := make(map[string][]byte)
+
+	for _, pg := range [][]byte{{}, {fd_TOK}, {fe_TOK}, {ff_TOK}} {
+		for tok := 0x81; tok < fd_TOK; tok++ {
+			code := append(append([]byte{}, pg...), byte(tok))
+			rdr := progRdr{src: bufio.NewReader(bytes.NewReader(code))}
+			val := rdr.readToken()
+
+			if len(val) > 0 {
+				kw[val] = code
+			}
+		}
+	}
+
+	return kw
+}
+
+// Encode builds the tokenized program image of the code.
+// Each line is crunched from the text it was typed in as, the same way
+// GW-BASIC crunches a line as it is typed in, so every statement type is
+// covered without a second writer for each kind of node and the spacing
+// comes out the way it went in.
+func Encode(cd *ast.Code) []byte {
+	return Tokenize(cd.Source())
+}
+
+// Tokenize crunches the lines of a program listing into a GW-BASIC
+// tokenized program image, ready to be written to a file
+func Tokenize(lines []string) []byte {
+	img := []byte{TOKEN_FILE}
+	addr := progStart
+
+	for _, line := range lines {
+		num, text, ok := splitLine(line)
+		if !ok {
+			continue
+		}
+
+		bts := crunchLine(text)
+
+		// link to the next line, line number, tokens and the end of line
+		addr += len(bts) + 5
+		img = appendInt(img, addr)
+		img = appendInt(img, num)
+		img = append(img, bts...)
+		img = append(img, eol_TOK)
+	}
+
+	img = append(img, eol_TOK, eol_TOK)
+	return append(img, eofMarks...)
+}
+
+// Protect converts a tokenized program image into the protected format.
+// The program bytes are encrypted with the inverse of the cipher protReader
+// removes, the trailing ctrl-Zs are left alone.
+func Protect(img []byte) []byte {
+	out := []byte{PROTECTED_FILE}
+
+	if len(img) == 0 {
+		return out
+	}
+
+	body := bytes.TrimRight(img[1:], string([]byte{eofMark}))
+
+	pw := protWriter{}
+	for _, bt := range body {
+		out = append(out, pw.encryptByte(bt))
+	}
+
+	return append(out, img[1+len(body):]...)
+}
+
+// encryptByte reverses each step of decryptByte
+func (pw *protWriter) encryptByte(bt byte) byte {
+	bt = (((bt - addBytesKey2[pw.addKey2Index]) ^ xorBytesKey2[pw.xorKey2Index]) ^ xorBytesKey1[pw.xorKey1Index]) + subBytesKey1[pw.subKey1Index]
+
+	pw.subKey1Index = pw.advIndex(pw.subKey1Index, 11)
+	pw.xorKey1Index = pw.advIndex(pw.xorKey1Index, 11)
+	pw.xorKey2Index = pw.advIndex(pw.xorKey2Index, 13)
+	pw.addKey2Index = pw.advIndex(pw.addKey2Index, 13)
+
+	return bt
+}
+
+// split the line number off the front of a listing line
+// the space the lister puts after the line number isn't stored
+func splitLine(line string) (int, string, bool) {
+	i := 0
+	for i < len(line) && isDigit(line[i]) {
+		i++
+	}
+
+	num, err := strconv.Atoi(line[:i])
+	if err != nil {
+		return 0, "", false
+	}
+
+	return num, strings.TrimPrefix(line[i:], " "), true
+}
+
+// crunchLine converts the text of one line into tokens
+func crunchLine(text string) []byte {
+	var out []byte
+	lineNum := false // true when a line number may come next
+
+	for i := 0; i < len(text); {
+		ch := text[i]
+
+		switch {
+		case ch == '"':
+			end := strings.IndexByte(text[i+1:], '"')
+			if end < 0 {
+				end = len(text)
+			} else {
+				end += i + 2
+			}
+			out = append(out, object.EncodeBytes(text[i:end])...)
+			i = end
+			lineNum = false
+		case ch == '\'':
+			// the short form REM is stored as ":REM'"
+			out = append(out, ':', rem_TOK, ticrem_TOK)
+			out = append(out, object.EncodeBytes(text[i+1:])...)
+			i = len(text)
+		case isLetter(ch):
+			var bts []byte
+			bts, i, lineNum = crunchWord(text, i)
+			out = append(out, bts...)
+		case isDigit(ch):
+			var bts []byte
+			bts, i = crunchNumber(text, i, lineNum)
+			out = append(out, bts...)
+		case ch == '&':
+			var bts []byte
+			bts, i = crunchAmpersand(text, i)
+			out = append(out, bts...)
+			lineNum = false
+		case ch >= utf8.RuneSelf:
+			r, sz := utf8.DecodeRuneInString(text[i:])
+			out = append(out, object.EncodeBytes(string(r))...)
+			i += sz
+			lineNum = false
+		default:
+			if tok, ok := keywords[string(ch)]; ok {
+				out = append(out, tok...)
+			} else {
+				out = append(out, ch)
+			}
+			// commas and ranges can be part of a list of line numbers
+			if (ch != ' ') && (ch != ',') && (ch != '-') {
+				lineNum = false
+			}
+			i++
+		}
+	}
+
+	return out
+}
+
+// crunchWord handles a name starting at text[i]
+// if the whole name is a keyword it gets replaced by its token
+// returns the bytes, the new position and if a line number may follow
+func crunchWord(text string, i int) ([]byte, int, bool) {
+	j := i
+	for j < len(text) && (isLetter(text[j]) || isDigit(text[j]) || text[j] == '.') {
+		j++
+	}
+	word := strings.ToUpper(text[i:j])
+
+	// some keywords carry a '$' or '(' with them
+	if j < len(text) && (text[j] == '$' || text[j] == '(') {
+		if _, ok := keywords[word+text[j:j+1]]; ok {
+			word = word + text[j:j+1]
+			j++
+		}
+	}
+
+	tok, ok := keywords[word]
+	if !ok {
+		// names are kept in upper case
+		if strings.HasPrefix(word, "FN") && len(word) > 2 {
+			// user defined function call
+			return append([]byte{fn_TOK}, word[2:]...), j, false
+		}
+		return []byte(word), j, false
+	}
+
+	switch word {
+	case "ELSE":
+		// ELSE is always stored with a leading colon
+		return append([]byte{':'}, tok...), j, true
+	case "REM":
+		// everything else on the line is the remark
+		return append(append([]byte{}, tok...), object.EncodeBytes(text[j:])...), len(text), false
+	case "DATA":
+		// data is stored as is up to the end of the statement
+		end := dataEnd(text, j)
+		return append(append([]byte{}, tok...), object.EncodeBytes(text[j:end])...), end, false
+	}
+
+	return tok, j, lineNumKeywords[word]
+}
+
+// find the end of a DATA statement, skipping over quoted strings
+func dataEnd(text string, i int) int {
+	quoted := false
+
+	for ; i < len(text); i++ {
+		if text[i] == '"' {
+			quoted = !quoted
+		}
+		if (text[i] == ':') && !quoted {
+			break
+		}
+	}
+
+	return i
+}
+
+// crunchNumber stores a numeric constant starting at text[i]
+// returns the bytes and the new position
+func crunchNumber(text string, i int, lineNum bool) ([]byte, int) {
+	j := numberEnd(text, i)
+	lit := text[i:j]
+
+	if num, err := strconv.Atoi(lit); err == nil {
+		switch {
+		case lineNum && (num <= 65529):
+			return appendInt([]byte{lineNum_TOK}, num), j
+		case num <= 10:
+			return []byte{byte(const0_TOK + num)}, j
+		case num <= 0xff:
+			return []byte{int1Byte_TOK, byte(num)}, j
+		case num <= 32767:
+			return appendInt([]byte{int2Byte_TOK}, num), j
+		}
+	}
+
+	// anything else is floating point
+	if bts := crunchFloat(lit); bts != nil {
+		return bts, j
+	}
+
+	return []byte(lit), j
+}
+
+// find the end of a numeric constant
+func numberEnd(text string, i int) int {
+	for i < len(text) && (isDigit(text[i]) || text[i] == '.') {
+		i++
+	}
+
+	if i < len(text) && strings.ContainsRune("EeDd", rune(text[i])) {
+		i++
+		if i < len(text) && (text[i] == '+' || text[i] == '-') {
+			i++
+		}
+		for i < len(text) && isDigit(text[i]) {
+			i++
+		}
+	}
+
+	if i < len(text) && (text[i] == '!' || text[i] == '#') {
+		i++
+	}
+
+	return i
+}
+
+// crunchFloat stores a floating point constant in MS Binary Format
+// a '#' suffix, a D exponent or more than seven digits make it double precision
+// returns nil if the constant can't be stored
+func crunchFloat(lit string) []byte {
+	src := strings.ToUpper(lit)
+	double := false
+
+	switch {
+	case strings.HasSuffix(src, "#"):
+		src = strings.TrimSuffix(src, "#")
+		double = true
+	case strings.HasSuffix(src, "!"):
+		src = strings.TrimSuffix(src, "!")
+	case strings.Contains(src, "D"):
+		src = strings.Replace(src, "D", "E", 1)
+		double = true
+	default:
+		double = sigDigits(strings.SplitN(src, "E", 2)[0]) > 7
+	}
+
+	val, err := strconv.ParseFloat(src, 64)
+	if err != nil {
+		return nil
+	}
+
+	if double {
+		if bts, ok := mbfDouble(val); ok {
+			return append([]byte{flt8Byte_TOK}, bts...)
+		}
+		return nil
+	}
+
+	if bts, ok := mbfSingle(val); ok {
+		return append([]byte{flt4Byte_TOK}, bts...)
+	}
+	return nil
+}
+
+// mbfSingle converts to a 4 byte MS Binary Format value
+// the reverse of read4ByteFloat
+func mbfSingle(val float64) ([]byte, bool) {
+	bts := make([]byte, 4)
+	binary.LittleEndian.PutUint32(bts, math.Float32bits(float32(val)))
+
+	sign := bts[3] & 0x80
+	ieeeExp := (bts[3] << 1) | (bts[2] >> 7)
+
+	if ieeeExp == 0 {
+		return []byte{0x00, 0x00, 0x00, 0x00}, true
+	}
+
+	// MBF can't hold the top of the IEEE range, or infinity
+	if ieeeExp > 253 {
+		return nil, false
+	}
+
+	bts[3] = ieeeExp + 2
+	bts[2] = sign | (bts[2] & 0x7f)
+
+	return bts, true
+}
+
+// mbfDouble converts to an 8 byte MS Binary Format value
+// the reverse of read8ByteFloat
+func mbfDouble(val float64) ([]byte, bool) {
+	bits := math.Float64bits(val)
+	ieeeExp := int(bits>>52) & 0x7ff
+	mbfExp := ieeeExp - 1023 + 129
+
+	if (ieeeExp == 0) || (mbfExp <= 0) {
+		return make([]byte, 8), true
+	}
+
+	if mbfExp > 0xff {
+		return nil, false
+	}
+
+	// MBF has three more bits of mantissa than IEEE
+	bts := make([]byte, 8)
+	binary.LittleEndian.PutUint64(bts, (bits&(1<<52-1))<<3)
+	bts[6] |= byte(bits>>56) & 0x80
+	bts[7] = byte(mbfExp)
+
+	return bts, true
+}
+
+// crunchAmpersand stores hex (&H) and octal (&O or just &) constants
+// they are kept as 16 bit values
+func crunchAmpersand(text string, i int) ([]byte, int) {
+	tok, digits, base := byte(oct_TOK), "01234567", 8
+	j := i + 1
+
+	if j < len(text) {
+		switch text[j] {
+		case 'H', 'h':
+			tok, digits, base = hex_TOK, "0123456789ABCDEFabcdef", 16
+			j++
+		case 'O', 'o':
+			j++
+		}
+	}
+
+	k := j
+	for k < len(text) && strings.IndexByte(digits, text[k]) >= 0 {
+		k++
+	}
+
+	val, err := strconv.ParseUint(text[j:k], base, 16)
+	if err != nil {
+		// not a constant, just an ampersand
+		return []byte{'&'}, i + 1
+	}
+
+	return appendInt([]byte{tok}, int(val)), k
+}
+
+// store a 16 bit value in little endian order
+func appendInt(bts []byte, val int) []byte {
+	return append(bts, byte(val&0xff), byte((val>>8)&0xff))
+}
+
+func isLetter(ch byte) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z'
+}
+
+func isDigit(ch byte) bool {
+	return '0' <= ch && ch <= '9'
+}
+
+// count the significant digits in a mantissa
+func sigDigits(mant string) int {
+	mant = strings.TrimLeft(mant, "-")
+	mant = strings.Replace(mant, ".", "", 1)
+
+	return len(strings.TrimLeft(mant, "0"))
+}
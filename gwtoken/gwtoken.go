@@ -5,6 +5,8 @@ import (
 	"encoding/binary"
 	"fmt"
 	"math"
+	"strconv"
+	"strings"
 
 	"github.com/navionguy/basicwasm/lexer"
 	"github.com/navionguy/basicwasm/object"
@@ -202,10 +204,8 @@ func (rdr *progRdr) readToken() string {
 		val = "\"" + rdr.readString()
 	case color_TOK:
 		val = "COLOR"
-	case const0_TOK, const1_TOK, const2_TOK, const3_TOK, const4_TOK, const5_TOK, const6_TOK, const7_TOK:
+	case const0_TOK, const1_TOK, const2_TOK, const3_TOK, const4_TOK, const5_TOK, const6_TOK, const7_TOK, const8_TOK, const9_TOK, const10_TOK:
 		val = fmt.Sprintf("%d", int(tok-const0_TOK))
-	case const8_TOK, const9_TOK: // discontinuity in map
-		val = fmt.Sprintf("%d", int(tok-const0_TOK-1))
 	case cont_TOK:
 		val = "CONT"
 	case csrlin_TOK:
@@ -755,7 +755,7 @@ func (rdr *progRdr) read8ByteFloat() string {
 
 	flt := math.Float64frombits(binary.LittleEndian.Uint64(bts))
 
-	return fmt.Sprintf("%E", flt)
+	return formatFloat(flt, 64)
 }
 
 func (rdr *progRdr) read4ByteFloat() string {
@@ -790,7 +790,66 @@ func (rdr *progRdr) read4ByteFloat() string {
 	bts[2] = (bts[2] & 0x7f) | (ieee_exp << 7)
 	flt := math.Float32frombits(binary.LittleEndian.Uint32(bts))
 
-	return fmt.Sprintf("%E", flt)
+	return formatFloat(float64(flt), 32)
+}
+
+// formatFloat gives the text for a floating point constant the way
+// GW-BASIC lists it.  A renumbered line is crunched again from this text
+// when the program is saved, so it has to read back as the same value
+// and precision, %E kept six decimals and always said single precision.
+// Single precision is good for seven digits, a constant with more
+// digits is read back as double precision.
+// Type suffixes keep short values from changing precision.
+func formatFloat(val float64, bitSize int) string {
+	dbl := bitSize == 64
+	digits := 7
+	expChar := "E"
+	if dbl {
+		digits = 16
+		expChar = "D"
+	}
+
+	mant, exp := floatDigits(strconv.FormatFloat(val, 'e', -1, bitSize))
+
+	if sigDigits(mant) > digits {
+		// more than single precision can show, round it off
+		mant, exp = floatDigits(strconv.FormatFloat(val, 'e', digits-1, bitSize))
+		val, _ = strconv.ParseFloat(fmt.Sprintf("%se%d", mant, exp), 64)
+		bitSize = 64
+	}
+
+	if (exp < -2) || (exp >= digits) {
+		return fmt.Sprintf("%s%s%+03d", mant, expChar, exp)
+	}
+
+	txt := strconv.FormatFloat(val, 'f', -1, bitSize)
+	whole := !strings.Contains(txt, ".")
+
+	switch {
+	case dbl && whole && (sigDigits(mant) <= 7):
+		// 5# would come back as an integer
+		return fmt.Sprintf("%s%s%+03d", mant, expChar, exp)
+	case dbl && (sigDigits(mant) <= 7):
+		return txt + "#"
+	case !dbl && whole && (val <= 32767):
+		return txt + "!"
+	}
+
+	return txt
+}
+
+// split the mantissa and exponent out of a value formatted with 'e'
+// the mantissa loses any trailing zeros
+func floatDigits(txt string) (string, int) {
+	prts := strings.SplitN(txt, "e", 2)
+	exp, _ := strconv.Atoi(prts[1])
+
+	mant := prts[0]
+	if strings.Contains(mant, ".") {
+		mant = strings.TrimRight(strings.TrimRight(mant, "0"), ".")
+	}
+
+	return mant, exp
 }
 
 // protReader hides an inner bufio.Reader and
@@ -809,7 +868,8 @@ func (pr *protReader) Peek(n int) ([]byte, error) {
 	xorKey2 := pr.xorKey2Index
 	addKey2 := pr.addKey2Index
 
-	bt = pr.decryptBytes(bt)
+	// decrypt a copy, the peeked bytes still belong to the reader
+	bt = pr.decryptBytes(append([]byte{}, bt...))
 
 	pr.subKey1Index = savSub1
 	pr.xorKey1Index = xorKey1
@@ -898,8 +958,9 @@ const (
 	const5_TOK   = 0x16
 	const6_TOK   = 0x17
 	const7_TOK   = 0x18
-	const8_TOK   = 0x1A
-	const9_TOK   = 0x1B
+	const8_TOK   = 0x19
+	const9_TOK   = 0x1A
+	const10_TOK  = 0x1B
 	int2Byte_TOK = 0x1C
 	flt4Byte_TOK = 0x1d
 	flt8Byte_TOK = 0x1f
@@ -3,10 +3,13 @@ package gwtoken
 import (
 	"bufio"
 	"bytes"
+	"os"
 	"testing"
 
+	"github.com/navionguy/basicwasm/lexer"
 	"github.com/navionguy/basicwasm/mocks"
 	"github.com/navionguy/basicwasm/object"
+	"github.com/navionguy/basicwasm/parser"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -262,8 +265,9 @@ func Test_BaseTokens(t *testing.T) {
 		{inp: []byte{0x16}, exp: "5", tok: const5_TOK},
 		{inp: []byte{0x17}, exp: "6", tok: const6_TOK},
 		{inp: []byte{0x18}, exp: "7", tok: const7_TOK},
-		{inp: []byte{0x1a}, exp: "8", tok: const8_TOK},
-		{inp: []byte{0x1b}, exp: "9", tok: const9_TOK},
+		{inp: []byte{0x19}, exp: "8", tok: const8_TOK},
+		{inp: []byte{0x1a}, exp: "9", tok: const9_TOK},
+		{inp: []byte{0x1b}, exp: "10", tok: const10_TOK},
 		{inp: []byte{0x1c, 0x72, 0x01}, exp: "370", tok: int2Byte_TOK},
 		{inp: []byte{0x1d}, exp: "0", tok: flt4Byte_TOK},
 		{inp: []byte{0x1f}, exp: "0", tok: flt8Byte_TOK},
@@ -530,9 +534,14 @@ func Test_Read4ByteFloat(t *testing.T) {
 	}{
 		{inp: []byte{0x00, 0x00}, exp: "0"},
 		{inp: []byte{0x00, 0x00, 0x00, 0x00}, exp: "0"},
-		{inp: []byte{0x09, 0xF6, 0x45, 0x71}, exp: "2.359880E-05"},
-		{inp: []byte{0x40, 0xF6, 0x45, 0x71}, exp: "2.359890E-05"},
-		{inp: []byte{0x2F, 0xFD, 0x6B, 0x88}, exp: "2.359890E+02"},
+		{inp: []byte{0x09, 0xF6, 0x45, 0x71}, exp: "2.35988E-05"},
+		{inp: []byte{0x40, 0xF6, 0x45, 0x71}, exp: "2.35989E-05"},
+		{inp: []byte{0x2F, 0xFD, 0x6B, 0x88}, exp: "235.989"},
+		{inp: []byte{0x00, 0x00, 0x00, 0x81}, exp: "1!"},
+		{inp: []byte{0x00, 0x40, 0x1C, 0x90}, exp: "40000"},
+		{inp: []byte{0xF9, 0x02, 0x15, 0xA2}, exp: "1E+10"},
+		{inp: []byte{0x6F, 0x12, 0x03, 0x77}, exp: "1E-03"},
+		{inp: []byte{0x0A, 0xD7, 0x23, 0x7A}, exp: "0.01"},
 	}
 
 	for _, tt := range tests {
@@ -549,9 +558,10 @@ func Test_Read8ByteFloat(t *testing.T) {
 	}{
 		{inp: []byte{0x00, 0x00}, exp: "0"},
 		{inp: []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, exp: "0"},
-		{inp: []byte{0xB1, 0xAE, 0x1C, 0x84, 0x8C, 0xE0, 0x12, 0x6D}, exp: "1.094320E-06"},
-		{inp: []byte{0x2B, 0xD4, 0xF2, 0x79, 0x40, 0xF6, 0x45, 0x71}, exp: "2.359890E-05"},
-		{inp: []byte{0x77, 0xBE, 0x9F, 0x1A, 0x2F, 0xFD, 0x6B, 0x88}, exp: "2.359890E+02"},
+		{inp: []byte{0xB1, 0xAE, 0x1C, 0x84, 0x8C, 0xE0, 0x12, 0x6D}, exp: "1.09432D-06"},
+		{inp: []byte{0x2B, 0xD4, 0xF2, 0x79, 0x40, 0xF6, 0x45, 0x71}, exp: "2.35989D-05"},
+		{inp: []byte{0x77, 0xBE, 0x9F, 0x1A, 0x2F, 0xFD, 0x6B, 0x88}, exp: "235.989#"},
+		{inp: []byte{0xC2, 0x68, 0x21, 0xA2, 0xDA, 0x0F, 0x49, 0x82}, exp: "3.141592653589793"},
 	}
 
 	for _, tt := range tests {
@@ -560,3 +570,140 @@ func Test_Read8ByteFloat(t *testing.T) {
 		assert.Equal(t, tt.exp, res, "got %s expected %s", res, tt.exp)
 	}
 }
+
+func Test_Tokenize(t *testing.T) {
+	tests := []struct {
+		inp []string
+		exp []byte
+	}{
+		{inp: []string{}, exp: []byte{0xFF, 0x00, 0x00, 0x1A, 0x1A, 0x1A}},
+		{inp: []string{`10 PRINT "Hello"`, `20 Y = 150`, `30 Z = 48`, `bogus`},
+			exp: []byte{0xFF, 0x7C, 0x12, 0x0A, 0x00, 0x91, 0x20, 0x22, 0x48, 0x65, 0x6C,
+				0x6C, 0x6F, 0x22, 0x00, 0x87, 0x12, 0x14, 0x00, 0x59, 0x20, 0xE7,
+				0x20, 0x0F, 0x96, 0x00, 0x92, 0x12, 0x1E, 0x00, 0x5A, 0x20, 0xE7,
+				0x20, 0x0F, 0x30, 0x00, 0x00, 0x00, 0x1A, 0x1A, 0x1A}},
+		{inp: []string{`10 GOTO 10`}, exp: []byte{0xFF, 0x78, 0x12, 0x0A, 0x00, 0x89, 0x20, 0x0E, 0x0A, 0x00, 0x00, 0x00, 0x00, 0x1A, 0x1A, 0x1A}},
+		{inp: []string{`10 X = 1000`}, exp: []byte{0xFF, 0x7A, 0x12, 0x0A, 0x00, 0x58, 0x20, 0xE7, 0x20, 0x1C, 0xE8, 0x03, 0x00, 0x00, 0x00, 0x1A, 0x1A, 0x1A}},
+		{inp: []string{`10 'hi`}, exp: []byte{0xFF, 0x78, 0x12, 0x0A, 0x00, 0x3A, 0x8F, 0xD9, 0x68, 0x69, 0x00, 0x00, 0x00, 0x1A, 0x1A, 0x1A}},
+		{inp: []string{`10 X = 10`}, exp: []byte{0xFF, 0x78, 0x12, 0x0A, 0x00, 0x58, 0x20, 0xE7, 0x20, 0x1B, 0x00, 0x00, 0x00, 0x1A, 0x1A, 0x1A}},
+		{inp: []string{`10 X = 1.5`}, exp: []byte{0xFF, 0x7C, 0x12, 0x0A, 0x00, 0x58, 0x20, 0xE7, 0x20, 0x1D, 0x00, 0x00, 0x40, 0x81, 0x00, 0x00, 0x00, 0x1A, 0x1A, 0x1A}},
+		{inp: []string{`10 X = 1.5#`}, exp: []byte{0xFF, 0x80, 0x12, 0x0A, 0x00, 0x58, 0x20, 0xE7, 0x20, 0x1F, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x40, 0x81, 0x00, 0x00, 0x00, 0x1A, 0x1A, 0x1A}},
+		{inp: []string{`10 X = &H1F`}, exp: []byte{0xFF, 0x7A, 0x12, 0x0A, 0x00, 0x58, 0x20, 0xE7, 0x20, 0x0C, 0x1F, 0x00, 0x00, 0x00, 0x00, 0x1A, 0x1A, 0x1A}},
+		{inp: []string{`10 X = &777`}, exp: []byte{0xFF, 0x7A, 0x12, 0x0A, 0x00, 0x58, 0x20, 0xE7, 0x20, 0x0B, 0xFF, 0x01, 0x00, 0x00, 0x00, 0x1A, 0x1A, 0x1A}},
+		{inp: []string{`10 X$ = MKI$(X)`}, exp: []byte{0xFF, 0x7D, 0x12, 0x0A, 0x00, 0x58, 0x24, 0x20, 0xE7, 0x20, 0xFD, 0x84, 0x28, 0x58, 0x29, 0x00, 0x00, 0x00, 0x1A, 0x1A, 0x1A}},
+	}
+
+	for _, tt := range tests {
+		img := Tokenize(tt.inp)
+
+		assert.Equal(t, tt.exp, img, "Tokenize(%v) didn't match", tt.inp)
+	}
+}
+
+func Test_Encode(t *testing.T) {
+	env := object.NewTermEnvironment(mocks.MockTerm{})
+	parser.New(lexer.New("10 PRINT \"Hello\"\n20 Y = 150\n30 Z = 48")).ParseProgram(env)
+
+	img := Encode(env.ListIter())
+	assert.Equal(t, byte(TOKEN_FILE), img[0], "Encode didn't build a tokenized file")
+
+	// and it loads back the same
+	env2 := object.NewTermEnvironment(mocks.MockTerm{})
+	ParseFile(bufio.NewReader(bytes.NewReader(img)), env2)
+	assert.Equal(t, env.ListIter().Listing(0, 100), env2.ListIter().Listing(0, 100), "Encode didn't round trip")
+}
+
+func Test_EncodeFile(t *testing.T) {
+	// a program saved by GW-BASIC comes back byte for byte
+	prg, err := os.ReadFile("../WRITER.BAS")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := object.NewTermEnvironment(mocks.MockTerm{})
+	ParseFile(bufio.NewReader(bytes.NewReader(prg)), env)
+	assert.Equal(t, prg, Encode(env.ListIter()), "WRITER.BAS didn't encode the same")
+
+	// typed lines keep their spacing, names go to upper case
+	env = object.NewTermEnvironment(mocks.MockTerm{})
+	parser.New(lexer.New("10 x=500\n  20 PRINT  x\r\n")).ParseProgram(env)
+	assert.Equal(t, Tokenize([]string{"10 X=500", "20 PRINT  X"}), Encode(env.ListIter()), "typed lines lost their spacing")
+}
+
+func Test_TokenizeRoundTrip(t *testing.T) {
+	tests := []string{
+		`10 PRINT "Hello"`,
+		`20 IF X > 5 THEN 100 ELSE 200`,
+		`30 ON X GOTO 10, 20, 30`,
+		`40 A$ = CHR$(65) + MID$(B$, 2, 3)`,
+		`50 DATA 1,2,"three": PRINT`,
+		`60 X = 3.14: Y = 1E+10`,
+		`70 'a comment`,
+		`80 REM remark: PRINT`,
+		`90 FIELD #1, 20 AS N$`,
+		`100 Z = FNA(3)`,
+		`110 PRINT SPC(5); TAB(10)`,
+		`120 TOTAL = 32000 + 300 - INT(7)`,
+		`130 PRINT "╠═╣"`,
+		`140 X = &H1F + &O17 - &HFFFF`,
+		`150 A# = 3.141592653589793: B = 2.5# + 4D+04 + 1D-05: C = 1!`,
+		`160 X = 40000 + 12345678 + 1E+10 + 0.01 + 10`,
+		`170 X$ = MKI$(10): PUT #1, 2: Y = LOF(1): ON X GOSUB 100, 200`,
+	}
+
+	for _, tt := range tests {
+		img := Tokenize([]string{tt})
+		rdr := progRdr{src: bufio.NewReader(bytes.NewReader(img[1:]))}
+		rdr.readLineHeader()
+		rdr.readLine()
+
+		assert.Equal(t, tt, rdr.lineInp, "round trip of %s failed", tt)
+
+		// and again through the protected format
+		img = Protect(img)
+		rdr = progRdr{src: &protReader{src: bufio.NewReader(bytes.NewReader(img[1:]))}}
+		rdr.readLineHeader()
+		rdr.readLine()
+
+		assert.Equal(t, tt, rdr.lineInp, "protected round trip of %s failed", tt)
+	}
+}
+
+func Test_TokenizeReencode(t *testing.T) {
+	tests := []string{
+		`10 X = .5 + 0.001 + 1.0 + 123456789 + 1.5E3 + 2.5# + 3!`,
+		`20 X = &hff + &o7 + &17 + &`,
+		`30 IF X THEN PRINT "Y" ELSE GOSUB 1000`,
+		`40 X = 1E+40 + 1D+300`,
+	}
+
+	for _, tt := range tests {
+		img := Tokenize([]string{tt})
+		rdr := progRdr{src: bufio.NewReader(bytes.NewReader(img[1:]))}
+		rdr.readLineHeader()
+		rdr.readLine()
+
+		assert.Equal(t, img, Tokenize([]string{rdr.lineInp}), "re-encoding %s as %s didn't match", tt, rdr.lineInp)
+	}
+}
+
+func Test_Protect(t *testing.T) {
+	tests := []struct {
+		inp []byte
+		exp []byte
+	}{
+		{inp: []byte{}, exp: []byte{0xFE}},
+		{inp: []byte{0xFF, 0x82, 0x12, 0x0a, 0x00, 0x91, 0x20, 0x22, 0x48, 0x65, 0x6c, 0x6c, 0x6f, 0x20, 0x57, 0x6f, 0x72, 0x6c, 0x64, 0x22, 0x00, 0x00, 0x00, 0x1A},
+			exp: []byte{0xFE, 0xCD, 0xA9, 0xBF, 0x54, 0xE2, 0x12, 0xBD, 0x59, 0x20, 0x65, 0x0D, 0x8F, 0xA2, 0x30, 0x98, 0xD3, 0x3E, 0xD3, 0xF1, 0xE6, 0x13, 0xA4, 0x1A}},
+	}
+
+	for _, tt := range tests {
+		img := Protect(tt.inp)
+
+		assert.Equal(t, tt.exp, img, "Protect(%v) didn't match", tt.inp)
+	}
+
+	// protecting what Tokenize builds must match too, ctrl-Zs and all
+	img := Protect(Tokenize([]string{`10 PRINT "Hello World"`}))
+	assert.Equal(t, append(tests[1].exp, eofMark, eofMark), img, "Protect(Tokenize()) didn't match")
+}
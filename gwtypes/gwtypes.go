@@ -35,4 +35,24 @@ type AnOpenFile interface {
 	AccessMode() AccessMode // the access mode for this open file
 	FQFN() string           // the fully qualified (drive:path/filename.ext) for the file
 	LockMode() LockMode     // the lock mode for this open file
+	EOF() bool              // true if there is nothing left to read
+	Loc() int               // current byte position in the file
+	Lof() int               // length of the file in bytes
+	PeekByte() (byte, bool) // look at the next byte without consuming it, false at end of file
+	GetByte() (byte, bool)  // read the next byte, false at end of file
+	Write([]byte)           // write bytes at the current position
+}
+
+// RandomFile supports the fixed length records used by FIELD, GET and PUT
+type RandomFile interface {
+	RecLen() int       // length of each record
+	Record() []byte    // the record buffer for the file
+	CurRecord() int    // the last record read or written
+	GetRecord(rec int) // read record rec into the record buffer
+	PutRecord(rec int) // write the record buffer out as record rec
+}
+
+// FileData gives access to the in-memory contents of a data file
+type FileData interface {
+	Data() *[]byte // the contents of the file
 }
@@ -1,54 +1,67 @@
 package keybuffer
 
 import (
-	"encoding/hex"
+	"context"
 	"errors"
 	"fmt"
-	"time"
+	"io"
+	"sync"
+	"sync/atomic"
 
 	"github.com/navionguy/basicwasm/ast"
 )
 
+// editing keys are passed along the way GW-BASIC reports them,
+// a NUL followed by the keyboard scan code
 const (
-	f1Key  = "1b4f50" // the escape sequences received for all 12 function keys
-	f2Key  = "1b4f51"
-	f3Key  = "1b4f52"
-	f4Key  = "1b4f53"
-	f5Key  = "1b5b31357e"
-	f6Key  = "1b5b31377e"
-	f7Key  = "1b5b31387e"
-	f8Key  = "1b5b31397e"
-	f9Key  = "1b5b32307e"
-	f10Key = "1b5b32317e"
-	f11Key = "1b5b41" // cursor up
-	f12Key = "1b5b44" // cursor left
-	f13Key = "1b5b43" // cursor right
-	f14Key = "1b5b42" // cursor down
+	ExtendedKey  = 0x00
+	ScanHome     = 0x47
+	ScanUp       = 0x48
+	ScanLeft     = 0x4b
+	ScanRight    = 0x4d
+	ScanEnd      = 0x4f
+	ScanDown     = 0x50
+	ScanInsert   = 0x52
+	ScanDelete   = 0x53
+	ScanCtrlEnd  = 0x75
+	ScanCtrlHome = 0x77
 )
 
+// KeyBuffer holds the keystrokes for one session, keys get saved
+// from the front-end's goroutine and read by the interpreter
 type KeyBuffer struct {
 	KeySettings *ast.KeySettings
 	keycodes    chan ([]byte)
 	inp         []byte
 	ind         int
-	sig_break   bool
-	spcKeys     map[string]string
+	sig_break   int32 // set to one when a ctrl-c comes in
+	expand      bool  // function keys type their macros
+	traps       keyTraps
+	closer      sync.Once
 }
 
-var kbuff KeyBuffer
+// New creates an empty key buffer
+func New() *KeyBuffer {
+	return &KeyBuffer{keycodes: make(chan []byte, 20)}
+}
 
-func GetKeyBuffer() *KeyBuffer {
-	fkeys := []string{f1Key, f2Key, f3Key, f4Key, f5Key, f6Key, f7Key, f8Key, f9Key, f10Key, f11Key, f12Key, f13Key, f14Key}
+// SetKeySettings gives the buffer the function key macros
+// and the user defined keys for ON KEY
+func (buff *KeyBuffer) SetKeySettings(ks *ast.KeySettings) {
+	buff.KeySettings = ks
 
-	if kbuff.spcKeys == nil {
-		kbuff.spcKeys = make(map[string]string)
+	buff.traps.mtx.Lock()
+	defer buff.traps.mtx.Unlock()
+	buff.traps.user = nil
+	if ks != nil {
+		buff.traps.user = append(buff.traps.user, ks.OnKeys...)
 	}
+}
 
-	for i, key := range fkeys {
-		ind := fmt.Sprintf("F%d", i+1)
-		kbuff.spcKeys[key] = ind
-	}
-	return &kbuff
+// ExpandMacros turns typing of the function key macros on or off
+// it is on at the command prompt and for INPUT, off for INKEY$
+func (buff *KeyBuffer) ExpandMacros(on bool) {
+	buff.expand = on
 }
 
 // SaveKeyStroke saves all the bytes generated by a keystroke
@@ -59,9 +72,13 @@ func (buff *KeyBuffer) SaveKeyStroke(key []byte) {
 		buff.keycodes = make(chan []byte, 20)
 	}
 
+	// keys trapped by ON KEY go to the program, not the buffer
+	if buff.checkForTrappedKey(key) {
+		return
+	}
+
 	// check for an escape sequence, like a function key
 	if (len(key) > 1) && (key[0] == 0x1b) {
-		// go see if maps to something have a macro set for
 		key = buff.checkForSpecialKeys(key)
 	}
 
@@ -81,35 +98,53 @@ func (buff *KeyBuffer) SaveKeyStroke(key []byte) {
 func (buff *KeyBuffer) checkForCtrlC(inp []byte) {
 	for _, k := range inp {
 		if k == 0x03 {
-			buff.sig_break = true
+			atomic.StoreInt32(&buff.sig_break, 1)
 		}
 	}
 }
 
 // check for special keys
 func (buff *KeyBuffer) checkForSpecialKeys(inp []byte) []byte {
-	if buff.KeySettings == nil {
-		// no macros have been set
-		return []byte("")
+	// keys without a character go thru as extended codes
+	// function keys stay keys until they are read
+	// so the macro only gets typed where it's wanted
+	if flags, scan, ok := keyScan(inp); ok {
+		if code, ok := extendedCode(flags, scan); ok {
+			return []byte{ExtendedKey, code}
+		}
+	}
+
+	return []byte("")
+}
+
+// is it one of F1 thru F10
+func isFuncKey(scan byte) bool {
+	return (scan >= ScanF1) && (scan < ScanF1+10)
+}
+
+// checkForMacro swaps a function key for its macro
+// if macros are being typed and the key has one
+func (buff *KeyBuffer) checkForMacro(inp []byte) []byte {
+	if !buff.expand || (buff.KeySettings == nil) || (len(inp) != 2) || (inp[0] != ExtendedKey) || !isFuncKey(inp[1]) {
+		return inp
 	}
 
-	// convert the bytes to a string for checking
-	a := kbuff.spcKeys[hex.EncodeToString(inp)]
-	mac := kbuff.KeySettings.Keys[a]
+	mac, ok := buff.KeySettings.Keys[fmt.Sprintf("F%d", inp[1]-ScanF1+1)]
+	if !ok {
+		return inp
+	}
 
-	// map the key label to the string to send and return it
 	return []byte(mac)
 }
 
 // has a Ctrl-C been entered
 func (buff *KeyBuffer) BreakSeen() bool {
-	time.Sleep(15 * time.Millisecond)
-	return buff.sig_break
+	return atomic.LoadInt32(&buff.sig_break) == 1
 }
 
 // reset the break flag
 func (buff *KeyBuffer) ClearBreak() {
-	buff.sig_break = false
+	atomic.StoreInt32(&buff.sig_break, 0)
 }
 
 // ReadByte returns the next byte, caller has to decide if he needs more
@@ -128,11 +163,64 @@ func (buff *KeyBuffer) ReadByte() (byte, error) {
 
 	// try to read key scan codes and report it
 	select {
-	case buff.inp = <-buff.keycodes:
-		buff.ind = 1
-		return buff.inp[0], nil
+	case inp, ok := <-buff.keycodes:
+		if ok {
+			if bt, ok := buff.startKey(inp); ok {
+				return bt, nil
+			}
+		}
 	default:
-		// nothing to report
+	}
+
+	// nothing to report
+	return ' ', errors.New("no data")
+}
+
+// ReadKey returns the next byte, waiting for a key to be pressed
+// it gives up when ctx is done or the buffer is closed and empty
+func (buff *KeyBuffer) ReadKey(ctx context.Context) (byte, error) {
+	if bt, err := buff.ReadByte(); err == nil {
+		return bt, nil
+	}
+
+	if buff.keycodes == nil {
 		return ' ', errors.New("no data")
 	}
+
+	for {
+		select {
+		case inp, ok := <-buff.keycodes:
+			if !ok {
+				return ' ', io.EOF
+			}
+			if bt, ok := buff.startKey(inp); ok {
+				return bt, nil
+			}
+		case <-ctx.Done():
+			return ' ', ctx.Err()
+		}
+	}
+}
+
+// start in on the bytes of a new keystroke
+func (buff *KeyBuffer) startKey(inp []byte) (byte, bool) {
+	buff.inp = buff.checkForMacro(inp)
+	if len(buff.inp) == 0 {
+		// a function key with an empty macro types nothing
+		return ' ', false
+	}
+	buff.ind = 1
+	return buff.inp[0], true
+}
+
+// Close says no more keys are coming, once the keys already
+// typed have been read ReadKey returns io.EOF
+// nothing can be saved after the buffer is closed
+func (buff *KeyBuffer) Close() {
+	buff.closer.Do(func() {
+		if buff.keycodes == nil {
+			buff.keycodes = make(chan []byte, 20)
+		}
+		close(buff.keycodes)
+	})
 }
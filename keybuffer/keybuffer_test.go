@@ -1,7 +1,10 @@
 package keybuffer
 
 import (
+	"context"
+	"io"
 	"testing"
+	"time"
 
 	"github.com/navionguy/basicwasm/ast"
 	"github.com/stretchr/testify/assert"
@@ -17,7 +20,7 @@ func Test_SaveKeyStroke(t *testing.T) {
 
 	for _, tt := range tests {
 		bytes := []byte(tt.inp)
-		bf := GetKeyBuffer()
+		bf := New()
 		bf.SaveKeyStroke(bytes)
 
 		assert.NotNil(t, bf.keycodes, "SaveKeyStroke failed to open channel")
@@ -33,15 +36,38 @@ func Test_FuncKeys(t *testing.T) {
 		inp []byte
 		exp []byte
 	}{
-		{inp: []byte{0x1b, 0x4f, 0x50}, exp: []byte("LIST")},
+		{inp: []byte{0x1b, 0x4f, 0x50}, exp: []byte{ExtendedKey, ScanF1}},
+		{inp: []byte{0x1b, 0x5b, 0x32, 0x31, 0x7e}, exp: []byte{ExtendedKey, ScanF1 + 9}},
+		{inp: []byte{0x1b, 0x5b, 0x41}, exp: []byte{ExtendedKey, ScanUp}},
+		{inp: []byte{0x1b, 0x5b, 0x44}, exp: []byte{ExtendedKey, ScanLeft}},
+		{inp: []byte{0x1b, 0x5b, 0x33, 0x7e}, exp: []byte{ExtendedKey, ScanDelete}},
+		{inp: []byte{0x1b, 0x5b, 0x31, 0x3b, 0x35, 0x46}, exp: []byte{ExtendedKey, ScanCtrlEnd}},
+		{inp: []byte("\x1b[5~"), exp: []byte{ExtendedKey, 73}},
+		{inp: []byte("\x1b[6;5~"), exp: []byte{ExtendedKey, 118}},
+		{inp: []byte("\x1b[5;5~"), exp: []byte{ExtendedKey, 132}},
+		{inp: []byte("\x1bOH"), exp: []byte{ExtendedKey, 71}},
+		{inp: []byte("\x1b[4~"), exp: []byte{ExtendedKey, 79}},
+		{inp: []byte("\x1b[2~"), exp: []byte{ExtendedKey, 82}},
+		{inp: []byte("\x1b[1;5C"), exp: []byte{ExtendedKey, 116}},
+		{inp: []byte("\x1b[1;5D"), exp: []byte{ExtendedKey, 115}},
+		{inp: []byte("\x1b[1;2A"), exp: []byte{ExtendedKey, 72}},
+		{inp: []byte("\x1b[1;2P"), exp: []byte{ExtendedKey, 84}},
+		{inp: []byte("\x1b[21;2~"), exp: []byte{ExtendedKey, 93}},
+		{inp: []byte("\x1b[15;5~"), exp: []byte{ExtendedKey, 98}},
+		{inp: []byte("\x1b[1;3Q"), exp: []byte{ExtendedKey, 105}},
+		{inp: []byte("\x1bq"), exp: []byte{ExtendedKey, 16}},
+		{inp: []byte("\x1bM"), exp: []byte{ExtendedKey, 50}},
+		{inp: []byte("\x1b1"), exp: []byte{ExtendedKey, 120}},
+		{inp: []byte("\x1b="), exp: []byte{ExtendedKey, 131}},
+		{inp: []byte("\x1b[Z"), exp: []byte{ExtendedKey, 15}},
 	}
 	kys := ast.KeySettings{Disp: true}
 	kys.Keys = make(map[string]string)
 	kys.Keys["F1"] = "LIST"
-	kbuff.KeySettings = &kys
+	bf := New()
+	bf.SetKeySettings(&kys)
 
 	for _, tt := range tests {
-		bf := GetKeyBuffer()
 		bf.SaveKeyStroke(tt.inp)
 
 		assert.NotNil(t, bf.keycodes, "SaveKeyStroke failed to open channel")
@@ -53,13 +79,44 @@ func Test_FuncKeys(t *testing.T) {
 
 }
 
+func Test_ExpandMacros(t *testing.T) {
+	tests := []struct {
+		inp    []byte
+		expand bool
+		exp    string
+	}{
+		{inp: []byte("\x1bOP"), expand: true, exp: "LIST "},
+		{inp: []byte("\x1bOQ"), expand: true, exp: "RUN\r"},
+		{inp: []byte("\x1bOP"), exp: "\x00;"},
+		{inp: []byte("\x1bOR"), expand: true, exp: ""},
+		{inp: []byte("\x1bOS"), expand: true, exp: "\x00>"},
+		{inp: []byte("\x1b[A"), expand: true, exp: "\x00H"},
+		{inp: []byte("A"), expand: true, exp: "A"},
+	}
+
+	kys := ast.KeySettings{Keys: map[string]string{"F1": "LIST ", "F2": "RUN\r", "F3": ""}}
+	for _, tt := range tests {
+		buff := new(KeyBuffer)
+		buff.KeySettings = &kys
+		buff.ExpandMacros(tt.expand)
+		buff.SaveKeyStroke(tt.inp)
+
+		var got []byte
+		for bt, err := buff.ReadByte(); err == nil; bt, err = buff.ReadByte() {
+			got = append(got, bt)
+		}
+
+		assert.Equal(t, tt.exp, string(got), "%q expanded wrong", tt.inp)
+	}
+}
+
 func Test_SawBreak(t *testing.T) {
 	var tt []byte
 	tt = append(tt, 0x03)
 	buff := new(KeyBuffer)
 	buff.SaveKeyStroke(tt)
 
-	assert.True(t, buff.sig_break, "Ctrl-C missed!")
+	assert.Equal(t, int32(1), buff.sig_break, "Ctrl-C missed!")
 	assert.True(t, buff.BreakSeen(), "Break not seen")
 	buff.ClearBreak()
 	assert.False(t, buff.BreakSeen(), "Flag not reset")
@@ -101,6 +158,41 @@ func Test_ReadByte(t *testing.T) {
 	}
 }
 
+func Test_ReadKey(t *testing.T) {
+	buff := New()
+	buff.SaveKeyStroke([]byte("AB"))
+	buff.Close()
+
+	for _, exp := range []byte("AB") {
+		bt, err := buff.ReadKey(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, exp, bt, "ReadKey got the wrong key")
+	}
+
+	_, err := buff.ReadKey(context.Background())
+	assert.Equal(t, io.EOF, err, "ReadKey didn't see the buffer was closed")
+
+	// a read that is cancelled gives up
+	buff = New()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = buff.ReadKey(ctx)
+	assert.Equal(t, context.Canceled, err, "ReadKey didn't give up")
+
+	// or waits for a key
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		buff.SaveKeyStroke([]byte("C"))
+	}()
+	bt, err := buff.ReadKey(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, byte('C'), bt, "ReadKey didn't wait for the key")
+
+	// a buffer that was never used
+	_, err = new(KeyBuffer).ReadKey(context.Background())
+	assert.Error(t, err)
+}
+
 func Test_EarlyRead(t *testing.T) {
 	buff := new(KeyBuffer)
 	bt, err := buff.ReadByte()
@@ -109,3 +201,105 @@ func Test_EarlyRead(t *testing.T) {
 		assert.Failf(t, "An early ReadByte return %b", string([]byte{bt}))
 	}
 }
+
+func Test_KeyScan(t *testing.T) {
+	tests := []struct {
+		inp   string
+		flags byte
+		scan  byte
+		fail  bool
+	}{
+		{inp: "a", scan: 0x1e},
+		{inp: "A", flags: FlagShift, scan: 0x1e},
+		{inp: "!", flags: FlagShift, scan: 0x02},
+		{inp: "\x03", flags: FlagCtrl, scan: 0x2e},
+		{inp: "\r", scan: ScanEnter},
+		{inp: "\x1b", scan: ScanEsc},
+		{inp: "\x1bx", flags: FlagAlt, scan: 0x2d},
+		{inp: "\x1bOP", scan: ScanF1},
+		{inp: "\x1b[21~", scan: ScanF1 + 9},
+		{inp: "\x1b[15;2~", flags: FlagShift, scan: ScanF1 + 4},
+		{inp: "\x1b[A", scan: ScanUp},
+		{inp: "\x1b[1;5D", flags: FlagCtrl, scan: ScanLeft},
+		{inp: "\x1b[1;3P", flags: FlagAlt, scan: ScanF1},
+		{inp: "\x1b[6~", scan: ScanPgDn},
+		{inp: "\x1b[99~", fail: true},
+		{inp: "\x1b[2A", fail: true},
+		{inp: "\x1bO", fail: true},
+		{inp: "\x80", fail: true},
+		{inp: "", fail: true},
+	}
+
+	for _, tt := range tests {
+		flags, scan, ok := keyScan([]byte(tt.inp))
+
+		assert.Equal(t, !tt.fail, ok, "%q scan ok wrong", tt.inp)
+		if ok {
+			assert.Equal(t, tt.flags, flags, "%q flags wrong", tt.inp)
+			assert.Equal(t, tt.scan, scan, "%q scan code wrong", tt.inp)
+		}
+	}
+}
+
+func Test_TrapKeys(t *testing.T) {
+	tests := []struct {
+		key     int
+		onKeys  []byte
+		inp     string
+		trapped bool
+	}{
+		{key: 1, inp: "\x1bOP", trapped: true},
+		{key: 10, inp: "\x1b[21~", trapped: true},
+		{key: 1, inp: "\x1b[1;2P"},
+		{key: 11, inp: "\x1b[A", trapped: true},
+		{key: 14, inp: "\x1b[B", trapped: true},
+		{key: 12, inp: "\x1b[C"},
+		{key: 15, onKeys: []byte{FlagCtrl, 0x2e}, inp: "\x03", trapped: true},
+		{key: 16, onKeys: []byte{0, 0, 0x02, 0x1e}, inp: "A", trapped: true},
+		{key: 16, onKeys: []byte{0, 0, 0x02, 0x1e}, inp: "a"},
+		{key: 20, onKeys: []byte{FlagCtrl, 0x2e}, inp: "\x03"},
+		{key: 0, inp: "\x1bOP"},
+	}
+
+	for _, tt := range tests {
+		buff := new(KeyBuffer)
+		buff.SetKeySettings(&ast.KeySettings{OnKeys: tt.onKeys})
+		buff.SetRunning(true)
+		buff.TrapKey(tt.key, true)
+		buff.SaveKeyStroke([]byte(tt.inp))
+
+		if !tt.trapped {
+			assert.Empty(t, buff.KeyHits(), "%q trapped as key %d", tt.inp, tt.key)
+			continue
+		}
+
+		assert.Equal(t, []int{tt.key}, buff.KeyHits(), "%q not trapped as key %d", tt.inp, tt.key)
+		assert.Empty(t, buff.KeyHits(), "%q hits didn't clear", tt.inp)
+		_, err := buff.ReadByte()
+		assert.NotNil(t, err, "%q went into the buffer", tt.inp)
+	}
+
+	// once cleared, the key goes in the buffer
+	buff := new(KeyBuffer)
+	buff.SetRunning(true)
+	buff.TrapKey(1, true)
+	buff.ClearTraps()
+	buff.SaveKeyStroke([]byte("\x1bOP"))
+	assert.Empty(t, buff.KeyHits(), "cleared trap still trapping")
+
+	// once the program ends, the key types its macro at the prompt
+	buff = new(KeyBuffer)
+	buff.SetKeySettings(&ast.KeySettings{Keys: map[string]string{"F1": "LIST "}})
+	buff.ExpandMacros(true)
+	buff.SetRunning(true)
+	buff.TrapKey(1, true)
+	buff.SetRunning(false)
+	buff.SaveKeyStroke([]byte("\x1bOP"))
+	assert.Empty(t, buff.KeyHits(), "trapped with no program running")
+
+	var got []byte
+	for bt, err := buff.ReadByte(); err == nil; bt, err = buff.ReadByte() {
+		got = append(got, bt)
+	}
+	assert.Equal(t, "LIST ", string(got), "macro wasn't typed")
+}
@@ -0,0 +1,114 @@
+package keybuffer
+
+import "sync"
+
+// KEY 1 thru 10 are the function keys, 11 thru 14 the cursor keys
+// and 15 thru 20 are defined by the user
+const (
+	FirstUserKey = 15
+	LastKey      = 20
+)
+
+// the scan codes for KEY 11 thru 14
+var cursorTraps = []byte{ScanUp, ScanLeft, ScanRight, ScanDown}
+
+// keys being trapped by ON KEY, the key strokes come in on
+// one goroutine and get checked for on another
+type keyTraps struct {
+	mtx     sync.Mutex
+	running bool              // keys only get trapped while a program runs
+	on      [LastKey + 1]bool // trapping is turned on or stopped
+	hit     []int             // trapped keys pressed since the last check
+	user    []byte            // shift flags and scan code for KEY 15 thru 20
+}
+
+// SetRunning tells the buffer if a program is running, at the
+// command prompt trapped keys go in the buffer like any other
+func (buff *KeyBuffer) SetRunning(on bool) {
+	buff.traps.mtx.Lock()
+	defer buff.traps.mtx.Unlock()
+	buff.traps.running = on
+}
+
+// TrapKey starts or stops catching key number n
+// trapped keys never make it into the buffer
+func (buff *KeyBuffer) TrapKey(n int, on bool) {
+	if (n < 1) || (n > LastKey) {
+		return
+	}
+
+	buff.traps.mtx.Lock()
+	defer buff.traps.mtx.Unlock()
+	buff.traps.on[n] = on
+}
+
+// ClearTraps stops catching any keys and forgets the ones caught
+func (buff *KeyBuffer) ClearTraps() {
+	buff.traps.mtx.Lock()
+	defer buff.traps.mtx.Unlock()
+	buff.traps.on = [LastKey + 1]bool{}
+	buff.traps.hit = nil
+}
+
+// KeyHits returns the numbers of the trapped keys pressed
+// since it was last called
+func (buff *KeyBuffer) KeyHits() []int {
+	buff.traps.mtx.Lock()
+	defer buff.traps.mtx.Unlock()
+	hits := buff.traps.hit
+	buff.traps.hit = nil
+	return hits
+}
+
+// checkForTrappedKey returns true if the key is being trapped
+// and notes that it was pressed
+func (buff *KeyBuffer) checkForTrappedKey(key []byte) bool {
+	flags, scan, ok := keyScan(key)
+	if !ok {
+		return false
+	}
+
+	buff.traps.mtx.Lock()
+	defer buff.traps.mtx.Unlock()
+
+	if !buff.traps.running {
+		return false
+	}
+
+	for n := 1; n <= LastKey; n++ {
+		if buff.traps.on[n] && buff.keyMatches(n, flags, scan) {
+			buff.traps.hit = append(buff.traps.hit, n)
+			return true
+		}
+	}
+
+	return false
+}
+
+// does the key pressed match KEY n
+func (buff *KeyBuffer) keyMatches(n int, flags byte, scan byte) bool {
+	switch {
+	case n <= 10:
+		return (flags == 0) && (scan == ScanF1+byte(n-1))
+	case n < FirstUserKey:
+		return (flags == 0) && (scan == cursorTraps[n-11])
+	}
+
+	// user keys are saved as shift flags and scan code pairs
+	ind := (n - FirstUserKey) * 2
+	if ind+1 >= len(buff.traps.user) {
+		return false
+	}
+
+	def := buff.traps.user[ind : ind+2]
+	return (def[1] != 0) && (def[1] == scan) && (userFlags(def[0]) == flags)
+}
+
+// either shift key will do, the lock keys can't be seen
+func userFlags(def byte) byte {
+	flags := def & (FlagCtrl | FlagAlt)
+	if def&0x03 != 0 {
+		flags |= FlagShift
+	}
+	return flags
+}
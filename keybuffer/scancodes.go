@@ -0,0 +1,264 @@
+package keybuffer
+
+import "strings"
+
+// keyboard flags as GW-BASIC reports them for KEY 15 thru 20
+const (
+	FlagShift = 0x01 // either shift key
+	FlagCtrl  = 0x04
+	FlagAlt   = 0x08
+)
+
+// more scan codes, the function keys and the rest of the keypad
+const (
+	ScanEsc       = 0x01
+	ScanBackspace = 0x0e
+	ScanTab       = 0x0f
+	ScanEnter     = 0x1c
+	ScanSpace     = 0x39
+	ScanF1        = 0x3b // F2 thru F10 follow in order
+	ScanPgUp      = 0x49
+	ScanPgDn      = 0x51
+)
+
+// extended codes for the keys held down with shift, ctrl or alt
+// each function key block runs F1 thru F10
+const (
+	ScanShiftF1   = 0x54
+	ScanCtrlF1    = 0x5e
+	ScanAltF1     = 0x68
+	ScanCtrlLeft  = 0x73
+	ScanCtrlRight = 0x74
+	ScanCtrlPgDn  = 0x76
+	ScanAlt1      = 0x78 // alt 2 thru 0, - and = follow in order
+	ScanCtrlPgUp  = 0x84
+)
+
+// the cursor and editing keys
+var padKeys = map[byte]bool{
+	ScanHome: true, ScanUp: true, ScanPgUp: true, ScanLeft: true, ScanRight: true,
+	ScanEnd: true, ScanDown: true, ScanPgDn: true, ScanInsert: true, ScanDelete: true,
+}
+
+// the keypad keys that change with ctrl
+var ctrlKeys = map[byte]byte{
+	ScanLeft:  ScanCtrlLeft,
+	ScanRight: ScanCtrlRight,
+	ScanEnd:   ScanCtrlEnd,
+	ScanPgDn:  ScanCtrlPgDn,
+	ScanHome:  ScanCtrlHome,
+	ScanPgUp:  ScanCtrlPgUp,
+}
+
+// scan codes for the keys on the main part of the keyboard
+var mainKeys = map[byte]byte{
+	'1': 0x02, '2': 0x03, '3': 0x04, '4': 0x05, '5': 0x06,
+	'6': 0x07, '7': 0x08, '8': 0x09, '9': 0x0a, '0': 0x0b,
+	'-': 0x0c, '=': 0x0d, '[': 0x1a, ']': 0x1b, ';': 0x27,
+	'\'': 0x28, '`': 0x29, '\\': 0x2b, ',': 0x33, '.': 0x34, '/': 0x35,
+	'q': 0x10, 'w': 0x11, 'e': 0x12, 'r': 0x13, 't': 0x14,
+	'y': 0x15, 'u': 0x16, 'i': 0x17, 'o': 0x18, 'p': 0x19,
+	'a': 0x1e, 's': 0x1f, 'd': 0x20, 'f': 0x21, 'g': 0x22,
+	'h': 0x23, 'j': 0x24, 'k': 0x25, 'l': 0x26,
+	'z': 0x2c, 'x': 0x2d, 'c': 0x2e, 'v': 0x2f, 'b': 0x30,
+	'n': 0x31, 'm': 0x32,
+	' ': ScanSpace, '\r': ScanEnter, '\t': ScanTab, 0x08: ScanBackspace, 0x7f: ScanBackspace,
+}
+
+// the shifted characters and the key they are on
+const shifted = `!@#$%^&*()_+{}:"~|<>?`
+const unshifted = "1234567890-=[];'`\\,./"
+
+// final bytes of the xterm cursor key sequences
+var cursorKeys = map[byte]byte{
+	'A': ScanUp,
+	'B': ScanDown,
+	'C': ScanRight,
+	'D': ScanLeft,
+	'H': ScanHome,
+	'F': ScanEnd,
+	'P': ScanF1,
+	'Q': ScanF1 + 1,
+	'R': ScanF1 + 2,
+	'S': ScanF1 + 3,
+}
+
+// numbered xterm sequences, ESC [ n ~
+var tildeKeys = map[string]byte{
+	"1":  ScanHome,
+	"2":  ScanInsert,
+	"3":  ScanDelete,
+	"4":  ScanEnd,
+	"5":  ScanPgUp,
+	"6":  ScanPgDn,
+	"7":  ScanHome,
+	"8":  ScanEnd,
+	"11": ScanF1,
+	"12": ScanF1 + 1,
+	"13": ScanF1 + 2,
+	"14": ScanF1 + 3,
+	"15": ScanF1 + 4,
+	"17": ScanF1 + 5,
+	"18": ScanF1 + 6,
+	"19": ScanF1 + 7,
+	"20": ScanF1 + 8,
+	"21": ScanF1 + 9,
+}
+
+// keyScan works out which key was pressed and the shift keys held down
+func keyScan(key []byte) (flags byte, scan byte, ok bool) {
+	if len(key) == 0 {
+		return 0, 0, false
+	}
+
+	if key[0] != 0x1b {
+		return charScan(key)
+	}
+
+	if len(key) == 1 {
+		return 0, ScanEsc, true
+	}
+
+	switch key[1] {
+	case '[':
+		return csiScan(string(key[2:]))
+	case 'O':
+		if len(key) == 3 {
+			scan, ok = cursorKeys[key[2]]
+			return 0, scan, ok
+		}
+		return 0, 0, false
+	}
+
+	// escape ahead of a character is the alt key
+	flags, scan, ok = charScan(key[1:])
+	return flags | FlagAlt, scan, ok
+}
+
+// a single character from the keyboard
+func charScan(key []byte) (byte, byte, bool) {
+	if len(key) != 1 {
+		return 0, 0, false
+	}
+
+	ch := key[0]
+	if scan, ok := mainKeys[ch]; ok {
+		return 0, scan, true
+	}
+
+	if (ch >= 'A') && (ch <= 'Z') {
+		return FlagShift, mainKeys[ch+'a'-'A'], true
+	}
+
+	if i := strings.IndexByte(shifted, ch); i >= 0 {
+		return FlagShift, mainKeys[unshifted[i]], true
+	}
+
+	// control characters are ctrl and a letter
+	if (ch >= 0x01) && (ch <= 0x1a) {
+		return FlagCtrl, mainKeys[ch+'a'-1], true
+	}
+
+	return 0, 0, false
+}
+
+// a control sequence, the bytes after ESC [
+// a modifier parameter carries the shift keys
+func csiScan(seq string) (byte, byte, bool) {
+	if len(seq) == 0 {
+		return 0, 0, false
+	}
+
+	final := seq[len(seq)-1]
+	params := strings.Split(seq[:len(seq)-1], ";")
+
+	// back tab
+	if seq == "Z" {
+		return FlagShift, ScanTab, true
+	}
+
+	var flags byte
+	if len(params) == 2 {
+		flags = modFlags(params[1])
+	}
+
+	if final == '~' {
+		scan, ok := tildeKeys[params[0]]
+		return flags, scan, ok
+	}
+
+	if (len(params[0]) > 0) && (params[0] != "1") {
+		return 0, 0, false
+	}
+
+	scan, ok := cursorKeys[final]
+	return flags, scan, ok
+}
+
+// xterm sends one plus the bits for shift, alt and ctrl
+func modFlags(param string) byte {
+	if len(param) != 1 || (param[0] < '2') || (param[0] > '8') {
+		return 0
+	}
+
+	mod := param[0] - '1'
+	var flags byte
+	if mod&1 != 0 {
+		flags |= FlagShift
+	}
+	if mod&2 != 0 {
+		flags |= FlagAlt
+	}
+	if mod&4 != 0 {
+		flags |= FlagCtrl
+	}
+
+	return flags
+}
+
+// extendedCode gives the code GW-BASIC reports after the NUL
+// for the keys that don't have a character of their own
+func extendedCode(flags byte, scan byte) (byte, bool) {
+	switch {
+	case (scan >= ScanF1) && (scan < ScanF1+10):
+		return funcKeyCode(flags, scan-ScanF1), true
+	case padKeys[scan]:
+		if flags&FlagCtrl != 0 {
+			code, ok := ctrlKeys[scan]
+			return code, ok
+		}
+		return scan, true
+	case (flags == FlagShift) && (scan == ScanTab):
+		return ScanTab, true
+	case flags&FlagAlt != 0:
+		return altKeyCode(scan)
+	}
+
+	return 0, false
+}
+
+// each shift key has its own block of function key codes
+func funcKeyCode(flags byte, num byte) byte {
+	switch {
+	case flags&FlagAlt != 0:
+		return ScanAltF1 + num
+	case flags&FlagCtrl != 0:
+		return ScanCtrlF1 + num
+	case flags&FlagShift != 0:
+		return ScanShiftF1 + num
+	}
+	return ScanF1 + num
+}
+
+// alt and a letter reports the scan code of the letter
+// alt and the top row digits have their own codes
+func altKeyCode(scan byte) (byte, bool) {
+	switch {
+	case (scan >= 0x02) && (scan <= 0x0d):
+		return ScanAlt1 + scan - 0x02, true
+	case (scan >= 0x10) && (scan <= 0x19), (scan >= 0x1e) && (scan <= 0x26), (scan >= 0x2c) && (scan <= 0x32):
+		return scan, true
+	}
+
+	return 0, false
+}
@@ -1,6 +1,8 @@
 package lexer
 
 import (
+	"strings"
+
 	"github.com/navionguy/basicwasm/token"
 )
 
@@ -11,6 +13,7 @@ type Lexer struct {
 	readPosition int  // current reading position in input (after current char)
 	ch           byte // current char under examination
 	passWhite    bool // do I let whitespace through to parser
+	lineStart    int  // where the line being scanned starts in input
 }
 
 // New create a new lexer object
@@ -33,6 +36,21 @@ func (l *Lexer) PassOff() {
 	l.passWhite = false
 }
 
+// Line returns the text of the line being scanned, just the way
+// it was typed in, without the line end
+func (l *Lexer) Line() string {
+	if l.lineStart >= len(l.input) {
+		return ""
+	}
+
+	line := l.input[l.lineStart:]
+	if end := strings.IndexByte(line, '\n'); end >= 0 {
+		line = line[:end]
+	}
+
+	return strings.TrimLeft(strings.TrimRight(line, "\r"), " \t")
+}
+
 // NextToken scans for the next token
 func (l *Lexer) NextToken() token.Token {
 	var tok token.Token
@@ -53,6 +71,7 @@ func (l *Lexer) NextToken() token.Token {
 	case '\n':
 		tok = newToken(token.EOL, l.ch)
 		l.readChar()
+		l.lineStart = l.position
 		return tok
 	case '$':
 		tok = newToken(token.TYPE_STR, l.ch)
@@ -104,6 +123,8 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.SLASH, l.ch)
 	case '*':
 		tok = newToken(token.ASTERISK, l.ch)
+	case '^':
+		tok = newToken(token.CARET, l.ch)
 	case '<':
 		if l.peekChar() == '=' {
 			ch := l.ch
@@ -209,8 +230,19 @@ func (l *Lexer) readNumber() (token.TokenType, string) {
 				l.readChar()
 			}
 		case '#':
+			// a type suffix ends the number
 			_, tt = l.chgType(tt, token.INT, token.INTD)
+			_, tt = l.chgType(tt, token.FIXED, token.FLOAT)
 			l.readChar()
+			err = true
+		case '!':
+			// don't steal the '!' from a "!="
+			if l.peekChar() != '=' {
+				_, tt = l.chgType(tt, token.INT, token.FLOAT)
+				_, tt = l.chgType(tt, token.FIXED, token.FLOAT)
+				l.readChar()
+			}
+			err = true
 		default:
 			err = true
 		}
@@ -27,7 +27,7 @@ func TestNextToken(t *testing.T) {
 	30 let add = fn(x, y) 	
 	40 let result = add(five,. ten)
 	50 !-/*5 	5 < 10 >	if ( 5 < 10 ) 		return true	 else 		return false	
-	60 10 == 10	9 != 10	9 <= 10	10>=9	let result = "Hello there!" $%:[]+&<>
+	60 10 == 10	9 != 10	9 <= 10	10>=9	let result = "Hello there!" $%:[]+&<>^
 	`
 	tests := []struct {
 		expectedType    token.TokenType
@@ -117,6 +117,7 @@ func TestNextToken(t *testing.T) {
 		{token.PLUS, "+"},
 		{token.AMPERSAND, "&"},
 		{token.NOT_EQ, "<>"},
+		{token.CARET, "^"},
 		{token.EOL, "\n"},
 		{token.EOF, "EOF"},
 	}
@@ -160,6 +161,10 @@ func TestReadNumber(t *testing.T) {
 		{"235.988E-7", token.FLOAT},
 		{"235D-12", token.FLOAT},
 		{"12#", token.INTD},
+		{"2.5#", token.FLOAT},
+		{"1.5E3#", token.FLOAT},
+		{"3!", token.FLOAT},
+		{"3.25!", token.FLOAT},
 	}
 
 	for _, tt := range tests {
@@ -194,6 +199,18 @@ func TestLineNumbers(t *testing.T) {
 	println(tt.Literal)
 }
 
+func TestLine(t *testing.T) {
+	l := New("10 X=500\r\n  20 PRINT  X\n")
+
+	assert.Equal(t, "10 X=500", l.Line())
+	for tok := l.NextToken(); tok.Literal != "20"; tok = l.NextToken() {
+	}
+	assert.Equal(t, "20 PRINT  X", l.Line())
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+	}
+	assert.Equal(t, "", l.Line())
+}
+
 func TestStatements(t *testing.T) {
 	type result struct {
 		expectedType    token.TokenType
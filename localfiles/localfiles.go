@@ -2,6 +2,7 @@ package localfiles
 
 import (
 	"io"
+	"strings"
 
 	"github.com/navionguy/basicwasm/berrors"
 	"github.com/navionguy/basicwasm/fileserv"
@@ -16,14 +17,31 @@ type aLocalFile struct {
 
 }
 
-// localFiles holds all of the data files accessed by programs.
+// LocalFiles holds all of the data files accessed by programs.
 // In this way, if one program creates a data file, and a later
-// program accesses it, the intended contents are preserved
-type localFiles struct {
-	dir map[string]*aLocalFile // maps the FQ filename to an AnOpenFile struct
+// program accesses it, the intended contents are preserved.
+// Each session has its own
+type LocalFiles struct {
+	dir  map[string]*aLocalFile // maps the FQ filename to an AnOpenFile struct
+	dirs map[string]bool        // directories created by programs
 }
 
-var lf localFiles // files stored locally
+// New creates an empty local file store
+func New() *LocalFiles {
+	return &LocalFiles{dir: make(map[string]*aLocalFile), dirs: make(map[string]bool)}
+}
+
+// For returns the local files for the session env belongs to
+// creating them the first time they are needed
+func For(env *object.Environment) object.LocalStore {
+	if ls := env.LocalFiles(); ls != nil {
+		return ls
+	}
+
+	lf := New()
+	env.SetLocalFiles(lf)
+	return lf
+}
 
 // support the object.Object interface
 func (lf *aLocalFile) Type() object.ObjectType {
@@ -34,13 +52,13 @@ func (lf *aLocalFile) Inspect() string {
 	return lf.FQFilename
 }
 
-// Open is called by the evaluator is trying to open a data file.
-func Open(FQFN string, env *object.Environment) object.Object {
-	// make sure local file store has been created
-	if lf.dir == nil {
-		lf.dir = make(map[string]*aLocalFile)
-	}
+// Data gives access to the contents of the file
+func (lf *aLocalFile) Data() *[]byte {
+	return lf.data
+}
 
+// Open is called by the evaluator is trying to open a data file.
+func (lf *LocalFiles) Open(FQFN string, env *object.Environment) object.Object {
 	// check local file map for FQFN
 	alf := lf.dir[FQFN]
 
@@ -49,11 +67,125 @@ func Open(FQFN string, env *object.Environment) object.Object {
 		return alf
 	}
 
-	return fetchFile(FQFN, env)
+	return lf.fetchFile(FQFN, env)
+}
+
+// Create is called when a program opens a file for output.
+// Any current contents of the file are lost.
+func (lf *LocalFiles) Create(FQFN string) object.Object {
+	fl := []byte{}
+	alf := aLocalFile{FQFilename: FQFN, readonly: false, data: &fl}
+	lf.dir[FQFN] = &alf
+
+	return &alf
+}
+
+// Find looks for a file in local storage without asking the server
+// returns nil if the file isn't held locally
+func (lf *LocalFiles) Find(FQFN string) object.Object {
+	alf := lf.dir[FQFN]
+	if alf == nil {
+		return nil
+	}
+
+	return alf
+}
+
+// Store is called when the interpreter writes a complete file, like a SAVE.
+// Any current contents of the file are replaced.
+func (lf *LocalFiles) Store(FQFN string, data []byte) object.Object {
+	alf := aLocalFile{FQFilename: FQFN, readonly: false, data: &data}
+	lf.dir[FQFN] = &alf
+
+	return &alf
+}
+
+// Remove deletes a file from local storage
+// returns false if the file wasn't held locally
+func (lf *LocalFiles) Remove(FQFN string) bool {
+	if lf.dir[FQFN] == nil {
+		return false
+	}
+
+	delete(lf.dir, FQFN)
+	return true
+}
+
+// Rename moves a locally held file to a new name
+// returns false if the file wasn't held locally
+func (lf *LocalFiles) Rename(oldFQFN, newFQFN string) bool {
+	alf := lf.dir[oldFQFN]
+	if alf == nil {
+		return false
+	}
+
+	delete(lf.dir, oldFQFN)
+	alf.FQFilename = newFQFN
+	lf.dir[newFQFN] = alf
+
+	return true
+}
+
+// List returns the names of all the files held locally
+// that live in directory dir
+func (lf *LocalFiles) List(dir string) []string {
+	var l []string
+
+	for fn := range lf.dir {
+		if strings.EqualFold(parentDir(fn), dir) {
+			l = append(l, fn)
+		}
+	}
+
+	return l
+}
+
+// MakeDir records a directory created by a program
+func (lf *LocalFiles) MakeDir(path string) {
+	lf.dirs[path] = true
+}
+
+// RemoveDir forgets a directory created by a program
+// returns false if the directory wasn't created locally
+func (lf *LocalFiles) RemoveDir(path string) bool {
+	if !lf.dirs[path] {
+		return false
+	}
+
+	delete(lf.dirs, path)
+	return true
+}
+
+// DirExists reports if a program created the directory
+func (lf *LocalFiles) DirExists(path string) bool {
+	return lf.dirs[path]
+}
+
+// DirEmpty reports if no local files or directories live in path
+func (lf *LocalFiles) DirEmpty(path string) bool {
+	if len(lf.List(path)) > 0 {
+		return false
+	}
+
+	for dir := range lf.dirs {
+		if strings.EqualFold(parentDir(dir), path) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parentDir gives the directory holding a file or directory
+// all full paths end with a '\'
+func parentDir(path string) string {
+	path = strings.TrimSuffix(path, `\`)
+
+	return path[:strings.LastIndex(path, `\`)+1]
 }
 
 // fetchFile tries to download the file from the server
-func fetchFile(FQFN string, env *object.Environment) object.Object {
+func (lf *LocalFiles) fetchFile(FQFN string, env *object.Environment) object.Object {
 
 	// go request the file from the server
 	rdr, err := fileserv.GetFile(FQFN, env)
@@ -64,17 +196,14 @@ func fetchFile(FQFN string, env *object.Environment) object.Object {
 		return err
 	}
 
-	fl := make([]byte, rdr.Size())
-	lf := aLocalFile{FQFilename: FQFN, readonly: false, data: &fl}
-
-	return &lf
+	return lf.storeFile(FQFN, rdr, env)
 }
 
 // storeFile takes the io.Reader returned from the file request and reads the contents
 // into memory.
 // NOTE! This is only called for data file requests.  Program file requests are not stored
 // since they are consumed once by the parser and then live in the AST.
-func storeFile(filename string, file io.Reader, env *object.Environment) object.Object {
+func (lf *LocalFiles) storeFile(filename string, file io.Reader, env *object.Environment) object.Object {
 
 	return lf.saveFile(filename, file, env)
 }
@@ -82,7 +211,7 @@ func storeFile(filename string, file io.Reader, env *object.Environment) object.
 // saveFile does the work of adding the file to local storage
 // I don't check to see if I already have the file stored.
 // That shouldn't happen, but if it does, it may signal my copy is old.
-func (lf *localFiles) saveFile(filename string, file io.Reader, env *object.Environment) object.Object {
+func (lf *LocalFiles) saveFile(filename string, file io.Reader, env *object.Environment) object.Object {
 
 	data, err := io.ReadAll(file)
 
@@ -31,13 +31,19 @@ func TestFetchFile(t *testing.T) {
 		trm.ExpMsg = &mocks.Expector{}
 
 		env := object.NewTermEnvironment(trm)
+		lf := New()
 
 		cl := mocks.MockClient{Contents: tt.contents, Url: "http://localhost:8080/drivec/test.dat", StatusCode: http.StatusOK}
 		env.SetClient(&cl)
 
-		res := fetchFile(tt.filename, env)
+		res := lf.fetchFile(tt.filename, env)
 
 		assert.Equal(t, tt.result.Type(), res.Type(), "unexpected fetchFile() result")
+
+		alf, ok := res.(*aLocalFile)
+		assert.True(t, ok, "fetchFile() didn't return a local file")
+		assert.Equal(t, tt.contents, string(*alf.Data()))
+		assert.Equal(t, alf, lf.dir[tt.filename], "fetched file not stored locally")
 	}
 }
 
@@ -54,13 +60,13 @@ func TestOpenFile(t *testing.T) {
 	for _, tt := range tests {
 		var trm mocks.MockTerm
 		env := object.NewTermEnvironment(trm)
+		lf := New()
 		if tt.preload {
-			lf.dir = make(map[string]*aLocalFile)
 			alf := &aLocalFile{FQFilename: tt.filename, readonly: true, data: &tt.contents}
 			lf.dir[tt.filename] = alf
 		}
 
-		Open(tt.filename, env)
+		lf.Open(tt.filename, env)
 	}
 }
 
@@ -72,7 +78,7 @@ func TestSaveFile(t *testing.T) {
 		{filename: "TEST.DAT", contents: []byte("This is some test data.")},
 	}
 
-	lf.dir = make(map[string]*aLocalFile)
+	lf := New()
 
 	for _, tt := range tests {
 		var trm mocks.MockTerm
@@ -90,15 +96,125 @@ func TestStoreFile(t *testing.T) {
 		{filename: "TESTFAIL.DAT"},
 	}
 
-	lf.dir = nil
-
 	for _, tt := range tests {
 		var trm mocks.MockTerm
 		env := object.NewTermEnvironment(trm)
 		b := []byte(tt.contents)
 		rdr := mocks.NewReader(b)
-		lf.dir = make(map[string]*aLocalFile)
 
-		storeFile(tt.filename, rdr, env)
+		New().storeFile(tt.filename, rdr, env)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	tests := []struct {
+		filename string
+		contents []byte
+	}{
+		{filename: "NEW.DAT"},
+		{filename: "TEST.DAT", contents: []byte("This is some test data.")},
+	}
+
+	for _, tt := range tests {
+		var trm mocks.MockTerm
+		env := object.NewTermEnvironment(trm)
+		lf := New()
+		if tt.contents != nil {
+			lf.dir[tt.filename] = &aLocalFile{FQFilename: tt.filename, readonly: true, data: &tt.contents}
+		}
+
+		res := lf.Create(tt.filename)
+		alf, ok := res.(*aLocalFile)
+
+		assert.True(t, ok, "Create() returned a %T", res)
+		assert.Empty(t, *alf.Data(), "Create() didn't give an empty file")
+		assert.Equal(t, alf, lf.Open(tt.filename, env), "Open() didn't find the new file")
 	}
 }
+
+func TestStoreAndFind(t *testing.T) {
+	tests := []struct {
+		filename string
+		contents []byte
+	}{
+		{filename: `c:\prog.bas\`, contents: []byte{0xff, 0x00, 0x00, 0x1a}},
+		{filename: `c:\prog.bas\`, contents: []byte("10 PRINT X\r\n\x1a")},
+	}
+
+	lf := New()
+	assert.Nil(t, lf.Find(`c:\none.bas\`), "Find() on an empty store found something")
+
+	for _, tt := range tests {
+		var trm mocks.MockTerm
+		env := object.NewTermEnvironment(trm)
+
+		res := lf.Store(tt.filename, tt.contents)
+		alf, ok := res.(*aLocalFile)
+
+		assert.True(t, ok, "Store() returned a %T", res)
+		assert.False(t, alf.readonly, "Store() made a readonly file")
+		assert.Equal(t, tt.contents, *alf.Data(), "Store() lost the contents")
+		assert.Equal(t, alf, lf.Find(tt.filename), "Find() didn't find the stored file")
+		assert.Equal(t, alf, lf.Open(tt.filename, env), "Open() didn't find the stored file")
+	}
+
+	assert.Nil(t, lf.Find(`c:\none.bas\`), "Find() found a file never stored")
+}
+
+func TestRemoveAndRename(t *testing.T) {
+	lf := New()
+	assert.False(t, lf.Remove(`c:\none.dat\`), "Remove() on an empty store succeeded")
+	assert.False(t, lf.Rename(`c:\none.dat\`, `c:\other.dat\`), "Rename() on an empty store succeeded")
+
+	lf.Store(`c:\one.dat\`, []byte("one"))
+	lf.Store(`c:\two.dat\`, []byte("two"))
+	lf.Store(`c:\sub\three.dat\`, []byte("three"))
+
+	assert.ElementsMatch(t, []string{`c:\one.dat\`, `c:\two.dat\`}, lf.List(`c:\`), "List() of root is wrong")
+	assert.ElementsMatch(t, []string{`c:\sub\three.dat\`}, lf.List(`c:\sub\`), "List() of sub is wrong")
+
+	assert.True(t, lf.Rename(`c:\one.dat\`, `c:\sub\uno.dat\`), "Rename() failed")
+	assert.Nil(t, lf.Find(`c:\one.dat\`), "Rename() left the old name")
+	alf := lf.Find(`c:\sub\uno.dat\`)
+	if assert.NotNil(t, alf, "Rename() lost the file") {
+		assert.Equal(t, `c:\sub\uno.dat\`, alf.Inspect())
+		assert.Equal(t, "one", string(*alf.(*aLocalFile).Data()))
+	}
+
+	assert.True(t, lf.Remove(`c:\two.dat\`), "Remove() failed")
+	assert.Nil(t, lf.Find(`c:\two.dat\`), "Remove() left the file")
+	assert.Empty(t, lf.List(`c:\`), "root should be empty")
+}
+
+func TestLocalDirs(t *testing.T) {
+	lf := New()
+
+	assert.False(t, lf.DirExists(`c:\sub\`), "DirExists() on an empty store")
+	assert.False(t, lf.RemoveDir(`c:\sub\`), "RemoveDir() on an empty store")
+	assert.True(t, lf.DirEmpty(`c:\`), "empty store isn't empty")
+
+	lf.MakeDir(`c:\sub\`)
+	assert.True(t, lf.DirExists(`c:\sub\`), "MakeDir() didn't make the directory")
+	assert.False(t, lf.DirEmpty(`c:\`), "root holds sub")
+	assert.True(t, lf.DirEmpty(`c:\sub\`), "sub should be empty")
+
+	lf.Store(`c:\sub\data.dat\`, []byte("data"))
+	assert.False(t, lf.DirEmpty(`c:\sub\`), "sub holds a file")
+
+	lf.Remove(`c:\sub\data.dat\`)
+	assert.True(t, lf.RemoveDir(`c:\sub\`), "RemoveDir() failed")
+	assert.False(t, lf.DirExists(`c:\sub\`), "RemoveDir() left the directory")
+}
+
+func TestSessionsApart(t *testing.T) {
+	var trm mocks.MockTerm
+	env1 := object.NewTermEnvironment(trm)
+	env2 := object.NewTermEnvironment(trm)
+
+	For(env1).Store(`c:\one.dat\`, []byte("one"))
+
+	assert.Same(t, For(env1), For(env1), "session got a second store")
+	assert.NotNil(t, For(env1).Find(`c:\one.dat\`), "file not kept")
+	assert.Nil(t, For(env2).Find(`c:\one.dat\`), "file seen by another session")
+	assert.NotNil(t, For(object.NewEnclosedEnvironment(env1)).Find(`c:\one.dat\`), "enclosed environment has its own store")
+}
@@ -1,16 +1,28 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 
 	"github.com/gorilla/mux"
+	"github.com/navionguy/basicwasm/ast"
+	"github.com/navionguy/basicwasm/cli"
+	"github.com/navionguy/basicwasm/console"
 	"github.com/navionguy/basicwasm/fileserv"
+	"github.com/navionguy/basicwasm/object"
+	"github.com/navionguy/basicwasm/settings"
 )
 
 var (
 	listen = flag.String("listen", ":8080", "listen address")
+	lpt    = flag.String("lpt", "", "file LPRINT output is added to, stderr if not given")
 )
 
 const (
@@ -18,6 +30,16 @@ const (
 )
 
 func main() {
+	flag.Parse()
+
+	// basicwasm run PROG.BAS runs a program without the browser
+	// basicwasm term [DIR] gives you the prompt in this terminal
+	switch flag.Arg(0) {
+	case "run":
+		os.Exit(runProgram(flag.Arg(1)))
+	case "term":
+		os.Exit(runTerminal(flag.Arg(1)))
+	}
 
 	rt := startup()
 	log.Fatal(http.ListenAndServe(*listen, rt))
@@ -38,6 +60,136 @@ func startup() *mux.Router {
 	return r
 }
 
+// runProgram runs a program from the command line
+// the directory holding it becomes drive C:, keys come from
+// stdin and the screen output goes to stdout
+func runProgram(prog string) int {
+	if len(prog) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: basicwasm [flags] run PROG.BAS")
+		return cli.ExitError
+	}
+
+	path, err := filepath.Abs(prog)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return cli.ExitError
+	}
+
+	l, err := serveDrives(filepath.Dir(path))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return cli.ExitError
+	}
+	defer l.Close()
+
+	env := nativeEnvironment(console.New(os.Stdin, os.Stdout), l)
+
+	out, err := printerOutput(*lpt)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return cli.ExitError
+	}
+	defer out.Close()
+	env.SetPrinterOutput(out)
+
+	return cli.Run(`C:\`+filepath.Base(path), env)
+}
+
+// runTerminal puts the tty in raw mode and runs the command line in it
+// dir becomes drive C:, SYSTEM or ctrl-\ ends the session
+func runTerminal(dir string) int {
+	if len(dir) == 0 {
+		dir = "."
+	}
+
+	l, err := serveDrives(dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return cli.ExitError
+	}
+	defer l.Close()
+
+	out, err := printerOutput(*lpt)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return cli.ExitError
+	}
+	defer out.Close()
+
+	trm, err := console.NewTerminal(os.Stdin)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return cli.ExitError
+	}
+	defer trm.Restore()
+
+	env := nativeEnvironment(trm, l)
+	env.SetPrinterOutput(out)
+	env.Terminal().Cls()
+	ses := cli.Start(context.Background(), env)
+
+	go func() {
+		trm.WaitQuit()
+		ses.Stop()
+	}()
+	ses.Wait()
+
+	return cli.ExitEnd
+}
+
+// serveDrives maps dir to drive C: and serves the drives on a
+// free local port, the interpreter gets its files from a file
+// server the same as it does in the browser
+func serveDrives(dir string) (net.Listener, error) {
+	path, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	flag.Set("driveC", path)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	r := mux.NewRouter()
+	fileserv.WrapFileSources(r)
+	go http.Serve(l, r)
+
+	return l, nil
+}
+
+// printerOutput opens the file standing in for LPT1:
+// without one, printed lines go to stderr
+func printerOutput(file string) (io.WriteCloser, error) {
+	if len(file) == 0 {
+		return nopCloser{os.Stderr}, nil
+	}
+
+	return os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+}
+
+// nopCloser keeps stderr open when the printer is done
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// nativeEnvironment builds an environment that talks to the console
+// and gets files from the server listening on l
+func nativeEnvironment(con object.Console, l net.Listener) *object.Environment {
+	env := object.NewTermEnvironment(con)
+	env.SaveSetting(settings.ServerURL, &ast.StringLiteral{Value: "http://" + l.Addr().String() + "/"})
+
+	// there is no event loop to hand back, other goroutines just need a turn
+	env.SetBudget(object.Budget{Statements: 1000})
+
+	return env
+}
+
 // gwbasicHTML serves up the main page
 func gwbasicHTML(w http.ResponseWriter, r *http.Request) {
 	http.ServeFile(w, r, "./assets/html/gwbasic.html")
@@ -1,10 +1,16 @@
 package main
 
 import (
+	"bytes"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/navionguy/basicwasm/cli"
+	"github.com/navionguy/basicwasm/console"
 )
 
 func Test_Routes(t *testing.T) {
@@ -44,3 +50,40 @@ func Test_Routes(t *testing.T) {
 		}
 	}
 }
+
+func Test_RunProgram(t *testing.T) {
+	tests := []struct {
+		file string
+		src  string
+		exp  string
+	}{
+		{file: "PROG1.BAS", src: "10 PRINT \"HI\",\"THERE\"\r\n", exp: "HI            THERE\n"},
+		{file: "Mixed.Bas", src: "10 PRINT \"MIXED\"\r\n", exp: "MIXED\n"},
+	}
+
+	for _, tt := range tests {
+		dir := t.TempDir()
+		err := ioutil.WriteFile(filepath.Join(dir, tt.file), []byte(tt.src), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		l, err := serveDrives(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var out bytes.Buffer
+		env := nativeEnvironment(console.New(strings.NewReader(""), &out), l)
+		rc := cli.Run(`C:\`+tt.file, env)
+		l.Close()
+
+		if rc != cli.ExitEnd {
+			t.Errorf("%s returned %d, output %q", tt.file, rc, out.String())
+		}
+
+		if out.String() != tt.exp {
+			t.Errorf("%s printed %q, wanted %q", tt.file, out.String(), tt.exp)
+		}
+	}
+}
@@ -6,6 +6,8 @@ type MockFile struct {
 	FileName string
 	AccMode  gwtypes.AccessMode
 	LckMode  gwtypes.LockMode
+	Data     []byte
+	Pos      int
 }
 
 func (maf *MockFile) AccessMode() gwtypes.AccessMode {
@@ -19,6 +21,38 @@ func (maf *MockFile) FQFN() string {
 func (maf *MockFile) LockMode() gwtypes.LockMode {
 	return maf.LckMode
 }
+
+func (maf *MockFile) EOF() bool {
+	return maf.Pos >= len(maf.Data)
+}
+
+func (maf *MockFile) Loc() int {
+	return maf.Pos
+}
+
+func (maf *MockFile) Lof() int {
+	return len(maf.Data)
+}
+
+func (maf *MockFile) PeekByte() (byte, bool) {
+	if maf.EOF() {
+		return 0, false
+	}
+	return maf.Data[maf.Pos], true
+}
+
+func (maf *MockFile) GetByte() (byte, bool) {
+	b, ok := maf.PeekByte()
+	if ok {
+		maf.Pos++
+	}
+	return b, ok
+}
+
+func (maf *MockFile) Write(bt []byte) {
+	maf.Data = append(maf.Data[:maf.Pos], bt...)
+	maf.Pos = len(maf.Data)
+}
 func MockAnOpenFile(name string) MockFile {
 	maf := MockFile{FileName: name}
 
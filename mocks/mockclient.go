@@ -2,16 +2,19 @@ package mocks
 
 import (
 	"errors"
+	"io"
 	"net/http"
 	"strings"
 )
 
 // mocks the parts of http.Client that I use
 type MockClient struct {
-	Contents   string // file contents to send back
-	Url        string // Url to validate
-	Err        error  // Error to return on call
-	StatusCode int    // Status code to return
+	Contents   string            // file contents to send back
+	Url        string            // Url to validate
+	Err        error             // Error to return on call
+	StatusCode int               // Status code to return
+	Sent       []string          // method and url of each request passed to Do
+	Pages      map[string]string // contents to send back for each url, others get a 404
 }
 
 func (mc *MockClient) Get(url string) (*http.Response, error) {
@@ -20,6 +23,15 @@ func (mc *MockClient) Get(url string) (*http.Response, error) {
 		return nil, mc.Err
 	}
 
+	// serving up different contents for each url?
+	if mc.Pages != nil {
+		page, ok := mc.Pages[url]
+		if !ok {
+			return &http.Response{Status: "404 Not Found", StatusCode: http.StatusNotFound}, nil
+		}
+		return &http.Response{Status: "200 OK", StatusCode: http.StatusOK, Body: &readCloser{rdr: strings.NewReader(page)}}, nil
+	}
+
 	// Do I need to validate the Url?
 	if (len(mc.Url) != 0) && (strings.Compare(mc.Url, url) != 0) {
 		rsp := http.Response{Status: "404 Not Found", StatusCode: mc.StatusCode}
@@ -35,6 +47,32 @@ func (mc *MockClient) Get(url string) (*http.Response, error) {
 	return &rsp, mc.Err
 }
 
+// Do mocks sending a request that changes a file
+// the body sent is saved in Contents so it can be checked
+func (mc *MockClient) Do(req *http.Request) (*http.Response, error) {
+	// am I expected to error?
+	if mc.Err != nil {
+		return nil, mc.Err
+	}
+
+	sent := req.Method + " " + req.URL.String()
+	if dest := req.Header.Get("Destination"); len(dest) > 0 {
+		sent = sent + " " + dest
+	}
+	mc.Sent = append(mc.Sent, sent)
+
+	if req.Body != nil {
+		bts, _ := io.ReadAll(req.Body)
+		mc.Contents = string(bts)
+	}
+
+	if mc.StatusCode == 0 {
+		mc.StatusCode = 200
+	}
+	rsp := http.Response{Status: http.StatusText(mc.StatusCode), StatusCode: mc.StatusCode, Body: &readCloser{rdr: strings.NewReader("")}}
+	return &rsp, nil
+}
+
 // implement a io.ReadCloser
 type readCloser struct {
 	rdr *strings.Reader
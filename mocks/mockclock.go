@@ -0,0 +1,20 @@
+package mocks
+
+import "time"
+
+// MockClock stands still until told to move
+type MockClock struct {
+	Time time.Time
+	Step time.Duration // moves the clock along each time it is read
+}
+
+func (mc *MockClock) Now() time.Time {
+	now := mc.Time
+	mc.Time = mc.Time.Add(mc.Step)
+	return now
+}
+
+// Advance moves the clock forward by d
+func (mc *MockClock) Advance(d time.Duration) {
+	mc.Time = mc.Time.Add(d)
+}
@@ -0,0 +1,61 @@
+package mocks
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MockConsole types scripted keystrokes and keeps a transcript
+// of everything sent to the screen
+type MockConsole struct {
+	Keys       []byte          // keystrokes still waiting to be typed
+	Transcript strings.Builder // all the output, in order
+}
+
+func (mc *MockConsole) Cls() {
+	mc.Transcript.WriteString("\x1b[2J")
+}
+
+func (mc *MockConsole) Print(msg string) {
+	mc.Transcript.WriteString(msg)
+}
+
+func (mc *MockConsole) Println(msg string) {
+	mc.Transcript.WriteString(msg + "\r\n")
+}
+
+// Locate shows up in the transcript as a cursor position sequence
+func (mc *MockConsole) Locate(row, col int) {
+	mc.Transcript.WriteString(fmt.Sprintf("\x1b[%d;%dH", row, col))
+}
+
+func (mc *MockConsole) Log(msg string) {
+}
+
+func (mc *MockConsole) GetCursor() (int, int) {
+	return 0, 0
+}
+
+func (mc *MockConsole) Read(col, row, len int) string {
+	return ""
+}
+
+// ReadKeys types the next count keys, giving back fewer once the script runs out
+func (mc *MockConsole) ReadKeys(count int) []byte {
+	if count > len(mc.Keys) {
+		count = len(mc.Keys)
+	}
+
+	keys := mc.Keys[:count]
+	mc.Keys = mc.Keys[count:]
+
+	return keys
+}
+
+func (mc *MockConsole) SoundBell() {
+	mc.Transcript.WriteString("\a")
+}
+
+func (mc *MockConsole) BreakCheck() bool {
+	return false
+}
@@ -18,5 +18,6 @@ func (ml *MockLexer) NextToken() token.Token {
 	return rc
 }
 
-func (ml *MockLexer) PassOn()  {}
-func (ml *MockLexer) PassOff() {}
+func (ml *MockLexer) PassOn()      {}
+func (ml *MockLexer) PassOff()     {}
+func (ml *MockLexer) Line() string { return "" }
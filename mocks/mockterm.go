@@ -105,7 +105,10 @@ func (mt MockTerm) Log(msg string) {
 	fmt.Println(msg)
 }
 
-func (mt MockTerm) Locate(int, int) {
+// Locate moves the cursor, the upper left is 1,1
+func (mt MockTerm) Locate(row, col int) {
+	*mt.Row = row - 1
+	*mt.Col = col - 1
 }
 
 func (mt MockTerm) GetCursor() (int, int) {
@@ -144,12 +147,11 @@ func (mt MockTerm) ReadKeys(count int) []byte {
 	bt := []byte(*mt.StrVal)
 
 	if count >= len(bt) {
-		mt.StrVal = nil
+		*mt.StrVal = ""
 		return bt
 	}
 
-	v := (*mt.StrVal)[:count]
-	mt.StrVal = &v
+	*mt.StrVal = (*mt.StrVal)[count:]
 
 	return bt[:count]
 }
@@ -0,0 +1,67 @@
+package object
+
+import (
+	"runtime"
+	"time"
+)
+
+// Budget says how long a program gets to run before the evaluator
+// lets everything else have a turn, in the browser that is the only
+// way the screen gets updated and key presses get delivered
+type Budget struct {
+	Statements int           // statements run between letting other goroutines run, zero for never
+	Interval   time.Duration // time run between pauses, zero for never
+	Pause      time.Duration // how long to give up each interval, zero just lets other goroutines run
+}
+
+// DefaultBudget lets other goroutines in every thousand statements
+// and pauses about once a frame to hand the browser back its event loop
+var DefaultBudget = Budget{Statements: 1000, Interval: 16 * time.Millisecond, Pause: time.Millisecond}
+
+// SetBudget changes how often the evaluator yields
+func (e *Environment) SetBudget(b Budget) {
+	if e.outer != nil {
+		e.outer.SetBudget(b)
+		return
+	}
+	e.budget = b
+	e.spent = 0
+	e.yielded = time.Now()
+}
+
+// Budget returns how often the evaluator yields
+func (e *Environment) Budget() Budget {
+	if e.outer != nil {
+		return e.outer.Budget()
+	}
+	return e.budget
+}
+
+// Spend counts a statement against the budget, other goroutines get
+// to run after every Statements statements and the program pauses
+// once each Interval has gone by
+func (e *Environment) Spend() {
+	if e.outer != nil {
+		e.outer.Spend()
+		return
+	}
+
+	e.spent++
+	if (e.budget.Statements > 0) && (e.spent >= e.budget.Statements) {
+		e.spent = 0
+		runtime.Gosched()
+	}
+
+	if (e.budget.Interval == 0) || (time.Since(e.yielded) < e.budget.Interval) {
+		return
+	}
+
+	if e.budget.Pause > 0 {
+		time.Sleep(e.budget.Pause)
+	} else {
+		runtime.Gosched()
+	}
+
+	e.spent = 0
+	e.yielded = time.Now()
+}
@@ -0,0 +1,165 @@
+package object
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/navionguy/basicwasm/berrors"
+	"github.com/navionguy/basicwasm/decimal"
+)
+
+// Clock supplies the current time
+// tests can provide their own to freeze it or move it along
+type Clock interface {
+	Now() time.Time
+}
+
+// the clock on the wall
+type sysClock struct{}
+
+func (sysClock) Now() time.Time { return time.Now() }
+
+// the PC timer ticks 18.2 times a second
+const ticksPerSecond = 1193180.0 / 65536.0
+
+// SetClock replaces the clock time is read from
+func (e *Environment) SetClock(clk Clock) {
+	if e.outer != nil {
+		e.outer.SetClock(clk)
+		return
+	}
+	e.clock = clk
+}
+
+// Now returns the time as BASIC sees it, the clock
+// plus whatever DATE$ and TIME$ have moved it by
+func (e *Environment) Now() time.Time {
+	if e.outer != nil {
+		return e.outer.Now()
+	}
+	return e.clock.Now().Add(e.clkOfs)
+}
+
+// setNow moves BASIC's time to t, the clock itself is left alone
+func (e *Environment) setNow(t time.Time) {
+	if e.outer != nil {
+		e.outer.setNow(t)
+		return
+	}
+	e.clkOfs = t.Sub(e.clock.Now())
+}
+
+// Date returns the date as DATE$ shows it, mm-dd-yyyy
+func (e *Environment) Date() string {
+	return e.Now().Format("01-02-2006")
+}
+
+// Time returns the time as TIME$ shows it, hh:mm:ss
+func (e *Environment) Time() string {
+	return e.Now().Format("15:04:05")
+}
+
+// Timer returns the seconds since midnight, the fraction
+// only moves each time the timer ticks
+func (e *Environment) Timer() *Fixed {
+	now := e.Now()
+
+	secs := (now.Hour()*60+now.Minute())*60 + now.Second()
+	ticks := int(float64(now.Nanosecond()) / float64(time.Second) * ticksPerSecond)
+	hundredths := secs*100 + int(float64(ticks)/ticksPerSecond*100)
+
+	// keep the trailing zeros from printing
+	exp := -2
+	for (exp < 0) && (hundredths%10 == 0) {
+		hundredths /= 10
+		exp++
+	}
+
+	return &Fixed{Value: decimal.New(hundredths, exp)}
+}
+
+// setDate handles DATE$ = "mm-dd-yy", the year can have four digits
+// and the separators can be slashes
+func (e *Environment) setDate(val Object) Object {
+	str, ok := val.(*String)
+	if !ok {
+		return StdError(e, berrors.TypeMismatch)
+	}
+
+	parts := strings.FieldsFunc(str.Value, func(r rune) bool { return r == '-' || r == '/' })
+	if len(parts) != 3 {
+		return StdError(e, berrors.IllegalFuncCallErr)
+	}
+
+	nums, ok := clockFields(parts)
+	if !ok {
+		return StdError(e, berrors.IllegalFuncCallErr)
+	}
+
+	month, day, year := nums[0], nums[1], nums[2]
+	switch {
+	case len(parts[2]) <= 2 && year < 80:
+		year += 2000
+	case len(parts[2]) <= 2:
+		year += 1900
+	}
+
+	if (year < 1980) || (year > 2099) || (month < 1) || (month > 12) || (day < 1) || (day > daysIn(month, year)) {
+		return StdError(e, berrors.IllegalFuncCallErr)
+	}
+
+	now := e.Now()
+	e.setNow(time.Date(year, time.Month(month), day, now.Hour(), now.Minute(), now.Second(), now.Nanosecond(), now.Location()))
+	return nil
+}
+
+// setTime handles TIME$ = "hh[:mm[:ss]]"
+func (e *Environment) setTime(val Object) Object {
+	str, ok := val.(*String)
+	if !ok {
+		return StdError(e, berrors.TypeMismatch)
+	}
+
+	parts := strings.Split(str.Value, ":")
+	if len(parts) > 3 {
+		return StdError(e, berrors.IllegalFuncCallErr)
+	}
+
+	nums, ok := clockFields(parts)
+	if !ok {
+		return StdError(e, berrors.IllegalFuncCallErr)
+	}
+
+	// minutes and seconds default to zero
+	nums = append(nums, 0, 0)
+	hour, min, sec := nums[0], nums[1], nums[2]
+
+	if (hour > 23) || (min > 59) || (sec > 59) {
+		return StdError(e, berrors.IllegalFuncCallErr)
+	}
+
+	now := e.Now()
+	e.setNow(time.Date(now.Year(), now.Month(), now.Day(), hour, min, sec, 0, now.Location()))
+	return nil
+}
+
+// converts each field to a number, they must be one or more digits
+func clockFields(parts []string) ([]int, bool) {
+	var nums []int
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		n, err := strconv.Atoi(p)
+		if (err != nil) || (n < 0) || strings.ContainsAny(p, "+-") {
+			return nil, false
+		}
+		nums = append(nums, n)
+	}
+
+	return nums, true
+}
+
+// how many days are in the month
+func daysIn(month, year int) int {
+	return time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
@@ -1,9 +1,12 @@
 package object
 
 import (
+	"context"
+	"io"
 	"math/rand"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/navionguy/basicwasm/ast"
 	"github.com/navionguy/basicwasm/berrors"
@@ -121,29 +124,72 @@ type Console interface {
 
 // HttpClient allows me to mock an http.Client, minimally
 type HttpClient interface {
-	//Do(req *http.Request) (*http.Response, error)
+	Do(req *http.Request) (*http.Response, error)
 	Get(url string) (*http.Response, error)
 }
 
+// LocalStore holds the data files and directories programs create,
+// each session has its own, the localfiles package provides it
+type LocalStore interface {
+	// Open returns the file, fetching it from the server if it isn't held
+	Open(FQFN string, env *Environment) Object
+	// Create starts a new empty file
+	Create(FQFN string) Object
+	// Find returns the file if it is held, nil if it isn't
+	Find(FQFN string) Object
+	// Store saves the contents of a file
+	Store(FQFN string, data []byte) Object
+	// Remove drops a file, false if it wasn't held
+	Remove(FQFN string) bool
+	// Rename moves a file, false if it wasn't held
+	Rename(oldFQFN, newFQFN string) bool
+	// List returns the files held in a directory
+	List(dir string) []string
+	// MakeDir creates a directory
+	MakeDir(path string)
+	// RemoveDir drops a directory, false if it wasn't created here
+	RemoveDir(path string) bool
+	// DirExists is true for directories created here
+	DirExists(path string) bool
+	// DirEmpty is true if nothing is held in the directory
+	DirEmpty(path string) bool
+}
+
 // Environment holds my variables and possibly an outer environment
 type Environment struct {
-	ForLoops  []ForBlock                    // any For Loops that are active
-	store     map[string]*variable          // variables and other program data
-	common    map[string]*variable          // variables that live through a CHAIN
-	files     map[int16]gwtypes.AnOpenFile  // currently open files by file number
-	dir       map[string]gwtypes.AnOpenFile // locally cached files by full name
-	settings  map[string]ast.Node           // environment settings
-	readOnly  map[string]bool               // my read only environment variables
-	outer     *Environment                  // possibly a temporary containing environment, or nil
-	program   *ast.Program                  // current Abstract Syntax Tree
-	term      Console                       // the terminal console object
-	fgrColors map[int]string                // foreground terminal colors
-	bgrColors map[int]string                // background terminal colors
+	ForLoops   []ForBlock                    // any For Loops that are active
+	WhileLoops []WhileBlock                  // any While Loops that are active
+	store      map[string]*variable          // variables and other program data
+	common     map[string]*variable          // variables that live through a CHAIN
+	files      map[int16]gwtypes.AnOpenFile  // currently open files by file number
+	fields     map[int16][]*FieldVar         // FIELD variables for each open file
+	cols       map[int16]int                 // print column for each open file
+	dir        map[string]gwtypes.AnOpenFile // locally cached files by full name
+	settings   map[string]ast.Node           // environment settings
+	readOnly   map[string]bool               // my read only environment variables
+	outer      *Environment                  // possibly a temporary containing environment, or nil
+	program    *ast.Program                  // current Abstract Syntax Tree
+	term       Console                       // the terminal console object
+	scrn       *Screen                       // what is on the terminal screen
+	kbuff      *keybuffer.KeyBuffer          // keystrokes waiting to be read
+	lpt        *LinePrinter                  // where LPRINT output goes
+	lptOut     io.Writer                     // what is hooked up to LPT1:
+	termKeys   bool                          // the terminal fills kbuff itself
+	ctx        context.Context               // done when the session ends
+	local      LocalStore                    // files held locally
+	fgrColors  map[int]string                // foreground terminal colors
+	bgrColors  map[int]string                // background terminal colors
 
 	// The following hold "state" information controlled by commands/statements
 	client  HttpClient     // for making server requests
 	rnd     *rand.Rand     // random number generator
 	rndVal  float32        // most recent generated value
+	clock   Clock          // where the time of day comes from
+	clkOfs  time.Duration  // how far DATE$ and TIME$ have moved the clock
+	traps   []*eventTrap   // events being trapped, ON TIMER and the like
+	budget  Budget         // how long to run before yielding
+	spent   int            // statements run since the last yield
+	yielded time.Time      // when the last yield happened
 	run     bool           // program is currently executing, if false, a command is executing
 	stack   []ast.RetPoint // return addresses for GOSUB/RETURN
 	traceOn bool           // is tracing turned on
@@ -158,40 +204,163 @@ func NewEnclosedEnvironment(outer *Environment) *Environment {
 	env := newEnvironment()
 	env.outer = outer
 	env.term = outer.term
+	env.scrn = outer.scrn
 	return env
 }
 
 // NewEnvironment creates a place to store variables and settings
 func newEnvironment() *Environment {
-	e := &Environment{settings: make(map[string]ast.Node)}
+	e := &Environment{kbuff: keybuffer.New(), ctx: context.Background()}
+	e.powerOn()
+	e.setReadOnlys()
+	e.setColorMap()
+
+	e.clock = sysClock{}
+	e.SetBudget(DefaultBudget)
+	dc := http.DefaultClient
+	e.SetClient(dc)
+	return e
+}
+
+// the state everything is in when the machine is switched on
+func (e *Environment) powerOn() {
+	e.settings = make(map[string]ast.Node)
 	e.dir = make(map[string]gwtypes.AnOpenFile)
-	e.files = make(map[int16]gwtypes.AnOpenFile)
+	e.ForLoops = nil
+	e.WhileLoops = nil
+	e.stack = nil
+	e.run = false
+	e.traceOn = false
+	e.clkOfs = 0
+	e.lpt = nil
 	e.ClearCommon()
 	e.CloseAllFiles()
 	e.ClearVars()
-	if e.program == nil {
-		e.program = &ast.Program{}
-	}
-	e.program.New()
+	e.ClearTraps()
+	e.NewProgram()
 	e.setDefaults()
-	e.setReadOnlys()
-	e.setColorMap()
 
 	// initialize my random number generator
 	e.rnd = rand.New(rand.NewSource(37))
 	e.rndVal = e.rnd.Float32()
-	dc := http.DefaultClient
-	e.SetClient(dc)
-	return e
+}
+
+// PowerOn puts the session back the way it was when the machine
+// was switched on, without starting a new one
+// the program, variables and open files are gone and the screen is
+// cleared, files saved to the disks and the file server are kept
+func (e *Environment) PowerOn() {
+	if e.outer != nil {
+		e.outer.PowerOn()
+		return
+	}
+
+	mom := e.GetSetting(settings.ServerURL)
+	e.powerOn()
+	if mom != nil {
+		e.SaveSetting(settings.ServerURL, mom)
+	}
+
+	e.kbuff.ClearBreak()
+	if e.scrn != nil {
+		e.scrn.reset()
+	}
 }
 
 // NewTermEnvironment creates an environment with a terminal front-end
 func NewTermEnvironment(term Console) *Environment {
 	env := newEnvironment()
 	env.term = term
+
+	// all output goes thru the screen buffer
+	if term != nil {
+		env.scrn = NewScreen(term)
+		env.term = env.scrn
+	}
+
+	// a console reading its own keys shares its buffer
+	if kh, ok := term.(keyHolder); ok {
+		env.kbuff = kh.KeyBuffer()
+		env.termKeys = true
+		if ks, ok := env.GetSetting(settings.KeyMacs).(*ast.KeySettings); ok {
+			env.kbuff.SetKeySettings(ks)
+		}
+	}
 	return env
 }
 
+// keyHolder is a console that fills its own key buffer
+type keyHolder interface {
+	KeyBuffer() *keybuffer.KeyBuffer
+}
+
+// KeyBuffer gives access to the keystrokes typed in this session
+func (e *Environment) KeyBuffer() *keybuffer.KeyBuffer {
+	if e.outer != nil {
+		return e.outer.KeyBuffer()
+	}
+	return e.kbuff
+}
+
+// Context is done once the session running the environment ends
+func (e *Environment) Context() context.Context {
+	if e.outer != nil {
+		return e.outer.Context()
+	}
+	return e.ctx
+}
+
+// SetContext ties the environment to the session running it
+func (e *Environment) SetContext(ctx context.Context) {
+	if e.outer != nil {
+		e.outer.SetContext(ctx)
+		return
+	}
+	e.ctx = ctx
+}
+
+// ReadKeys waits for count keystrokes, giving back fewer if the session
+// ends or the keyboard has nothing more to give
+// a terminal filling its own key buffer is read directly so the
+// wait can be cut short
+func (e *Environment) ReadKeys(count int) []byte {
+	if e.outer != nil {
+		return e.outer.ReadKeys(count)
+	}
+
+	if !e.termKeys {
+		return e.term.ReadKeys(count)
+	}
+
+	var keys []byte
+	for len(keys) < count {
+		bt, err := e.kbuff.ReadKey(e.ctx)
+		if err != nil {
+			break
+		}
+		keys = append(keys, bt)
+	}
+
+	return keys
+}
+
+// LocalFiles returns the session's local file store, nil until it has one
+func (e *Environment) LocalFiles() LocalStore {
+	if e.outer != nil {
+		return e.outer.LocalFiles()
+	}
+	return e.local
+}
+
+// SetLocalFiles hands the session its local file store
+func (e *Environment) SetLocalFiles(lf LocalStore) {
+	if e.outer != nil {
+		e.outer.SetLocalFiles(lf)
+		return
+	}
+	e.local = lf
+}
+
 // set defaults for all the settings that have defaults
 func (e *Environment) setDefaults() {
 	// I always start on driveC
@@ -200,20 +369,16 @@ func (e *Environment) setDefaults() {
 	// setup default function key macros
 	kys := ast.KeySettings{Disp: true}
 	kys.Keys = make(map[string]string)
-	kys.Keys["F1"] = "LIST"
-	kys.Keys["F2"] = "RUN"
-	kys.Keys["F3"] = `LOAD "`
-	kys.Keys["F4"] = `SAVE "`
+	kys.Keys["F1"] = "LIST "
+	kys.Keys["F2"] = "RUN\r"
+	kys.Keys["F3"] = `LOAD"`
+	kys.Keys["F4"] = `SAVE"`
 	kys.Keys["F5"] = "CONT\r"
-	kys.Keys["F6"] = ", \"LPT1:\" \r"
+	kys.Keys["F6"] = ",\"LPT1:\"\r"
 	kys.Keys["F7"] = "TRON\r"
 	kys.Keys["F8"] = "TROFF\r"
-	kys.Keys["F9"] = "KEY"
+	kys.Keys["F9"] = "KEY "
 	kys.Keys["F10"] = "SCREEN 0,0,0\r"
-	kys.Keys["F11"] = "\x1b[A" // Up Arrow
-	kys.Keys["F12"] = "\x1b[D" // Left Arrow
-	kys.Keys["F13"] = "\x1b[C" // Right Arrow
-	kys.Keys["F14"] = "\x1b[B" // Down Arrow
 	e.SaveSetting(settings.KeyMacs, &kys)
 }
 
@@ -227,6 +392,7 @@ func (e *Environment) setReadOnlys() {
 	e.readOnly["ERL"] = true
 	e.readOnly["ERR"] = true
 	e.readOnly["INKEY$"] = true
+	e.readOnly["TIMER"] = true
 }
 
 // setup screen color mappings
@@ -314,11 +480,17 @@ func (e *Environment) Get(name string) Object {
 
 	// check for my special case
 	if strings.EqualFold(name, "INKEY$") {
-		bt, err := keybuffer.GetKeyBuffer().ReadByte()
-		if err != nil {
-			return &String{Value: ""}
-		}
-		return &String{Value: string(bt)}
+		return &String{Value: e.bufferedKey()}
+	}
+
+	// the clock values are always current
+	switch name {
+	case "DATE$":
+		return &String{Value: e.Date()}
+	case "TIME$":
+		return &String{Value: e.Time()}
+	case "TIMER":
+		return e.Timer()
 	}
 
 	// am I in an enclosed environment?
@@ -397,6 +569,14 @@ func (e *Environment) Set(name string, val Object) Object {
 		return StdError(e, berrors.Syntax)
 	}
 
+	// setting the date or time moves the clock
+	switch name {
+	case "DATE$":
+		return e.setDate(val)
+	case "TIME$":
+		return e.setTime(val)
+	}
+
 	// is he already saved?
 	t, ok := e.store[name]
 
@@ -434,7 +614,7 @@ func (e *Environment) SaveSetting(name string, obj ast.Node) {
 		if !ok {
 			return
 		}
-		keybuffer.GetKeyBuffer().KeySettings = ks
+		e.KeyBuffer().SetKeySettings(ks)
 	}
 }
 
@@ -453,6 +633,7 @@ func (e *Environment) Pop() *ast.RetPoint {
 
 	ret := e.stack[l-1]
 	e.stack = e.stack[:l-1]
+	e.endHandlers()
 
 	return &ret
 }
@@ -472,6 +653,49 @@ func (e *Environment) Terminal() Console {
 	return e.term
 }
 
+// InKey reads a key if there is one, extended keys
+// come back as a NUL followed by their code
+// it never waits for one to be pressed
+func (e *Environment) InKey() string {
+	if e.outer != nil {
+		return e.outer.InKey()
+	}
+
+	if e.termKeys {
+		return e.bufferedKey()
+	}
+
+	keys := e.term.ReadKeys(1)
+	if (len(keys) == 1) && (keys[0] == keybuffer.ExtendedKey) {
+		keys = append(keys, e.term.ReadKeys(1)...)
+	}
+
+	return string(keys)
+}
+
+// takes a key from the key buffer if one is waiting
+func (e *Environment) bufferedKey() string {
+	kb := e.KeyBuffer()
+	bt, err := kb.ReadByte()
+	if err != nil {
+		return ""
+	}
+
+	// extended keys bring their code along
+	keys := []byte{bt}
+	if bt == keybuffer.ExtendedKey {
+		if code, err := kb.ReadByte(); err == nil {
+			keys = append(keys, code)
+		}
+	}
+	return string(keys)
+}
+
+// Screen gives access to the screen contents
+func (e *Environment) Screen() *Screen {
+	return e.scrn
+}
+
 // SetTrace turns it on or off
 func (e *Environment) SetTrace(on bool) {
 	e.traceOn = on
@@ -496,6 +720,7 @@ func (e *Environment) SetClient(cl HttpClient) {
 // SetRun controls the "a program is running"
 func (e *Environment) SetRun(run bool) {
 	e.run = run
+	e.KeyBuffer().SetRunning(run)
 }
 
 // Quick test to see if program is currently running
@@ -541,6 +766,11 @@ func (e *Environment) AddCmdStmt(stmt ast.Statement) {
 	e.program.AddCmdStmt(stmt)
 }
 
+// ListIter walks the program without disturbing the one running it
+func (e *Environment) ListIter() *ast.Code {
+	return e.program.ListIter()
+}
+
 func (e *Environment) CmdLineIter() *ast.Code {
 	return e.program.CmdLineIter()
 }
@@ -580,3 +810,19 @@ func DecodeBytes(bts []byte) string {
 
 	return string(r)
 }
+
+// convert a string back into CP437 values
+// anything without a CP437 value becomes a '?'
+func EncodeBytes(str string) []byte {
+	var bts []byte
+
+	for _, r := range str {
+		b, ok := charmap.CodePage437.EncodeRune(r)
+		if !ok {
+			b = '?'
+		}
+		bts = append(bts, b)
+	}
+
+	return bts
+}
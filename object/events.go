@@ -0,0 +1,149 @@
+package object
+
+import (
+	"fmt"
+	"time"
+)
+
+// states an event trap can be in
+const (
+	TrapOff  = iota // events are ignored
+	TrapOn          // an event GOSUBs to the handler
+	TrapStop        // events are remembered until trapping is turned back on
+)
+
+// eventTrap follows one ON event GOSUB
+type eventTrap struct {
+	name     string        // the event, TIMER or KEYn
+	line     int           // line number of the handler, zero if there isn't one
+	state    int           // TrapOff, TrapOn or TrapStop
+	interval time.Duration // how often the timer goes off
+	next     time.Time     // when the timer goes off next
+	pending  bool          // the event happened, but hasn't been handled
+	depth    int           // stack depth inside the handler, zero when not handling
+}
+
+// find the trap for an event, creating it if needed
+func (e *Environment) trap(name string) *eventTrap {
+	for _, tr := range e.traps {
+		if tr.name == name {
+			return tr
+		}
+	}
+
+	tr := &eventTrap{name: name}
+	e.traps = append(e.traps, tr)
+	return tr
+}
+
+// ClearTraps turns off all event trapping
+func (e *Environment) ClearTraps() {
+	e.traps = nil
+	e.KeyBuffer().ClearTraps()
+}
+
+// SetTimer sets how often the timer event happens and the line to GOSUB to
+func (e *Environment) SetTimer(interval time.Duration, line int) {
+	tr := e.trap("TIMER")
+	tr.interval = interval
+	tr.line = line
+	tr.next = e.Now().Add(interval)
+}
+
+// SetKeyTrap sets the line to GOSUB to when a key is pressed
+func (e *Environment) SetKeyTrap(key int, line int) {
+	e.trap(keyEvent(key)).line = line
+}
+
+// SetKeyState turns trapping of a key on, off or stops it
+// the key buffer holds on to the key unless trapping is off
+func (e *Environment) SetKeyState(key int, state int) {
+	e.SetTrapState(keyEvent(key), state)
+	e.KeyBuffer().TrapKey(key, state != TrapOff)
+}
+
+// the event name for a key number
+func keyEvent(key int) string {
+	return fmt.Sprintf("KEY%d", key)
+}
+
+// SetTrapState turns trapping of an event on, off or stops it
+func (e *Environment) SetTrapState(name string, state int) {
+	tr := e.trap(name)
+
+	switch state {
+	case TrapOn:
+		// the timer starts counting when it gets turned on
+		if tr.state == TrapOff {
+			tr.next = e.Now().Add(tr.interval)
+		}
+	case TrapOff:
+		tr.pending = false
+	}
+
+	tr.state = state
+}
+
+// NextEvent returns the line number of the handler for an event that
+// needs handling, zero if there isn't one
+// trapping of the event is held off until the handler RETURNs
+func (e *Environment) NextEvent() int {
+	e.checkTimer()
+	e.checkKeys()
+
+	for _, tr := range e.traps {
+		if !tr.pending || (tr.state != TrapOn) || (tr.depth > 0) {
+			continue
+		}
+
+		tr.pending = false
+		if tr.line == 0 {
+			continue
+		}
+
+		// the countdown starts over once the event is handled
+		if tr.interval > 0 {
+			tr.next = e.Now().Add(tr.interval)
+		}
+
+		// the handler is called with the return address pushed
+		tr.depth = len(e.stack) + 1
+		return tr.line
+	}
+
+	return 0
+}
+
+// see if the timer has gone off
+func (e *Environment) checkTimer() {
+	for _, tr := range e.traps {
+		if (tr.interval == 0) || (tr.state == TrapOff) {
+			continue
+		}
+
+		now := e.Now()
+		if !now.Before(tr.next) {
+			tr.pending = true
+			tr.next = now.Add(tr.interval)
+		}
+	}
+}
+
+// see if any trapped keys were pressed
+func (e *Environment) checkKeys() {
+	for _, key := range e.KeyBuffer().KeyHits() {
+		tr := e.trap(keyEvent(key))
+		if tr.state != TrapOff {
+			tr.pending = true
+		}
+	}
+}
+
+// a RETURN out of a handler lets its event be trapped again
+func (e *Environment) endHandlers() {
+	for _, tr := range e.traps {
+		if tr.depth > len(e.stack) {
+			tr.depth = 0
+		}
+	}
+}
@@ -159,6 +159,11 @@ type ForBlock struct {
 	Four *ast.ForStatement // the actual statement
 }
 
+type WhileBlock struct {
+	Code  ast.RetPoint        // the location in the AST of the WHILE statement
+	While *ast.WhileStatement // the actual statement
+}
+
 // String values
 type String struct {
 	Value string
@@ -243,7 +248,8 @@ func (f *Function) Inspect() string {
 
 // HaltSignal tells the eval loop to stop executing
 type HaltSignal struct {
-	Msg string
+	Msg  string
+	Exit bool // SYSTEM was executed, the session should end
 }
 
 func (hs *HaltSignal) Type() ObjectType { return HALT_SIGNAL }
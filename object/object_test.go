@@ -1,14 +1,15 @@
 package object
 
 import (
+	"bytes"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/navionguy/basicwasm/ast"
 	"github.com/navionguy/basicwasm/berrors"
 	"github.com/navionguy/basicwasm/decimal"
 	"github.com/navionguy/basicwasm/gwtypes"
-	"github.com/navionguy/basicwasm/keybuffer"
 	"github.com/navionguy/basicwasm/mocks"
 	"github.com/navionguy/basicwasm/settings"
 	"github.com/navionguy/basicwasm/token"
@@ -45,6 +46,28 @@ func Test_AddOpenFile(t *testing.T) {
 	}
 }
 
+func Test_FileCol(t *testing.T) {
+	f1 := mocks.MockAnOpenFile("Data.txt")
+	var mt mocks.MockTerm
+	env := NewTermEnvironment(mt)
+
+	env.AddOpenFile(1, &f1)
+	env.SetFileCol(1, 5)
+	assert.Equal(t, 5, env.FileCol(1))
+
+	// opening the number again starts a new line
+	env.AddOpenFile(1, &f1)
+	assert.Equal(t, 0, env.FileCol(1), "OPEN didn't reset the column")
+
+	env.SetFileCol(1, 5)
+	env.CloseFile(1)
+	assert.Equal(t, 0, env.FileCol(1), "CLOSE didn't reset the column")
+
+	env.SetFileCol(2, 5)
+	env.CloseAllFiles()
+	assert.Equal(t, 0, env.FileCol(2), "CLOSE didn't reset every column")
+}
+
 func Test_Array(t *testing.T) {
 	arr := Array{}
 
@@ -122,15 +145,56 @@ func Test_CloseFile(t *testing.T) {
 		}
 
 		for i := range tt.num {
+			assert.NotNil(t, env.GetOpenFile(tt.num[i]), "GetOpenFile failed")
+
 			rc := env.CloseFile(tt.num[i])
 
 			assert.True(t, rc, "CloseFile failed")
+			assert.Nil(t, env.GetOpenFile(tt.num[i]), "file still open after CloseFile")
 		}
 
 		assert.False(t, env.CloseFile(tt.fail), "CloseFile should have failed")
+		assert.Empty(t, env.FindOpenFiles("Data.txt"), "closed file still found")
 	}
 }
 
+func Test_Fields(t *testing.T) {
+	var mt mocks.MockTerm
+	env := NewTermEnvironment(mt)
+
+	a := &FieldVar{Name: "A$", Width: 4, Value: &String{Value: "ABCD"}}
+	b := &FieldVar{Name: "B$", Offset: 4, Width: 2, Value: &String{Value: "EF"}}
+	env.Set("A$", a.Value)
+	env.Set("B$", b.Value)
+	env.AddField(1, a)
+	env.AddField(1, b)
+
+	assert.Len(t, env.Fields(1), 2)
+	fn, fv := env.FindField("a$")
+	assert.Equal(t, int16(1), fn)
+	assert.Equal(t, a, fv)
+
+	// binding the variable again moves it to the new file
+	a2 := &FieldVar{Name: "A$", Width: 4, Value: a.Value}
+	env.AddField(2, a2)
+	assert.Len(t, env.Fields(1), 1)
+	fn, fv = env.FindField("A$")
+	assert.Equal(t, int16(2), fn)
+	assert.Equal(t, a2, fv)
+
+	// assigning a new value breaks the binding
+	env.Set("B$", &String{Value: "XY"})
+	_, fv = env.FindField("B$")
+	assert.Nil(t, fv)
+	assert.Empty(t, env.Fields(1))
+
+	// closing the file drops his fields
+	env.AddOpenFile(2, nil)
+	env.CloseAllFiles()
+	_, fv = env.FindField("A$")
+	assert.Nil(t, fv)
+}
+
 func Test_ClearVars(t *testing.T) {
 	env := newEnvironment()
 
@@ -173,6 +237,85 @@ func Test_CodeInterface(t *testing.T) {
 	assert.NotNil(t, cd)
 }
 
+func Test_Clock(t *testing.T) {
+	tests := []struct {
+		date  string
+		time  string
+		err   int
+		exp   string
+		timer string
+	}{
+		{exp: "06-15-1988 13:45:30", timer: "49530"},
+		{date: "01-02-90", exp: "01-02-1990 13:45:30", timer: "49530"},
+		{date: "12/31/2079", exp: "12-31-2079 13:45:30", timer: "49530"},
+		{date: "02-29-79", err: berrors.IllegalFuncCallErr},
+		{date: "02-29-2000", exp: "02-29-2000 13:45:30", timer: "49530"},
+		{date: "13-01-1990", err: berrors.IllegalFuncCallErr},
+		{date: "01-01-1979", err: berrors.IllegalFuncCallErr},
+		{date: "01-01", err: berrors.IllegalFuncCallErr},
+		{time: "8", exp: "06-15-1988 08:00:00", timer: "28800"},
+		{time: "8:05", exp: "06-15-1988 08:05:00", timer: "29100"},
+		{time: "23:59:59", exp: "06-15-1988 23:59:59", timer: "86399"},
+		{time: "0:0:1", exp: "06-15-1988 00:00:01", timer: "1"},
+		{time: "24:00", err: berrors.IllegalFuncCallErr},
+		{time: "12:60", err: berrors.IllegalFuncCallErr},
+		{time: "1:2:3:4", err: berrors.IllegalFuncCallErr},
+		{time: "noon", err: berrors.IllegalFuncCallErr},
+	}
+
+	for _, tt := range tests {
+		clk := &mocks.MockClock{Time: time.Date(1988, time.June, 15, 13, 45, 30, 0, time.Local)}
+		env := newEnvironment()
+		env.SetClock(clk)
+
+		var rc Object
+		if len(tt.date) > 0 {
+			rc = env.Set("DATE$", &String{Value: tt.date})
+		}
+		if len(tt.time) > 0 {
+			rc = env.Set("TIME$", &String{Value: tt.time})
+		}
+
+		if tt.err != 0 {
+			assert.Equal(t, StdError(env, tt.err), rc, "%s%s didn't fail", tt.date, tt.time)
+			continue
+		}
+		assert.Nil(t, rc, "%s%s failed", tt.date, tt.time)
+		assert.Equal(t, tt.exp, env.Get("DATE$").Inspect()+" "+env.Get("TIME$").Inspect(), "%s%s set the wrong time", tt.date, tt.time)
+		assert.Equal(t, tt.timer, env.Get("TIMER").Inspect(), "%s%s wrong TIMER", tt.date, tt.time)
+	}
+}
+
+func Test_ClockRunning(t *testing.T) {
+	clk := &mocks.MockClock{Time: time.Date(2021, time.March, 1, 23, 59, 59, 0, time.Local)}
+	env := newEnvironment()
+	encenv := NewEnclosedEnvironment(env)
+	env.SetClock(clk)
+
+	assert.Equal(t, "03-01-2021", encenv.Date(), "enclosed environment has the wrong date")
+	assert.Equal(t, "23:59:59", encenv.Time(), "enclosed environment has the wrong time")
+
+	// a tick is about 55ms, less than that doesn't show
+	clk.Advance(50 * time.Millisecond)
+	assert.Equal(t, "86399", env.Timer().Inspect(), "TIMER moved too soon")
+	clk.Advance(10 * time.Millisecond)
+	assert.Equal(t, "86399.05", env.Timer().Inspect(), "TIMER didn't tick")
+
+	// TIME$ set from the enclosed environment moves everybody's clock
+	assert.Nil(t, encenv.Set("TIME$", &String{Value: "10:00"}), "TIME$ set failed")
+	clk.Advance(time.Hour)
+	assert.Equal(t, "11:00:00", env.Time(), "set clock didn't keep running")
+
+	// over midnight the date changes
+	clk.Advance(13 * time.Hour)
+	assert.Equal(t, "03-02-2021", env.Date(), "date didn't roll over")
+	assert.Equal(t, "0", env.Timer().Inspect(), "TIMER didn't roll over")
+
+	assert.Equal(t, StdError(env, berrors.TypeMismatch), env.Set("DATE$", &Integer{Value: 1}), "DATE$ took a number")
+	assert.Equal(t, StdError(env, berrors.TypeMismatch), env.Set("TIME$", &Integer{Value: 1}), "TIME$ took a number")
+	assert.Equal(t, StdError(env, berrors.Syntax), env.Set("TIMER", &Integer{Value: 1}), "TIMER isn't read only")
+}
+
 func Test_Common(t *testing.T) {
 	env := newEnvironment()
 
@@ -210,12 +353,33 @@ func Test_DecodeByte(t *testing.T) {
 	assert.EqualValues(t, exp, str, "decodeByte values don't match ")
 }
 
+func Test_EncodeBytes(t *testing.T) {
+	tests := []struct {
+		inp string
+		exp []byte
+	}{
+		{inp: "∙═╠╬", exp: []byte{0xf9, 0xcd, 0xcc, 0xce}},
+		{inp: "Hi!", exp: []byte{'H', 'i', '!'}},
+		{inp: "€", exp: []byte{'?'}},
+	}
+
+	for _, tt := range tests {
+		bts := EncodeBytes(tt.inp)
+
+		assert.EqualValuesf(t, tt.exp, bts, "EncodeBytes(%s) got %v", tt.inp, bts)
+	}
+}
+
 func Test_DefaultKeys(t *testing.T) {
 	tests := []struct {
 		key string
 		val string
 	}{
-		{key: `F1`, val: `LIST`},
+		{key: `F1`, val: `LIST `},
+		{key: `F2`, val: "RUN\r"},
+		{key: `F3`, val: `LOAD"`},
+		{key: `F6`, val: ",\"LPT1:\"\r"},
+		{key: `F10`, val: "SCREEN 0,0,0\r"},
 	}
 
 	var mt mocks.MockTerm
@@ -452,6 +616,93 @@ func Test_RestartSignal(t *testing.T) {
 	assert.Equal(t, "RESTART", rs.Inspect(), "Restart Inspect() returned %s", rs.Inspect())
 }
 
+func Test_Screen(t *testing.T) {
+	tests := []struct {
+		out   string // printed to the screen
+		width int
+		row   int // where the cursor should end up
+		col   int
+		read  int    // row to read back
+		exp   string // what should be there
+		lline string // the logical line containing read
+		first int    // first row of the logical line
+		last  int    // last row of the logical line
+	}{
+		{out: "HELLO", row: 0, col: 5, exp: "HELLO", lline: "HELLO"},
+		{out: "HELLO\r\nWORLD", row: 1, col: 5, read: 1, exp: "WORLD", lline: "WORLD", first: 1, last: 1},
+		{out: strings.Repeat("X", 85), row: 1, col: 5, read: 1, exp: "XXXXX", lline: strings.Repeat("X", 85), last: 1},
+		{out: strings.Repeat("X", 80), row: 0, col: 79, exp: strings.Repeat("X", 80), lline: strings.Repeat("X", 80)},
+		{out: strings.Repeat("X", 45), width: 40, row: 1, col: 5, read: 1, exp: "XXXXX", lline: strings.Repeat("X", 45), last: 1},
+		{out: "A\tB", row: 0, col: 15, exp: "A             B", lline: "A             B"},
+		{out: "A\tB\tC", width: 40, row: 0, col: 29, exp: "A             B             C", lline: "A             B             C"},
+		{out: strings.Repeat("X", 30) + "\tB", width: 40, row: 1, col: 1, read: 1, exp: "B", lline: "B", first: 1, last: 1},
+		{out: "ABC\bD", row: 0, col: 3, exp: "ABD", lline: "ABD"},
+		{out: "HELLO\x1b[1;3H\x1b[@", row: 0, col: 2, exp: "HE LLO", lline: "HE LLO"},
+		{out: "HELLO\x1b[1;3H\x1b[P", row: 0, col: 2, exp: "HELO", lline: "HELO"},
+		{out: "HELLO\x1b[1;3H\x1b[K", row: 0, col: 2, exp: "HE", lline: "HE"},
+		{out: "HELLO\x1b[1;3H\x1b[2K", row: 0, col: 2, exp: "", lline: ""},
+		{out: "HELLO\x1b[2J", row: 0, col: 5, exp: "", lline: ""},
+		{out: "\x1b[3;5HHI\x1b[2D\x1b[1A", row: 1, col: 4, read: 2, exp: "    HI", lline: "    HI", first: 2, last: 2},
+		{out: "\x1b]0;title\x07HI", row: 0, col: 2, exp: "HI", lline: "HI"},
+		{out: "TOP" + strings.Repeat("\r\n", 25) + "BOTTOM", row: 24, col: 6, read: 23, exp: "", lline: "", first: 23, last: 23},
+		{out: "TOP" + strings.Repeat("\r\n", 24), row: 24, col: 0, exp: "TOP", lline: "TOP"},
+		{out: "TOP\x1b[2;10r" + strings.Repeat("\r\n", 12) + "X", row: 9, col: 1, exp: "TOP", lline: "TOP"},
+	}
+
+	for _, tt := range tests {
+		trm := mocks.MockTerm{}
+		mocks.InitMockTerm(&trm)
+		scrn := NewScreen(trm)
+		if tt.width != 0 {
+			scrn.SetWidth(tt.width)
+		}
+		scrn.Print(tt.out)
+
+		row, col := scrn.GetCursor()
+		assert.Equal(t, tt.row, row, "%q left cursor on wrong row", tt.out)
+		assert.Equal(t, tt.col, col, "%q left cursor in wrong column", tt.out)
+		assert.Equal(t, tt.exp, scrn.Read(0, tt.read, scrn.Width()), "%q read back wrong", tt.out)
+
+		lline, last := scrn.LogicalLine(tt.read)
+		assert.Equal(t, tt.lline, lline, "%q logical line wrong", tt.out)
+		assert.Equal(t, tt.last, last, "%q logical line ended on wrong row", tt.out)
+		assert.Equal(t, tt.first, scrn.LineStart(tt.read), "%q logical line started on wrong row", tt.out)
+	}
+}
+
+func Test_ScreenCell(t *testing.T) {
+	trm := mocks.MockTerm{}
+	mocks.InitMockTerm(&trm)
+	scrn := NewScreen(trm)
+
+	scrn.Print("A\x1b[34m\x1b[47mB\x1b[0mC\x1b[93mD")
+
+	tests := []struct {
+		col  int
+		ch   byte
+		attr byte
+	}{
+		{col: 0, ch: 'A', attr: DefaultAttr},
+		{col: 1, ch: 'B', attr: GWBlue | GWWhite<<4},
+		{col: 2, ch: 'C', attr: DefaultAttr},
+		{col: 3, ch: 'D', attr: GWBrown + 8},
+		{col: 4, ch: ' ', attr: DefaultAttr},
+	}
+
+	for _, tt := range tests {
+		ch, attr := scrn.Cell(0, tt.col)
+		assert.Equal(t, tt.ch, ch, "wrong character in column %d", tt.col)
+		assert.Equal(t, tt.attr, attr, "wrong attribute in column %d", tt.col)
+	}
+
+	// a screen clear puts things back
+	scrn.Println("")
+	scrn.Cls()
+	row, col := scrn.GetCursor()
+	assert.Equal(t, 0, row+col, "Cls didn't home the cursor")
+	assert.True(t, *trm.SawCls, "Cls didn't reach the terminal")
+}
+
 func Test_Settings(t *testing.T) {
 	name := "test"
 	env := newEnvironment()
@@ -480,7 +731,7 @@ func Test_SettingKeyMac(t *testing.T) {
 		env := newEnvironment()
 
 		env.SaveSetting(settings.KeyMacs, tt.sett)
-		ks := keybuffer.GetKeyBuffer().KeySettings
+		ks := env.KeyBuffer().KeySettings
 
 		if tt.fail {
 			assert.NotEqualValuesf(t, tt.sett, ks, "KeyMacs setting saved to KeyBuffer when it shouldn't have")
@@ -490,6 +741,118 @@ func Test_SettingKeyMac(t *testing.T) {
 	}
 }
 
+func Test_SessionKeyBuffers(t *testing.T) {
+	env1 := newEnvironment()
+	env2 := newEnvironment()
+
+	assert.NotSame(t, env1.KeyBuffer(), env2.KeyBuffer(), "sessions are sharing a key buffer")
+	assert.Same(t, env1.KeyBuffer(), NewEnclosedEnvironment(env1).KeyBuffer(), "enclosed environment has its own key buffer")
+
+	env1.KeyBuffer().SaveKeyStroke([]byte("A"))
+	_, err := env2.KeyBuffer().ReadByte()
+	assert.Error(t, err, "key stroke leaked into the other session")
+
+	ch, err := env1.KeyBuffer().ReadByte()
+	assert.NoError(t, err)
+	assert.Equal(t, byte('A'), ch)
+}
+
+func Test_PowerOn(t *testing.T) {
+	var mt mocks.MockTerm
+	mocks.InitMockTerm(&mt)
+	env := NewTermEnvironment(mt)
+	mom := &ast.StringLiteral{Value: "http://localhost:8080/"}
+	env.SaveSetting(settings.ServerURL, mom)
+	env.SaveSetting(settings.Auto, &ast.AutoCommand{})
+	env.AddStatement(&ast.EndStatement{})
+	env.Set("A", &Integer{Value: 5})
+	env.Common("A")
+	f := mocks.MockAnOpenFile("Data.txt")
+	env.AddOpenFile(1, &f)
+	env.SetTrace(true)
+	env.SetTimer(time.Second, 10)
+	env.Push(ast.RetPoint{})
+	env.Screen().SetWidth(40)
+
+	NewEnclosedEnvironment(env).PowerOn()
+
+	assert.Equal(t, 0, env.StatementIter().Len(), "program survived")
+	assert.Equal(t, &Integer{Value: 0}, env.Get("A"), "variables survived")
+	assert.Empty(t, env.common, "common variables survived")
+	assert.Nil(t, env.GetOpenFile(1), "file left open")
+	assert.False(t, env.GetTrace(), "tracing still on")
+	assert.Empty(t, env.traps, "timer still set")
+	assert.Nil(t, env.Pop(), "stack not emptied")
+	assert.Nil(t, env.GetSetting(settings.Auto), "auto still on")
+	assert.Equal(t, mom, env.GetSetting(settings.ServerURL), "lost the server")
+	assert.NotNil(t, env.GetSetting(settings.KeyMacs), "function keys not set")
+	assert.Equal(t, ScreenCols, env.Screen().Width(), "screen width not reset")
+}
+
+func Test_LinePrinter(t *testing.T) {
+	var mt mocks.MockTerm
+	mocks.InitMockTerm(&mt)
+	env := NewTermEnvironment(mt)
+
+	lp := NewEnclosedEnvironment(env).LinePrinter()
+	assert.Same(t, env.LinePrinter(), lp, "printer not shared")
+
+	lp.Print("A\tB")
+	assert.Equal(t, "A             B", lp.line.String())
+	assert.Equal(t, 15, lp.col)
+
+	lp.Println("C")
+	assert.Empty(t, lp.line.String(), "line not sent")
+	assert.Equal(t, 0, lp.col)
+
+	env.PowerOn()
+	assert.NotSame(t, lp, env.LinePrinter(), "printer not reset")
+
+	var paper bytes.Buffer
+	NewEnclosedEnvironment(env).SetPrinterOutput(&paper)
+	env.LinePrinter().Print("A\t")
+	env.LinePrinter().Println("B")
+	env.LinePrinter().Println("")
+	assert.Equal(t, "A             B\r\n\r\n", paper.String())
+
+	env.PowerOn()
+	env.LinePrinter().Println("STILL HOOKED UP")
+	assert.Equal(t, "A             B\r\n\r\nSTILL HOOKED UP\r\n", paper.String())
+}
+
+func Test_Budget(t *testing.T) {
+	tests := []struct {
+		budget Budget
+		stmts  int
+		spent  int
+		paused bool // the interval went by
+	}{
+		{budget: Budget{Statements: 3}, stmts: 2, spent: 2},
+		{budget: Budget{Statements: 3}, stmts: 3, spent: 0},
+		{budget: Budget{Statements: 3, Pause: time.Microsecond}, stmts: 7, spent: 1},
+		{budget: Budget{Statements: 3, Interval: time.Hour, Pause: time.Hour}, stmts: 7, spent: 1},
+		{budget: Budget{}, stmts: 5, spent: 5},
+		{budget: Budget{Interval: time.Nanosecond}, stmts: 4, spent: 0, paused: true},
+		{budget: Budget{Statements: 1000, Interval: time.Nanosecond, Pause: time.Microsecond}, stmts: 2, spent: 0, paused: true},
+	}
+
+	for _, tt := range tests {
+		env := newEnvironment()
+		assert.Equal(t, DefaultBudget, env.Budget())
+
+		sub := NewEnclosedEnvironment(env)
+		sub.SetBudget(tt.budget)
+		assert.Equal(t, tt.budget, env.Budget(), "%v wasn't set", tt.budget)
+		start := env.yielded
+
+		for i := 0; i < tt.stmts; i++ {
+			sub.Spend()
+		}
+		assert.Equal(t, tt.spent, env.spent, "%v yielded at the wrong time", tt.budget)
+		assert.Equal(t, tt.paused, env.yielded != start, "%v paused at the wrong time", tt.budget)
+	}
+}
+
 func Test_Stack(t *testing.T) {
 	tests := []struct {
 		pushCount int
@@ -6,13 +6,24 @@ import (
 	"github.com/navionguy/basicwasm/gwtypes"
 )
 
+// FieldVar is a string variable bound to part of a file's record buffer
+type FieldVar struct {
+	Name   string  // the variable name
+	Offset int     // where the field starts in the record buffer
+	Width  int     // how many bytes the field holds
+	Value  *String // the value last stored in the variable
+}
+
 func (e *Environment) AddOpenFile(num int16, file gwtypes.AnOpenFile) {
 	e.files[num] = file
+	delete(e.cols, num)
 }
 
 // CloseAllFiles closes all open files
 func (e *Environment) CloseAllFiles() {
 	e.files = make(map[int16]gwtypes.AnOpenFile)
+	e.fields = make(map[int16][]*FieldVar)
+	e.cols = make(map[int16]int)
 }
 
 // CloseFile closes a file based on its handle
@@ -20,11 +31,70 @@ func (e *Environment) CloseFile(f int16) bool {
 	if e.files[f] == nil {
 		return false
 	}
-	e.files[f] = nil
+	delete(e.files, f)
+	delete(e.fields, f)
+	delete(e.cols, f)
 
 	return true
 }
 
+// FileCol returns the column PRINT # left file f at, zero at the start of a line
+func (e *Environment) FileCol(f int16) int {
+	return e.cols[f]
+}
+
+// SetFileCol saves the column PRINT # left file f at
+func (e *Environment) SetFileCol(f int16, col int) {
+	e.cols[f] = col
+}
+
+// AddField binds a variable to part of the record buffer for file f
+// a variable can only be bound to one field at a time
+func (e *Environment) AddField(f int16, fv *FieldVar) {
+	for fn, fl := range e.fields {
+		for i, old := range fl {
+			if strings.EqualFold(old.Name, fv.Name) {
+				e.fields[fn] = append(fl[:i], fl[i+1:]...)
+				break
+			}
+		}
+	}
+
+	e.fields[f] = append(e.fields[f], fv)
+}
+
+// Fields returns the variables still bound to the record buffer of file f
+func (e *Environment) Fields(f int16) []*FieldVar {
+	var l []*FieldVar
+
+	for _, fv := range e.fields[f] {
+		if e.Get(fv.Name) == Object(fv.Value) {
+			l = append(l, fv)
+		}
+	}
+
+	return l
+}
+
+// FindField returns the binding for a FIELD variable, nil if there isn't one
+// assigning a variable with LET breaks its binding to the buffer
+func (e *Environment) FindField(name string) (int16, *FieldVar) {
+	for fn := range e.fields {
+		for _, fv := range e.Fields(fn) {
+			if strings.EqualFold(fv.Name, name) {
+				return fn, fv
+			}
+		}
+	}
+
+	return 0, nil
+}
+
+// GetOpenFile returns the file open as number f, nil if there isn't one
+func (e *Environment) GetOpenFile(f int16) gwtypes.AnOpenFile {
+	return e.files[f]
+}
+
 // find all the currently open instances of files that
 // match the fully qualified file name provided
 func (e *Environment) FindOpenFiles(file string) []gwtypes.AnOpenFile {
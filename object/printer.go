@@ -0,0 +1,67 @@
+package object
+
+import (
+	"io"
+	"strings"
+)
+
+// LinePrinter stands in for LPT1:, there is no paper so each
+// finished line goes to the printer output, or to the console
+// log when nothing is hooked up
+type LinePrinter struct {
+	out  io.Writer       // where finished lines go
+	term Console         // where finished lines get logged without an out
+	line strings.Builder // the line being printed
+	col  int             // column for tabbing to the next print zone
+}
+
+// LinePrinter returns the printer for LPRINT
+func (e *Environment) LinePrinter() *LinePrinter {
+	if e.outer != nil {
+		return e.outer.LinePrinter()
+	}
+
+	if e.lpt == nil {
+		e.lpt = &LinePrinter{out: e.lptOut, term: e.term}
+	}
+	return e.lpt
+}
+
+// SetPrinterOutput hooks up LPT1:, each line LPRINTed is
+// written to w ending in a carriage return/line feed
+func (e *Environment) SetPrinterOutput(w io.Writer) {
+	if e.outer != nil {
+		e.outer.SetPrinterOutput(w)
+		return
+	}
+	e.lptOut = w
+	e.lpt = nil
+}
+
+// Print adds the string to the line, commas tab out to the next print zone
+func (lp *LinePrinter) Print(s string) {
+	for _, b := range []byte(s) {
+		if b == '\t' {
+			for pad := PrintZone - (lp.col % PrintZone); pad > 0; pad-- {
+				lp.line.WriteByte(' ')
+				lp.col++
+			}
+			continue
+		}
+		lp.line.WriteByte(b)
+		lp.col++
+	}
+}
+
+// Println finishes the line and sends it to the printer output
+func (lp *LinePrinter) Println(s string) {
+	lp.Print(s)
+	switch {
+	case lp.out != nil:
+		io.WriteString(lp.out, lp.line.String()+"\r\n")
+	case lp.term != nil:
+		lp.term.Log(lp.line.String())
+	}
+	lp.line.Reset()
+	lp.col = 0
+}
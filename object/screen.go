@@ -0,0 +1,470 @@
+package object
+
+import (
+	"strconv"
+	"strings"
+)
+
+// size of the GW-BASIC text screen
+const (
+	ScreenRows = 25
+	ScreenCols = 80
+)
+
+// PrintZone is the width of the zones a comma in PRINT tabs out to
+const PrintZone = 14
+
+// DefaultAttr is white on black
+const DefaultAttr = GWWhite
+
+// maps the ANSI color order onto the GW-BASIC color numbers
+var ansiToGW = [8]byte{GWBlack, GWRed, GWGreen, GWBrown, GWBlue, GWMagenta, GWCyan, GWWhite}
+
+// one character position on the screen
+type cell struct {
+	ch   rune
+	attr byte // foreground in the low nibble, background in the high
+}
+
+// Screen keeps a copy of everything on the text display.
+// All output passes thru it on the way to the terminal, which
+// just renders it, so what is on screen and where the cursor
+// sits is always known without asking the terminal.
+type Screen struct {
+	term   Console  // the terminal doing the actual display
+	cells  [][]cell // ScreenRows rows of cols cells
+	cont   []bool   // true if the row is a continuation of the one above
+	cols   int      // 80 or 40 columns
+	row    int      // cursor position, upper left is 0,0
+	col    int      //
+	top    int      // scrolling region set by VIEW PRINT
+	bottom int      //
+	attr   byte     // attribute given to new characters
+	wrap   bool     // cursor is past the last column, next character wraps
+	esc    []byte   // escape sequence still being received
+	insert bool     // Insert key was pressed, typing pushes the line right
+}
+
+// NewScreen builds a blank screen in front of the terminal
+func NewScreen(term Console) *Screen {
+	scrn := &Screen{term: term, cols: ScreenCols, attr: DefaultAttr}
+	scrn.clear()
+
+	return scrn
+}
+
+// empty all the cells and home the cursor
+func (scrn *Screen) clear() {
+	scrn.cells = make([][]cell, ScreenRows)
+	scrn.cont = make([]bool, ScreenRows)
+	for i := range scrn.cells {
+		scrn.cells[i] = scrn.blankRow()
+	}
+	scrn.top = 0
+	scrn.bottom = ScreenRows - 1
+	scrn.row, scrn.col = 0, 0
+	scrn.wrap = false
+}
+
+func (scrn *Screen) blankRow() []cell {
+	row := make([]cell, scrn.cols)
+	for i := range row {
+		row[i] = cell{ch: ' ', attr: scrn.attr}
+	}
+
+	return row
+}
+
+// Cls clears the screen contents
+func (scrn *Screen) Cls() {
+	scrn.clear()
+	scrn.term.Cls()
+}
+
+// back to 80 columns of white on black, the way it powers on
+func (scrn *Screen) reset() {
+	scrn.Print(SGRReset)
+	scrn.cols = ScreenCols
+	scrn.Cls()
+}
+
+// Print outputs the passed string at the current cursor position
+func (scrn *Screen) Print(msg string) {
+	scrn.term.Print(scrn.put(msg))
+}
+
+// Println prints the string followed by a CR/LF
+func (scrn *Screen) Println(msg string) {
+	out := scrn.put(msg + "\r\n")
+
+	// may have had to add line breaks for the terminal
+	if scrn.cols < ScreenCols {
+		scrn.term.Print(out)
+		return
+	}
+	scrn.term.Println(strings.TrimSuffix(out, "\r\n"))
+}
+
+// Locate moves the cursor to the desired (row, col), upper left is 1,1
+func (scrn *Screen) Locate(row, col int) {
+	scrn.moveTo(row-1, col-1)
+	scrn.term.Locate(row, col)
+}
+
+// Log string to browser debug console
+func (scrn *Screen) Log(msg string) {
+	scrn.term.Log(msg)
+}
+
+// GetCursor returns the cursor location (row, col), upper left is 0,0
+func (scrn *Screen) GetCursor() (int, int) {
+	return scrn.row, scrn.col
+}
+
+// Read returns the contents of the screen range, trailing blanks dropped
+func (scrn *Screen) Read(col, row, length int) string {
+	if (row < 0) || (row >= ScreenRows) || (col < 0) || (col >= scrn.cols) {
+		return ""
+	}
+
+	var out strings.Builder
+	for i := col; (i < scrn.cols) && (i < col+length); i++ {
+		out.WriteRune(scrn.cells[row][i].ch)
+	}
+
+	return strings.TrimRight(out.String(), " ")
+}
+
+// ReadKeys reads up to (count) keycode values
+func (scrn *Screen) ReadKeys(count int) []byte {
+	return scrn.term.ReadKeys(count)
+}
+
+// SoundBell emits facsimile of a console beep
+func (scrn *Screen) SoundBell() {
+	scrn.term.SoundBell()
+}
+
+// BreakCheck returns true if a ctrl-c was entered
+func (scrn *Screen) BreakCheck() bool {
+	return scrn.term.BreakCheck()
+}
+
+// Cell returns the CP437 character and attribute at (row, col), upper left is 0,0
+func (scrn *Screen) Cell(row, col int) (byte, byte) {
+	c := scrn.cells[row][col]
+
+	return EncodeBytes(string(c.ch))[0], c.attr
+}
+
+// Width is the number of columns across the screen
+func (scrn *Screen) Width() int {
+	return scrn.cols
+}
+
+// SetWidth switches between 40 and 80 columns, which clears the screen
+func (scrn *Screen) SetWidth(cols int) {
+	if cols == scrn.cols {
+		return
+	}
+
+	scrn.cols = cols
+	scrn.Cls()
+}
+
+// InsertMode is true while typed characters push the rest of the
+// line to the right rather than overwriting it
+func (scrn *Screen) InsertMode() bool {
+	return scrn.insert
+}
+
+// SetInsertMode turns insert mode on or off
+func (scrn *Screen) SetInsertMode(on bool) {
+	scrn.insert = on
+}
+
+// LogicalLine gathers the text of the line that includes row, following
+// it thru any rows it wrapped onto
+// returns the text and the last row it occupies
+func (scrn *Screen) LogicalLine(row int) (string, int) {
+	first := scrn.LineStart(row)
+
+	last := row
+	for (last+1 < ScreenRows) && scrn.cont[last+1] {
+		last++
+	}
+
+	var out strings.Builder
+	for r := first; r <= last; r++ {
+		for _, c := range scrn.cells[r] {
+			out.WriteRune(c.ch)
+		}
+	}
+
+	return strings.TrimRight(out.String(), " "), last
+}
+
+// LineStart finds the row the logical line that includes row begins on
+func (scrn *Screen) LineStart(row int) int {
+	for (row > 0) && scrn.cont[row] {
+		row--
+	}
+
+	return row
+}
+
+// put updates the cells with the output and returns
+// what the terminal needs to show the same thing
+func (scrn *Screen) put(msg string) string {
+	var out strings.Builder
+
+	for _, r := range msg {
+		if (len(scrn.esc) > 0) || (r == 0x1b) {
+			scrn.escape(byte(r))
+			out.WriteRune(r)
+			continue
+		}
+
+		switch r {
+		case '\r':
+			scrn.col = 0
+			scrn.wrap = false
+		case '\n':
+			scrn.lineFeed()
+			scrn.cont[scrn.row] = false
+		case '\b':
+			if scrn.col > 0 {
+				scrn.col--
+			}
+			scrn.wrap = false
+		case '\t':
+			// spaces out to the next print zone, or the next row
+			// if there isn't another zone on this one
+			pad := PrintZone - scrn.col%PrintZone
+			if scrn.col+pad >= scrn.cols {
+				out.WriteString(scrn.put("\r\n"))
+			} else {
+				out.WriteString(scrn.put(strings.Repeat(" ", pad)))
+			}
+			continue
+		default:
+			if r < ' ' {
+				break
+			}
+			if scrn.wrap {
+				scrn.nextRow()
+				// the terminal is wider than me, so it won't wrap on its own
+				if scrn.cols < ScreenCols {
+					out.WriteString("\r\n")
+				}
+			}
+			scrn.cells[scrn.row][scrn.col] = cell{ch: r, attr: scrn.attr}
+			if scrn.col < scrn.cols-1 {
+				scrn.col++
+			} else {
+				scrn.wrap = true
+			}
+		}
+
+		out.WriteRune(r)
+	}
+
+	return out.String()
+}
+
+// wrap onto the next row, it continues the current one
+func (scrn *Screen) nextRow() {
+	scrn.col = 0
+	scrn.wrap = false
+	scrn.lineFeed()
+	scrn.cont[scrn.row] = true
+}
+
+// move down a row, scrolling if at the bottom
+func (scrn *Screen) lineFeed() {
+	scrn.wrap = false
+	if scrn.row == scrn.bottom {
+		scrn.scroll()
+		return
+	}
+
+	if scrn.row < ScreenRows-1 {
+		scrn.row++
+	}
+}
+
+// scroll the rows in the view up by one
+func (scrn *Screen) scroll() {
+	copy(scrn.cells[scrn.top:scrn.bottom], scrn.cells[scrn.top+1:scrn.bottom+1])
+	copy(scrn.cont[scrn.top:scrn.bottom], scrn.cont[scrn.top+1:scrn.bottom+1])
+	scrn.cells[scrn.bottom] = scrn.blankRow()
+	scrn.cont[scrn.bottom] = false
+}
+
+// put the cursor at (row, col), keeping it on the screen
+func (scrn *Screen) moveTo(row, col int) {
+	scrn.row = clamp(row, 0, ScreenRows-1)
+	scrn.col = clamp(col, 0, scrn.cols-1)
+	scrn.wrap = false
+}
+
+func clamp(v, low, high int) int {
+	if v < low {
+		return low
+	}
+	if v > high {
+		return high
+	}
+	return v
+}
+
+// collect an escape sequence, acting on it once complete
+func (scrn *Screen) escape(b byte) {
+	scrn.esc = append(scrn.esc, b)
+	seq := scrn.esc
+
+	switch {
+	case len(seq) < 2:
+		return
+	case seq[1] == ']': // OSC runs until a bell
+		if b != 0x07 {
+			return
+		}
+	case seq[1] != '[': // two character sequence
+	case len(seq) == 2:
+		return
+	case (b >= 0x40) && (b <= 0x7e): // CSI is done at the final byte
+		scrn.csi(string(seq[2:len(seq)-1]), b)
+	default:
+		return
+	}
+
+	scrn.esc = nil
+}
+
+// carry out a control sequence, parms are ; separated
+func (scrn *Screen) csi(parms string, final byte) {
+	var p []int
+	for _, s := range strings.Split(parms, ";") {
+		n, _ := strconv.Atoi(s)
+		p = append(p, n)
+	}
+
+	// first parameter, defaulting to one
+	n := p[0]
+	if n == 0 {
+		n = 1
+	}
+
+	switch final {
+	case 'A':
+		scrn.moveTo(scrn.row-n, scrn.col)
+	case 'B':
+		scrn.moveTo(scrn.row+n, scrn.col)
+	case 'C':
+		scrn.moveTo(scrn.row, scrn.col+n)
+	case 'D':
+		scrn.moveTo(scrn.row, scrn.col-n)
+	case 'd':
+		scrn.moveTo(n-1, scrn.col)
+	case '`', 'G':
+		scrn.moveTo(scrn.row, n-1)
+	case 'H', 'f':
+		col := 1
+		if len(p) > 1 && p[1] > 0 {
+			col = p[1]
+		}
+		scrn.moveTo(n-1, col-1)
+	case '@':
+		scrn.insertChars(n)
+	case 'P':
+		scrn.deleteChars(n)
+	case 'K':
+		scrn.eraseLine(p[0])
+	case 'J':
+		if p[0] == 2 {
+			row, col := scrn.row, scrn.col
+			scrn.clear()
+			scrn.row, scrn.col = row, col
+		}
+	case 'r':
+		scrn.setView(p)
+	case 'm':
+		scrn.setAttr(p)
+	}
+}
+
+// open up n blanks at the cursor, pushing the rest right
+func (scrn *Screen) insertChars(n int) {
+	line := scrn.cells[scrn.row]
+	for i := len(line) - 1; i >= scrn.col; i-- {
+		if i-n >= scrn.col {
+			line[i] = line[i-n]
+		} else {
+			line[i] = cell{ch: ' ', attr: scrn.attr}
+		}
+	}
+}
+
+// remove n characters at the cursor, pulling the rest left
+func (scrn *Screen) deleteChars(n int) {
+	line := scrn.cells[scrn.row]
+	for i := scrn.col; i < len(line); i++ {
+		if i+n < len(line) {
+			line[i] = line[i+n]
+		} else {
+			line[i] = cell{ch: ' ', attr: scrn.attr}
+		}
+	}
+}
+
+// 0 erases to end of line, 1 from the start, 2 the whole line
+func (scrn *Screen) eraseLine(mode int) {
+	start, end := scrn.col, scrn.cols
+	switch mode {
+	case 1:
+		start, end = 0, scrn.col+1
+	case 2:
+		start = 0
+	}
+
+	for i := start; i < end; i++ {
+		scrn.cells[scrn.row][i] = cell{ch: ' ', attr: scrn.attr}
+	}
+}
+
+// limit scrolling to rows top thru bottom, cursor goes home
+func (scrn *Screen) setView(p []int) {
+	top, bottom := 1, ScreenRows
+	if p[0] > 0 {
+		top = p[0]
+	}
+	if (len(p) > 1) && (p[1] > 0) {
+		bottom = p[1]
+	}
+
+	if top >= bottom || bottom > ScreenRows {
+		return
+	}
+
+	scrn.top, scrn.bottom = top-1, bottom-1
+	scrn.moveTo(0, 0)
+}
+
+// track the colors being selected
+func (scrn *Screen) setAttr(p []int) {
+	for _, sgr := range p {
+		switch {
+		case sgr == 0:
+			scrn.attr = DefaultAttr
+		case (sgr >= 30) && (sgr <= 37):
+			scrn.attr = scrn.attr&0xf0 | ansiToGW[sgr-30]
+		case (sgr >= 90) && (sgr <= 97):
+			scrn.attr = scrn.attr&0xf0 | (ansiToGW[sgr-90] + 8)
+		case (sgr >= 40) && (sgr <= 47):
+			scrn.attr = scrn.attr&0x0f | ansiToGW[sgr-40]<<4
+		case (sgr >= 100) && (sgr <= 107):
+			scrn.attr = scrn.attr&0x0f | (ansiToGW[sgr-100]+8)<<4
+		}
+	}
+}
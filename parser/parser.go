@@ -1,7 +1,6 @@
 package parser
 
 import (
-	"fmt"
 	"math"
 	"strconv"
 	"strings"
@@ -18,16 +17,28 @@ const (
 	_ int = iota
 	// LOWEST defines the bottom of the priority stack
 	LOWEST
+	IMPLICATION // IMP
+	EQUIVALENCE // EQV
+	EXCLUSIVE   // XOR
+	LOGICALOR   // OR
+	LOGICALAND  // AND
+	LOGICALNOT  // NOT X
 	EQUALS      // ==
 	LESSGREATER // > or <
 	SUM         // +
 	PRODUCT     // *
 	PREFIX      // -X or !X
+	POWER       // ^
 	CALL        // myFunction(X)
 	INDEX
 )
 
 var precedences = map[token.TokenType]int{
+	token.IMP:      IMPLICATION,
+	token.EQV:      EQUIVALENCE,
+	token.XOR:      EXCLUSIVE,
+	token.OR:       LOGICALOR,
+	token.AND:      LOGICALAND,
 	token.EQ:       EQUALS,
 	token.NOT_EQ:   EQUALS,
 	token.LT:       LESSGREATER,
@@ -40,9 +51,9 @@ var precedences = map[token.TokenType]int{
 	token.SLASH:    PRODUCT,
 	token.ASTERISK: PRODUCT,
 	token.MOD:      PRODUCT,
+	token.CARET:    POWER,
 	token.LPAREN:   CALL,
 	token.LBRACKET: INDEX,
-	token.INKEY:    CALL,
 }
 
 // Parser an instance
@@ -51,8 +62,9 @@ type Parser struct {
 
 	curToken  token.Token
 	peekToken token.Token
-	curLine   int  // current line number being parsed
-	cmdInput  bool // are we parsing from the terminal?
+	curLine   int    // current line number being parsed
+	lineText  string // the line as it was typed in
+	cmdInput  bool   // are we parsing from the terminal?
 	env       *object.Environment
 
 	prefixParseFns map[token.TokenType]prefixParseFn
@@ -69,6 +81,7 @@ type TokenIzer interface {
 	NextToken() token.Token // returns the next token to process
 	PassOn()                // turns on passing whitespace
 	PassOff()               // turns off passing whitespace
+	Line() string           // the text of the line being scanned
 }
 
 // New create and return a Parser instance
@@ -92,19 +105,24 @@ func New(l TokenIzer) *Parser {
 	p.registerPrefix(token.LIST, p.parseListExpression)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(token.NOT, p.parseNotExpression)
 	p.registerPrefix(token.OFF, p.parseOffExpression)
 	p.registerPrefix(token.ON, p.parseOnExpression)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.TIMER, p.parseTimerVar)
 	p.registerPrefix(token.USING, p.parseUsingExpression)
 
 	// and infix elements
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
+	p.registerInfix(token.AND, p.parseInfixExpression)
 	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
 	p.registerInfix(token.BSLASH, p.parseInfixExpression)
+	p.registerInfix(token.CARET, p.parseInfixExpression)
 	p.registerInfix(token.EQ, p.parseInfixExpression)
+	p.registerInfix(token.EQV, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
 	p.registerInfix(token.GTE, p.parseInfixExpression)
-	p.registerInfix(token.INKEY, p.parseInKeyExpression)
+	p.registerInfix(token.IMP, p.parseInfixExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
@@ -112,9 +130,11 @@ func New(l TokenIzer) *Parser {
 	p.registerInfix(token.MINUS, p.parseInfixExpression)
 	p.registerInfix(token.MOD, p.parseInfixExpression)
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.OR, p.parseInfixExpression)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
 	p.registerInfix(token.RPAREN, p.parseInfixExpression)
 	p.registerInfix(token.SLASH, p.parseInfixExpression)
+	p.registerInfix(token.XOR, p.parseInfixExpression)
 
 	// Read two tokens, so curToken and peekToken are both set
 	p.nextToken()
@@ -129,6 +149,7 @@ func (p *Parser) nextToken() {
 	// If I see EOL followed by INT, that is actually a line number
 	if p.curTokenIs(token.EOL) && p.peekTokenIs(token.INT) {
 		p.peekToken.Type = token.LINENUM
+		p.lineText = p.l.Line()
 	}
 }
 
@@ -174,24 +195,23 @@ func (p *Parser) ParseCmd(env *object.Environment) {
 	env.CmdParsed()
 }
 
-// ParseUsingRunTime takes the using expression and parses it into
-// a format string for printing
-func (p *Parser) ParseUsingRunTime() string {
+// ParseConstant parses a single constant typed in response to INPUT
+// returns nil if anything follows the constant
+func (p *Parser) ParseConstant() ast.Expression {
 	// skip over the newline the lexer inserts
 	p.nextToken()
-	rc := ""
 
-	switch p.curToken.Literal {
-	case token.HASHTAG:
-		rc = rc + p.parseUsingNumeric("")
-	case token.PLUS:
-		p.nextToken()
-		if p.curTokenIs(token.HASHTAG) {
-			rc = rc + p.parseUsingNumeric("+")
-		}
+	// a leading integer isn't a line number here
+	if p.curTokenIs(token.LINENUM) {
+		p.curToken.Type = token.INT
 	}
+	exp := p.parseExpression(LOWEST)
 
-	return rc
+	if !p.peekTokenIs(token.EOF) {
+		return nil
+	}
+
+	return exp
 }
 
 // parseStatment builds a statement out and adds it to the the
@@ -223,8 +243,12 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseContCommand()
 	case token.DATA:
 		return p.parseDataStatement()
+	case token.DELETE:
+		return p.parseDeleteCommand()
 	case token.DIM:
 		return p.parseDimStatement()
+	case token.EDIT:
+		return p.parseEditCommand()
 	case token.END:
 		return p.parseEndStatement()
 	case token.EOL:
@@ -236,20 +260,36 @@ func (p *Parser) parseStatement() ast.Statement {
 		return nil
 	case token.ERROR:
 		return p.parseErrorStatement()
+	case token.FIELD:
+		return p.parseFieldStatement()
 	case token.FILES:
 		return p.parseFilesCommand()
 	case token.FOR:
 		return p.parseForStatement()
+	case token.GET:
+		return p.parseGetStatement()
 	case token.GOSUB:
 		return p.parseGosubStatement()
 	case token.GOTO:
 		return p.parseGotoStatement()
 	case token.IF:
 		return p.parseIfStatement()
+	case token.INPUT:
+		return p.parseInputStatement()
 	case token.KEY:
+		if p.peekTokenIs(token.LPAREN) {
+			return p.parseEventTrapStatement()
+		}
 		return p.parseKeyStatement()
+	case token.KILL:
+		return p.parseKillStatement()
 	case token.LET:
 		return p.parseLetStatement()
+	case token.LINE:
+		if p.peekTokenIs(token.INPUT) {
+			return p.parseLineInputStatement()
+		}
+		return p.parseExpressionStatement()
 	case token.LINENUM:
 		return p.parseLineNumber()
 	case token.LIST:
@@ -258,6 +298,16 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseLocateStatement()
 	case token.LOAD:
 		return p.parseLoadCommand()
+	case token.LPRINT:
+		return p.parsePrintStatement()
+	case token.LSET:
+		return p.parseLsetStatement()
+	case token.MERGE:
+		return p.parseMergeCommand()
+	case token.MKDIR:
+		return p.parseMkDirStatement()
+	case token.NAME:
+		return p.parseNameStatement()
 	case token.NEW:
 		return p.parseNewCommand()
 	case token.NEXT:
@@ -270,28 +320,50 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parsePaletteStatement()
 	case token.PRINT:
 		return p.parsePrintStatement()
+	case token.PUT:
+		return p.parsePutStatement()
 	case token.READ:
 		return p.parseReadStatement()
 	case token.REM:
 		return p.parseRemStatement()
+	case token.RENUM:
+		return p.parseRenumCommand()
+	case token.RESET:
+		return p.parseResetStatement()
 	case token.RESTORE:
 		return p.parseRestoreStatement()
 	case token.RESUME:
 		return p.parseResumeStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.RMDIR:
+		return p.parseRmDirStatement()
+	case token.RSET:
+		return p.parseRsetStatement()
 	case token.RUN:
 		return p.parseRunCommand()
+	case token.SAVE:
+		return p.parseSaveCommand()
 	case token.SCREEN:
 		return p.parseScreenStatement()
 	case token.STOP:
 		return p.parseStopStatement()
+	case token.SYSTEM:
+		return p.parseSystemCommand()
+	case token.TIMER:
+		return p.parseEventTrapStatement()
 	case token.TROFF:
 		return p.parseTroffCommand()
 	case token.TRON:
 		return p.parseTronCommand()
 	case token.VIEW:
 		return p.parseViewStatement()
+	case token.WEND:
+		return p.parseWendStatement()
+	case token.WHILE:
+		return p.parseWhileStatement()
+	case token.WRITE:
+		return p.parseWriteStatement()
 	default:
 		// we get here with things that appear to be identifiers
 		// first check, is it a builtin function?
@@ -774,6 +846,20 @@ func (p *Parser) parseFilesCommand() *ast.FilesCommand {
 	return cd
 }
 
+// parseFileRedirect checks for the "#n," that sends a statement to a file
+// returns false if the file number isn't followed by a comma
+func (p *Parser) parseFileRedirect(fn **ast.FileNumber) bool {
+	if !p.peekTokenIs(token.HASHTAG) {
+		return true
+	}
+	p.nextToken()
+	*fn = &ast.FileNumber{Token: p.curToken}
+	p.nextToken()
+	(*fn).Numbr = p.parseExpression(LOWEST)
+
+	return p.expectPeek(token.COMMA)
+}
+
 // parseFileNumber reads in a file number
 func (p *Parser) parseFileNumber() ast.FileNumber {
 	stmt := ast.FileNumber{}
@@ -933,7 +1019,11 @@ func (p *Parser) parseFixedPointLiteral() ast.Expression {
 // Parse floating point number
 func (p *Parser) parseFloatingPointLiteral() ast.Expression {
 	// check for double precision, literal will use 'D' instead of 'E'
+	// or end with a '#'
 	src := p.curToken.Literal
+	if strings.HasSuffix(src, "#") {
+		return p.parseDoubleFloatingPointLiteral(strings.TrimSuffix(src, "#"))
+	}
 	if strings.ContainsAny(src, "dD") {
 		src = strings.Replace(src, "d", "E", 1)
 		src = strings.Replace(src, "D", "E", 1)
@@ -942,7 +1032,7 @@ func (p *Parser) parseFloatingPointLiteral() ast.Expression {
 
 	// assume single precision, try to convert it
 	lit := &ast.FloatSingleLiteral{Token: p.curToken}
-	value, err := strconv.ParseFloat(p.curToken.Literal, 32)
+	value, err := strconv.ParseFloat(strings.TrimSuffix(src, "!"), 32)
 
 	// if conversion fails, sweep up the trash
 	if err != nil {
@@ -984,6 +1074,7 @@ func (p *Parser) parseLineNumber() *ast.LineNumStmt {
 	}
 
 	stmt.Value = int32(tv)
+	stmt.Source = p.lineText
 	p.curLine = tv
 
 	// little detour here, if I see linenum*EOL AND auto is on
@@ -1052,6 +1143,11 @@ func (p *Parser) parseListStatement() *ast.ListStatement {
 func (p *Parser) parsePrintStatement() *ast.PrintStatement {
 	stmt := &ast.PrintStatement{Token: p.curToken}
 
+	if !p.parseFileRedirect(&stmt.File) {
+		p.parseTrash(&stmt.Trash)
+		return stmt
+	}
+
 	for !p.chkEndOfStatement() {
 		p.nextToken()
 		stmt.Items = append(stmt.Items, p.parseExpression(LOWEST))
@@ -1071,12 +1167,12 @@ func (p *Parser) parsePrintStatement() *ast.PrintStatement {
 // returns true if curToken is the end of the statement
 
 func (p *Parser) atEndOfStatement() bool {
-	return p.curTokenIs(token.COLON) || p.curTokenIs(token.LINENUM) || p.curTokenIs(token.EOF) || p.curTokenIs(token.EOL)
+	return p.curTokenIs(token.COLON) || p.curTokenIs(token.LINENUM) || p.curTokenIs(token.EOF) || p.curTokenIs(token.EOL) || p.curTokenIs(token.ELSE)
 }
 
 // returns true if the next token would put us at the end of a statement
 func (p *Parser) chkEndOfStatement() bool {
-	rc := p.peekTokenIs(token.COLON) || p.peekTokenIs(token.LINENUM) || p.peekTokenIs(token.EOF) || p.peekTokenIs(token.EOL) || p.peekTokenIs(token.REM) || p.curTokenIs(token.EOF) || p.curTokenIs(token.EOL)
+	rc := p.peekTokenIs(token.COLON) || p.peekTokenIs(token.LINENUM) || p.peekTokenIs(token.EOF) || p.peekTokenIs(token.EOL) || p.peekTokenIs(token.REM) || p.peekTokenIs(token.ELSE) || p.curTokenIs(token.EOF) || p.curTokenIs(token.EOL)
 
 	return rc
 }
@@ -1298,6 +1394,8 @@ func (p *Parser) parseOnStatement() ast.Statement {
 	switch p.peekToken.Type {
 	case token.ERROR:
 		return p.parseOnErrorStatement()
+	case token.TIMER, token.KEY:
+		return p.parseOnEventStatement()
 	}
 
 	// should be an expression followed by GOTO/GOSUB
@@ -1412,7 +1510,7 @@ func (p *Parser) parseOpenStatementBrief(stmt *ast.OpenStatement) {
 	}
 
 	// evalutor will figure out if filenum is vlaid
-	stmt.FileNumber = ast.FileNumber{Token: p.curToken}
+	stmt.FileNumber = ast.FileNumber{Numbr: p.parseExpression(LOWEST)}
 	p.nextToken()
 
 	if !strings.EqualFold(p.curToken.Literal, token.COMMA) {
@@ -1480,7 +1578,7 @@ func (p *Parser) parseVerboseLock(stmt *ast.OpenStatement) {
 			p.nextToken()
 		}
 
-		stmt.FileNumber.Token = p.curToken
+		stmt.FileNumber.Numbr = p.parseExpression(LOWEST)
 		p.nextToken()
 	}
 
@@ -1696,6 +1794,35 @@ func (p *Parser) parseRunKeepOpen(cmd ast.RunCommand) *ast.RunCommand {
 	return &cmd
 }
 
+// SAVE filename[,A|,P]
+// the file format defaults to tokenized
+func (p *Parser) parseSaveCommand() *ast.SaveCommand {
+	cmd := ast.SaveCommand{Token: p.curToken}
+
+	p.nextToken()
+	cmd.Path = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.nextToken()
+
+		opt := strings.ToUpper(p.curToken.Literal)
+		if p.curTokenIs(token.IDENT) && ((opt == "A") || (opt == "P")) {
+			cmd.Format = opt
+		} else {
+			p.parseTrash(&cmd.Trash)
+			return &cmd
+		}
+	}
+
+	if !p.chkEndOfStatement() {
+		p.nextToken()
+		p.parseTrash(&cmd.Trash)
+	}
+
+	return &cmd
+}
+
 // ScreenStatement allows user to configure screen mode for
 // different display adapters.  MDA,CGA,EGA and such
 func (p *Parser) parseScreenStatement() *ast.ScreenStatement {
@@ -1741,10 +1868,22 @@ func (p *Parser) parseStopStatement() *ast.StopStatement {
 	return &stmt
 }
 
+// SYSTEM takes no parameters
+func (p *Parser) parseSystemCommand() *ast.SystemCommand {
+	cmd := ast.SystemCommand{Token: p.curToken}
+
+	return &cmd
+}
+
 // start parsing an Identifier
 func (p *Parser) parseIdentifier() ast.Expression {
 	exp := p.innerParseIdentifier()
 
+	// INKEY$ reads like a variable but takes a key from the keyboard
+	if exp.Value == token.INKEY {
+		return &ast.InkeyExpression{Token: token.Token{Type: token.INKEY, Literal: exp.Token.Literal}}
+	}
+
 	return exp
 }
 
@@ -1881,14 +2020,18 @@ func (p *Parser) parseIfStatement() ast.Statement {
 
 	expression.Consequence = p.parseIfOption()
 
-	// if there is no ELSE we are done
-	if !p.peekTokenIs(token.ELSE) {
+	// GOTO and GOSUB stop on the ELSE, other statements just before it
+	switch {
+	case p.curTokenIs(token.ELSE):
+		p.nextToken()
+	case p.peekTokenIs(token.ELSE):
+		p.nextToken()
+		p.nextToken()
+	default:
+		// there is no ELSE, we are done
 		return &expression
 	}
 
-	p.nextToken()
-	p.nextToken()
-
 	expression.Alternative = p.parseIfOption()
 
 	return &expression
@@ -1912,6 +2055,129 @@ func (p *Parser) parseIfOption() ast.Statement {
 	return exp
 }
 
+// INPUT[;]["prompt"{;|,}] variable[, variable]...
+func (p *Parser) parseInputStatement() *ast.InputStatement {
+	defer untrace(trace("parseInputStatement"))
+	stmt := ast.InputStatement{Token: p.curToken, QMark: true}
+
+	if p.peekTokenIs(token.HASHTAG) {
+		// reading from a file, no prompting
+		stmt.QMark = false
+		if !p.parseFileRedirect(&stmt.File) {
+			p.parseTrash(&stmt.Trash)
+			return &stmt
+		}
+	} else {
+		stmt.SameLine = p.parseInputSameLine()
+		if !p.parseInputPrompt(&stmt.Prompt, &stmt.QMark) {
+			p.parseTrash(&stmt.Trash)
+			return &stmt
+		}
+	}
+
+	if p.chkEndOfStatement() {
+		return &stmt
+	}
+
+	for {
+		p.nextToken()
+
+		if !p.curTokenIs(token.IDENT) {
+			p.parseTrash(&stmt.Trash)
+			return &stmt
+		}
+		stmt.Vars = append(stmt.Vars, p.innerParseIdentifier())
+
+		if p.chkEndOfStatement() {
+			return &stmt
+		}
+
+		if !p.expectPeek(token.COMMA) {
+			p.nextToken()
+			p.parseTrash(&stmt.Trash)
+			return &stmt
+		}
+
+		// dangling comma
+		if p.chkEndOfStatement() {
+			p.parseTrash(&stmt.Trash)
+			return &stmt
+		}
+	}
+}
+
+// LINE INPUT[;]["prompt";] string variable
+func (p *Parser) parseLineInputStatement() ast.Statement {
+	defer untrace(trace("parseLineInputStatement"))
+	p.nextToken()
+	stmt := ast.LineInputStatement{Token: token.Token{Type: token.LINE, Literal: "LINE INPUT"}}
+
+	qmark := true
+	if p.peekTokenIs(token.HASHTAG) {
+		if !p.parseFileRedirect(&stmt.File) {
+			p.parseTrash(&stmt.Trash)
+			return &stmt
+		}
+	} else {
+		stmt.SameLine = p.parseInputSameLine()
+		if !p.parseInputPrompt(&stmt.Prompt, &qmark) || !qmark {
+			p.parseTrash(&stmt.Trash)
+			return &stmt
+		}
+	}
+
+	if p.chkEndOfStatement() {
+		return &stmt
+	}
+
+	p.nextToken()
+	if !p.curTokenIs(token.IDENT) {
+		p.parseTrash(&stmt.Trash)
+		return &stmt
+	}
+	stmt.Var = p.innerParseIdentifier()
+
+	if !p.chkEndOfStatement() {
+		p.nextToken()
+		p.parseTrash(&stmt.Trash)
+	}
+
+	return &stmt
+}
+
+// a semicolon right after INPUT keeps the cursor on the same line
+func (p *Parser) parseInputSameLine() bool {
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+		return true
+	}
+	return false
+}
+
+// parse the optional prompt string and the separator that follows it
+// returns false if the separator is missing
+func (p *Parser) parseInputPrompt(prompt **ast.StringLiteral, qmark *bool) bool {
+	if !p.peekTokenIs(token.STRING) {
+		return true
+	}
+	p.nextToken()
+	*prompt = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+
+	switch p.peekToken.Type {
+	case token.SEMICOLON:
+		*qmark = true
+	case token.COMMA:
+		*qmark = false
+	default:
+		// prompt gets swept up as trash
+		*prompt = nil
+		return false
+	}
+	p.nextToken()
+
+	return true
+}
+
 // users is trying to define a function
 func (p *Parser) parseFunctionLiteral() ast.Expression {
 	// what I'm hoping to produce
@@ -1978,6 +2244,11 @@ func (p *Parser) parseEOFExpression() ast.Expression {
 }
 
 func (p *Parser) checkForFuncCall() bool {
+	// only a name can be a function, the end of input has the literal EOF
+	if !p.curTokenIs(token.IDENT) {
+		return false
+	}
+
 	// user defined functions must start with FN
 	if (len(p.curToken.Literal) > 2) && (p.curToken.Literal[0:2] == "FN") {
 		return true
@@ -2059,16 +2330,6 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 	}
 	leftExp := prefix()
 
-	ink, ok := leftExp.(*ast.Identifier)
-
-	if ok {
-		infix := p.infixParseFns[token.TokenType(ink.Value)]
-
-		if infix != nil {
-			leftExp = infix(leftExp)
-		}
-	}
-
 	for !p.peekTokenIs(token.COLON) && !p.peekTokenIs((token.RBRACKET)) && precedence < p.peekPrecedence() {
 		infix := p.infixParseFns[p.peekToken.Type]
 		if infix == nil {
@@ -2082,12 +2343,6 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 	return leftExp
 }
 
-func (p *Parser) parseInKeyExpression(inKey ast.Expression) ast.Expression {
-	exp := &ast.CallExpression{Token: p.curToken, Function: inKey}
-	p.nextToken()
-	return exp
-}
-
 func (p *Parser) parsePrefixExpression() ast.Expression {
 	expression := &ast.PrefixExpression{
 		Token:    p.curToken,
@@ -2098,10 +2353,21 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 	return expression
 }
 
+// NOT binds looser than the relational operators, NOT A = B is NOT (A = B)
+func (p *Parser) parseNotExpression() ast.Expression {
+	expression := &ast.PrefixExpression{
+		Token:    p.curToken,
+		Operator: strings.ToUpper(p.curToken.Literal),
+	}
+	p.nextToken()
+	expression.Right = p.parseExpression(LOGICALNOT)
+	return expression
+}
+
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	expression := &ast.InfixExpression{
 		Token:    p.curToken,
-		Operator: p.curToken.Literal,
+		Operator: strings.ToUpper(p.curToken.Literal),
 		Left:     left,
 	}
 	precedence := p.curPrecedence()
@@ -2165,37 +2431,6 @@ func (p *Parser) parseUsingExpression() ast.Expression {
 	return stmt
 }
 
-// using statement includes a numeric field
-func (p *Parser) parseUsingNumeric(flag string) string {
-	// setup all my variables
-	width := 0
-	precision := 0
-	sawDecimal := false
-
-	// count the total field width and places after the decimal
-	for done := false; !done; p.nextToken() {
-		switch p.curToken.Literal {
-		case token.HASHTAG:
-			width++
-			if sawDecimal {
-				precision++
-			}
-		case token.PERIOD:
-			width++
-			sawDecimal = true
-		default:
-			// if I don't expect it, I'm done
-			done = true
-		}
-	}
-
-	if precision > 0 {
-		return fmt.Sprintf("%%%s%d.%df", flag, width, precision)
-	}
-
-	return fmt.Sprintf("%%%s%d.f", flag, width)
-}
-
 // Precedence of the peekToken
 func (p *Parser) peekPrecedence() int {
 	if p, ok := precedences[p.peekToken.Type]; ok {
@@ -2265,3 +2500,451 @@ func (p *Parser) parseViewPrintStatement() ast.Statement {
 
 	return &vp
 }
+
+// WEND closes the innermost WHILE loop
+func (p *Parser) parseWendStatement() *ast.WendStatement {
+	defer untrace(trace("parseWendStatement"))
+	wnd := ast.WendStatement{Token: p.curToken}
+
+	if !p.chkEndOfStatement() {
+		p.nextToken()
+		p.parseTrash(&wnd.Trash)
+	}
+
+	return &wnd
+}
+
+// WHILE condition, loop runs until condition is false
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+	defer untrace(trace("parseWhileStatement"))
+	whl := ast.WhileStatement{Token: p.curToken}
+
+	if p.chkEndOfStatement() {
+		// no condition, evaluator will complain
+		return &whl
+	}
+
+	p.nextToken()
+	whl.Condition = p.parseExpression(LOWEST)
+
+	if !p.chkEndOfStatement() {
+		p.nextToken()
+		p.parseTrash(&whl.Trash)
+	}
+
+	return &whl
+}
+
+// WRITE [#n,] list of expressions
+func (p *Parser) parseWriteStatement() *ast.WriteStatement {
+	defer untrace(trace("parseWriteStatement"))
+	stmt := ast.WriteStatement{Token: p.curToken}
+
+	if !p.parseFileRedirect(&stmt.File) {
+		p.parseTrash(&stmt.Trash)
+		return &stmt
+	}
+
+	for !p.chkEndOfStatement() {
+		p.nextToken()
+		stmt.Items = append(stmt.Items, p.parseExpression(LOWEST))
+
+		if p.chkEndOfStatement() {
+			return &stmt
+		}
+
+		// items can be seperated by either a comma or a semicolon
+		if !p.peekTokenIs(token.COMMA) && !p.peekTokenIs(token.SEMICOLON) {
+			p.nextToken()
+			p.parseTrash(&stmt.Trash)
+			return &stmt
+		}
+		p.nextToken()
+	}
+
+	return &stmt
+}
+
+// FIELD [#]filenum, width AS string-var [,width AS string-var]...
+func (p *Parser) parseFieldStatement() *ast.FieldStatement {
+	defer untrace(trace("parseFieldStatement"))
+	stmt := ast.FieldStatement{Token: p.curToken}
+
+	if p.chkEndOfStatement() {
+		return &stmt
+	}
+	p.nextToken()
+	stmt.File = p.parseFileExpression()
+
+	for !p.chkEndOfStatement() {
+		if !p.expectPeek(token.COMMA) {
+			p.nextToken()
+			p.parseTrash(&stmt.Trash)
+			return &stmt
+		}
+		p.nextToken()
+		stmt.Widths = append(stmt.Widths, p.parseExpression(LOWEST))
+
+		if !p.expectPeek(token.AS) || !p.expectPeek(token.IDENT) {
+			p.nextToken()
+			p.parseTrash(&stmt.Trash)
+			return &stmt
+		}
+		stmt.Vars = append(stmt.Vars, p.innerParseIdentifier())
+	}
+
+	return &stmt
+}
+
+// GET [#]filenum[, record]
+func (p *Parser) parseGetStatement() *ast.GetStatement {
+	defer untrace(trace("parseGetStatement"))
+	stmt := ast.GetStatement{Token: p.curToken}
+
+	stmt.Record = p.parseRecordAccess(&stmt.File, &stmt.Trash)
+
+	return &stmt
+}
+
+// PUT [#]filenum[, record]
+func (p *Parser) parsePutStatement() *ast.PutStatement {
+	defer untrace(trace("parsePutStatement"))
+	stmt := ast.PutStatement{Token: p.curToken}
+
+	stmt.Record = p.parseRecordAccess(&stmt.File, &stmt.Trash)
+
+	return &stmt
+}
+
+// parseRecordAccess reads the file number and optional record number
+// for GET and PUT, returns the record number expression
+func (p *Parser) parseRecordAccess(fn *ast.FileNumber, trash *[]ast.TrashStatement) ast.Expression {
+	if p.chkEndOfStatement() {
+		return nil
+	}
+	p.nextToken()
+	*fn = p.parseFileExpression()
+
+	if p.chkEndOfStatement() {
+		return nil
+	}
+
+	if !p.expectPeek(token.COMMA) {
+		p.nextToken()
+		p.parseTrash(trash)
+		return nil
+	}
+	p.nextToken()
+	rec := p.parseExpression(LOWEST)
+
+	if !p.chkEndOfStatement() {
+		p.nextToken()
+		p.parseTrash(trash)
+	}
+
+	return rec
+}
+
+// parseFileExpression reads a file number where the '#' is optional
+// and the number can be any numeric expression
+func (p *Parser) parseFileExpression() ast.FileNumber {
+	fn := ast.FileNumber{}
+
+	if p.curTokenIs(token.HASHTAG) {
+		fn.Token = p.curToken
+		p.nextToken()
+	}
+	fn.Numbr = p.parseExpression(LOWEST)
+
+	return fn
+}
+
+// LSET string-var = string-exp
+func (p *Parser) parseLsetStatement() *ast.LsetStatement {
+	defer untrace(trace("parseLsetStatement"))
+	stmt := ast.LsetStatement{Token: p.curToken}
+
+	stmt.Name, stmt.Value = p.parseJustifyStatement(&stmt.Trash)
+
+	return &stmt
+}
+
+// RSET string-var = string-exp
+func (p *Parser) parseRsetStatement() *ast.RsetStatement {
+	defer untrace(trace("parseRsetStatement"))
+	stmt := ast.RsetStatement{Token: p.curToken}
+
+	stmt.Name, stmt.Value = p.parseJustifyStatement(&stmt.Trash)
+
+	return &stmt
+}
+
+// parseJustifyStatement reads the "var = value" for LSET and RSET
+func (p *Parser) parseJustifyStatement(trash *[]ast.TrashStatement) (*ast.Identifier, ast.Expression) {
+	if !p.expectPeek(token.IDENT) {
+		p.nextToken()
+		p.parseTrash(trash)
+		return nil, nil
+	}
+	name := p.innerParseIdentifier()
+
+	if !p.expectPeek(token.ASSIGN) {
+		p.nextToken()
+		p.parseTrash(trash)
+		return name, nil
+	}
+	p.nextToken()
+	value := p.parseExpression(LOWEST)
+
+	if !p.chkEndOfStatement() {
+		p.nextToken()
+		p.parseTrash(trash)
+	}
+
+	return name, value
+}
+
+// KILL filespec
+func (p *Parser) parseKillStatement() *ast.KillStatement {
+	defer untrace(trace("parseKillStatement"))
+	stmt := ast.KillStatement{Token: p.curToken}
+
+	stmt.Path = p.parseFilePath(&stmt.Trash)
+
+	return &stmt
+}
+
+// MKDIR pathname
+func (p *Parser) parseMkDirStatement() *ast.MkDirStatement {
+	defer untrace(trace("parseMkDirStatement"))
+	stmt := ast.MkDirStatement{Token: p.curToken}
+
+	stmt.Path = p.parseFilePath(&stmt.Trash)
+
+	return &stmt
+}
+
+// RMDIR pathname
+func (p *Parser) parseRmDirStatement() *ast.RmDirStatement {
+	defer untrace(trace("parseRmDirStatement"))
+	stmt := ast.RmDirStatement{Token: p.curToken}
+
+	stmt.Path = p.parseFilePath(&stmt.Trash)
+
+	return &stmt
+}
+
+// parseFilePath reads the single path expression used by
+// KILL, MKDIR and RMDIR, nil if no path was given
+func (p *Parser) parseFilePath(trash *[]ast.TrashStatement) ast.Expression {
+	if p.chkEndOfStatement() {
+		return nil // evaluator will display error
+	}
+	p.nextToken()
+	path := p.parseExpression(LOWEST)
+
+	if !p.chkEndOfStatement() {
+		p.nextToken()
+		p.parseTrash(trash)
+	}
+
+	return path
+}
+
+// NAME oldname AS newname
+func (p *Parser) parseNameStatement() *ast.NameStatement {
+	defer untrace(trace("parseNameStatement"))
+	stmt := ast.NameStatement{Token: p.curToken}
+
+	if p.chkEndOfStatement() {
+		return &stmt
+	}
+	p.nextToken()
+	stmt.OldName = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.AS) {
+		if !p.chkEndOfStatement() {
+			p.nextToken()
+			p.parseTrash(&stmt.Trash)
+		}
+		return &stmt
+	}
+
+	stmt.NewName = p.parseFilePath(&stmt.Trash)
+
+	return &stmt
+}
+
+// RESET closes all the open files
+func (p *Parser) parseResetStatement() *ast.ResetStatement {
+	defer untrace(trace("parseResetStatement"))
+	stmt := ast.ResetStatement{Token: p.curToken}
+
+	if !p.chkEndOfStatement() {
+		p.nextToken()
+		p.parseTrash(&stmt.Trash)
+	}
+
+	return &stmt
+}
+
+// DELETE [line][-[line]]
+func (p *Parser) parseDeleteCommand() *ast.DeleteCommand {
+	defer untrace(trace("parseDeleteCommand"))
+	cmd := ast.DeleteCommand{Token: p.curToken}
+
+	if p.peekTokenIs(token.INT) {
+		p.nextToken()
+		cmd.Start = p.curToken.Literal
+	}
+
+	if p.peekTokenIs(token.MINUS) {
+		p.nextToken()
+		cmd.Lrange = p.curToken.Literal
+
+		if p.peekTokenIs(token.INT) {
+			p.nextToken()
+			cmd.Stop = p.curToken.Literal
+		}
+	}
+
+	if !p.chkEndOfStatement() {
+		p.nextToken()
+		p.parseTrash(&cmd.Trash)
+	}
+
+	return &cmd
+}
+
+// MERGE filename
+func (p *Parser) parseMergeCommand() *ast.MergeCommand {
+	defer untrace(trace("parseMergeCommand"))
+	cmd := ast.MergeCommand{Token: p.curToken}
+
+	cmd.Path = p.parseFilePath(&cmd.Trash)
+
+	return &cmd
+}
+
+// RENUM [new][,[old][,increment]]
+func (p *Parser) parseRenumCommand() *ast.RenumCommand {
+	defer untrace(trace("parseRenumCommand"))
+	cmd := ast.RenumCommand{Token: p.curToken}
+
+	parms := []*string{&cmd.NewNum, &cmd.OldNum, &cmd.Increment}
+	for i, parm := range parms {
+		if i > 0 {
+			if !p.peekTokenIs(token.COMMA) {
+				break
+			}
+			p.nextToken()
+		}
+
+		if p.peekTokenIs(token.INT) {
+			p.nextToken()
+			*parm = p.curToken.Literal
+		}
+	}
+
+	if !p.chkEndOfStatement() {
+		p.nextToken()
+		p.parseTrash(&cmd.Trash)
+	}
+
+	return &cmd
+}
+
+// EDIT line
+func (p *Parser) parseEditCommand() *ast.EditCommand {
+	defer untrace(trace("parseEditCommand"))
+	cmd := ast.EditCommand{Token: p.curToken}
+
+	if p.peekTokenIs(token.INT) {
+		p.nextToken()
+		cmd.Line = p.curToken.Literal
+	}
+
+	if !p.chkEndOfStatement() {
+		p.nextToken()
+		p.parseTrash(&cmd.Trash)
+	}
+
+	return &cmd
+}
+
+// ON TIMER(n) GOSUB line
+func (p *Parser) parseOnEventStatement() *ast.OnEventStatement {
+	defer untrace(trace("parseOnEventStatement"))
+	stmt := ast.OnEventStatement{Token: p.curToken}
+
+	p.nextToken()
+	stmt.Event = p.curToken
+
+	if !p.expectPeek(token.LPAREN) {
+		p.parseRestAsTrash(&stmt.Trash)
+		return &stmt
+	}
+	p.nextToken()
+	param := p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) || !p.expectPeek(token.GOSUB) || !p.expectPeek(token.INT) {
+		p.parseRestAsTrash(&stmt.Trash)
+		return &stmt
+	}
+	stmt.Param = param
+	stmt.Jump, _ = strconv.Atoi(p.curToken.Literal)
+
+	if !p.chkEndOfStatement() {
+		p.parseRestAsTrash(&stmt.Trash)
+	}
+
+	return &stmt
+}
+
+// TIMER ON|OFF|STOP or KEY(n) ON|OFF|STOP
+func (p *Parser) parseEventTrapStatement() *ast.EventTrapStatement {
+	defer untrace(trace("parseEventTrapStatement"))
+	stmt := ast.EventTrapStatement{Token: p.curToken}
+
+	// KEY says which key in parens
+	if p.peekTokenIs(token.LPAREN) {
+		p.nextToken()
+		p.nextToken()
+		stmt.Param = p.parseExpression(LOWEST)
+
+		if !p.expectPeek(token.RPAREN) {
+			p.parseRestAsTrash(&stmt.Trash)
+			return &stmt
+		}
+	}
+
+	if !p.peekTokenIs(token.ON) && !p.peekTokenIs(token.OFF) && !p.peekTokenIs(token.STOP) {
+		p.parseRestAsTrash(&stmt.Trash)
+		return &stmt
+	}
+	p.nextToken()
+	stmt.State = p.curToken
+
+	if !p.chkEndOfStatement() {
+		p.parseRestAsTrash(&stmt.Trash)
+	}
+
+	return &stmt
+}
+
+// TIMER used as a value, the seconds since midnight
+func (p *Parser) parseTimerVar() ast.Expression {
+	return &ast.Identifier{Token: p.curToken, Value: strings.ToUpper(p.curToken.Literal)}
+}
+
+// the statement went wrong, the rest of it is trash
+// if it just stopped short, the missing piece is the trash
+func (p *Parser) parseRestAsTrash(trash *[]ast.TrashStatement) {
+	if p.chkEndOfStatement() {
+		*trash = append(*trash, ast.TrashStatement{Token: token.Token{Type: token.EOL}})
+		return
+	}
+
+	p.nextToken()
+	p.parseTrash(trash)
+}
@@ -1030,6 +1030,14 @@ func TestOnStatement(t *testing.T) {
 		{inp: "10 ON ERROR GOTO 10000000000000000000", exp: "ON ERROR GOTO", jmp: 0},
 		{inp: "10 ON X GOTO 100, 200, 300", exp: "ON X GOTO 100, 200, 300"},
 		{inp: "10 ON X GOSUB 100, 200, 300", exp: "ON X GOSUB 100, 200, 300"},
+		{inp: "10 ON TIMER(60) GOSUB 1000", exp: "ON TIMER(60) GOSUB 1000", jmp: 1000},
+		{inp: "10 ON TIMER(N * 2) GOSUB 0", exp: "ON TIMER(N * 2) GOSUB 0"},
+		{inp: "10 ON TIMER 60 GOSUB 1000", exp: "ON TIMER 60 GOSUB 1000", tpe: 1},
+		{inp: "10 ON TIMER(60) GOTO 1000", exp: "ON TIMER GOTO 1000", tpe: 1},
+		{inp: "10 ON TIMER(60) GOSUB", exp: "ON TIMER ", tpe: 1},
+		{inp: "10 ON TIMER(60) GOSUB 1000 X", exp: "ON TIMER(60) GOSUB 1000 X", jmp: 1000, tpe: 1},
+		{inp: "10 ON KEY(1) GOSUB 500", exp: "ON KEY(1) GOSUB 500", jmp: 500},
+		{inp: "10 on key(15) gosub 0", exp: "ON KEY(15) GOSUB 0"},
 	}
 
 	for _, tt := range tests {
@@ -1045,6 +1053,10 @@ func TestOnStatement(t *testing.T) {
 			assert.EqualValues(t, tt.exp, stmt.String(), "ON ERROR parse fail")
 			assert.EqualValues(t, tt.jmp, stmt.Jump, "got the wrong line")
 
+		case *ast.OnEventStatement:
+			assert.EqualValues(t, tt.exp, stmt.String(), "ON TIMER parse fail")
+			assert.EqualValues(t, tt.jmp, stmt.Jump, "%s got the wrong line", tt.inp)
+			assert.Equal(t, tt.tpe == 1, stmt.HasTrash(), "%s trash wrong", tt.inp)
 		}
 	}
 }
@@ -1333,6 +1345,59 @@ func Test_StopStatement(t *testing.T) {
 	assert.Equal(t, token.STOP, stmt.Token.Literal)
 }
 
+func Test_SystemCommand(t *testing.T) {
+	l := lexer.New("SYSTEM")
+	p := New(l)
+	env := object.NewTermEnvironment(mocks.MockTerm{})
+	p.ParseCmd(env)
+	iter := env.CmdLineIter()
+
+	assert.Equal(t, 1, iter.Len())
+	_, ok := iter.Value().(*ast.SystemCommand)
+	assert.True(t, ok, "SYSTEM failed to parse to ast.SystemCommand")
+}
+
+func Test_EventTrapStatement(t *testing.T) {
+	tests := []struct {
+		inp   string
+		exp   string
+		trash bool
+	}{
+		{inp: "10 TIMER ON", exp: "TIMER ON"},
+		{inp: "10 timer off", exp: "TIMER OFF"},
+		{inp: "10 TIMER STOP : REM", exp: "TIMER STOP"},
+		{inp: "10 TIMER", exp: "TIMER ", trash: true},
+		{inp: "10 TIMER = 5", exp: "TIMER = 5", trash: true},
+		{inp: "10 TIMER ON X", exp: "TIMER ON X", trash: true},
+		{inp: "10 KEY(1) ON", exp: "KEY(1) ON"},
+		{inp: "10 key (N + 1) stop", exp: "KEY(N + 1) STOP"},
+		{inp: "10 KEY(1 OFF", exp: "KEY(1) OFF", trash: true},
+		{inp: "10 KEY(1)", exp: "KEY(1) ", trash: true},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.inp))
+		env := object.NewTermEnvironment(mocks.MockTerm{})
+		p.ParseProgram(env)
+		iter := env.StatementIter()
+		iter.Next()
+
+		stmt, ok := iter.Value().(*ast.EventTrapStatement)
+		assert.True(t, ok, "%s didn't parse to an EventTrapStatement", tt.inp)
+		assert.Equal(t, tt.exp, stmt.String(), "%s parsed wrong", tt.inp)
+		assert.Equal(t, tt.trash, stmt.HasTrash(), "%s trash wrong", tt.inp)
+	}
+
+	// TIMER can also be a value
+	p := New(lexer.New("10 LET X = TIMER"))
+	env := object.NewTermEnvironment(mocks.MockTerm{})
+	p.ParseProgram(env)
+	iter := env.StatementIter()
+	iter.Next()
+
+	assert.Equal(t, "LET X = TIMER", iter.Value().String(), "TIMER value parsed wrong")
+}
+
 func Test_StringLiteralExpression(t *testing.T) {
 	tests := []struct {
 		inp  string
@@ -1417,32 +1482,6 @@ func Test_UsingExpression(t *testing.T) {
 	}
 }
 
-func Test_UsingRunTime(t *testing.T) {
-	tst := []struct {
-		inp  string
-		exp  string
-		data float32
-		fmt  string
-	}{
-		{inp: `#####`, exp: `%5.f`, data: 328, fmt: "  328"},
-		{inp: `###.##`, exp: `%6.2f`, data: 123.456, fmt: "123.46"},
-		{inp: `###.##`, exp: `%6.2f`, data: -123.456, fmt: "-123.46"},
-		{inp: `+###.##`, exp: `%+6.2f`, data: 123.456, fmt: "+123.46"},
-		{inp: `+###.##`, exp: `%+6.2f`, data: -123.456, fmt: "-123.46"},
-	}
-
-	for _, tt := range tst {
-		l := lexer.New(tt.inp)
-		p := New(l)
-		//env := object.NewTermEnvironment(mocks.MockTerm{})
-		rc := p.ParseUsingRunTime()
-
-		assert.EqualValuesf(t, tt.exp, rc, "%s", tt.inp)
-		res := fmt.Sprintf(rc, tt.data)
-		assert.EqualValuesf(t, tt.fmt, res, "%s", tt.inp)
-	}
-}
-
 func TestIntegerLiteralExpression(t *testing.T) {
 	dblTok := token.Token{Type: token.INTD, Literal: "65999"}
 	fltTok := token.Token{Type: token.FLOAT, Literal: "4294967295"}
@@ -1455,6 +1494,8 @@ func TestIntegerLiteralExpression(t *testing.T) {
 		{`10 5`, 2, &ast.IntegerLiteral{Value: 5, Token: token.Token{Type: token.INT, Literal: "5"}}},
 		{`20 65999#`, 2, &ast.DblIntegerLiteral{Value: 65999, Token: dblTok}},
 		{`30 4294967295`, 2, &ast.FloatSingleLiteral{Token: fltTok, Value: 4294967295}},
+		{`40 2.5#`, 2, &ast.FloatDoubleLiteral{Token: token.Token{Type: token.FLOAT, Literal: "2.5#"}, Value: 2.5}},
+		{`50 3!`, 2, &ast.FloatSingleLiteral{Token: token.Token{Type: token.FLOAT, Literal: "3!"}, Value: 3}},
 	}
 
 	for _, tt := range tests {
@@ -1787,6 +1828,8 @@ func TestIfStatement(t *testing.T) {
 		{inp: "50 IF X < Y THEN 300 ELSE END", cons: "GOTO", alt: "END", op: "<", exp: "IF X < Y THEN 300 ELSE END"},
 		{inp: "60 IF X < Y, THEN 300 ELSE END", cons: "GOTO", alt: "END", op: "<", exp: "IF X < Y THEN 300 ELSE END"},
 		{inp: "70 IF X = Y, THEN 300 ELSE END", cons: "GOTO", alt: "END", op: "=", exp: "IF X = Y THEN 300 ELSE END"},
+		{inp: "80 IF X < Y THEN GOSUB 300 ELSE RESUME 400", cons: "GOSUB", alt: "RESUME", op: "<", exp: "IF X < Y THEN GOSUB 300 ELSE RESUME 400"},
+		{inp: "90 IF X > Y THEN RESUME 300 ELSE GOTO 400", cons: "RESUME", alt: "GOTO", op: ">", exp: "IF X > Y THEN RESUME 300 ELSE GOTO 400"},
 	}
 
 	for _, tt := range tests {
@@ -1843,6 +1886,9 @@ func Test_ParseInkeyExpression(t *testing.T) {
 		exp []string
 	}{
 		{inp: `10 X$ = INKEY$ : END`},
+		{inp: `10 A$=INKEY$`, exp: []string{" A$ = INKEY$"}},
+		{inp: `10 A$=INKEY$+""`, exp: []string{` A$ = INKEY$ + ""`}},
+		{inp: `10 IF INKEY$="" THEN 10`, exp: []string{`IF INKEY$ = "" THEN 10`}},
 	}
 
 	for _, tt := range tests {
@@ -2027,13 +2073,14 @@ func TestCheckForFuncCall(t *testing.T) {
 		{inp: "LEN", exp: true},
 		{inp: "FNA", exp: true},
 		{inp: "MUFIN", exp: false},
+		{inp: "", exp: false},
 	}
 
 	for _, tt := range tst {
 		l := lexer.New(tt.inp)
 		p := New(l)
 		p.nextToken() // skip the starting EOL
-		p.checkForFuncCall()
+		assert.Equal(t, tt.exp, p.checkForFuncCall(), "checkForFuncCall(%q) wrong", tt.inp)
 	}
 }
 
@@ -2315,6 +2362,10 @@ func testIfResult(t *testing.T, rt string, exp string, stmt ast.Statement) bool
 		_, ok = stmt.(*ast.GotoStatement)
 	case "END":
 		_, ok = stmt.(*ast.EndStatement)
+	case "GOSUB":
+		_, ok = stmt.(*ast.GosubStatement)
+	case "RESUME":
+		_, ok = stmt.(*ast.ResumeStatement)
 	}
 
 	if !ok {
@@ -2516,3 +2567,293 @@ func Test_ViewStatement(t *testing.T) {
 		assert.Equal(t, tt.inp, cmd.String())
 	}
 }
+
+func Test_WhileWendStatements(t *testing.T) {
+	tests := []struct {
+		inp string
+		exp string
+	}{
+		{inp: `WHILE X < 10`, exp: `WHILE X < 10`},
+		{inp: `WHILE`, exp: `WHILE`},
+		{inp: `WHILE X Y`, exp: `WHILE X Y`},
+		{inp: `WEND`, exp: `WEND`},
+		{inp: `WEND X`, exp: `WEND X`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.inp)
+		p := New(l)
+		env := object.NewTermEnvironment(mocks.MockTerm{})
+		p.ParseCmd(env)
+
+		itr := env.CmdLineIter()
+		cmd := itr.Value()
+		assert.Equal(t, tt.exp, cmd.String())
+	}
+}
+
+func Test_InputStatements(t *testing.T) {
+	tests := []struct {
+		inp   string
+		exp   string
+		trash bool
+	}{
+		{inp: `INPUT A`, exp: `INPUT A`},
+		{inp: `INPUT; A, B$`, exp: `INPUT; A, B$`},
+		{inp: `INPUT "Name"; N$`, exp: `INPUT "Name"; N$`},
+		{inp: `INPUT "X,Y", X, Y`, exp: `INPUT "X,Y", X, Y`},
+		{inp: `INPUT`, exp: `INPUT `},
+		{inp: `INPUT "Name" N$`, exp: `INPUT  "Name" N $`, trash: true},
+		{inp: `INPUT A,`, exp: `INPUT A,`, trash: true},
+		{inp: `INPUT A B`, exp: `INPUT A B`, trash: true},
+		{inp: `INPUT 5`, exp: `INPUT  5`, trash: true},
+		{inp: `LINE INPUT L$`, exp: `LINE INPUT L$`},
+		{inp: `LINE INPUT; "Say it"; L$`, exp: `LINE INPUT; "Say it"; L$`},
+		{inp: `LINE INPUT "Say it", L$`, exp: `LINE INPUT "Say it"; , L $`, trash: true},
+		{inp: `LINE INPUT L$ X`, exp: `LINE INPUT L$ X`, trash: true},
+		{inp: `LINE INPUT 5`, exp: `LINE INPUT  5`, trash: true},
+		{inp: `INPUT #1, A, B$`, exp: `INPUT #1, A, B$`},
+		{inp: `INPUT #F, A`, exp: `INPUT #F, A`},
+		{inp: `INPUT #1 A`, exp: `INPUT #1,  1 A`, trash: true},
+		{inp: `LINE INPUT #2, L$`, exp: `LINE INPUT #2, L$`},
+		{inp: `LINE INPUT #2 L$`, exp: `LINE INPUT #2,  2 L $`, trash: true},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.inp)
+		p := New(l)
+		env := object.NewTermEnvironment(mocks.MockTerm{})
+		p.ParseCmd(env)
+
+		itr := env.CmdLineIter()
+		cmd := itr.Value()
+		assert.Equalf(t, tt.exp, cmd.String(), "%s parsed incorrectly", tt.inp)
+
+		tc, ok := cmd.(ast.TrashCan)
+		assert.True(t, ok)
+		assert.Equalf(t, tt.trash, tc.HasTrash(), "%s trash check failed", tt.inp)
+	}
+}
+
+func Test_ParseConstant(t *testing.T) {
+	tests := []struct {
+		inp string
+		exp string
+	}{
+		{inp: `42`, exp: `42`},
+		{inp: `-7`, exp: `-7`},
+		{inp: `&H10`, exp: `&H10`},
+		{inp: `3 4`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.inp)
+		p := New(l)
+		exp := p.ParseConstant()
+
+		if len(tt.exp) == 0 {
+			assert.Nilf(t, exp, "%s should not have parsed", tt.inp)
+			continue
+		}
+		assert.Equal(t, tt.exp, exp.String())
+	}
+}
+
+func Test_LogicalOperatorParsing(t *testing.T) {
+	tests := []struct {
+		inp string
+		op  string
+		exp string
+	}{
+		{inp: `A OR B AND C`, op: "OR", exp: `A OR B AND C`},
+		{inp: `A and B or C`, op: "OR", exp: `A AND B OR C`},
+		{inp: `A XOR B OR C`, op: "XOR", exp: `A XOR B OR C`},
+		{inp: `A EQV B XOR C`, op: "EQV", exp: `A EQV B XOR C`},
+		{inp: `A IMP B EQV C`, op: "IMP", exp: `A IMP B EQV C`},
+		{inp: `NOT A = B`, op: "NOT", exp: `NOT A = B`},
+		{inp: `NOT A AND B`, op: "AND", exp: `NOT A AND B`},
+		{inp: `A < B AND C`, op: "AND", exp: `A < B AND C`},
+		{inp: `-A ^ B`, op: "-", exp: `-A ^ B`},
+		{inp: `A * B ^ C`, op: "*", exp: `A * B ^ C`},
+		{inp: `A ^ B ^ C`, op: "^", exp: `A ^ B ^ C`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.inp)
+		p := New(l)
+		exp := p.ParseConstant()
+
+		assert.NotNilf(t, exp, "%s failed to parse", tt.inp)
+		assert.Equal(t, tt.exp, exp.String())
+
+		switch top := exp.(type) {
+		case *ast.InfixExpression:
+			assert.Equalf(t, tt.op, top.Operator, "%s has the wrong top operator", tt.inp)
+		case *ast.PrefixExpression:
+			assert.Equalf(t, tt.op, top.Operator, "%s has the wrong top operator", tt.inp)
+		default:
+			t.Fatalf("%s parsed to a %T", tt.inp, exp)
+		}
+	}
+}
+
+func Test_PrintAndWriteStatements(t *testing.T) {
+	tests := []struct {
+		inp   string
+		exp   string
+		trash bool
+	}{
+		{inp: `PRINT #1, "Hello"`, exp: `PRINT #1, "Hello" `},
+		{inp: `PRINT #2, A; B$,`, exp: `PRINT #2, A;B$,`},
+		{inp: `PRINT #1 A`, exp: `PRINT #1,  1 A`, trash: true},
+		{inp: `LPRINT "Hello"`, exp: `LPRINT "Hello" `},
+		{inp: `PRINT #1, USING "##.##"; A`, exp: `PRINT #1, USING "##.##";A `},
+		{inp: `WRITE`, exp: `WRITE `},
+		{inp: `WRITE A, "B"; C$`, exp: `WRITE A, "B", C$`},
+		{inp: `WRITE #3, A, B`, exp: `WRITE #3, A, B`},
+		{inp: `WRITE #3 A`, exp: `WRITE #3,  3 A`, trash: true},
+		{inp: `WRITE A B`, exp: `WRITE A B`, trash: true},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.inp)
+		p := New(l)
+		env := object.NewTermEnvironment(mocks.MockTerm{})
+		p.ParseCmd(env)
+
+		itr := env.CmdLineIter()
+		cmd := itr.Value()
+		assert.Equalf(t, tt.exp, cmd.String(), "%s parsed incorrectly", tt.inp)
+
+		tc, ok := cmd.(ast.TrashCan)
+		assert.True(t, ok)
+		assert.Equalf(t, tt.trash, tc.HasTrash(), "%s trash check failed", tt.inp)
+	}
+}
+
+func Test_RandomFileStatements(t *testing.T) {
+	tests := []struct {
+		inp   string
+		exp   string
+		trash bool
+	}{
+		{inp: `FIELD #1, 20 AS N$, 4 AS A$`, exp: `FIELD #1, 20 AS N$, 4 AS A$`},
+		{inp: `FIELD F, W AS N$`, exp: `FIELD F, W AS N$`},
+		{inp: `FIELD #1, 20 N$`, exp: `FIELD #1, 20 AS  N $`, trash: true},
+		{inp: `FIELD #1 20`, exp: `FIELD #1 20`, trash: true},
+		{inp: `GET #1`, exp: `GET #1`},
+		{inp: `GET 2, R + 1`, exp: `GET 2, R + 1`},
+		{inp: `GET #1, 5 6`, exp: `GET #1, 5 6`, trash: true},
+		{inp: `PUT #1, 5`, exp: `PUT #1, 5`},
+		{inp: `PUT #1 5`, exp: `PUT #1 5`, trash: true},
+		{inp: `LSET N$ = "Fred"`, exp: `LSET N$ = "Fred"`},
+		{inp: `RSET A$ = MKI$(5)`, exp: `RSET A$ = MKI$(5)`},
+		{inp: `LSET N$ "Fred"`, exp: `LSET N$ =  "Fred"`, trash: true},
+		{inp: `RSET = "Fred"`, exp: `RSET  =  = "Fred"`, trash: true},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.inp)
+		p := New(l)
+		env := object.NewTermEnvironment(mocks.MockTerm{})
+		p.ParseCmd(env)
+
+		itr := env.CmdLineIter()
+		cmd := itr.Value()
+		assert.Equalf(t, tt.exp, cmd.String(), "%s parsed incorrectly", tt.inp)
+
+		tc, ok := cmd.(ast.TrashCan)
+		assert.True(t, ok)
+		assert.Equalf(t, tt.trash, tc.HasTrash(), "%s trash check failed", tt.inp)
+	}
+}
+
+func Test_SaveCommand(t *testing.T) {
+	tests := []struct {
+		inp    string // command to parse
+		format string // format option expected
+		trash  bool
+	}{
+		{inp: `SAVE "HEWORLD.BAS"`},
+		{inp: `SAVE "HIWORLD.BAS",A`, format: "A"},
+		{inp: `SAVE "HIWORLD.BAS",p`, format: "P"},
+		{inp: `SAVE "HERWORLD.BAS",F`, trash: true},
+		{inp: `SAVE "HERWORLD.BAS",A F`, format: "A", trash: true},
+		{inp: `SAVE "HERWORLD.BAS" F`, trash: true},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.inp)
+		p := New(l)
+		env := object.NewTermEnvironment(mocks.MockTerm{})
+		p.ParseCmd(env)
+
+		itr := env.CmdLineIter()
+		stmt := itr.Value()
+		cmd, ok := stmt.(*ast.SaveCommand)
+
+		if !ok {
+			t.Fatalf("(%s) parse didn't return SaveCommand, got %T instead", tt.inp, stmt)
+		}
+
+		assert.Equal(t, tt.format, cmd.Format, "Format incorrect")
+		assert.Equal(t, tt.trash, cmd.HasTrash(), "%s trash check failed", tt.inp)
+	}
+}
+
+func Test_FileManagementStatements(t *testing.T) {
+	tests := []struct {
+		inp   string // command to parse
+		exp   string // how it should list
+		trash bool
+	}{
+		{inp: `KILL "DATA.TXT"`, exp: `KILL "DATA.TXT"`},
+		{inp: `kill "*.DAT"`, exp: `KILL "*.DAT"`},
+		{inp: `KILL`, exp: `KILL`},
+		{inp: `KILL "DATA.TXT" X`, exp: `KILL "DATA.TXT" X`, trash: true},
+		{inp: `MKDIR "SUB"`, exp: `MKDIR "SUB"`},
+		{inp: `RMDIR "SUB"`, exp: `RMDIR "SUB"`},
+		{inp: `RMDIR "SUB", 5`, exp: `RMDIR "SUB", 5`, trash: true},
+		{inp: `NAME "A.DAT" AS "B.DAT"`, exp: `NAME "A.DAT" AS "B.DAT"`},
+		{inp: `NAME A$ AS B$`, exp: `NAME A$ AS B$`},
+		{inp: `NAME "A.DAT" "B.DAT"`, exp: `NAME "A.DAT" "B.DAT"`, trash: true},
+		{inp: `NAME "A.DAT"`, exp: `NAME "A.DAT"`},
+		{inp: `RESET`, exp: `RESET`},
+		{inp: `RESET 1`, exp: `RESET 1`, trash: true},
+		{inp: `MERGE "SUBS"`, exp: `MERGE "SUBS"`},
+		{inp: `MERGE`, exp: `MERGE`},
+		{inp: `DELETE 100-200`, exp: `DELETE 100-200`},
+		{inp: `DELETE -50`, exp: `DELETE -50`},
+		{inp: `DELETE 300-`, exp: `DELETE 300-`},
+		{inp: `DELETE 40`, exp: `DELETE 40`},
+		{inp: `DELETE`, exp: `DELETE`},
+		{inp: `DELETE 10-20 X`, exp: `DELETE 10-20 X`, trash: true},
+		{inp: `RENUM`, exp: `RENUM`},
+		{inp: `RENUM 100`, exp: `RENUM 100`},
+		{inp: `RENUM 100,,20`, exp: `RENUM 100,,20`},
+		{inp: `RENUM ,50`, exp: `RENUM ,50`},
+		{inp: `RENUM 1000,300,50`, exp: `RENUM 1000,300,50`},
+		{inp: `RENUM 100 X`, exp: `RENUM 100 X`, trash: true},
+		{inp: `EDIT 120`, exp: `EDIT 120`},
+		{inp: `EDIT`, exp: `EDIT`},
+		{inp: `EDIT 120 X`, exp: `EDIT 120 X`, trash: true},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.inp)
+		p := New(l)
+		env := object.NewTermEnvironment(mocks.MockTerm{})
+		p.ParseCmd(env)
+
+		itr := env.CmdLineIter()
+		stmt := itr.Value()
+		tc, ok := stmt.(ast.TrashCan)
+
+		if !ok {
+			t.Fatalf("(%s) parse returned %T, which has no trash can", tt.inp, stmt)
+		}
+
+		assert.Equal(t, tt.exp, stmt.String(), "%s didn't parse correctly", tt.inp)
+		assert.Equal(t, tt.trash, tc.HasTrash(), "%s trash check failed", tt.inp)
+	}
+}
@@ -46,9 +46,11 @@ func (p *Parser) parseNextExpression() ast.Expression {
 func (p *Parser) parseTrash(Trash *[]ast.TrashStatement) {
 
 	for {
-		if !p.atEndOfStatement() {
-			*Trash = append(*Trash, ast.TrashStatement{Token: token.Token{Type: p.curToken.Type, Literal: p.curToken.Literal}})
+		if p.atEndOfStatement() {
+			// don't eat the statements that follow
+			return
 		}
+		*Trash = append(*Trash, ast.TrashStatement{Token: token.Token{Type: p.curToken.Type, Literal: p.curToken.Literal}})
 
 		if p.chkEndOfStatement() {
 			return
@@ -2,6 +2,7 @@ package settings
 
 const (
 	Auto      = "auto"    // is auto line numbering turned on
+	Edit      = "edit"    // program line waiting to be edited
 	ERL       = "erl"     // line number error detected
 	ERR       = "err"     // error code detected
 	KeyMacs   = "keymacs" // all defined func key macros
@@ -1,12 +1,11 @@
 package terminal
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"syscall/js"
 
-	"time"
-
 	"github.com/navionguy/basicwasm/keybuffer"
 )
 
@@ -19,12 +18,17 @@ type Terminal struct {
 
 // New creates a new Terminal object
 func New(t js.Value) *Terminal {
-	env := &Terminal{term: t, kbuff: keybuffer.GetKeyBuffer()}
+	env := &Terminal{term: t, kbuff: keybuffer.New()}
 
 	// t.Call("setOption", "scrollback", 0)
 	return env
 }
 
+// KeyBuffer holds the keys pressed in this terminal
+func (t *Terminal) KeyBuffer() *keybuffer.KeyBuffer {
+	return t.kbuff
+}
+
 // Println prints the string follow by CRLF
 func (t *Terminal) Println(msg string) {
 	t.Print(msg + "\r\n")
@@ -78,19 +82,16 @@ func (t *Terminal) Read(col, row, len int) string {
 	return inp
 }
 
-// ReadKeys reads the requested number of keystrokes
+// ReadKeys waits for the requested number of keystrokes
 func (t *Terminal) ReadKeys(count int) []byte {
 	var keys []byte
 
-	for i := 0; i < count; {
-		bt, err := t.kbuff.ReadByte()
-
+	for len(keys) < count {
+		bt, err := t.kbuff.ReadKey(context.Background())
 		if err != nil {
-			time.Sleep(100 * time.Millisecond)
-		} else {
-			keys = append(keys, bt)
-			i++
+			break
 		}
+		keys = append(keys, bt)
 	}
 
 	/*if len(keys) > 0 {
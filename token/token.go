@@ -27,6 +27,7 @@ const (
 	ASTERISK = "*"
 	SLASH    = "/"
 	BSLASH   = "\\"
+	CARET    = "^"
 
 	LT = "<"
 	GT = ">"
@@ -63,6 +64,7 @@ const (
 	// Keywords
 	ACCESS  = "ACCESS"
 	ALL     = "ALL"
+	AND     = "AND"
 	APPEND  = "APPEND"
 	AS      = "AS"
 	AUTO    = "AUTO"
@@ -81,52 +83,76 @@ const (
 	DEF     = "DEF"
 	DELETE  = "DELETE"
 	DIM     = "DIM"
+	EDIT    = "EDIT"
 	ELSE    = "ELSE"
 	END     = "END"
+	EQV     = "EQV"
 	ERROR   = "ERROR"
 	FALSE   = "FALSE"
+	FIELD   = "FIELD"
 	FILES   = "FILES"
 	FOR     = "FOR"
+	GET     = "GET"
 	GOSUB   = "GOSUB"
 	GOTO    = "GOTO"
 	IF      = "IF"
+	IMP     = "IMP"
 	INKEY   = "INKEY$"
 	INPUT   = "INPUT"
 	KEY     = "KEY"
+	KILL    = "KILL"
 	LEN     = "LEN"
 	LET     = "LET"
+	LINE    = "LINE"
 	LIST    = "LIST"
 	LOAD    = "LOAD"
 	LOCATE  = "LOCATE"
 	LOCK    = "LOCK"
+	LPRINT  = "LPRINT"
+	LSET    = "LSET"
 	MERGE   = "MERGE"
+	MKDIR   = "MKDIR"
 	MOD     = "MOD"
+	NAME    = "NAME"
 	NEW     = "NEW"
 	NEXT    = "NEXT"
+	NOT     = "NOT"
 	OFF     = "OFF"
 	ON      = "ON"
 	OPEN    = "OPEN"
+	OR      = "OR"
 	OUTPUT  = "OUTPUT"
 	PALETTE = "PALETTE"
 	PRINT   = "PRINT"
+	PUT     = "PUT"
 	RANDOM  = "RANDOM"
 	READ    = "READ"
 	REM     = "REM"
+	RENUM   = "RENUM"
+	RESET   = "RESET"
 	RESTORE = "RESTORE"
 	RESUME  = "RESUME"
 	RETURN  = "RETURN"
+	RMDIR   = "RMDIR"
+	RSET    = "RSET"
 	RUN     = "RUN"
+	SAVE    = "SAVE"
 	SCREEN  = "SCREEN"
 	SHARED  = "SHARED"
 	STOP    = "STOP"
+	SYSTEM  = "SYSTEM"
 	THEN    = "THEN"
+	TIMER   = "TIMER"
 	TO      = "TO"
 	TRON    = "TRON"
 	TROFF   = "TROFF"
 	TRUE    = "TRUE"
 	USING   = "USING"
 	VIEW    = "VIEW"
+	WEND    = "WEND"
+	WHILE   = "WHILE"
 	WRITE   = "WRITE"
+	XOR     = "XOR"
 )
 
 type Token struct {
@@ -137,6 +163,7 @@ type Token struct {
 var keywords = map[string]TokenType{
 	"access":  ACCESS,
 	"all":     ALL,
+	"and":     AND,
 	"append":  APPEND,
 	"auto":    AUTO,
 	"as":      AS,
@@ -155,52 +182,76 @@ var keywords = map[string]TokenType{
 	"def":     DEF,
 	"delete":  DELETE,
 	"dim":     DIM,
+	"edit":    EDIT,
 	"else":    ELSE,
 	"end":     END,
+	"eqv":     EQV,
 	"error":   ERROR,
 	"false":   FALSE,
+	"field":   FIELD,
 	"files":   FILES,
 	"for":     FOR,
+	"get":     GET,
 	"gosub":   GOSUB,
 	"goto":    GOTO,
 	"if":      IF,
+	"imp":     IMP,
 	"inkey$":  INKEY,
 	"input":   INPUT,
 	"key":     KEY,
+	"kill":    KILL,
 	//"len":     LEN,
 	"let":     LET,
+	"line":    LINE,
 	"list":    LIST,
 	"load":    LOAD,
 	"locate":  LOCATE,
 	"lock":    LOCK,
+	"lprint":  LPRINT,
+	"lset":    LSET,
 	"merge":   MERGE,
+	"mkdir":   MKDIR,
 	"mod":     MOD,
+	"name":    NAME,
 	"new":     NEW,
 	"next":    NEXT,
+	"not":     NOT,
 	"off":     OFF,
 	"on":      ON,
 	"open":    OPEN,
+	"or":      OR,
 	"output":  OUTPUT,
 	"palette": PALETTE,
 	"print":   PRINT,
+	"put":     PUT,
 	"random":  RANDOM,
 	"read":    READ,
 	"rem":     REM,
+	"renum":   RENUM,
+	"reset":   RESET,
 	"restore": RESTORE,
 	"resume":  RESUME,
 	"return":  RETURN,
+	"rmdir":   RMDIR,
+	"rset":    RSET,
 	"run":     RUN,
+	"save":    SAVE,
 	"screen":  SCREEN,
 	"shared":  SHARED,
 	"stop":    STOP,
+	"system":  SYSTEM,
 	"then":    THEN,
+	"timer":   TIMER,
 	"to":      TO,
 	"tron":    TRON,
 	"troff":   TROFF,
 	"true":    TRUE,
 	"using":   USING,
 	"view":    VIEW,
+	"wend":    WEND,
+	"while":   WHILE,
 	"write":   WRITE,
+	"xor":     XOR,
 }
 
 // LookupIdent returns a TokenType object
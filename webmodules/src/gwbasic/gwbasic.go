@@ -1,11 +1,12 @@
 package main
 
 import (
+	"context"
+	"sync"
 	"syscall/js"
 
 	"github.com/navionguy/basicwasm/ast"
 	"github.com/navionguy/basicwasm/cli"
-	"github.com/navionguy/basicwasm/keybuffer"
 	"github.com/navionguy/basicwasm/object"
 	"github.com/navionguy/basicwasm/settings"
 	"github.com/navionguy/basicwasm/terminal"
@@ -22,12 +23,34 @@ func registerCallbacks() {
 	env := object.NewTermEnvironment(term)
 	env.SaveSetting(settings.ServerURL, &ast.StringLiteral{Value: momma.String()})
 
-	cli.Start(env)
-	env.Terminal().Log("cli started")
+	var mtx sync.Mutex
+	var ses *cli.Session
+
+	// SYSTEM has nowhere to go in the browser, the machine powers back on
+	go func() {
+		for {
+			mtx.Lock()
+			ses = cli.Start(context.Background(), env)
+			mtx.Unlock()
+			env.Terminal().Log("cli started")
+
+			ses.Wait()
+			env.PowerOn()
+		}
+	}()
 
 	js.Global().Set("keyPress", js.FuncOf(func(this js.Value, inputs []js.Value) interface{} {
-		kbuff := keybuffer.GetKeyBuffer()
-		kbuff.SaveKeyStroke([]byte(inputs[0].String()))
+		term.KeyBuffer().SaveKeyStroke([]byte(inputs[0].String()))
+		return nil
+	}))
+
+	// start over without reloading the page
+	js.Global().Set("resetBasic", js.FuncOf(func(this js.Value, inputs []js.Value) interface{} {
+		mtx.Lock()
+		defer mtx.Unlock()
+		if ses != nil {
+			ses.Reset()
+		}
 		return nil
 	}))
 }